@@ -0,0 +1,98 @@
+package simplejsonext
+
+import "unsafe"
+
+const (
+	// Sizes of the chunks an Arena allocates at a time
+	arenaStringChunk = 32 * 1024
+	arenaElemChunk   = 4 * 1024
+)
+
+// Arena holds the memory for the strings and arrays of values parsed with
+// Parser.ParseInArena, so that it can be recycled all at once instead of being
+// garbage collected piece by piece. Objects are still ordinary maps, which
+// the runtime allocates itself.
+//
+// The zero Arena is ready to use. An Arena is not safe for concurrent use.
+//
+// WARNING: Values parsed into an Arena are only valid until the Arena is
+// Reset. After that, their strings and arrays will be overwritten by the next
+// values parsed into it. Building with the simplejsonext_debug tag makes Reset
+// fill the released memory with garbage rather than reusing it, so that values
+// used after Reset are easy to spot.
+type Arena struct {
+	strs  arenaChunks[byte]
+	elems arenaChunks[any]
+}
+
+// Poison left in released arena memory in debug builds.
+type arenaReleased struct{}
+
+const arenaReleasedByte = 0xdd
+
+// Reset releases everything allocated from the arena, which makes all of the
+// values parsed into it invalid.
+func (a *Arena) Reset() {
+	a.strs.reset(arenaReleasedByte)
+	a.elems.reset(arenaReleased{})
+}
+
+// Returns a copy of b that lives in the arena.
+func (a *Arena) string(b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+	s := a.strs.alloc(len(b), arenaStringChunk)
+	copy(s, b)
+	return unsafe.String(unsafe.SliceData(s), len(s))
+}
+
+// Returns a slice of n elements from the arena.
+func (a *Arena) array(n int) []any {
+	return a.elems.alloc(n, arenaElemChunk)
+}
+
+// A list of fixed-size chunks of memory that are handed out in pieces.
+type arenaChunks[T any] struct {
+	chunks [][]T // every chunk allocated, reused after reset
+	cur    int   // index of the chunk being handed out
+	used   int   // how much of the current chunk is handed out
+}
+
+func (c *arenaChunks[T]) alloc(n int, chunkSize int) []T {
+	if n > chunkSize/4 {
+		// Big allocations would waste too much of a chunk.
+		return make([]T, n)
+	}
+	for {
+		if c.cur < len(c.chunks) {
+			if chunk := c.chunks[c.cur]; c.used+n <= len(chunk) {
+				s := chunk[c.used : c.used+n : c.used+n]
+				c.used += n
+				return s
+			}
+			c.cur++
+			c.used = 0
+			continue
+		}
+		c.chunks = append(c.chunks, make([]T, chunkSize))
+	}
+}
+
+func (c *arenaChunks[T]) reset(poison T) {
+	for i := 0; i < len(c.chunks) && i <= c.cur; i++ {
+		if arenaDebug {
+			for j := range c.chunks[i] {
+				c.chunks[i][j] = poison
+			}
+		} else {
+			clear(c.chunks[i]) // don't keep released values alive
+		}
+	}
+	if arenaDebug {
+		// Never reuse released memory, so that it stays poisoned.
+		c.chunks = nil
+	}
+	c.cur = 0
+	c.used = 0
+}
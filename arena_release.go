@@ -0,0 +1,10 @@
+//go:build !simplejsonext_debug
+
+package simplejsonext
+
+// Whether arenas poison released memory instead of reusing it.
+const arenaDebug = false
+
+// Whether the Emitter checks that each AppendJSONExt method appends exactly
+// one well-formed value.
+const emitDebug = false
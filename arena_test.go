@@ -0,0 +1,88 @@
+package simplejsonext
+
+import (
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func inArena(a *Arena, s string) bool {
+	at := uintptr(unsafe.Pointer(unsafe.StringData(s)))
+	for _, chunk := range a.strs.chunks {
+		start := uintptr(unsafe.Pointer(unsafe.SliceData(chunk)))
+		if at >= start && at < start+uintptr(len(chunk)) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestParseInArena(t *testing.T) {
+	big := strings.Repeat("x", arenaStringChunk)
+	doc := `{"name": "run", "tags": ["a", "b\n", ""], "nested": [[1, 2], []], "big": "` + big + `"}`
+	expected, err := UnmarshalString(doc)
+	require.NoError(t, err)
+
+	var a Arena
+	p := NewParserFromString(doc)
+	v, err := p.ParseInArena(&a)
+	require.NoError(t, err)
+	assert.Equal(t, expected, v)
+	assert.Nil(t, p.(*parser).arena, "the arena is only used for one value")
+
+	obj := v.(map[string]any)
+	for key := range obj {
+		assert.True(t, inArena(&a, key), key)
+	}
+	assert.True(t, inArena(&a, obj["name"].(string)))
+	assert.True(t, inArena(&a, obj["tags"].([]any)[1].(string)))
+	assert.False(t, inArena(&a, obj["big"].(string)), "big strings get their own memory")
+	arr := obj["nested"].([]any)[0].([]any)
+	assert.Equal(t, len(arr), cap(arr))
+	assert.Same(t, &a.elems.chunks[0][0], unsafe.SliceData(obj["tags"].([]any)))
+
+	// Many values spill over into more chunks.
+	for i := 0; i < 2000; i++ {
+		p.ResetString(`["` + strings.Repeat("y", 100) + `", 1, 2, 3]`)
+		v, err := p.ParseInArena(&a)
+		require.NoError(t, err)
+		assert.Equal(t, strings.Repeat("y", 100), v.([]any)[0])
+	}
+	assert.Greater(t, len(a.strs.chunks), 1)
+	assert.Greater(t, len(a.elems.chunks), 1)
+
+	// After a reset, the memory is recycled for new values.
+	a.Reset()
+	p.ResetString(`["after reset"]`)
+	v, err = p.ParseInArena(&a)
+	require.NoError(t, err)
+	assert.Equal(t, []any{"after reset"}, v)
+	if !arenaDebug {
+		assert.Same(t, &a.elems.chunks[0][0], unsafe.SliceData(v.([]any)))
+		for _, chunk := range a.elems.chunks[1:] {
+			for _, e := range chunk {
+				assert.Nil(t, e, "released values are not kept alive")
+			}
+		}
+	}
+}
+
+func TestArenaReleasedValues(t *testing.T) {
+	var a Arena
+	v, err := NewParserFromString(`["hello", {"k": "v"}]`).ParseInArena(&a)
+	require.NoError(t, err)
+	arr := v.([]any)
+	s := arr[0].(string)
+	a.Reset()
+	if arenaDebug {
+		assert.Equal(t, strings.Repeat("\xdd", len("hello")), s)
+		assert.Equal(t, arenaReleased{}, arr[0])
+		_, err := Marshal(arr)
+		assert.ErrorContains(t, err, "arenaReleased")
+	} else {
+		assert.Nil(t, arr[0])
+	}
+}
@@ -0,0 +1,70 @@
+package simplejsonext
+
+import (
+	"fmt"
+)
+
+func (p *parser) ParseFloatArray() (arr []float64, err error) {
+	defer recoverPanic(&err)
+	ty, err := p.parseType()
+	if err != nil {
+		return nil, err
+	}
+	if ty != arrayTy {
+		if kind := kindOf(ty); kind != KindInvalid {
+			if err = p.Skip(); err != nil {
+				return nil, err
+			}
+			return nil, fmt.Errorf("simple json: expected an array but found %s", kind)
+		}
+		// A stray comma or closing bracket
+		return nil, p.readByte('[')
+	}
+	p.begin++
+	for {
+		if ty, err = p.parseType(); err != nil {
+			return nil, err
+		}
+		if ty == endGroupSym {
+			if err = p.readByte(']'); err != nil {
+				return nil, err
+			}
+			if arr == nil {
+				arr = []float64{}
+			}
+			return arr, nil
+		} else if len(arr) == 0 {
+			if ty == commaSym {
+				return nil, errUnexpectedComma
+			}
+		} else {
+			if err = p.consumeComma(ty); err != nil {
+				return nil, err
+			}
+			if ty, err = p.parseType(); err != nil {
+				return nil, err
+			}
+		}
+		if err = p.checkCount(len(arr), false); err != nil {
+			return nil, err
+		}
+		if ty != numberTy {
+			switch kind := kindOf(ty); {
+			case kind != KindInvalid:
+				return nil, fmt.Errorf("simple json: expected a number at index %d but found %s", len(arr), kind)
+			case ty == commaSym:
+				return nil, errUnexpectedComma
+			default:
+				return nil, errUnexpectedEnd
+			}
+		}
+		i, f, isFloat, err := p.scanNumber()
+		if err != nil {
+			return nil, err
+		}
+		if !isFloat {
+			f = float64(i)
+		}
+		arr = append(arr, f)
+	}
+}
@@ -0,0 +1,80 @@
+package simplejsonext
+
+import (
+	"io"
+	"math"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFloatArray(t *testing.T) {
+	const doc = ` [1, -2.5, 1e3 ,-0, NaN, -Infinity, 9223372036854775808, 12345678901234567890123, 1e999, 0.1] [] [7]`
+	for name, p := range map[string]Parser{
+		"slice":    NewParserFromString(doc),
+		"one byte": NewParser(iotest.OneByteReader(strings.NewReader(doc))),
+	} {
+		t.Run(name, func(t *testing.T) {
+			generic, err := NewParserFromString(doc).Parse()
+			require.NoError(t, err)
+			arr, err := p.ParseFloatArray()
+			require.NoError(t, err)
+			require.Len(t, arr, len(generic.([]any)))
+			for i, v := range generic.([]any) {
+				expected, ok := v.(float64)
+				if !ok {
+					expected = float64(v.(int64))
+				}
+				assert.Equal(t, math.Float64bits(expected), math.Float64bits(arr[i]), "index %d", i)
+			}
+
+			arr, err = p.ParseFloatArray()
+			require.NoError(t, err)
+			assert.Equal(t, []float64{}, arr)
+			arr, err = p.ParseFloatArray()
+			require.NoError(t, err)
+			assert.Equal(t, []float64{7}, arr)
+			_, err = p.ParseFloatArray()
+			assert.Equal(t, io.EOF, err)
+		})
+	}
+}
+
+func TestParseFloatArrayErrors(t *testing.T) {
+	for _, test := range []struct {
+		doc string
+		err string
+	}{
+		{`[1, 2, "3"]`, "simple json: expected a number at index 2 but found string"},
+		{`[null]`, "simple json: expected a number at index 0 but found null"},
+		{`[1, [2]]`, "simple json: expected a number at index 1 but found array"},
+		{`{"a": [1]}`, "simple json: expected an array but found object"},
+		{`1`, "simple json: expected an array but found number"},
+		{`[1,]`, errUnexpectedEnd.Error()},
+		{`[,1]`, errUnexpectedComma.Error()},
+		{`[1,,2]`, errUnexpectedComma.Error()},
+		{`]`, "simple json: expected '[' but found ']'"},
+		{`[1 2]`, "simple json: expected ',' but found '2'"},
+		{`[1, 2`, io.EOF.Error()},
+		{`[1, 2x]`, "simple json: expected token but found 'x'"},
+	} {
+		_, err := NewParserFromString(test.doc).ParseFloatArray()
+		assert.EqualError(t, err, test.err, test.doc)
+	}
+
+	_, err := NewParserFromString(`[1, NaN]`, WithDeNaN(DeNaNError)).ParseFloatArray()
+	assert.EqualError(t, err, `simple json: non-finite number "NaN"`)
+	_, err = NewParserFromString(`[1, 1e999]`, WithStrictNumbers()).ParseFloatArray()
+	assert.EqualError(t, err, `simple json: number "1e999" out of range`)
+
+	// A value that is not an array is consumed.
+	p := NewParserFromString(`{"a": [1]} [2]`)
+	_, err = p.ParseFloatArray()
+	assert.Error(t, err)
+	arr, err := p.ParseFloatArray()
+	require.NoError(t, err)
+	assert.Equal(t, []float64{2}, arr)
+}
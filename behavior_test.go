@@ -10,8 +10,12 @@ import (
 	"strings"
 	"sync"
 	"testing"
+	"testing/iotest"
+	"time"
+	"unicode/utf8"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/wandb/simplejsonext"
 )
@@ -39,8 +43,6 @@ type options struct {
 	// omitting the decimal point, which can cause them to round-trip to the
 	// same value in an integer type rather than the original floating point.
 	tolerateFloatToIntRoundTrip bool
-	// If set, do not require empty map values to have the same null-ness
-	tolerateMapNil bool
 }
 
 // Test equality between two values, including understanding NaNs and checking
@@ -60,7 +62,8 @@ func floatIntEqual(f float64, i int64) bool {
 // Deep equality for our supported types, with nuances.
 //
 // The only types supported are: nil, bool, string, int64, float64, []any,
-// map[string]any, and map[any]any.
+// map[string]any, and *simplejsonext.OrderedObject. Ordered objects are only
+// equal when their members appear in the same order.
 //
 // We optionally tolerate expected float64 values being found as int64, including
 // in map keys.
@@ -119,7 +122,7 @@ func equalImpl(expected any, actual any, opt options) error {
 			if len(av) > len(ev) {
 				return errors.New("more object keys than expected")
 			}
-			if opt.tolerateMapNil && (av == nil) != (ev == nil) {
+			if (av == nil) != (ev == nil) {
 				return errors.New("one object, but not both, are nil")
 			}
 			for k, wantV := range ev {
@@ -133,6 +136,28 @@ func equalImpl(expected any, actual any, opt options) error {
 			}
 			return nil
 		}
+	case *simplejsonext.OrderedObject:
+		if av, ok := actual.(*simplejsonext.OrderedObject); ok {
+			if (av == nil) != (ev == nil) {
+				return errors.New("one ordered object, but not both, are nil")
+			}
+			if ev == nil {
+				return nil
+			}
+			if len(ev.Members) != len(av.Members) {
+				return errors.New("ordered objects have different lengths")
+			}
+			for i := range ev.Members {
+				if ev.Members[i].Key != av.Members[i].Key {
+					return fmt.Errorf("expected key %#v but found %#v at index %d",
+						ev.Members[i].Key, av.Members[i].Key, i)
+				}
+				if err := equalImpl(ev.Members[i].Value, av.Members[i].Value, opt); err != nil {
+					return fmt.Errorf("%s at key %#v", err, ev.Members[i].Key)
+				}
+			}
+			return nil
+		}
 	}
 	return fmt.Errorf("expected %[1]T(%#[1]v) but got %[2]T(%#[2]v)", expected, actual)
 }
@@ -240,6 +265,7 @@ var (
 		{`9223372036854775808`, float64(9223372036854775808)},
 		{`-9223372036854775809`, float64(-9223372036854775809)},
 		{`-0.0`, negativeZero},
+		{`-0`, negativeZero},
 		{`.1`, errors.New("simple json: expected token but found '.'")},
 		{`"foo"`, "foo"},
 		// UTF-16 escapes
@@ -276,6 +302,48 @@ var (
 				0.9279897927636401,
 			},
 		},
+		// Boundaries of exact fast-path float parsing: the largest exactly
+		// representable mantissa and power of ten, one past each, and halfway
+		// cases between adjacent floats that must round to even
+		{`[
+				9007199254740992.0,
+				9007199254740993.0,
+				9007199254740995.0,
+				1e22,
+				1e23,
+				9007199254740992e22,
+				9007199254740992e-22,
+				1e-22,
+				1e-23,
+				0.000000000000000000000123456789,
+				2.2250738585072011e-308,
+				2.2250738585072012e-308,
+				4.9406564584124654e-324,
+				1.7976931348623157e308,
+				0.1,
+				0.30000000000000004,
+				-123456789012.5e-3
+			]`,
+			[]any{
+				9007199254740992.0,
+				9007199254740992.0,
+				9007199254740996.0,
+				1e22,
+				1e23,
+				9007199254740992e22,
+				9007199254740992e-22,
+				1e-22,
+				1e-23,
+				1.23456789e-22,
+				2.225073858507201e-308,
+				2.2250738585072014e-308,
+				5e-324,
+				1.7976931348623157e308,
+				0.1,
+				0.30000000000000004,
+				-123456789.0125,
+			},
+		},
 		// Unpaired surrogates become replacement characters
 		{`"\ud83ddca5"`, "\ufffddca5"},
 		// Each unpaired surrogate gets its own replacement character without
@@ -301,7 +369,7 @@ var (
 		{`+1000000000000000000`, errors.New("simple json: expected token but found '+'")},
 		{`+123`, errors.New("simple json: expected token but found '+'")},
 		// Objects
-		{`{}`, map[string]any(nil)},
+		{`{}`, map[string]any{}},
 		{`{1.0: "a", false: true, null: 1, -Infinity: []}`, errors.New("simple json: expected '\"' but found '1'")},
 		{`{"x": true,`, io.EOF},
 		{"\"\t\"", errors.New("simple json: control character, tab, or newline in string value")},
@@ -323,8 +391,9 @@ var (
 		{`{"1": 1]`, errors.New("simple json: expected '}' but found ']'")},
 		{`[1}`, errors.New("simple json: expected ']' but found '}'")},
 	}
-	// Corner cases currently handled only by the standard library
-	standardOnlyCornerCases = []jsonCase{
+	// Invalid UTF-8, as handled by the standard library, and by the ext parser
+	// with WithReplaceInvalidUTF8
+	invalidUTF8Cases = []jsonCase{
 		// Invalid UTF-8 becomes all replacement characters for every wonky byte
 		// UTF-8 has certain bytes that never appear and cannot encode surrogates
 		//    " U+dc00      U+d800    "
@@ -369,6 +438,7 @@ var (
 		{`01`, errors.New("extra data")},
 		{`02.3`, errors.New("extra data")},
 		{`-01`, errors.New("extra data")},
+		{`-00`, errors.New("extra data")},
 		// Other trailing data cases
 		{`1,2`, errors.New("extra data")},
 		{`[1]2`, errors.New("extra data")},
@@ -387,6 +457,7 @@ var (
 		{`01`, float64(0)},
 		{`02.3`, float64(0)},
 		{`-01`, negativeZero},
+		{`-00`, negativeZero},
 		// The standard library parser ignored trailing data when streaming, but
 		// parses some values somewhat differently
 		{`1,2`, float64(1)},
@@ -422,6 +493,8 @@ var (
 		{`01`, int64(1)},
 		{`02.3`, float64(2.3)},
 		{`-01`, int64(-1)},
+		// Negative zero keeps its sign as a float64, however many zeros it has
+		{`-00`, negativeZero},
 		// Trailing decimal points are allowed
 		{`1.`, float64(1)},
 		{`1.e1`, float64(10)},
@@ -433,10 +506,13 @@ var (
 		{`5e1a892d`, errors.New("strconv.ParseFloat: parsing \"5e1a892\": invalid syntax")},
 		{`5e1f892d`, errors.New("strconv.ParseFloat: parsing \"5e1f892\": invalid syntax")},
 		{`NaNa`, errors.New("strconv.ParseFloat: parsing \"NaNa\": invalid syntax")},
-		// Invalid UTF-8 is not detected. Currently the ext parser does not
-		// do any introspection of the validitiy of the UTF-8 text.
-		// Understanding multi-byte codepoints is never required for parsing
-		// valid data and adds additional cost.
+		{nestedArrayJSON(501), errors.New("simple json: maximum nesting depth exceeded: depth 501 at offset 501")},
+	}
+	// The invalidUTF8Cases, as the ext parser handles them by default
+	simpleInvalidUTF8Cases = []jsonCase{
+		// Invalid UTF-8 is not detected unless WithReplaceInvalidUTF8 is
+		// given. Understanding multi-byte codepoints is never required for
+		// parsing valid data and adds additional cost.
 		//   "  U+dc00      U+d800    "
 		{"\"\xed\xb0\x80\xed\xa0\x80\"", "\xed\xb0\x80\xed\xa0\x80"},
 		// invalid UTF-8 byte
@@ -444,7 +520,6 @@ var (
 		// Overlong encoding is also passed through (this is the 3-byte overlong
 		// encoding of the nul character and the character '!')
 		{"\"\xe0\x80\x80\xe0\x80\xa1\"", "\xe0\x80\x80\xe0\x80\xa1"},
-		{nestedArrayJSON(501), errors.New("simple json: maximum nesting depth exceeded")},
 	}
 	simpleCasesUnmarshaling = []jsonCase{
 		// Errors on all data after a top-level value has ended
@@ -470,7 +545,21 @@ var (
 		{`NaNvvvvv`, math.NaN()},
 		{`Infrared`, math.Inf(1)},
 		// Empty map with trailing data
-		{`{}foobar`, map[string]any(nil)},
+		{`{}foobar`, map[string]any{}},
+	}
+	// The streaming cases, with WithStrictNumberEnds
+	simpleCasesStreamingStrictNumbers = []jsonCase{
+		// Still ignores all data after a top-level value has ended
+		{`1,2`, int64(1)},
+		{`[1]2`, []any{int64(1)}},
+		{`"foo"{}bar`, "foo"},
+		{`{}foobar`, map[string]any{}},
+		// ...except when it runs into a number
+		{`123zfoo456bar`, errors.New("simple json: expected end of number but found 'z'")},
+		{`5e1b892d`, errors.New("simple json: expected end of number but found 'b'")},
+		{`0xff`, errors.New("simple json: expected end of number but found 'x'")},
+		{`NaNvvvvv`, errors.New(`simple json: invalid token "NaNvvvvv"`)},
+		{`Infrared`, errors.New(`simple json: invalid token "Infrared"`)},
 	}
 )
 
@@ -480,12 +569,11 @@ func TestExtUnmarshalBehavior(t *testing.T) {
 		testBehavior(t, json.Unmarshal, json.Marshal,
 			options{
 				tolerateDifferentErrorMessages: true,
-				tolerateMapNil:                 true,
 			},
 			standardCases,
 			standardBehaviorForExtCases,
 			standardBehaviorForExtCasesUnmarshaling,
-			standardOnlyCornerCases,
+			invalidUTF8Cases,
 		)
 	})
 	t.Run("stdlib parser streaming", func(t *testing.T) {
@@ -500,12 +588,11 @@ func TestExtUnmarshalBehavior(t *testing.T) {
 		testBehavior(t, streamUnmarshal, streamMarshal,
 			options{
 				tolerateDifferentErrorMessages: true,
-				tolerateMapNil:                 true,
 			},
 			standardCases,
 			standardBehaviorForExtCases,
 			standardBehaviorForExtCasesStreaming,
-			standardOnlyCornerCases,
+			invalidUTF8Cases,
 		)
 	})
 	t.Run("simple jsonext parser", func(t *testing.T) {
@@ -517,6 +604,30 @@ func TestExtUnmarshalBehavior(t *testing.T) {
 			options{tolerateFloatToIntRoundTrip: true},
 			standardCases,
 			simpleCases,
+			simpleInvalidUTF8Cases,
+			simpleCasesUnmarshaling,
+		)
+	})
+	t.Run("simple jsonext v2-style", func(t *testing.T) {
+		unmarshalRead := func(data []byte, dest any) error {
+			err := simplejsonext.UnmarshalRead(bytes.NewReader(data), dest)
+			// The cases expect the error without the offset, which
+			// TestUnmarshalRead checks.
+			if errors.Is(err, simplejsonext.ErrTrailingData) {
+				return simplejsonext.ErrTrailingData
+			}
+			return err
+		}
+		marshalWrite := func(v any) ([]byte, error) {
+			var b bytes.Buffer
+			err := simplejsonext.MarshalWrite(&b, v)
+			return b.Bytes(), err
+		}
+		testBehavior(t, unmarshalRead, marshalWrite,
+			options{tolerateFloatToIntRoundTrip: true},
+			standardCases,
+			simpleCases,
+			simpleInvalidUTF8Cases,
 			simpleCasesUnmarshaling,
 		)
 	})
@@ -536,7 +647,288 @@ func TestExtUnmarshalBehavior(t *testing.T) {
 			options{tolerateFloatToIntRoundTrip: true},
 			standardCases,
 			simpleCases,
+			simpleInvalidUTF8Cases,
 			simpleCasesStreaming,
 		)
 	})
+	t.Run("simple jsonext parser replacing invalid UTF-8", func(t *testing.T) {
+		unmarshalReplacing := func(b []byte, dest any) (err error) {
+			*(dest.(*any)), err = simplejsonext.UnmarshalWithOptions(b, simplejsonext.WithReplaceInvalidUTF8())
+			return
+		}
+		testBehavior(t, unmarshalReplacing, simplejsonext.Marshal,
+			options{tolerateFloatToIntRoundTrip: true},
+			standardCases,
+			simpleCases,
+			simpleCasesUnmarshaling,
+			invalidUTF8Cases,
+		)
+	})
+	t.Run("simple jsonext parser streaming replacing invalid UTF-8", func(t *testing.T) {
+		streamUnmarshal := func(data []byte, dest any) (err error) {
+			p := simplejsonext.NewParser(iotest.OneByteReader(bytes.NewReader(data)), simplejsonext.WithReplaceInvalidUTF8())
+			*(dest.(*any)), err = p.Parse()
+			return
+		}
+		testBehavior(t, streamUnmarshal, simplejsonext.Marshal,
+			options{tolerateFloatToIntRoundTrip: true},
+			standardCases,
+			simpleCases,
+			simpleCasesStreaming,
+			invalidUTF8Cases,
+		)
+	})
+	t.Run("simple jsonext parser streaming strict number ends", func(t *testing.T) {
+		streamUnmarshal := func(data []byte, dest any) (err error) {
+			p := simplejsonext.NewParser(bytes.NewBuffer(data), simplejsonext.WithStrictNumberEnds())
+			*(dest.(*any)), err = p.Parse()
+			return
+		}
+		testBehavior(t,
+			streamUnmarshal,
+			simplejsonext.Marshal,
+			options{tolerateFloatToIntRoundTrip: true},
+			standardCases,
+			simpleCases,
+			simpleInvalidUTF8Cases,
+			simpleCasesStreamingStrictNumbers,
+		)
+	})
+}
+
+// Skip must accept and reject exactly the same data as Parse.
+func TestSkipBehavior(t *testing.T) {
+	for _, cc := range [][]jsonCase{standardCases, simpleCases, simpleInvalidUTF8Cases, simpleCasesUnmarshaling, simpleCasesStreaming} {
+		for _, c := range cc {
+			testName := c.s
+			if len(testName) > 100 {
+				testName = testName[:100]
+			}
+			t.Run(testName, func(t *testing.T) {
+				p := simplejsonext.NewParserFromString(c.s)
+				_, parseErr := p.Parse()
+				if parseErr == nil {
+					parseErr = p.CheckEmpty()
+				}
+				for _, p := range []simplejsonext.Parser{
+					simplejsonext.NewParserFromString(c.s),
+					simplejsonext.NewParser(strings.NewReader(c.s)),
+				} {
+					skipErr := p.Skip()
+					if skipErr == nil {
+						skipErr = p.CheckEmpty()
+					}
+					assert.Equal(t, parseErr, skipErr)
+				}
+			})
+		}
+	}
+}
+
+func TestEstimateMarshalSize(t *testing.T) {
+	for _, cc := range [][]jsonCase{standardCases, simpleCases, simpleInvalidUTF8Cases, simpleCasesUnmarshaling, simpleCasesStreaming} {
+		for _, c := range cc {
+			if _, isErr := c.v.(error); isErr {
+				continue
+			}
+			v, err := simplejsonext.NewParserFromString(c.s).Parse()
+			if err != nil {
+				continue
+			}
+			marshaled, err := simplejsonext.Marshal(v)
+			if assert.NoError(t, err) {
+				assert.Equal(t, len(marshaled), simplejsonext.EstimateMarshalSize(v), c.s)
+			}
+		}
+	}
+
+	var nilPtr *int
+	answer := 42
+	for _, v := range []any{
+		int32(-7), int(12345), uint64(math.MaxUint64), uint32(3), uint(0),
+		float32(0.1), float32(math.Inf(-1)), float64(1e21), negativeZero,
+		[]byte("hello bytes"), []byte(nil), []any(nil), map[string]any(nil),
+		time.Date(2024, 2, 3, 4, 5, 6, 789, time.UTC),
+		time.Date(2024, 2, 3, 4, 5, 6, 0, time.FixedZone("x", 3600)),
+		errors.New("oh \"no\"\n"),
+		nilPtr, &answer, []string{"a", "b\tc"}, []int{}, map[string]int{"x": 1, "y\x01": 2},
+		&simplejsonext.OrderedObject{Members: []simplejsonext.Member{{Key: "k", Value: []any{1.5, nil}}}},
+		simplejsonext.OrderedObject{},
+		(*simplejsonext.OrderedObject)(nil),
+	} {
+		marshaled, err := simplejsonext.Marshal(v)
+		if assert.NoError(t, err) {
+			assert.Equal(t, len(marshaled), simplejsonext.EstimateMarshalSize(v), "%#v", v)
+		}
+	}
+
+	for _, v := range []any{
+		complex(1, 2),
+		map[int]any{1: 2},
+		[]any{1, complex(1, 2)},
+		map[string]any{"a": make(chan int)},
+	} {
+		_, err := simplejsonext.Marshal(v)
+		assert.Error(t, err)
+		assert.Equal(t, -1, simplejsonext.EstimateMarshalSize(v))
+	}
+}
+
+// A reader that returns at most size bytes from each Read, and optionally
+// io.EOF along with the last of its data.
+type chunkReader struct {
+	data       string
+	size       int
+	eofWithEnd bool
+}
+
+func (r *chunkReader) Read(b []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(b[:min(len(b), r.size)], r.data)
+	r.data = r.data[n:]
+	if len(r.data) == 0 && r.eofWithEnd {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Parses up to three values in a row, recording each value or error.
+func parseSeveral(p simplejsonext.Parser) (results []any) {
+	for i := 0; i < 3; i++ {
+		v, err := p.Parse()
+		if err != nil {
+			return append(results, err.Error())
+		}
+		results = append(results, v)
+	}
+	return results
+}
+
+// Skips up to three values in a row, recording any error.
+func skipSeveral(p simplejsonext.Parser) (results []any) {
+	for i := 0; i < 3; i++ {
+		if err := p.Skip(); err != nil {
+			return append(results, err.Error())
+		}
+		results = append(results, nil)
+	}
+	return results
+}
+
+// Reads up to 50 tokens, recording each token or error.
+func tokenizeSeveral(p simplejsonext.Parser) (results []any) {
+	for i := 0; i < 50; i++ {
+		tok, err := p.StdToken()
+		if err != nil {
+			return append(results, err.Error())
+		}
+		if d, ok := tok.(json.Delim); ok {
+			tok = d.String()
+		}
+		results = append(results, tok)
+	}
+	return results
+}
+
+// The reader-backed parser must not care how its input is split into reads.
+func TestChunkedReaderBehavior(t *testing.T) {
+	for _, cc := range [][]jsonCase{standardCases, simpleCases, simpleInvalidUTF8Cases, simpleCasesUnmarshaling, simpleCasesStreaming} {
+		for _, c := range cc {
+			testName := c.s
+			if len(testName) > 100 {
+				testName = testName[:100]
+			}
+			t.Run(testName, func(t *testing.T) {
+				for name, read := range map[string]func(simplejsonext.Parser) []any{
+					"parse": parseSeveral,
+					"skip":  skipSeveral,
+					"token": tokenizeSeveral,
+				} {
+					expected := read(simplejsonext.NewParserFromString(c.s))
+					for size := 1; size <= 16; size++ {
+						for _, eofWithEnd := range []bool{false, true} {
+							r := &chunkReader{data: c.s, size: size, eofWithEnd: eofWithEnd}
+							actual := read(simplejsonext.NewParser(r))
+							if err := equalImpl(expected, actual, options{}); err != nil {
+								t.Fatalf("%s in chunks of %d, EOF with data %v: %s: expected %#v, got %#v",
+									name, size, eofWithEnd, err, expected, actual)
+							}
+						}
+					}
+				}
+			})
+		}
+	}
+}
+
+// The Emitter's handling of the strings in the invalid UTF-8 corpus.
+func TestEmitInvalidUTF8Behavior(t *testing.T) {
+	marshal := func(v any, opts ...simplejsonext.EmitOption) (string, error) {
+		var b bytes.Buffer
+		err := simplejsonext.MarshalWrite(&b, v, opts...)
+		return b.String(), err
+	}
+	replace := simplejsonext.WithInvalidUTF8(simplejsonext.InvalidUTF8Replace)
+	fail := simplejsonext.WithInvalidUTF8(simplejsonext.InvalidUTF8Fail)
+	require.Equal(t, len(invalidUTF8Cases), len(simpleInvalidUTF8Cases))
+	for i, c := range simpleInvalidUTF8Cases {
+		require.Equal(t, invalidUTF8Cases[i].s, c.s)
+		raw, replaced := c.v.(string), invalidUTF8Cases[i].v.(string)
+
+		// By default the bytes pass through.
+		out, err := marshal(raw)
+		require.NoError(t, err)
+		assert.Equal(t, c.s, out)
+
+		// Replacing leaves the valid runes around them alone, including with
+		// escaping.
+		out, err = marshal("é"+raw+"世<", replace)
+		require.NoError(t, err)
+		assert.Equal(t, `"é`+replaced+`世<"`, out)
+		assert.True(t, utf8.ValidString(out))
+		out, err = marshal(raw+"\u2028>", replace, simplejsonext.WithEscapeHTML())
+		require.NoError(t, err)
+		assert.Equal(t, `"`+replaced+`\u2028\u003e"`, out)
+		var std string
+		require.NoError(t, json.Unmarshal([]byte(c.s), &std))
+		assert.Equal(t, std, replaced)
+
+		// Failing says where, for values and keys, in every kind of array
+		// and object.
+		for _, test := range []struct {
+			v    any
+			path []any
+			key  bool
+		}{
+			{raw, nil, false},
+			{"ok" + raw, nil, false},
+			{map[string]any{"a": []any{"ok", raw}}, []any{"a", 1}, false},
+			{map[string]any{raw: 1}, []any{raw}, true},
+			{[]map[string]string{{}, {"b": raw}}, []any{1, "b"}, false},
+			{map[string][]string{raw: {}}, []any{raw}, true},
+			{&simplejsonext.OrderedObject{Members: []simplejsonext.Member{{Key: "x"}, {Key: "y", Value: []any{raw}}}}, []any{"y", 0}, false},
+			{&simplejsonext.OrderedObject{Members: []simplejsonext.Member{{Key: raw}}}, []any{raw}, true},
+		} {
+			offset := 0
+			if s, ok := test.v.(string); ok {
+				offset = len(s) - len(raw)
+			}
+			_, err := marshal(test.v, fail)
+			assert.Equal(t, &simplejsonext.InvalidUTF8Error{Path: test.path, Key: test.key, Offset: offset}, err)
+		}
+	}
+
+	// Paths are found through values too deep to emit recursively.
+	deep := any(map[string]any{"k": "\xff"})
+	var path []any
+	for i := 0; i < 1500; i++ {
+		deep = []any{nil, deep}
+		path = append(path, 1)
+	}
+	_, err := marshal(deep, fail)
+	assert.Equal(t, &simplejsonext.InvalidUTF8Error{Path: append(path, "k")}, err)
+	assert.EqualError(t, &simplejsonext.InvalidUTF8Error{Path: []any{"a", 1}, Offset: 2},
+		`simple json: invalid UTF-8 at byte 2 of the string at $["a"][1]`)
 }
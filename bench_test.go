@@ -0,0 +1,455 @@
+package simplejsonext_test
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/wandb/simplejsonext"
+)
+
+// An array of objects with many short, escape-free strings.
+var stringHeavyDoc = func() []byte {
+	var sb strings.Builder
+	sb.WriteByte('[')
+	for i := 0; i < 1000; i++ {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		fmt.Fprintf(&sb, `{"name": "run-%d", "state": "finished", "user": "someone", "tags": ["a", "b", "c"]}`, i)
+	}
+	sb.WriteByte(']')
+	return []byte(sb.String())
+}()
+
+func BenchmarkParseStrings(b *testing.B) {
+	for _, bench := range []struct {
+		name string
+		opts []simplejsonext.ParseOption
+	}{
+		{"copied", nil},
+		{"zero copy", []simplejsonext.ParseOption{simplejsonext.WithZeroCopyStrings()}},
+		{"interned keys", []simplejsonext.ParseOption{simplejsonext.WithKeyInterning()}},
+		{"unsafe", []simplejsonext.ParseOption{simplejsonext.WithUnsafeStrings()}},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(stringHeavyDoc)))
+			p := simplejsonext.NewParserFromSlice(stringHeavyDoc, bench.opts...)
+			for i := 0; i < b.N; i++ {
+				p.ResetSlice(stringHeavyDoc)
+				if _, err := p.Parse(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// An array of objects whose strings all need unescaping, plus literals.
+var escapeHeavyDoc = func() []byte {
+	var sb strings.Builder
+	sb.WriteByte('[')
+	for i := 0; i < 1000; i++ {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		fmt.Fprintf(&sb, `{"path\/%d": "line\none\ttab \"quoted\" é💥", "ok": true, "none": null}`, i)
+	}
+	sb.WriteByte(']')
+	return []byte(sb.String())
+}()
+
+func BenchmarkParseEscapes(b *testing.B) {
+	for _, bench := range []struct {
+		name string
+		opts []simplejsonext.ParseOption
+	}{
+		{"copied", nil},
+		{"unsafe", []simplejsonext.ParseOption{simplejsonext.WithUnsafeStrings()}},
+		{"raw", []simplejsonext.ParseOption{simplejsonext.WithRawStrings()}},
+		{"raw zero copy", []simplejsonext.ParseOption{simplejsonext.WithRawStrings(), simplejsonext.WithZeroCopyStrings()}},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(escapeHeavyDoc)))
+			p := simplejsonext.NewParserFromSlice(escapeHeavyDoc, bench.opts...)
+			for i := 0; i < b.N; i++ {
+				p.ResetSlice(escapeHeavyDoc)
+				if _, err := p.Parse(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkParseLongStrings(b *testing.B) {
+	for _, bench := range []struct {
+		name string
+		doc  string
+	}{
+		{"escape free", `"` + strings.Repeat("abcdefghijklmnopqrstuvwxyz é 0123456789 ", 2000) + `"`},
+		{"escape dense", `"` + strings.Repeat(`a\nb\t\"c\\é`, 2000) + `"`},
+		{"late escape", `"` + strings.Repeat("abcdefghijklmnopqrstuvwxyz é 0123456789 ", 2000) + `\n"`},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(bench.doc)))
+			p := simplejsonext.NewParserFromString(bench.doc)
+			for i := 0; i < b.N; i++ {
+				p.ResetString(bench.doc)
+				if _, err := p.Parse(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// A large pretty-printed document that is mostly structure and whitespace.
+var prettyDoc = func() []byte {
+	var sb strings.Builder
+	sb.WriteString("[\n")
+	for i := 0; i < 2000; i++ {
+		if i > 0 {
+			sb.WriteString(",\n")
+		}
+		fmt.Fprintf(&sb, "  {\n    \"step\": %d,\n    \"values\": [\n      %d,\n      %d,\n      %d\n    ],\n    \"ok\": true\n  }", i, i%7, i%13, i%100)
+	}
+	sb.WriteString("\n]\n")
+	return []byte(sb.String())
+}()
+
+func BenchmarkParsePretty(b *testing.B) {
+	b.ReportAllocs()
+	b.SetBytes(int64(len(prettyDoc)))
+	p := simplejsonext.NewParserFromSlice(prettyDoc)
+	for i := 0; i < b.N; i++ {
+		p.ResetSlice(prettyDoc)
+		if _, err := p.Parse(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// A large array of numbers, and many small arrays.
+var (
+	numericArrayDoc = func() []byte {
+		var sb strings.Builder
+		sb.WriteByte('[')
+		for i := 0; i < 100000; i++ {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			fmt.Fprintf(&sb, "%d.%d", i, i%10)
+		}
+		sb.WriteByte(']')
+		return []byte(sb.String())
+	}()
+	smallArraysDoc = []byte("[" + strings.TrimSuffix(strings.Repeat("[1,2,3],", 10000), ",") + "]")
+)
+
+func BenchmarkParseArrays(b *testing.B) {
+	for _, bench := range []struct {
+		name string
+		doc  []byte
+	}{
+		{"large numeric", numericArrayDoc},
+		{"many small", smallArraysDoc},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(bench.doc)))
+			p := simplejsonext.NewParserFromSlice(bench.doc)
+			for i := 0; i < b.N; i++ {
+				p.ResetSlice(bench.doc)
+				if _, err := p.Parse(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// Arrays of small counters, as found in histograms and step logs.
+var smallIntsDoc = func() []byte {
+	var sb strings.Builder
+	sb.WriteByte('[')
+	for i := 0; i < 10000; i++ {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		fmt.Fprintf(&sb, "[%d, %d, %d, %d]", i%1000, -(i % 100), i%2, (i*7)%300)
+	}
+	sb.WriteByte(']')
+	return []byte(sb.String())
+}()
+
+func BenchmarkParseSmallInts(b *testing.B) {
+	b.ReportAllocs()
+	b.SetBytes(int64(len(smallIntsDoc)))
+	p := simplejsonext.NewParserFromSlice(smallIntsDoc)
+	for i := 0; i < b.N; i++ {
+		p.ResetSlice(smallIntsDoc)
+		if _, err := p.Parse(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseInArena(b *testing.B) {
+	reportGC := func(b *testing.B, before runtime.MemStats) {
+		var after runtime.MemStats
+		runtime.ReadMemStats(&after)
+		b.ReportMetric(float64(after.NumGC-before.NumGC)/float64(b.N), "gcs/op")
+		b.ReportMetric(float64(after.PauseTotalNs-before.PauseTotalNs)/float64(b.N), "gc-pause-ns/op")
+	}
+	b.Run("heap", func(b *testing.B) {
+		b.ReportAllocs()
+		p := simplejsonext.NewParserFromSlice(stringHeavyDoc)
+		var before runtime.MemStats
+		runtime.ReadMemStats(&before)
+		for i := 0; i < b.N; i++ {
+			p.ResetSlice(stringHeavyDoc)
+			if _, err := p.Parse(); err != nil {
+				b.Fatal(err)
+			}
+		}
+		reportGC(b, before)
+	})
+	b.Run("arena", func(b *testing.B) {
+		b.ReportAllocs()
+		p := simplejsonext.NewParserFromSlice(stringHeavyDoc)
+		var a simplejsonext.Arena
+		var before runtime.MemStats
+		runtime.ReadMemStats(&before)
+		for i := 0; i < b.N; i++ {
+			a.Reset()
+			p.ResetSlice(stringHeavyDoc)
+			if _, err := p.ParseInArena(&a); err != nil {
+				b.Fatal(err)
+			}
+		}
+		reportGC(b, before)
+	})
+}
+
+func BenchmarkEmitFloats(b *testing.B) {
+	values := make([]any, 10000)
+	for i := range values {
+		switch i % 100 {
+		case 0:
+			values[i] = math.NaN()
+		case 1:
+			values[i] = math.Inf(1)
+		case 2:
+			values[i] = math.Inf(-1)
+		default:
+			values[i] = float64(i) / 7
+		}
+	}
+	b.ReportAllocs()
+	e := simplejsonext.NewEmitter(io.Discard)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := e.Emit(values); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseFloats(b *testing.B) {
+	b.ReportAllocs()
+	b.SetBytes(int64(len(numericArrayDoc)))
+	p := simplejsonext.NewParserFromSlice(numericArrayDoc)
+	for i := 0; i < b.N; i++ {
+		p.ResetSlice(numericArrayDoc)
+		if _, err := p.Parse(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// Sums every float in a document without building it.
+type floatSummer struct {
+	simplejsonext.NopVisitor
+	sum float64
+}
+
+func (s *floatSummer) OnFloat(v float64) error {
+	s.sum += v
+	return nil
+}
+
+func BenchmarkVisitFloatSum(b *testing.B) {
+	var sb strings.Builder
+	sb.WriteByte('[')
+	for i := 0; i < 1_000_000; i++ {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		fmt.Fprintf(&sb, "%d.25", i%1000)
+	}
+	sb.WriteByte(']')
+	doc := []byte(sb.String())
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(doc)))
+	p := simplejsonext.NewParserFromSlice(doc)
+	s := &floatSummer{}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p.ResetSlice(doc)
+		s.sum = 0
+		if err := p.Visit(s); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// A few megabytes of nested records of the kind we log.
+var largeRecord = func() map[string]any {
+	rows := make([]any, 20000)
+	for i := range rows {
+		rows[i] = map[string]any{
+			"step":    int64(i),
+			"loss":    1 / float64(i+1),
+			"name":    fmt.Sprintf("run-%d", i),
+			"metrics": []any{float64(i) * 0.5, int64(i * 3), true, nil},
+		}
+	}
+	return map[string]any{"history": rows, "summary": map[string]any{"state": "finished"}}
+}()
+
+func BenchmarkMarshalLarge(b *testing.B) {
+	exact := simplejsonext.EstimateMarshalSize(largeRecord)
+	for _, bench := range []struct {
+		name    string
+		marshal func() ([]byte, error)
+	}{
+		{"no hint", func() ([]byte, error) { return simplejsonext.MarshalWithCapacity(largeRecord, 0) }},
+		{"estimated", func() ([]byte, error) { return simplejsonext.Marshal(largeRecord) }},
+		{"exact hint", func() ([]byte, error) { return simplejsonext.MarshalWithCapacity(largeRecord, exact) }},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(exact))
+			for i := 0; i < b.N; i++ {
+				if _, err := bench.marshal(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkWalkDeNaN(b *testing.B) {
+	dirty := map[string]any{"history": largeRecord["history"], "summary": math.NaN()}
+	for _, bench := range []struct {
+		name string
+		v    any
+	}{
+		{"clean", largeRecord},
+		{"one replaced", dirty},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				simplejsonext.WalkDeNaN(bench.v)
+			}
+		})
+	}
+}
+
+func BenchmarkParseFloatArray(b *testing.B) {
+	var sb strings.Builder
+	sb.WriteByte('[')
+	for i := 0; i < 1_000_000; i++ {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		fmt.Fprintf(&sb, "%d.%d", i%1000, i%7)
+	}
+	sb.WriteByte(']')
+	doc := []byte(sb.String())
+
+	for _, bench := range []struct {
+		name  string
+		parse func(p simplejsonext.Parser) error
+	}{
+		{"generic", func(p simplejsonext.Parser) error { _, err := p.Parse(); return err }},
+		{"float array", func(p simplejsonext.Parser) error { _, err := p.ParseFloatArray(); return err }},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(doc)))
+			p := simplejsonext.NewParserFromSlice(doc)
+			for i := 0; i < b.N; i++ {
+				p.ResetSlice(doc)
+				if err := bench.parse(p); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+type benchPoint struct{ X, Y float64 }
+
+func (p *benchPoint) AppendJSONExt(dst []byte) ([]byte, error) {
+	dst = append(dst, `{"x":`...)
+	dst = strconv.AppendFloat(dst, p.X, 'g', -1, 64)
+	dst = append(dst, `,"y":`...)
+	dst = strconv.AppendFloat(dst, p.Y, 'g', -1, 64)
+	return append(dst, '}'), nil
+}
+
+func BenchmarkEmitAppender(b *testing.B) {
+	points := make([]any, 1000)
+	for i := range points {
+		points[i] = &benchPoint{X: float64(i) / 7, Y: -float64(i)}
+	}
+	var v any = points // boxed once, rather than in every call to Emit
+	e := simplejsonext.NewEmitter(io.Discard)
+	b.ReportAllocs()
+	b.SetBytes(int64(simplejsonext.EstimateMarshalSize(v)))
+	for i := 0; i < b.N; i++ {
+		if err := e.Emit(v); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEstimateDecodedSize(b *testing.B) {
+	for _, bench := range []struct {
+		name string
+		doc  []byte
+	}{
+		{"strings", stringHeavyDoc},
+		{"escapes", escapeHeavyDoc},
+	} {
+		b.Run(bench.name+"/estimate", func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(bench.doc)))
+			for i := 0; i < b.N; i++ {
+				if _, err := simplejsonext.EstimateDecodedSize(bench.doc); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(bench.name+"/parse", func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(bench.doc)))
+			for i := 0; i < b.N; i++ {
+				if _, err := simplejsonext.Unmarshal(bench.doc); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
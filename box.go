@@ -0,0 +1,44 @@
+package simplejsonext
+
+import "math"
+
+// The range of integers that are parsed into shared boxes.
+const (
+	minBoxedInt = -128
+	maxBoxedInt = 1024
+)
+
+// Pre-boxed values for the most common numbers. Boxing a scalar into an any
+// usually allocates, but the boxes are immutable, so the same ones can be
+// handed out every time.
+var (
+	intBoxes     [maxBoxedInt - minBoxedInt + 1]any
+	floatZeroBox any = float64(0)
+	floatOneBox  any = float64(1)
+)
+
+func init() {
+	for i := range intBoxes {
+		intBoxes[i] = int64(i + minBoxedInt)
+	}
+}
+
+// Returns i as an any, without allocating if it is small.
+func boxInt(i int64) any {
+	if i >= minBoxedInt && i <= maxBoxedInt {
+		return intBoxes[i-minBoxedInt]
+	}
+	return i
+}
+
+// Returns f as an any, without allocating if it is exactly 0 or 1. Negative
+// zero gets its own box.
+func boxFloat(f float64) any {
+	switch math.Float64bits(f) {
+	case 0:
+		return floatZeroBox
+	case math.Float64bits(1):
+		return floatOneBox
+	}
+	return f
+}
@@ -0,0 +1,45 @@
+package simplejsonext
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBoxedNumbers(t *testing.T) {
+	for _, i := range []int64{minBoxedInt - 1, minBoxedInt, -1, 0, 1, 255, 256, maxBoxedInt, maxBoxedInt + 1, math.MinInt64} {
+		assert.Equal(t, any(i), boxInt(i))
+	}
+	for _, f := range []float64{0, 1, -1, 0.5, math.Inf(1)} {
+		assert.Equal(t, any(f), boxFloat(f))
+	}
+	negZero := boxFloat(math.Copysign(0, -1)).(float64)
+	assert.True(t, math.Signbit(negZero))
+	assert.True(t, math.IsNaN(boxFloat(math.NaN()).(float64)))
+
+	v, err := UnmarshalString(`[-129, -128, 0, 1000, 1024, 1025, 0.0, -0.0, 1.0, 1e0, 1, NaN]`)
+	require.NoError(t, err)
+	arr := v.([]any)
+	assert.Equal(t, []any{
+		int64(-129), int64(-128), int64(0), int64(1000), int64(1024), int64(1025),
+		float64(0), negZero, float64(1), float64(1), int64(1),
+	}, arr[:11])
+	assert.True(t, math.Signbit(arr[7].(float64)))
+
+	// Shared boxes must not confuse anything that compares or rewrites
+	// values: rewriting one element leaves its equal neighbours alone.
+	res, err := Walk([]any{int64(5), int64(5), float64(1), float64(1)}, func(path []string, v any) (any, error) {
+		if len(path) == 1 && path[0] == "0" {
+			return int64(6), nil
+		}
+		return v, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []any{int64(6), int64(5), float64(1), float64(1)}, res)
+
+	deNaNed := WalkDeNaN(arr)
+	assert.Equal(t, arr[:11], deNaNed.([]any)[:11])
+	assert.Equal(t, int64(1000), arr[3], "the input is not modified")
+}
@@ -0,0 +1,128 @@
+package simplejsonext
+
+import (
+	"fmt"
+	"math"
+)
+
+// Extension is a feature of the extended JSON this package reads that
+// standard JSON, as defined by RFC 8259, does not have.
+type Extension int
+
+const (
+	// ExtNonFiniteToken is NaN, Inf or Infinity, with or without a sign.
+	ExtNonFiniteToken Extension = iota
+	// ExtLeadingZeros is a number with extra zeros before its digits, as
+	// in 007 or -00.
+	ExtLeadingZeros
+	// ExtTrailingDecimalPoint is a decimal point with no digits after it, as
+	// in 1. or 1.e5.
+	ExtTrailingDecimalPoint
+	// ExtLeadingDecimalPoint is a decimal point with no digits before it,
+	// which is only allowed after a sign, as in -.5.
+	ExtLeadingDecimalPoint
+	// ExtNumberOverflow is a number too big for a float64, which is read as
+	// an infinity.
+	ExtNumberOverflow
+)
+
+var extensionNames = [...]string{
+	ExtNonFiniteToken:       "non-finite token",
+	ExtLeadingZeros:         "leading zeros",
+	ExtTrailingDecimalPoint: "trailing decimal point",
+	ExtLeadingDecimalPoint:  "leading decimal point",
+	ExtNumberOverflow:       "number overflow",
+}
+
+func (x Extension) String() string {
+	if x < 0 || int(x) >= len(extensionNames) {
+		return fmt.Sprintf("Extension(%d)", int(x))
+	}
+	return extensionNames[x]
+}
+
+// ExtensionUse records the first use of an Extension in a document.
+type ExtensionUse struct {
+	Extension Extension
+	Offset    int64 // of the value that uses it
+}
+
+// CheckReport is the result of Check.
+type CheckReport struct {
+	// Strict is whether the document is standard JSON, using no extensions.
+	Strict bool
+	// Extensions are those the document uses, each with its first use, in
+	// order of their offsets.
+	Extensions []ExtensionUse
+}
+
+// Uses reports whether the document uses x.
+func (r *CheckReport) Uses(x Extension) bool {
+	for _, use := range r.Extensions {
+		if use.Extension == x {
+			return true
+		}
+	}
+	return false
+}
+
+// Check validates b as Unmarshal would, without building its value, and
+// reports whether it is also standard JSON, which encoding/json accepts, or
+// which extensions it needs. The error is for documents that are not valid
+// at all.
+func Check(b []byte) (CheckReport, error) {
+	report := CheckReport{Strict: true}
+	p := parser{readBuf: b, size: len(b)}
+	p.cfg.onNumber = func(view []byte, f float64, isFloat bool) {
+		// The number has just been read.
+		offset := p.InputOffset() - int64(len(view))
+		for _, x := range numberExtensions(view, f, isFloat) {
+			if !report.Uses(x) {
+				report.Strict = false
+				report.Extensions = append(report.Extensions, ExtensionUse{Extension: x, Offset: offset})
+			}
+		}
+	}
+	if err := p.Skip(); err != nil {
+		return CheckReport{}, err
+	}
+	if err := p.CheckEmpty(); err != nil {
+		return CheckReport{}, err
+	}
+	return report, nil
+}
+
+// Returns the extensions that the number spelled b, which the parser has
+// accepted, needs.
+func numberExtensions(b []byte, f float64, isFloat bool) (exts []Extension) {
+	if isStandardNumber(b) {
+		if isFloat && math.IsInf(f, 0) {
+			exts = append(exts, ExtNumberOverflow)
+		}
+		return
+	}
+	i := 0
+	if b[0] == '-' {
+		i++
+	}
+	if b[i] == 'N' || b[i] == 'I' {
+		return append(exts, ExtNonFiniteToken)
+	}
+	start := i
+	for i < len(b) && b[i] >= '0' && b[i] <= '9' {
+		i++
+	}
+	switch {
+	case i == start:
+		exts = append(exts, ExtLeadingDecimalPoint)
+	case i-start > 1 && b[start] == '0':
+		exts = append(exts, ExtLeadingZeros)
+	}
+	if i < len(b) && b[i] == '.' && (i+1 == len(b) || b[i+1] < '0' || b[i+1] > '9') {
+		exts = append(exts, ExtTrailingDecimalPoint)
+	}
+	if isFloat && math.IsInf(f, 0) {
+		exts = append(exts, ExtNumberOverflow)
+	}
+	return
+}
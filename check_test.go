@@ -0,0 +1,86 @@
+package simplejsonext_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wandb/simplejsonext"
+)
+
+func TestCheckStandardCases(t *testing.T) {
+	for _, cc := range standardCases {
+		report, err := simplejsonext.Check([]byte(cc.s))
+		if _, isErr := cc.v.(error); isErr {
+			assert.Error(t, err, cc.s)
+			continue
+		}
+		require.NoError(t, err, cc.s)
+		assert.True(t, report.Strict, cc.s)
+		assert.Empty(t, report.Extensions, cc.s)
+		assert.True(t, json.Valid([]byte(cc.s)), cc.s)
+	}
+}
+
+func TestCheckSimpleCases(t *testing.T) {
+	type use = simplejsonext.ExtensionUse
+	expected := map[string][]use{
+		`9e999`:     {{simplejsonext.ExtNumberOverflow, 0}},
+		`-9e999`:    {{simplejsonext.ExtNumberOverflow, 0}},
+		hugeNumber:  {{simplejsonext.ExtNumberOverflow, 0}},
+		megaNumber:  {{simplejsonext.ExtNumberOverflow, 0}},
+		`NaN`:       {{simplejsonext.ExtNonFiniteToken, 0}},
+		`Inf`:       {{simplejsonext.ExtNonFiniteToken, 0}},
+		`Infinity`:  {{simplejsonext.ExtNonFiniteToken, 0}},
+		`-Inf`:      {{simplejsonext.ExtNonFiniteToken, 0}},
+		`-Infinity`: {{simplejsonext.ExtNonFiniteToken, 0}},
+		`01`:        {{simplejsonext.ExtLeadingZeros, 0}},
+		`02.3`:      {{simplejsonext.ExtLeadingZeros, 0}},
+		`-01`:       {{simplejsonext.ExtLeadingZeros, 0}},
+		`-00`:       {{simplejsonext.ExtLeadingZeros, 0}},
+		`1.`:        {{simplejsonext.ExtTrailingDecimalPoint, 0}},
+		`1.e1`:      {{simplejsonext.ExtTrailingDecimalPoint, 0}},
+		`-.1`:       {{simplejsonext.ExtLeadingDecimalPoint, 0}},
+	}
+	for _, cc := range simpleCases {
+		report, err := simplejsonext.Check([]byte(cc.s))
+		if _, isErr := cc.v.(error); isErr {
+			assert.Error(t, err, cc.s)
+			continue
+		}
+		require.NoError(t, err, cc.s)
+		uses, isExtended := expected[cc.s]
+		assert.Equal(t, !isExtended, report.Strict, cc.s)
+		assert.Equal(t, uses, report.Extensions, cc.s)
+		// encoding/json accepts huge numbers as syntax, but fails to read them.
+		if isExtended && uses[0].Extension == simplejsonext.ExtNumberOverflow {
+			var v any
+			assert.Error(t, json.Unmarshal([]byte(cc.s), &v))
+		} else if isExtended {
+			assert.False(t, json.Valid([]byte(cc.s)), cc.s)
+		}
+	}
+}
+
+func TestCheckFirstUses(t *testing.T) {
+	doc := `{"a": [1, 2.5, NaN, 007], "b": {"c": -.5, "d": Infinity}, "e": [01., 1e400, "NaN"]}`
+	report, err := simplejsonext.Check([]byte(doc))
+	require.NoError(t, err)
+	assert.False(t, report.Strict)
+	assert.Equal(t, []simplejsonext.ExtensionUse{
+		{Extension: simplejsonext.ExtNonFiniteToken, Offset: 15},
+		{Extension: simplejsonext.ExtLeadingZeros, Offset: 20},
+		{Extension: simplejsonext.ExtLeadingDecimalPoint, Offset: 37},
+		{Extension: simplejsonext.ExtTrailingDecimalPoint, Offset: 64},
+		{Extension: simplejsonext.ExtNumberOverflow, Offset: 69},
+	}, report.Extensions)
+	assert.True(t, report.Uses(simplejsonext.ExtLeadingZeros))
+	assert.Equal(t, "leading decimal point", simplejsonext.ExtLeadingDecimalPoint.String())
+
+	for _, doc := range []string{``, `[1,]`, `1 2`, `{"a": 01x}`} {
+		_, err := simplejsonext.Check([]byte(doc))
+		assert.Error(t, err, doc)
+	}
+}
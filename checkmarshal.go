@@ -0,0 +1,135 @@
+package simplejsonext
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+)
+
+var errCyclic = errors.New("simple json: cannot emit a value that contains itself")
+
+// MarshalCheckError is returned by CheckMarshalable, listing every problem
+// found.
+type MarshalCheckError struct {
+	// Problems are in the order an Emitter would come across them. Each is an
+	// *InvalidUTF8Error, which records its own path, or a *PathError holding
+	// the path of the value and the error an Emitter would fail with.
+	Problems []error
+}
+
+func (e *MarshalCheckError) Error() string {
+	lines := make([]string, len(e.Problems))
+	for i, problem := range e.Problems {
+		lines[i] = problem.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (e *MarshalCheckError) Unwrap() []error {
+	return e.Problems
+}
+
+// CheckMarshalable reports whether an Emitter configured by opts could write
+// v, without writing anything. It follows the same rules as the Emitter, but
+// rather than stopping at the first value that cannot be written, it carries
+// on to find them all, and returns them in a *MarshalCheckError. Values that
+// contain themselves, which an Emitter would never finish writing, are
+// reported too.
+func CheckMarshalable(v any, opts ...EmitOption) error {
+	e := &emitter{w: io.Discard}
+	e.s = e.a[:0]
+	e.cfg.apply(opts)
+	e.cfg.indented = false // layout does not matter
+	var problems []error
+	problem := func(path []any, err error) {
+		if ue, ok := err.(*InvalidUTF8Error); ok {
+			ue.Path = append(path, ue.Path...)
+			problems = append(problems, ue)
+		} else {
+			problems = append(problems, &PathError{Path: path, Err: err})
+		}
+	}
+
+	// The arrays and objects being checked, as in emitDeep, with what
+	// identifies each of them, if anything, to tell when one contains itself.
+	type checkFrame struct {
+		emitFrame
+		id any
+	}
+	var stack []checkFrame
+	onPath := make(map[any]bool)
+	path := func() []any {
+		var path []any
+		for i := range stack {
+			if elem := stack[i].pathElem(); elem != nil {
+				path = append(path, elem)
+			}
+		}
+		return path
+	}
+	for {
+		id := containerID(v)
+		if id != nil && onPath[id] {
+			problem(path(), errCyclic)
+		} else {
+			e.level = 0
+			f, isContainer, err := e.beginContainer(v)
+			if err != nil {
+				problem(path(), err)
+			} else if isContainer {
+				stack = append(stack, checkFrame{emitFrame: f, id: id})
+				if id != nil {
+					onPath[id] = true
+				}
+			}
+		}
+		// Find the next value to check.
+		for {
+			if len(stack) == 0 {
+				if len(problems) > 0 {
+					return &MarshalCheckError{Problems: problems}
+				}
+				return nil
+			}
+			top := &stack[len(stack)-1]
+			key, val, ok := top.next()
+			if !ok {
+				delete(onPath, top.id)
+				stack = stack[:len(stack)-1]
+				continue
+			}
+			if top.object {
+				if err := e.emitString(key); err != nil {
+					if ue, ok := err.(*InvalidUTF8Error); ok {
+						ue.Key = true
+					}
+					problem(path(), err)
+				}
+			}
+			v = val
+			break
+		}
+	}
+}
+
+// Identifies the memory of a pointer, map, or slice, to tell when it is
+// reached again while inside itself, or returns nil for other values.
+func containerID(v any) any {
+	type sliceID struct {
+		ptr uintptr
+		len int
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Map:
+		if !rv.IsNil() {
+			return rv.Pointer()
+		}
+	case reflect.Slice:
+		if rv.Len() > 0 {
+			return sliceID{rv.Pointer(), rv.Len()}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package simplejsonext
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type badAppender struct{}
+
+func (badAppender) AppendJSONExt(dst []byte) ([]byte, error) {
+	return dst, errors.New("no")
+}
+
+func TestCheckMarshalable(t *testing.T) {
+	good := []any{
+		nil, true, int64(1), uint(2), 1.5, math.NaN(), "s", []byte("b"),
+		map[string]any{"a": []any{map[string]int{"x": 1}}},
+		&OrderedObject{Members: []Member{{Key: "k", Value: []string{"v"}}}},
+		RawMessage(`{"raw": 1}`), big.NewInt(7), errors.New("oops"),
+	}
+	for _, v := range good {
+		assert.NoError(t, CheckMarshalable(v), "%#v", v)
+		_, err := Marshal(v)
+		assert.NoError(t, err, "%#v", v)
+	}
+
+	v := map[string]any{
+		"ok":    "fine",
+		"ch":    make(chan int),
+		"list":  []any{int64(1), complex64(1), []any{func() {}}},
+		"keys":  map[int]string{1: "one"},
+		"float": math.Inf(1),
+		"bad":   "\xff",
+		"\xfe":  true,
+		"obj":   &OrderedObject{Members: []Member{{Key: "app", Value: badAppender{}}}},
+		"deep":  map[string]map[string]any{"x": {"y": complex(1, 2)}},
+	}
+	err := CheckMarshalable(v, WithSortedKeys())
+	var checkErr *MarshalCheckError
+	require.ErrorAs(t, err, &checkErr)
+	assert.Equal(t, `simple json: at $["ch"]: cannot emit unsupported type chan int
+simple json: at $["deep"]["x"]["y"]: cannot emit unsupported type complex128
+simple json: at $["keys"]: cannot emit unsupported type map[int]string
+simple json: at $["list"][1]: cannot emit unsupported type complex64
+simple json: at $["list"][2][0]: cannot emit unsupported type func()
+simple json: at $["obj"]["app"]: no`, err.Error())
+
+	// Emit options change what is a problem.
+	err = CheckMarshalable(v, WithSortedKeys(), WithStrictFloats(), WithInvalidUTF8(InvalidUTF8Fail))
+	require.ErrorAs(t, err, &checkErr)
+	assert.Len(t, checkErr.Problems, 9)
+	var utf8Errs []*InvalidUTF8Error
+	for _, problem := range checkErr.Problems {
+		if utf8Err, ok := problem.(*InvalidUTF8Error); ok {
+			utf8Errs = append(utf8Errs, utf8Err)
+		}
+	}
+	assert.Equal(t, []*InvalidUTF8Error{{Path: []any{"bad"}}, {Path: []any{"\xfe"}, Key: true}}, utf8Errs)
+	assert.EqualError(t, checkErr.Problems[3], `simple json: at $["float"]: cannot emit non-finite float +Inf`)
+
+	// Whatever CheckMarshalable accepts, Marshal can write, and the first
+	// problem it reports is the error Marshal gives.
+	for _, opts := range [][]EmitOption{{WithSortedKeys()}, {WithSortedKeys(), WithStrictFloats(), WithInvalidUTF8(InvalidUTF8Fail)}} {
+		err = CheckMarshalable(v, opts...)
+		require.ErrorAs(t, err, &checkErr)
+		var buf bytes.Buffer
+		emitErr := NewEmitter(&buf, opts...).Emit(v)
+		require.Error(t, emitErr)
+		assert.Contains(t, checkErr.Problems[0].Error(), emitErr.Error()[len("simple json: "):])
+	}
+}
+
+func TestCheckMarshalableCycles(t *testing.T) {
+	m := map[string]any{"a": int64(1)}
+	m["self"] = m
+	s := []any{int64(1), nil}
+	s[1] = s
+	shared := []any{"x"}
+	v := map[string]any{"m": m, "s": s, "twice": []any{shared, shared}, "p": &m}
+	err := CheckMarshalable(v, WithSortedKeys())
+	assert.EqualError(t, err, `simple json: at $["m"]["self"]: cannot emit a value that contains itself
+simple json: at $["p"]["self"]["self"]: cannot emit a value that contains itself
+simple json: at $["s"][1]: cannot emit a value that contains itself`)
+	assert.ErrorIs(t, err, errCyclic)
+
+	// Values nested too deeply to recurse through are still checked.
+	var deep any = func() {}
+	for i := 0; i < 3*maxEmitRecursion; i++ {
+		deep = []any{deep}
+	}
+	err = CheckMarshalable(deep)
+	var pathErr *PathError
+	require.ErrorAs(t, err, &pathErr)
+	assert.Len(t, pathErr.Path, 3*maxEmitRecursion)
+}
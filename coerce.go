@@ -0,0 +1,48 @@
+package simplejsonext
+
+import (
+	"math"
+)
+
+// AsInt64 returns v as an int64 if it is an int64, or a float64 with an
+// integral value in the range of int64. NaN, infinities, fractional values and
+// floats too large for int64 are rejected. Negative zero becomes 0.
+func AsInt64(v any) (int64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return n, true
+	case float64:
+		// 2^63 is exactly representable as a float64, but is one more than
+		// math.MaxInt64. NaN fails these comparisons.
+		if n >= math.MinInt64 && n < -math.MinInt64 && n == math.Trunc(n) {
+			return int64(n), true
+		}
+	}
+	return 0, false
+}
+
+// AsFloat64 returns v as a float64 if it is a float64 (including NaN and the
+// infinities) or an int64. Integers beyond ±2^53 are rounded to the nearest
+// float64.
+func AsFloat64(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// AsBool returns v as a bool if it is one. No other values are converted.
+func AsBool(v any) (bool, bool) {
+	b, ok := v.(bool)
+	return b, ok
+}
+
+// AsString returns v as a string if it is one. No other values are converted;
+// in particular, the strings written by WalkDeNaN are not numbers.
+func AsString(v any) (string, bool) {
+	s, ok := v.(string)
+	return s, ok
+}
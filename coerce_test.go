@@ -0,0 +1,87 @@
+package simplejsonext
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAsInt64(t *testing.T) {
+	cases := []struct {
+		in  any
+		out int64
+		ok  bool
+	}{
+		{int64(5), 5, true},
+		{int64(math.MinInt64), math.MinInt64, true},
+		{3.0, 3, true},
+		{-3.0, -3, true},
+		{math.Copysign(0, -1), 0, true},
+		{float64(1 << 53), 1 << 53, true},
+		{float64(math.MinInt64), math.MinInt64, true},
+		{math.Nextafter(1<<63, 0), 1<<63 - 1024, true},
+		{float64(1 << 63), 0, false},
+		{-math.Nextafter(-(1 << 63), math.Inf(-1)), 0, false},
+		{0.5, 0, false},
+		{math.NaN(), 0, false},
+		{math.Inf(1), 0, false},
+		{math.Inf(-1), 0, false},
+		{"5", 0, false},
+		{true, 0, false},
+		{nil, 0, false},
+		{5, 0, false}, // only the types produced by the parser
+	}
+	for _, c := range cases {
+		out, ok := AsInt64(c.in)
+		assert.Equal(t, c.ok, ok, "%#v", c.in)
+		assert.Equal(t, c.out, out, "%#v", c.in)
+	}
+}
+
+func TestAsFloat64(t *testing.T) {
+	cases := []struct {
+		in  any
+		out float64
+		ok  bool
+	}{
+		{1.5, 1.5, true},
+		{int64(-2), -2, true},
+		{int64(1<<53 + 1), 1 << 53, true},
+		{math.Inf(-1), math.Inf(-1), true},
+		{"1.5", 0, false},
+		{false, 0, false},
+		{nil, 0, false},
+	}
+	for _, c := range cases {
+		out, ok := AsFloat64(c.in)
+		assert.Equal(t, c.ok, ok, "%#v", c.in)
+		assert.Equal(t, c.out, out, "%#v", c.in)
+	}
+
+	f, ok := AsFloat64(math.NaN())
+	assert.True(t, ok)
+	assert.True(t, math.IsNaN(f))
+
+	f, ok = AsFloat64(math.Copysign(0, -1))
+	assert.True(t, ok)
+	assert.True(t, math.Signbit(f))
+}
+
+func TestAsBoolAndString(t *testing.T) {
+	b, ok := AsBool(true)
+	assert.True(t, ok)
+	assert.True(t, b)
+	_, ok = AsBool("true")
+	assert.False(t, ok)
+	_, ok = AsBool(int64(1))
+	assert.False(t, ok)
+
+	s, ok := AsString("NaN")
+	assert.True(t, ok)
+	assert.Equal(t, "NaN", s)
+	_, ok = AsString(math.NaN())
+	assert.False(t, ok)
+	_, ok = AsString(nil)
+	assert.False(t, ok)
+}
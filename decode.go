@@ -1,5 +1,11 @@
 package simplejsonext
 
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
 // Unmarshal decodes a JSON representation from b as a generic value:
 // int64, float64, string, bool, nil, []any, or map[string]any.
 func Unmarshal(b []byte) (any, error) {
@@ -11,15 +17,22 @@ func Unmarshal(b []byte) (any, error) {
 	return val, p.CheckEmpty()
 }
 
-func UnmarshalObject(b []byte) (map[string]any, error) {
-	p := NewParserFromSlice(b)
-	val, err := p.ParseObject()
+// UnmarshalWithOptions is like Unmarshal, with a parser configured by opts.
+func UnmarshalWithOptions(b []byte, opts ...ParseOption) (any, error) {
+	p := NewParserFromSlice(b, opts...)
+	val, err := p.Parse()
 	if err != nil {
 		return nil, err
 	}
 	return val, p.CheckEmpty()
 }
 
+func UnmarshalObject(b []byte) (map[string]any, error) {
+	p := NewParserFromSlice(b)
+	val, err := p.ParseObject()
+	return checkObjectEmpty(val, err, p.CheckEmpty)
+}
+
 // UnmarshalString decodes a JSON representation from b as a generic
 // value: int64, float64, string, bool, nil, []any, or map[string]any.
 func UnmarshalString(s string) (any, error) {
@@ -31,11 +44,122 @@ func UnmarshalString(s string) (any, error) {
 	return val, p.CheckEmpty()
 }
 
+// UnmarshalStringWithOptions is like UnmarshalString, with a parser
+// configured by opts.
+func UnmarshalStringWithOptions(s string, opts ...ParseOption) (any, error) {
+	p := NewParserFromString(s, opts...)
+	val, err := p.Parse()
+	if err != nil {
+		return nil, err
+	}
+	return val, p.CheckEmpty()
+}
+
 func UnmarshalObjectString(s string) (map[string]any, error) {
 	p := NewParserFromString(s)
 	val, err := p.ParseObject()
+	return checkObjectEmpty(val, err, p.CheckEmpty)
+}
+
+// TrailingDataError is returned by UnmarshalReader and UnmarshalObjectReader
+// when something other than whitespace follows the value.
+type TrailingDataError struct {
+	// Offset is the position of the first byte after the value that is not
+	// whitespace.
+	Offset int64
+}
+
+func (e *TrailingDataError) Error() string {
+	return fmt.Sprintf("%v at offset %d", ErrTrailingData, e.Offset)
+}
+
+func (e *TrailingDataError) Is(target error) bool {
+	return target == ErrTrailingData
+}
+
+// UnmarshalReader decodes a single JSON value read from r as a generic value:
+// int64, float64, string, bool, nil, []any, or map[string]any. Unlike
+// NewParser(r).Parse(), a *TrailingDataError is returned if anything other
+// than whitespace follows the value before the end of the reader. Reading
+// stops at the first such byte, so trailing data is never buffered beyond the
+// parser's read buffer.
+func UnmarshalReader(r io.Reader) (any, error) {
+	p := NewParser(r)
+	val, err := p.Parse()
 	if err != nil {
 		return nil, err
 	}
-	return val, p.CheckEmpty()
+	return val, checkReaderEmpty(p)
+}
+
+// UnmarshalObjectReader decodes a single JSON object read from r, returning a
+// *NotObjectError if the value is not an object, or a *TrailingDataError if
+// anything other than whitespace follows it before the end of the reader.
+func UnmarshalObjectReader(r io.Reader) (map[string]any, error) {
+	p := NewParser(r)
+	val, err := p.ParseObject()
+	return checkObjectEmpty(val, err, func() error { return checkReaderEmpty(p) })
+}
+
+// Like CheckEmpty, but reports where trailing data starts.
+func checkReaderEmpty(p Parser) error {
+	err := p.CheckEmpty()
+	if err == ErrTrailingData {
+		return &TrailingDataError{Offset: p.InputOffset()}
+	}
+	return err
+}
+
+// Finishes the UnmarshalObject functions, using checkEmpty to check for
+// trailing data. Trailing data is reported in preference to a value that is
+// not an object, since then the input is not valid JSON either way.
+func checkObjectEmpty(val map[string]any, err error, checkEmpty func() error) (map[string]any, error) {
+	if err == nil {
+		return val, checkEmpty()
+	}
+	if errors.Is(err, ErrNotObject) {
+		if emptyErr := checkEmpty(); emptyErr != nil {
+			return nil, emptyErr
+		}
+	}
+	return nil, err
+}
+
+// UnquoteString decodes b, which must hold a single JSON string literal
+// including its quotes, using the same rules as Unmarshal. As with Unmarshal,
+// whitespace may surround the literal but anything else is an error.
+func UnquoteString(b []byte) (string, error) {
+	p := parser{readBuf: b, size: len(b)}
+	if err := p.skipSpaces(); err != nil {
+		return "", err
+	}
+	v, err := p.parseString()
+	if err != nil {
+		return "", err
+	}
+	return string(v), p.CheckEmpty()
+}
+
+// UnquoteStringPrefix decodes the JSON string literal at the very start of b,
+// returning the string and the number of bytes of b it occupied, including
+// both quotes. Any bytes after the closing quote are ignored.
+func UnquoteStringPrefix(b []byte) (s string, n int, err error) {
+	p := parser{readBuf: b, size: len(b)}
+	v, err := p.parseString()
+	if err != nil {
+		return "", 0, err
+	}
+	return string(v), p.begin, nil
+}
+
+// UnmarshalRead decodes the single JSON value read from r into the value that
+// dest points to, in the manner of encoding/json/v2. Like UnmarshalReader, it
+// returns a *TrailingDataError if anything other than whitespace follows the
+// value. dest may be any destination that UnmarshalInto accepts.
+func UnmarshalRead(r io.Reader, dest any, opts ...ParseOption) error {
+	p := NewParser(r, opts...)
+	if err := p.ParseInto(dest); err != nil {
+		return err
+	}
+	return checkReaderEmpty(p)
 }
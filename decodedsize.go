@@ -0,0 +1,105 @@
+package simplejsonext
+
+import "math"
+
+// The model of memory used by EstimateDecodedSize, in bytes on a 64-bit
+// platform, following how the parser builds values and how the Go runtime
+// lays them out.
+const (
+	// Each value is held in an interface, in an array's backing slice, a
+	// map, or the result.
+	decodedSlotSize = 16
+	// Numbers and strings are boxed when put in an interface: int64 and
+	// float64 take 8 bytes, except for the common numbers the parser has
+	// boxes for already, and strings take a 16-byte header besides their
+	// bytes.
+	decodedNumberSize       = 8
+	decodedStringHeaderSize = 16
+	// A []any takes a boxed 24-byte slice header.
+	decodedArraySize = 24
+	// A map takes a 48-byte header and a first group of eight slots, each
+	// for the header of a key and the interface of a value. Past eight
+	// members, each takes about this much more, for its slot and the slack
+	// left by groups not being full.
+	decodedMapSize      = 48 + 8 + 8*(16+16)
+	decodedMapGroupLen  = 8
+	decodedMapEntrySize = 48
+)
+
+// EstimateDecodedSize returns roughly how many bytes of memory the value
+// Unmarshal would return for b holds on to, without parsing it into memory.
+// b is checked as Skip would check it, and must hold a single value, followed
+// by nothing but whitespace. Nothing is allocated.
+//
+// The estimate follows a model of how values are laid out, counting the bytes
+// of strings and keys once their escapes are decoded, the interfaces, slices
+// and maps that hold values, and the boxing of numbers. It leaves out memory
+// used only while parsing and the rounding of allocations up to the sizes the
+// runtime provides, and can only approximate the layout of maps, so it is
+// not exact, but is usually within half again of the real size either way.
+func EstimateDecodedSize(b []byte) (int64, error) {
+	p := GetParserFromSlice(b).(*parser)
+	defer PutParser(p)
+	v := &p.sizeVisitor
+	v.size, v.keys = 0, v.keys[:0]
+	err := p.doVisit(p.maxDepth(), v)
+	if err == nil {
+		err = p.CheckEmpty()
+	}
+	if err != nil {
+		return 0, err
+	}
+	return v.size, nil
+}
+
+type decodedSizeVisitor struct {
+	size int64
+	keys []int // the number of keys so far of each object being visited
+}
+
+func (v *decodedSizeVisitor) add(n int) error {
+	v.size += int64(n)
+	return nil
+}
+
+func (v *decodedSizeVisitor) OnNull() error     { return v.add(decodedSlotSize) }
+func (v *decodedSizeVisitor) OnBool(bool) error { return v.add(decodedSlotSize) }
+
+func (v *decodedSizeVisitor) OnInt(i int64) error {
+	if i >= minBoxedInt && i <= maxBoxedInt {
+		return v.add(decodedSlotSize)
+	}
+	return v.add(decodedSlotSize + decodedNumberSize)
+}
+
+func (v *decodedSizeVisitor) OnFloat(f float64) error {
+	if f == 0 && !math.Signbit(f) || f == 1 {
+		return v.add(decodedSlotSize)
+	}
+	return v.add(decodedSlotSize + decodedNumberSize)
+}
+
+func (v *decodedSizeVisitor) OnString(s []byte) error {
+	return v.add(decodedSlotSize + decodedStringHeaderSize + len(s))
+}
+
+func (v *decodedSizeVisitor) OnArrayBegin() error { return v.add(decodedSlotSize + decodedArraySize) }
+func (v *decodedSizeVisitor) OnArrayEnd() error   { return nil }
+
+func (v *decodedSizeVisitor) OnObjectBegin() error {
+	v.keys = append(v.keys, 0)
+	return v.add(decodedSlotSize + decodedMapSize)
+}
+
+func (v *decodedSizeVisitor) OnKey(key []byte) error {
+	n := &v.keys[len(v.keys)-1]
+	if *n++; *n > decodedMapGroupLen {
+		return v.add(decodedMapEntrySize + len(key))
+	}
+	return v.add(len(key))
+}
+
+func (v *decodedSizeVisitor) OnObjectEnd() error {
+	v.keys = v.keys[:len(v.keys)-1]
+	return nil
+}
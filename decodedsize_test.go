@@ -0,0 +1,87 @@
+package simplejsonext
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Returns a JSON array of n elements, each written by elem.
+func jsonArrayOf(n int, elem func(sb *strings.Builder, i int)) []byte {
+	var sb strings.Builder
+	sb.WriteByte('[')
+	for i := range n {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		elem(&sb, i)
+	}
+	sb.WriteByte(']')
+	return []byte(sb.String())
+}
+
+func TestEstimateDecodedSize(t *testing.T) {
+	docs := map[string][]byte{
+		"records": jsonArrayOf(2000, func(sb *strings.Builder, i int) {
+			fmt.Fprintf(sb, `{"name": "run-%d", "state": "finished", "loss": %g, "step": %d, "tags": ["a", "b\n"]}`, i, float64(i)/7, i*1000)
+		}),
+		"floats": jsonArrayOf(20000, func(sb *strings.Builder, i int) {
+			fmt.Fprintf(sb, "%g", float64(i)/3)
+		}),
+		"small ints": jsonArrayOf(20000, func(sb *strings.Builder, i int) {
+			fmt.Fprintf(sb, "%d", i%100)
+		}),
+		"escaped strings": jsonArrayOf(5000, func(sb *strings.Builder, i int) {
+			fmt.Fprintf(sb, `"ééé %d \n\t\"quoted\" 💥"`, i)
+		}),
+		"big object": func() []byte {
+			b := jsonArrayOf(20000, func(sb *strings.Builder, i int) {
+				fmt.Fprintf(sb, `"key-%d": {"v": %d}`, i, i*1000)
+			})
+			b[0], b[len(b)-1] = '{', '}'
+			return b
+		}(),
+	}
+	for name, doc := range docs {
+		estimate, err := EstimateDecodedSize(doc)
+		require.NoError(t, err)
+
+		// What the parsed value holds onto once parsing is done.
+		p := NewParserFromSlice(doc)
+		var before, after runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+		v, err := p.Parse()
+		runtime.GC()
+		runtime.ReadMemStats(&after)
+		require.NoError(t, err)
+		runtime.KeepAlive(v)
+		measured := float64(after.HeapAlloc - before.HeapAlloc)
+
+		ratio := float64(estimate) / measured
+		assert.True(t, ratio > 0.6 && ratio < 1.5, "%s: estimated %d, measured %.0f", name, estimate, measured)
+		assert.Zero(t, testing.AllocsPerRun(10, func() {
+			_, _ = EstimateDecodedSize(doc)
+		}), name)
+	}
+}
+
+func TestEstimateDecodedSizeErrors(t *testing.T) {
+	for _, doc := range []string{``, `[1, 2`, `{"a": }`, `"\x"`, `1 2`} {
+		_, err := EstimateDecodedSize([]byte(doc))
+		assert.Error(t, err, doc)
+	}
+	n, err := EstimateDecodedSize([]byte(` null `))
+	require.NoError(t, err)
+	assert.Equal(t, int64(decodedSlotSize), n)
+	// Escapes count as the bytes they decode to.
+	plain, err := EstimateDecodedSize([]byte(`"ab"`))
+	require.NoError(t, err)
+	escaped, err := EstimateDecodedSize([]byte(`"a\u0062"`))
+	require.NoError(t, err)
+	assert.Equal(t, plain, escaped)
+}
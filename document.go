@@ -0,0 +1,266 @@
+package simplejsonext
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+)
+
+// Document is a JSON document held as its text, for editing without
+// disturbing its formatting. Changes rewrite only the text of the values they
+// touch; everything else, including whitespace, key order, the spelling of
+// numbers and escapes, and comments, if the Document was parsed with
+// WithComments, stays byte for byte as it was.
+//
+// A Document finds values by scanning its text, so each operation takes time
+// in proportion to the size of the document. It is meant for configuration
+// files and the like, not for large data.
+type Document struct {
+	src  []byte
+	opts []ParseOption
+}
+
+// ParseDocument checks that b holds a single valid value, surrounded by
+// nothing but whitespace, and returns a Document of a copy of it. The options
+// are used whenever the Document reads its text; options that change the text
+// itself, such as WithEncodingDetection, are not supported.
+func ParseDocument(b []byte, opts ...ParseOption) (*Document, error) {
+	p := NewParserFromSlice(b, opts...)
+	if err := p.Skip(); err != nil {
+		return nil, err
+	}
+	if err := p.CheckEmpty(); err != nil {
+		return nil, err
+	}
+	return &Document{src: bytes.Clone(b), opts: opts}, nil
+}
+
+// Bytes returns the text of the document, which must not be modified.
+func (d *Document) Bytes() []byte {
+	return d.src
+}
+
+// Get parses the value found by following path, as the Get function does for
+// parsed values. Where an object has a key more than once, the last one is
+// used, as Parse would.
+func (d *Document) Get(path ...any) (any, error) {
+	t, err := d.find(path)
+	if err != nil {
+		return nil, err
+	} else if t.missing {
+		return nil, pathError(path, ErrPathNotFound)
+	}
+	return UnmarshalWithOptions(d.src[t.start:t.end], d.opts...)
+}
+
+// Set replaces the value found by following path with value, written in the
+// compact form of Marshal. If the last element of path is a key that the
+// object it leads to does not have, a member is added to the end of the
+// object instead, laid out like the last member already there. With an empty
+// path, the whole value is replaced.
+//
+// Errors are as for Get; nothing is changed if Set fails.
+func (d *Document) Set(value any, path ...any) error {
+	text, err := Marshal(value)
+	if err != nil {
+		return err
+	}
+	t, err := d.find(path)
+	if err != nil {
+		return err
+	}
+	if t.missing {
+		var member []byte
+		if t.lead != nil {
+			member = append(append(member, ','), t.lead...)
+		}
+		member = AppendQuote(member, path[len(path)-1].(string))
+		if t.sep != nil {
+			member = append(member, t.sep...)
+		} else {
+			member = append(member, ':')
+		}
+		text = append(member, text...)
+	}
+	d.src = slices.Concat(d.src[:t.start], text, d.src[t.end:])
+	return nil
+}
+
+// Where a value is in a Document, or where a new member can go.
+type docTarget struct {
+	start, end int
+	// Whether the last key of the path is missing from its object, so that
+	// start and end are where a new member goes.
+	missing bool
+	// For a missing key, the whitespace before the key of the object's last
+	// member and the text between that key and its value, or nil if the
+	// object is empty.
+	lead, sep []byte
+}
+
+// Finds the value at path, or where to add it if only its key is missing.
+func (d *Document) find(path []any) (t docTarget, err error) {
+	defer recoverPanic(&err)
+	p := NewParserFromSlice(d.src, d.opts...).(*parser)
+	for i, elem := range path {
+		var ty valType
+		if ty, err = p.parseType(); err != nil {
+			return t, err
+		}
+		switch step := elem.(type) {
+		case string:
+			if ty != objectTy {
+				return t, d.wrongType(p, path[:i], "object")
+			}
+			if t, err = d.findMember(p, step); err != nil {
+				return t, err
+			}
+			if t.missing {
+				if i < len(path)-1 {
+					return t, pathError(path[:i+1], ErrPathNotFound)
+				}
+				return t, nil
+			}
+		case int:
+			if ty != arrayTy {
+				return t, d.wrongType(p, path[:i], "array")
+			}
+			var found bool
+			if found, err = d.findElem(p, step); err != nil {
+				return t, err
+			} else if !found {
+				return t, pathError(path[:i+1], ErrPathNotFound)
+			}
+		default:
+			return t, pathError(path[:i+1],
+				fmt.Errorf("path elements must be string or int, not %T", elem))
+		}
+	}
+	if err = p.skipSpaces(); err != nil {
+		return t, err
+	}
+	t = docTarget{start: p.begin}
+	if err = p.Skip(); err != nil {
+		return t, err
+	}
+	t.end = p.begin
+	return t, nil
+}
+
+// Returns a *WrongTypeError for the value at the front of p.
+func (d *Document) wrongType(p *parser, path []any, expected string) error {
+	raw, err := p.ReadRaw()
+	if err != nil {
+		return err
+	}
+	found, err := UnmarshalWithOptions(raw, d.opts...)
+	if err != nil {
+		return err
+	}
+	return pathError(path, &WrongTypeError{Expected: expected, Found: found})
+}
+
+// Leaves p at the value of the last member of the object at its front with
+// the given key, or returns where to add one if there is none.
+func (d *Document) findMember(p *parser, key string) (t docTarget, err error) {
+	if err = p.readByte('{'); err != nil {
+		return
+	}
+	found := -1
+	for n := 0; ; n++ {
+		sepEnd := p.begin // just after the { or comma
+		var ty valType
+		if ty, err = p.parseType(); err != nil {
+			return
+		}
+		if ty == endGroupSym {
+			if found >= 0 {
+				p.begin = found
+				return docTarget{}, nil
+			}
+			if n == 0 {
+				t.start = sepEnd
+			}
+			t.end = t.start
+			t.missing = true
+			return t, nil
+		}
+		if n > 0 {
+			if err = p.consumeComma(ty); err != nil {
+				return
+			}
+			sepEnd = p.begin
+			if err = p.skipSpaces(); err != nil {
+				return
+			}
+		}
+		keyStart := p.begin
+		var k []byte
+		if k, err = p.parseString(); err != nil {
+			return
+		}
+		match := string(k) == key
+		keyEnd := p.begin
+		if err = p.skipSpaces(); err != nil {
+			return
+		}
+		if err = p.readByte(':'); err != nil {
+			return
+		}
+		if err = p.skipSpaces(); err != nil {
+			return
+		}
+		if match {
+			found = p.begin
+		}
+		t.lead = trailingSpace(d.src[sepEnd:keyStart])
+		t.sep = d.src[keyEnd:p.begin]
+		if len(bytes.Trim(t.sep, " \t\r\n:")) > 0 {
+			t.sep = []byte(": ") // there are comments in the way
+		}
+		if err = p.Skip(); err != nil {
+			return
+		}
+		t.start = p.begin
+	}
+}
+
+// Leaves p at element i of the array at its front, reporting whether it has
+// one.
+func (d *Document) findElem(p *parser, i int) (bool, error) {
+	if err := p.readByte('['); err != nil {
+		return false, err
+	}
+	for n := 0; ; n++ {
+		ty, err := p.parseType()
+		if err != nil {
+			return false, err
+		}
+		if ty == endGroupSym {
+			return false, nil
+		}
+		if n > 0 {
+			if err = p.consumeComma(ty); err != nil {
+				return false, err
+			}
+			if err = p.skipSpaces(); err != nil {
+				return false, err
+			}
+		}
+		if n == i {
+			return true, nil
+		}
+		if err = p.Skip(); err != nil {
+			return false, err
+		}
+	}
+}
+
+// Returns the whitespace at the end of b, which is never nil.
+func trailingSpace(b []byte) []byte {
+	i := len(b)
+	for i > 0 && spaceTable[b[i-1]] {
+		i--
+	}
+	return b[i:len(b):len(b)]
+}
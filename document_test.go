@@ -0,0 +1,157 @@
+package simplejsonext
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const documentText = `{
+    "name":   "sweep-7",
+    "lr":     1.50E-3,
+    "steps":  1e5,
+    "layers": [64, 64,
+               32],
+    "notes":  "café",
+    "extra":  {"nan": NaN, "empty": {}}
+}
+`
+
+// Returns the numbers of the lines that differ between a and b, which must
+// have the same number of lines.
+func changedLines(t *testing.T, a, b string) (changed []int) {
+	t.Helper()
+	aLines, bLines := strings.Split(a, "\n"), strings.Split(b, "\n")
+	require.Equal(t, len(aLines), len(bLines), b)
+	for i := range aLines {
+		if aLines[i] != bLines[i] {
+			changed = append(changed, i+1)
+		}
+	}
+	return changed
+}
+
+func TestDocumentSet(t *testing.T) {
+	for _, test := range []struct {
+		path  []any
+		value any
+		line  int
+		text  string
+	}{
+		{[]any{"lr"}, 3e-4, 3, `    "lr":     0.0003,`},
+		{[]any{"name"}, "sweep-8", 2, `    "name":   "sweep-8",`},
+		{[]any{"layers", 2}, int64(16), 6, `               16],`},
+		{[]any{"layers", 0}, nil, 5, `    "layers": [null, 64,`},
+		{[]any{"extra", "empty"}, map[string]any{"k": true}, 8, `    "extra":  {"nan": NaN, "empty": {"k":true}}`},
+	} {
+		doc, err := ParseDocument([]byte(documentText))
+		require.NoError(t, err)
+		require.NoError(t, doc.Set(test.value, test.path...), test.path)
+		out := string(doc.Bytes())
+		assert.Equal(t, []int{test.line}, changedLines(t, documentText, out), out)
+		assert.Equal(t, test.text, strings.Split(out, "\n")[test.line-1])
+
+		// The output still parses, with only that value changed.
+		expected, err := Unmarshal([]byte(documentText))
+		require.NoError(t, err)
+		parent, err := Get(expected, test.path[:len(test.path)-1]...)
+		require.NoError(t, err)
+		switch parent := parent.(type) {
+		case map[string]any:
+			parent[test.path[len(test.path)-1].(string)] = test.value
+		case []any:
+			parent[test.path[len(test.path)-1].(int)] = test.value
+		}
+		actual, err := Unmarshal(doc.Bytes())
+		require.NoError(t, err)
+		assert.Equal(t, WalkDeNaN(expected), WalkDeNaN(actual), test.path)
+		got, err := doc.Get(test.path...)
+		require.NoError(t, err)
+		assert.Equal(t, test.value, got)
+	}
+
+	// Replacing everything keeps the surrounding whitespace.
+	doc, err := ParseDocument([]byte(" \n[1,  2] \n"))
+	require.NoError(t, err)
+	require.NoError(t, doc.Set([]any{"x"}))
+	assert.Equal(t, " \n[\"x\"] \n", string(doc.Bytes()))
+}
+
+func TestDocumentAddKey(t *testing.T) {
+	doc, err := ParseDocument([]byte(documentText))
+	require.NoError(t, err)
+	require.NoError(t, doc.Set(int64(8), "batch"))
+	require.NoError(t, doc.Set("x", "extra", "new"))
+	require.NoError(t, doc.Set(int64(1), "extra", "empty", "a"))
+	assert.Equal(t, `{
+    "name":   "sweep-7",
+    "lr":     1.50E-3,
+    "steps":  1e5,
+    "layers": [64, 64,
+               32],
+    "notes":  "café",
+    "extra":  {"nan": NaN, "empty": {"a":1}, "new": "x"},
+    "batch":  8
+}
+`, string(doc.Bytes()))
+
+	doc, err = ParseDocument([]byte(`{"a":1}`))
+	require.NoError(t, err)
+	require.NoError(t, doc.Set("é\n", "b"))
+	assert.Equal(t, `{"a":1,"b":"é\n"}`, string(doc.Bytes()))
+
+	// Keys that appear more than once are edited where Parse reads them.
+	doc, err = ParseDocument([]byte(`{"a": 1, "a": 2}`))
+	require.NoError(t, err)
+	require.NoError(t, doc.Set(int64(3), "a"))
+	assert.Equal(t, `{"a": 1, "a": 3}`, string(doc.Bytes()))
+}
+
+func TestDocumentComments(t *testing.T) {
+	text := "// settings\n{\n  /* rate */ \"lr\" /* ! */ : 0.1, // tuned\n  \"n\": 2 // count\n}\n"
+	doc, err := ParseDocument([]byte(text), WithComments())
+	require.NoError(t, err)
+	require.NoError(t, doc.Set(0.2, "lr"))
+	require.NoError(t, doc.Set(int64(3), "m"))
+	assert.Equal(t, "// settings\n{\n  /* rate */ \"lr\" /* ! */ : 0.2, // tuned\n  \"n\": 2,\n  \"m\": 3 // count\n}\n", string(doc.Bytes()))
+	v, err := NewParserFromSlice(doc.Bytes(), WithComments()).UnmarshalFull()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"lr": 0.2, "n": int64(2), "m": int64(3)}, v)
+	_, err = ParseDocument([]byte(text))
+	assert.Error(t, err)
+}
+
+func TestDocumentErrors(t *testing.T) {
+	_, err := ParseDocument([]byte(`{"a": 1} 2`))
+	assert.ErrorIs(t, err, ErrTrailingData)
+	_, err = ParseDocument([]byte(`{"a": }`))
+	assert.Error(t, err)
+
+	doc, err := ParseDocument([]byte(documentText))
+	require.NoError(t, err)
+	for _, path := range [][]any{{"missing", "a"}, {"layers", 3}, {"layers", -1}} {
+		err = doc.Set(int64(1), path...)
+		assert.ErrorIs(t, err, ErrPathNotFound, path)
+		_, err = doc.Get(path...)
+		assert.ErrorIs(t, err, ErrPathNotFound, path)
+	}
+	_, err = doc.Get("missing")
+	assert.ErrorIs(t, err, ErrPathNotFound)
+
+	err = doc.Set(int64(1), "name", "first")
+	var wrongType *WrongTypeError
+	if assert.ErrorAs(t, err, &wrongType) {
+		assert.EqualError(t, err, `simple json: at $["name"]: expected object but found string`)
+	}
+	err = doc.Set(int64(1), "extra", 0)
+	assert.ErrorAs(t, err, &wrongType)
+	err = doc.Set(int64(1), 1.5)
+	assert.EqualError(t, err, "simple json: at $[1.5]: path elements must be string or int, not float64")
+	err = doc.Set(func() {}, "name")
+	assert.Error(t, err)
+
+	// Nothing changed.
+	assert.Equal(t, documentText, string(doc.Bytes()))
+}
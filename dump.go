@@ -0,0 +1,184 @@
+package simplejsonext
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// DumpOptions bounds the output of DumpWithOptions. Zero values mean no limit.
+type DumpOptions struct {
+	// MaxStringBytes is the longest string shown whole. Longer strings are
+	// cut to at most this many bytes, without splitting a UTF-8 sequence, and
+	// followed by a note of how many bytes were left out.
+	MaxStringBytes int
+	// MaxArrayElements is the most elements shown of each array. A line
+	// noting how many were left out takes the place of the rest.
+	MaxArrayElements int
+}
+
+// DefaultDumpOptions are the limits used by Dump and DumpValue.
+var DefaultDumpOptions = DumpOptions{
+	MaxStringBytes:   256,
+	MaxArrayElements: 100,
+}
+
+// Dump renders v for debugging, with the type of every number spelled out,
+// using DefaultDumpOptions. It never fails. The format is stable:
+//
+//   - null, true and false are written as such.
+//   - Numbers are written as their Go type applied to their value, like
+//     int64(3), float64(3), float32(0.5), float64(NaN), float64(+Inf), and
+//     float64(-0.0) for negative zero. A Number is written as Number(1.10),
+//     with its exact text.
+//   - Strings are quoted as by strconv.Quote, and a string that is cut short
+//     is followed by a note like "abc"… (+12 bytes).
+//   - Arrays and objects are written over several lines, one element or
+//     member per line, indented by two spaces per level, with the keys of
+//     maps sorted. Empty ones are written as [] and {}.
+//   - *OrderedObject values are written like maps, but prefixed with
+//     "ordered" and with their keys in order. A nil one is written as null.
+//   - Anything else is written as its Go type in angle brackets, like
+//     <time.Time>.
+func Dump(v any) string {
+	return DumpWithOptions(v, DefaultDumpOptions)
+}
+
+// DumpWithOptions is like Dump, with the given limits.
+func DumpWithOptions(v any, opts DumpOptions) string {
+	d := dumper{opts: opts}
+	d.dump(v, 0)
+	return d.sb.String()
+}
+
+// DumpValue wraps a value so that formatting it with the fmt package, with
+// any verb, writes Dump(V).
+type DumpValue struct {
+	V any
+}
+
+var _ fmt.Formatter = DumpValue{}
+
+func (d DumpValue) Format(f fmt.State, _ rune) {
+	_, _ = f.Write([]byte(Dump(d.V)))
+}
+
+type dumper struct {
+	opts DumpOptions
+	sb   strings.Builder
+}
+
+func (d *dumper) newline(depth int) {
+	d.sb.WriteByte('\n')
+	for i := 0; i < depth; i++ {
+		d.sb.WriteString("  ")
+	}
+}
+
+func (d *dumper) dump(v any, depth int) {
+	if depth > maxDepth {
+		// Only a value that contains itself gets this deep.
+		d.sb.WriteString("…")
+		return
+	}
+	switch vt := v.(type) {
+	case nil:
+		d.sb.Write(nullBytes[:])
+	case bool:
+		d.sb.WriteString(strconv.FormatBool(vt))
+	case int64, int32, int, uint64, uint32, uint:
+		fmt.Fprintf(&d.sb, "%T(%d)", vt, vt)
+	case float64:
+		d.dumpFloat("float64", vt, 64)
+	case float32:
+		d.dumpFloat("float32", float64(vt), 32)
+	case Number:
+		fmt.Fprintf(&d.sb, "Number(%s)", string(vt))
+	case string:
+		d.dumpString(vt)
+	case []any:
+		if len(vt) == 0 {
+			d.sb.WriteString("[]")
+			return
+		}
+		d.sb.WriteByte('[')
+		shown := len(vt)
+		if d.opts.MaxArrayElements > 0 {
+			shown = min(shown, d.opts.MaxArrayElements)
+		}
+		for _, elem := range vt[:shown] {
+			d.newline(depth + 1)
+			d.dump(elem, depth+1)
+		}
+		if shown < len(vt) {
+			d.newline(depth + 1)
+			fmt.Fprintf(&d.sb, "… (+%d more)", len(vt)-shown)
+		}
+		d.newline(depth)
+		d.sb.WriteByte(']')
+	case map[string]any:
+		keys := make([]string, 0, len(vt))
+		for key := range vt {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		d.dumpMembers(len(keys), func(i int) (string, any) { return keys[i], vt[keys[i]] }, depth)
+	case *OrderedObject:
+		if vt == nil {
+			d.sb.Write(nullBytes[:])
+			return
+		}
+		d.sb.WriteString("ordered")
+		d.dumpMembers(len(vt.Members), func(i int) (string, any) { return vt.Members[i].Key, vt.Members[i].Value }, depth)
+	default:
+		fmt.Fprintf(&d.sb, "<%T>", vt)
+	}
+}
+
+func (d *dumper) dumpFloat(typeName string, f float64, bitSize int) {
+	d.sb.WriteString(typeName)
+	d.sb.WriteByte('(')
+	switch {
+	case math.IsNaN(f):
+		d.sb.WriteString("NaN")
+	case f == 0 && math.Signbit(f):
+		d.sb.WriteString("-0.0")
+	default:
+		// Infinities come out as +Inf and -Inf.
+		d.sb.WriteString(strconv.FormatFloat(f, 'g', -1, bitSize))
+	}
+	d.sb.WriteByte(')')
+}
+
+func (d *dumper) dumpString(s string) {
+	if d.opts.MaxStringBytes <= 0 || len(s) <= d.opts.MaxStringBytes {
+		d.sb.WriteString(strconv.Quote(s))
+		return
+	}
+	cut := d.opts.MaxStringBytes
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	d.sb.WriteString(strconv.Quote(s[:cut]))
+	fmt.Fprintf(&d.sb, "… (+%d bytes)", len(s)-cut)
+}
+
+func (d *dumper) dumpMembers(n int, member func(i int) (string, any), depth int) {
+	if n == 0 {
+		d.sb.WriteString("{}")
+		return
+	}
+	d.sb.WriteByte('{')
+	for i := 0; i < n; i++ {
+		key, value := member(i)
+		d.newline(depth + 1)
+		d.sb.WriteString(strconv.Quote(key))
+		d.sb.WriteString(": ")
+		d.dump(value, depth+1)
+	}
+	d.newline(depth)
+	d.sb.WriteByte('}')
+}
@@ -0,0 +1,91 @@
+package simplejsonext
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDumpGolden(t *testing.T) {
+	v := map[string]any{
+		"z":     nil,
+		"b":     true,
+		"ints":  []any{int64(3), int(-4), uint64(math.MaxUint64), int32(0)},
+		"float": []any{float64(3), 0.5, math.NaN(), math.Inf(1), math.Inf(-1), math.Copysign(0, -1), float32(0.1), 1e21},
+		"str":   "quote \" and\nnewline",
+		"empty": []any{map[string]any{}, []any{}, &OrderedObject{}},
+		"ordered": &OrderedObject{Members: []Member{
+			{Key: "y", Value: "first"},
+			{Key: "x", Value: []any{int64(1)}},
+		}},
+		"other": []any{time.Time{}, []string{"a"}, (*OrderedObject)(nil)},
+	}
+	const expected = `{
+  "b": true
+  "empty": [
+    {}
+    []
+    ordered{}
+  ]
+  "float": [
+    float64(3)
+    float64(0.5)
+    float64(NaN)
+    float64(+Inf)
+    float64(-Inf)
+    float64(-0.0)
+    float32(0.1)
+    float64(1e+21)
+  ]
+  "ints": [
+    int64(3)
+    int(-4)
+    uint64(18446744073709551615)
+    int32(0)
+  ]
+  "ordered": ordered{
+    "y": "first"
+    "x": [
+      int64(1)
+    ]
+  }
+  "other": [
+    <time.Time>
+    <[]string>
+    null
+  ]
+  "str": "quote \" and\nnewline"
+  "z": null
+}`
+	assert.Equal(t, expected, Dump(v))
+	assert.Equal(t, expected, fmt.Sprint(DumpValue{v}))
+	assert.Equal(t, "x: "+expected, fmt.Sprintf("x: %#v", DumpValue{v}))
+
+	assert.Equal(t, "int64(0)", Dump(int64(0)))
+	assert.Equal(t, "float64(0)", Dump(float64(0)))
+	assert.Equal(t, "null", Dump(nil))
+}
+
+func TestDumpLimits(t *testing.T) {
+	v := []any{strings.Repeat("é", 5), int64(1), int64(2), int64(3)}
+	const expected = `[
+  "éé"… (+6 bytes)
+  int64(1)
+  … (+2 more)
+]`
+	assert.Equal(t, expected, DumpWithOptions(v, DumpOptions{MaxStringBytes: 5, MaxArrayElements: 2}))
+
+	// No limits at all.
+	long := strings.Repeat("x", 1000)
+	assert.Equal(t, `"`+long+`"`, DumpWithOptions(long, DumpOptions{}))
+	assert.Equal(t, `"`+long[:256]+`"… (+744 bytes)`, Dump(long))
+
+	// A value that contains itself is cut off rather than looping forever.
+	loop := []any{nil}
+	loop[0] = loop
+	assert.True(t, strings.HasSuffix(strings.TrimRight(Dump(loop), "\n ]"), "…"))
+}
@@ -2,12 +2,17 @@ package simplejsonext
 
 import (
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
 	"math"
+	"math/big"
 	"reflect"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 var (
@@ -15,84 +20,286 @@ var (
 	trueBytes  = [...]byte{'t', 'r', 'u', 'e'}
 	falseBytes = [...]byte{'f', 'a', 'l', 's', 'e'}
 
+	infinityBytes    = [...]byte{'I', 'n', 'f', 'i', 'n', 'i', 't', 'y'}
+	negInfinityBytes = [...]byte{'-', 'I', 'n', 'f', 'i', 'n', 'i', 't', 'y'}
+	// Negative zero has a decimal point so that it parses back as a float
+	negZeroBytes = [...]byte{'-', '0', '.', '0'}
+
 	arrayOpen  = [...]byte{'['}
 	arrayClose = [...]byte{']'}
 
 	mapOpen  = [...]byte{'{'}
 	mapClose = [...]byte{'}'}
 
+	quote  = [...]byte{'"'}
 	comma  = [...]byte{','}
 	column = [...]byte{':'}
 
+	// The separator between keys and values when indenting
+	columnSpace = [...]byte{':', ' '}
+
 	hexChars = [...]byte{'0', '1', '2', '3', '4', '5', '6', '7', '8', '9', 'a', 'b', 'c', 'd', 'e', 'f'}
 )
 
 type Emitter interface {
+	// Emit writes val. Besides the simple JSON types, structs are written as
+	// objects of their exported fields, as encoding/json writes them: in the
+	// order they are declared, named by their json tags, leaving out fields
+	// tagged `json:"-"`, or tagged omitempty and empty, and with the fields of
+	// embedded structs written as the struct's own.
 	Emit(val any) error
+	// Comment writes text as // comments for WithComments to read, one for
+	// each of its lines, each ending with a newline followed, as inside
+	// values, by the prefix from WithIndent. Comment writes between whole
+	// values: before the first, or after the newline that follows one. To
+	// comment on a member or element inside a value, wrap it in Commented.
+	// Comment fails without WithIndent, since compact output is meant for
+	// readers of strict JSON.
+	Comment(text string) error
+	// Stats returns counts of what the Emitter has written since it was
+	// created or last Reset.
+	Stats() EmitterStats
 	Reset(io.Writer)
 }
 
+// Commented is a value preceded by a comment when it is emitted with
+// WithIndent. The comment is written as Emitter.Comment writes it: before the
+// value if it is an array element or stands alone, or before the key if it is
+// the value of an object member. Without WithIndent the comment is left out
+// and only Value is written.
+type Commented struct {
+	Comment string
+	Value   any
+}
+
+// EmitterStats counts the output of an Emitter.
+type EmitterStats struct {
+	// BytesWritten is the number of bytes the Emitter has passed to its
+	// writer, including those of values that failed part way through.
+	BytesWritten int64
+	// Values is the number of calls to Emit that succeeded.
+	Values int64
+}
+
+// JSONExtAppender is implemented by types that write their own JSON, which
+// may be extended JSON, by appending it to dst, so that they can be emitted
+// without allocating. AppendJSONExt must append exactly one value, and return
+// the extended slice.
+//
+// The Emitter calls AppendJSONExt on any value that implements it other than
+// those of the types it handles itself, such as RawMessage and *big.Int, and
+// a nil pointer, which it writes as null. It takes precedence over the error
+// interface. Methods of other interfaces, such as json.Marshaler, are never
+// called. The output is only checked in builds with the simplejsonext_debug
+// tag.
+type JSONExtAppender interface {
+	AppendJSONExt(dst []byte) ([]byte, error)
+}
+
 type emitter struct {
-	w io.Writer
-	s []byte
-	a [128]byte
+	w     io.Writer
+	s     []byte
+	a     [128]byte
+	fixed []byte     // scratch for strings with invalid UTF-8 replaced
+	cfg   emitConfig // optional behaviors, retained across resets
+	depth int        // how many indented arrays and objects we are in
+	level int        // how many containers and pointers emit is recursing through
+	stats EmitterStats
 }
 
-func NewEmitter(w io.Writer) Emitter {
+func NewEmitter(w io.Writer, opts ...EmitOption) Emitter {
 	e := &emitter{w: w}
 	e.s = e.a[:0]
+	e.cfg.apply(opts)
 	return e
 }
 
 func (e *emitter) Reset(w io.Writer) {
 	e.w = w
+	e.depth = 0
+	e.stats = EmitterStats{}
 	if cap(e.s) > oversizedBuffer {
 		e.s = e.a[:0]
 	}
+	if cap(e.fixed) > oversizedBuffer {
+		e.fixed = nil
+	}
+}
+
+func (e *emitter) Stats() EmitterStats {
+	return e.stats
+}
+
+// Writes b to the writer, counting the bytes written.
+func (e *emitter) write(b []byte) error {
+	n, err := e.w.Write(b)
+	e.stats.BytesWritten += int64(n)
+	return err
 }
 
 func (e *emitter) emitNil() (err error) {
-	_, err = e.w.Write(nullBytes[:])
+	err = e.write(nullBytes[:])
 	return
 }
 
 func (e *emitter) emitBool(v bool) (err error) {
 	if v {
-		_, err = e.w.Write(trueBytes[:])
+		err = e.write(trueBytes[:])
 	} else {
-		_, err = e.w.Write(falseBytes[:])
+		err = e.write(falseBytes[:])
 	}
 	return
 }
 
 func (e *emitter) emitInt(v int64, _ int) (err error) {
-	_, err = e.w.Write(strconv.AppendInt(e.s[:0], v, 10))
+	err = e.write(AppendInt(e.s[:0], v))
 	return
 }
 
 func (e *emitter) emitUint(v uint64, _ int) (err error) {
-	_, err = e.w.Write(strconv.AppendUint(e.s[:0], v, 10))
+	err = e.write(strconv.AppendUint(e.s[:0], v, 10))
 	return
 }
 
 func (e *emitter) emitFloat(v float64, bitSize int) (err error) {
-	// AppendFloat writes NaN the way we want, but spells infinity values as
-	// `+Inf` and `-Inf`, which we don't like as much.
-	if math.IsInf(v, +1) {
-		_, err = e.w.Write([]byte("Infinity"))
-	} else if math.IsInf(v, -1) {
-		_, err = e.w.Write([]byte("-Infinity"))
-	} else {
-		_, err = e.w.Write(strconv.AppendFloat(e.s[:0], v, 'g', -1, bitSize))
+	if e.cfg.strictFloats && (math.IsNaN(v) || math.IsInf(v, 0)) {
+		return fmt.Errorf("simple json: cannot emit non-finite float %v", v)
 	}
+	err = e.write(AppendFloat(e.s[:0], v, FloatFormat{BitSize: bitSize}))
 	return
 }
 
+// AppendInt appends i to dst as the Emitter writes it, and returns the
+// extended slice.
+func AppendInt(dst []byte, i int64) []byte {
+	return strconv.AppendInt(dst, i, 10)
+}
+
+// FloatFormat configures AppendFloat.
+type FloatFormat struct {
+	// BitSize is 32 to write the shortest text that parses back to the same
+	// float32, and 64, or 0, to do so for float64.
+	BitSize int
+}
+
+// AppendFloat appends f to dst as the Emitter writes it, and returns the
+// extended slice. That is the shortest text that parses back to f, which for
+// an integral value has no decimal point and so parses back as an integer,
+// except that negative zero is written as -0.0 to keep its sign. NaN and the
+// infinities are written as the extended JSON tokens NaN, Infinity and
+// -Infinity.
+func AppendFloat(dst []byte, f float64, opts FloatFormat) []byte {
+	// strconv.AppendFloat writes NaN the way we want, but spells infinity
+	// values as `+Inf` and `-Inf`, which we don't like as much.
+	switch {
+	case math.IsInf(f, +1):
+		return append(dst, infinityBytes[:]...)
+	case math.IsInf(f, -1):
+		return append(dst, negInfinityBytes[:]...)
+	case f == 0 && math.Signbit(f):
+		return append(dst, negZeroBytes[:]...)
+	}
+	bitSize := opts.BitSize
+	if bitSize != 32 {
+		bitSize = 64
+	}
+	return strconv.AppendFloat(dst, f, 'g', -1, bitSize)
+}
+
+// InvalidUTF8Error is returned by an Emitter using InvalidUTF8Fail when a
+// string is not valid UTF-8.
+type InvalidUTF8Error struct {
+	// Path holds the object keys and array indices leading to the string, as
+	// for Get. For an object key, it ends with the key itself.
+	Path []any
+	// Key is whether the string is an object key rather than a value.
+	Key bool
+	// Offset is the position in the string of its first invalid byte.
+	Offset int
+}
+
+func (e *InvalidUTF8Error) Error() string {
+	what := "string"
+	if e.Key {
+		what = "object key"
+	}
+	return fmt.Sprintf("simple json: invalid UTF-8 at byte %d of the %s at %s", e.Offset, what, formatPath(e.Path))
+}
+
+// Adds elem to the front of the path of an *InvalidUTF8Error, as it is
+// returned from inside the array or object where elem is the index or key.
+func atPath(err error, elem any) error {
+	if ue, ok := err.(*InvalidUTF8Error); ok {
+		ue.Path = append([]any{elem}, ue.Path...)
+	}
+	return err
+}
+
+// Like atPath, for an error writing key itself.
+func atKey(err error, key string) error {
+	if ue, ok := err.(*InvalidUTF8Error); ok {
+		ue.Key = true
+	}
+	return atPath(err, key)
+}
+
+// Returns the offset of the first byte of invalid UTF-8 in v.
+func invalidUTF8Offset(v string) int {
+	for i := 0; i < len(v); {
+		r, n := utf8.DecodeRuneInString(v[i:])
+		if r == utf8.RuneError && n == 1 {
+			return i
+		}
+		i += n
+	}
+	return len(v)
+}
+
+// Returns v, or a fixed copy of it if it is not valid UTF-8, as the
+// InvalidUTF8Policy says.
+func (e *emitter) validUTF8(v string) (string, error) {
+	if e.cfg.invalidUTF8 != InvalidUTF8PassThrough && !utf8.ValidString(v) {
+		if e.cfg.invalidUTF8 == InvalidUTF8Fail {
+			return v, &InvalidUTF8Error{Offset: invalidUTF8Offset(v)}
+		}
+		e.fixed = appendValidUTF8(e.fixed[:0], bytesNoCopy(v))
+		v = stringNoCopy(e.fixed)
+	}
+	return v, nil
+}
+
 func (e *emitter) emitString(v string) (err error) {
+	if v, err = e.validUTF8(v); err != nil {
+		return err
+	}
+	var s []byte
+	if e.cfg.escapeHTML || e.cfg.escapeSeparators {
+		s = append(e.s[:0], '"')
+		s = appendEscapedHTML(s, v, e.cfg.escapeHTML)
+		s = append(s, '"')
+	} else {
+		s = AppendQuote(e.s[:0], v)
+	}
+	e.s = s[:0] // in case the buffer was reallocated
+
+	err = e.write(s)
+	return
+}
+
+// AppendQuote appends the JSON string literal for s, including the
+// surrounding quotes, to dst and returns the extended buffer. The escaping is
+// exactly that used by the Emitter.
+func AppendQuote(dst []byte, s string) []byte {
+	dst = append(dst, '"')
+	dst = AppendEscaped(dst, s)
+	return append(dst, '"')
+}
+
+// AppendEscaped is like AppendQuote, but does not add the surrounding quotes.
+func AppendEscaped(dst []byte, v string) []byte {
 	i := 0
 	j := 0
 	n := len(v)
-	s := append(e.s[:0], '"')
 
 	for j != n {
 		b := v[j]
@@ -120,24 +327,66 @@ func (e *emitter) emitString(v string) (err error) {
 		default:
 			if b < 32 {
 				// Control characters not cased up above MUST be escaped.
-				s = append(s, v[i:j-1]...)
-				s = append(s, '\\', 'u', '0', '0', hexChars[(b&0xf0)>>4], hexChars[b&0xf])
+				dst = append(dst, v[i:j-1]...)
+				dst = append(dst, '\\', 'u', '0', '0', hexChars[(b&0xf0)>>4], hexChars[b&0xf])
 				i = j
 			}
 			continue
 		}
 
-		s = append(s, v[i:j-1]...)
-		s = append(s, '\\', b)
+		dst = append(dst, v[i:j-1]...)
+		dst = append(dst, '\\', b)
 		i = j
 	}
 
-	s = append(s, v[i:j]...)
-	s = append(s, '"')
-	e.s = s[:0] // in case the buffer was reallocated
+	return append(dst, v[i:j]...)
+}
 
-	_, err = e.w.Write(s)
-	return
+// Like AppendEscaped, but also escapes the characters that WithEscapeHTML
+// calls for.
+func appendEscapedHTML(dst []byte, v string, html bool) []byte {
+	i := 0
+	for j := 0; j < len(v); j++ {
+		var r rune
+		switch b := v[j]; {
+		case html && (b == '<' || b == '>' || b == '&'):
+			r = rune(b)
+		case b == 0xe2 && j+2 < len(v) && v[j+1] == 0x80 && (v[j+2] == 0xa8 || v[j+2] == 0xa9):
+			r = 0x2000 | rune(v[j+2]&0x7f) // U+2028 or U+2029
+		default:
+			continue
+		}
+		dst = AppendEscaped(dst, v[i:j])
+		dst = append(dst, '\\', 'u',
+			hexChars[r>>12&0xf], hexChars[r>>8&0xf], hexChars[r>>4&0xf], hexChars[r&0xf])
+		if r > 0x7f {
+			j += 2
+		}
+		i = j + 1
+	}
+	return AppendEscaped(dst, v[i:])
+}
+
+// EscapeString returns s escaped as the contents of a JSON string literal,
+// without the surrounding quotes.
+func EscapeString(s string) string {
+	return string(AppendEscaped(make([]byte, 0, quotedLen(s)-2), s))
+}
+
+// Returns the length of s when written as a quoted string by AppendQuote.
+func quotedLen(s string) int {
+	n := len(s) + 2
+	for i := 0; i < len(s); i++ {
+		switch b := s[i]; b {
+		case '"', '\\', '\b', '\f', '\n', '\r', '\t':
+			n++
+		default:
+			if b < 32 {
+				n += 5 // \u00XX
+			}
+		}
+	}
+	return n
 }
 
 func (e *emitter) emitBytes(v []byte) (err error) {
@@ -154,10 +403,51 @@ func (e *emitter) emitBytes(v []byte) (err error) {
 	base64.StdEncoding.Encode(s[1:], v)
 	s[n-1] = '"'
 
-	_, err = e.w.Write(s)
+	err = e.write(s)
+	return
+}
+
+func (e *emitter) emitRaw(v RawMessage) (err error) {
+	if len(v) == 0 {
+		return e.emitNil()
+	}
+	if err = checkRawMessage(v); err != nil {
+		return
+	}
+	err = e.write(v)
+	return
+}
+
+func (e *emitter) emitAppended(v JSONExtAppender) (err error) {
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Pointer && rv.IsNil() {
+		return e.emitNil()
+	}
+	s, err := v.AppendJSONExt(e.s[:0])
+	if err != nil {
+		return
+	}
+	if emitDebug {
+		if err = checkAppended(s); err != nil {
+			return fmt.Errorf("simple json: invalid output from AppendJSONExt of %T: %w", v, err)
+		}
+	}
+	e.s = s[:0] // in case the buffer was reallocated
+	err = e.write(s)
 	return
 }
 
+// Checks that s holds exactly one value, without whitespace around it.
+func checkAppended(s []byte) error {
+	p := parser{readBuf: s, size: len(s)}
+	if len(s) == 0 || spaceTable[s[0]] || spaceTable[s[len(s)-1]] {
+		return errors.New("simple json: expected exactly one value")
+	}
+	if err := p.Skip(); err != nil {
+		return err
+	}
+	return p.CheckEmpty()
+}
+
 func (e *emitter) emitTime(v time.Time) (err error) {
 	s := e.s[:0]
 
@@ -166,7 +456,7 @@ func (e *emitter) emitTime(v time.Time) (err error) {
 	s = append(s, '"')
 
 	e.s = s[:0]
-	_, err = e.w.Write(s)
+	err = e.write(s)
 	return
 }
 
@@ -174,42 +464,184 @@ func (e *emitter) emitError(v error) (err error) {
 	return e.emitString(v.Error())
 }
 
-func (e *emitter) emitArrayBegin(_ int) (err error) {
-	_, err = e.w.Write(arrayOpen[:])
-	return
+func (e *emitter) emitArrayBegin(n int) (err error) {
+	return e.emitOpen(arrayOpen[:], n)
 }
 
-func (e *emitter) emitArrayEnd() (err error) {
-	_, err = e.w.Write(arrayClose[:])
-	return
+func (e *emitter) emitArrayEnd(n int) (err error) {
+	return e.emitClose(arrayClose[:], n)
 }
 
 func (e *emitter) emitArrayNext() (err error) {
-	_, err = e.w.Write(comma[:])
-	return
+	return e.emitNext()
 }
 
-func (e *emitter) emitMapBegin(_ int) (err error) {
-	_, err = e.w.Write(mapOpen[:])
-	return
+func (e *emitter) emitMapBegin(n int) (err error) {
+	return e.emitOpen(mapOpen[:], n)
 }
 
-func (e *emitter) emitMapEnd() (err error) {
-	_, err = e.w.Write(mapClose[:])
-	return
+func (e *emitter) emitMapEnd(n int) (err error) {
+	return e.emitClose(mapClose[:], n)
 }
 
 func (e *emitter) emitMapValue() (err error) {
-	_, err = e.w.Write(column[:])
+	if e.cfg.indented {
+		err = e.write(columnSpace[:])
+	} else {
+		err = e.write(column[:])
+	}
 	return
 }
 
 func (e *emitter) emitMapNext() (err error) {
-	_, err = e.w.Write(comma[:])
+	return e.emitNext()
+}
+
+// Writes the start of an array or object of n elements.
+func (e *emitter) emitOpen(b []byte, n int) (err error) {
+	if !e.cfg.indented || n == 0 {
+		err = e.write(b)
+		return
+	}
+	e.depth++
+	return e.writeIndented(b[0])
+}
+
+// Writes the end of an array or object of n elements.
+func (e *emitter) emitClose(b []byte, n int) (err error) {
+	if !e.cfg.indented || n == 0 {
+		err = e.write(b)
+		return
+	}
+	e.depth--
+	s := e.appendNewline(e.s[:0])
+	s = append(s, b...)
+	e.s = s[:0]
+	err = e.write(s)
+	return
+}
+
+// Writes the separator between elements.
+func (e *emitter) emitNext() (err error) {
+	if !e.cfg.indented {
+		err = e.write(comma[:])
+		return
+	}
+	return e.writeIndented(comma[0])
+}
+
+// Writes b followed by the start of a new indented line.
+func (e *emitter) writeIndented(b byte) (err error) {
+	s := e.appendNewline(append(e.s[:0], b))
+	e.s = s[:0]
+	err = e.write(s)
 	return
 }
 
+func (e *emitter) appendNewline(s []byte) []byte {
+	s = append(s, '\n')
+	s = append(s, e.cfg.prefix...)
+	for i := 0; i < e.depth; i++ {
+		s = append(s, e.cfg.indent...)
+	}
+	return s
+}
+
 func (e *emitter) Emit(v interface{}) (err error) {
+	e.level = 0
+	e.depth = 0 // in case the last value failed partway through
+	if err = e.emit(v); err == nil {
+		e.stats.Values++
+	}
+	return err
+}
+
+var errCompactComment = errors.New("simple json: cannot emit comments without indentation")
+
+func (e *emitter) Comment(text string) error {
+	if !e.cfg.indented {
+		return errCompactComment
+	}
+	return e.emitComment(text)
+}
+
+// Writes text as comments at the current depth, or nothing if the output is
+// not indented.
+func (e *emitter) emitComment(text string) error {
+	if !e.cfg.indented {
+		return nil
+	}
+	s := e.s[:0]
+	for more := true; more; {
+		var line string
+		line, text, more = cutLine(text)
+		s = append(s, '/', '/')
+		if line != "" {
+			s = append(s, ' ')
+			s = append(s, line...)
+		}
+		s = e.appendNewline(s)
+	}
+	e.s = s[:0]
+	return e.write(s)
+}
+
+// Writes the comment on the value of an object member, which goes before its
+// key, and returns the value to write after the key.
+func (e *emitter) emitMemberComment(v any) (any, error) {
+	c, ok := v.(Commented)
+	if !ok {
+		return v, nil
+	}
+	return c.Value, e.emitComment(c.Comment)
+}
+
+// Splits s at its first line break, which may be \n, \r, \r\n, or U+2028 or
+// U+2029 since JavaScript ends comments at those too.
+func cutLine(s string) (line, rest string, found bool) {
+	i := strings.IndexAny(s, "\r\n\u2028\u2029")
+	if i < 0 {
+		return s, "", false
+	}
+	n := 1
+	if s[i] == '\r' && strings.HasPrefix(s[i+1:], "\n") {
+		n = 2
+	} else if s[i] >= utf8.RuneSelf {
+		n = 3 // U+2028 and U+2029 are three bytes long
+	}
+	return s[:i], s[i+n:], true
+}
+
+// Binary exponents of *big.Float values beyond this, about 10^±9864, are too
+// costly to write out in decimal.
+const maxBigFloatExp = 1 << 15
+
+// Appends f in decimal, with as many digits as it takes to read back the same
+// value at f's precision. Like float64 values, infinities are written as
+// Infinity unless strict, and negative zero as -0.0.
+func appendBigFloat(dst []byte, f *big.Float, strict bool) ([]byte, error) {
+	if f.IsInf() {
+		if strict {
+			return dst, fmt.Errorf("simple json: cannot emit non-finite float %v", f)
+		}
+		if f.Signbit() {
+			return append(dst, negInfinityBytes[:]...), nil
+		}
+		return append(dst, infinityBytes[:]...), nil
+	}
+	if f.Sign() == 0 {
+		if f.Signbit() {
+			return append(dst, negZeroBytes[:]...), nil
+		}
+		return append(dst, '0'), nil
+	}
+	if exp := f.MantExp(nil); exp > maxBigFloatExp || exp < -maxBigFloatExp {
+		return dst, fmt.Errorf("simple json: cannot emit *big.Float with binary exponent %d", exp)
+	}
+	return f.Append(dst, 'g', -1), nil
+}
+
+func (e *emitter) emit(v any) (err error) {
 	switch vt := v.(type) {
 	case nil:
 		return e.emitNil()
@@ -232,14 +664,37 @@ func (e *emitter) Emit(v interface{}) (err error) {
 	case float32:
 		return e.emitFloat(float64(vt), 32)
 	case string:
-		return e.emitString(vt)
+		return e.emitStringValue(vt)
+	case Number:
+		return e.emitNumber(vt)
+	case *big.Int:
+		if vt == nil {
+			return e.emitNil()
+		}
+		err = e.write(vt.Append(e.s[:0], 10))
+		return
+	case *big.Float:
+		if vt == nil {
+			return e.emitNil()
+		}
+		var s []byte
+		if s, err = appendBigFloat(e.s[:0], vt, e.cfg.strictFloats); err != nil {
+			return
+		}
+		err = e.write(s)
+		e.s = s[:0]
+		return
 	case []any:
-		err = e.emitArrayBegin(0)
+		if e.level >= maxEmitRecursion {
+			return e.emitDeep(v)
+		}
+		e.level++
+		err = e.emitArrayBegin(len(vt))
 		if err != nil {
 			return
 		}
 		notFirst := false
-		for _, av := range vt {
+		for i, av := range vt {
 			if notFirst {
 				err = e.emitArrayNext()
 				if err != nil {
@@ -247,14 +702,22 @@ func (e *emitter) Emit(v interface{}) (err error) {
 				}
 			}
 			notFirst = true
-			err = e.Emit(av)
+			err = e.emit(av)
 			if err != nil {
-				return
+				return atPath(err, i)
 			}
 		}
-		return e.emitArrayEnd()
+		e.level--
+		return e.emitArrayEnd(len(vt))
 	case map[string]any:
-		err = e.emitMapBegin(0)
+		if e.cfg.sortKeys {
+			return e.emitOrderedObject(&OrderedObject{Members: sortedMembers(reflect.ValueOf(vt))})
+		}
+		if e.level >= maxEmitRecursion {
+			return e.emitDeep(v)
+		}
+		e.level++
+		err = e.emitMapBegin(len(vt))
 		if err != nil {
 			return
 		}
@@ -267,28 +730,59 @@ func (e *emitter) Emit(v interface{}) (err error) {
 				}
 			}
 			notFirst = true
-			err = e.emitString(key)
+			value, err = e.emitMemberComment(value)
 			if err != nil {
 				return
 			}
+			err = e.emitString(key)
+			if err != nil {
+				return atKey(err, key)
+			}
 			err = e.emitMapValue()
 			if err != nil {
 				return
 			}
-			err = e.Emit(value)
+			err = e.emit(value)
 			if err != nil {
-				return
+				return atPath(err, key)
 			}
 		}
-		return e.emitMapEnd()
+		e.level--
+		return e.emitMapEnd(len(vt))
+	case *OrderedObject:
+		if vt == nil {
+			return e.emitNil()
+		}
+		return e.emitOrderedObject(vt)
+	case OrderedObject:
+		return e.emitOrderedObject(&vt)
+	case Commented:
+		if err = e.emitComment(vt.Comment); err != nil {
+			return
+		}
+		return e.emit(vt.Value)
+	case RawMessage:
+		return e.emitRaw(vt)
 	case []byte:
+		if e.cfg.bytesAsStrings {
+			return e.emitString(stringNoCopy(vt))
+		}
 		return e.emitBytes(vt)
 	case time.Time:
 		return e.emitTime(vt)
+	case JSONExtAppender:
+		return e.emitAppended(vt)
 	case error:
 		return e.emitError(vt)
 	default:
 		ty := reflect.TypeOf(vt)
+		if ty.Kind() == reflect.Pointer || ty.Kind() == reflect.Slice || ty.Kind() == reflect.Map {
+			if e.level >= maxEmitRecursion {
+				return e.emitDeep(v)
+			}
+			e.level++
+			defer func() { e.level-- }()
+		}
 		if ty.Kind() == reflect.Pointer {
 			rp := reflect.ValueOf(v) // rp is the reflected pointer value of v
 			if rp.IsNil() {
@@ -296,12 +790,12 @@ func (e *emitter) Emit(v interface{}) (err error) {
 				return e.emitNil()
 			} else {
 				// v is a non-nil pointer; dereference it and emit that
-				return e.Emit(rp.Elem().Interface())
+				return e.emit(rp.Elem().Interface())
 			}
 		} else if ty.Kind() == reflect.Slice {
 			// Support non-`any` slices via reflection
 			rv := reflect.ValueOf(v)
-			err = e.emitArrayBegin(0)
+			err = e.emitArrayBegin(rv.Len())
 			if err != nil {
 				return
 			}
@@ -315,12 +809,12 @@ func (e *emitter) Emit(v interface{}) (err error) {
 					}
 				}
 				notFirst = true
-				err = e.Emit(av)
+				err = e.emit(av)
 				if err != nil {
-					return
+					return atPath(err, i)
 				}
 			}
-			return e.emitArrayEnd()
+			return e.emitArrayEnd(rv.Len())
 		} else if ty.Kind() == reflect.Map {
 			// Support non-`any`-valued maps via reflection, as long as the key
 			// type is exactly `string`
@@ -329,7 +823,10 @@ func (e *emitter) Emit(v interface{}) (err error) {
 			}
 
 			rv := reflect.ValueOf(v)
-			err = e.emitMapBegin(0)
+			if e.cfg.sortKeys {
+				return e.emitOrderedObject(&OrderedObject{Members: sortedMembers(rv)})
+			}
+			err = e.emitMapBegin(rv.Len())
 			if err != nil {
 				return
 			}
@@ -345,25 +842,88 @@ func (e *emitter) Emit(v interface{}) (err error) {
 					}
 				}
 				notFirst = true
-				err = e.emitString(key)
+				value, err = e.emitMemberComment(value)
 				if err != nil {
 					return
 				}
+				err = e.emitString(key)
+				if err != nil {
+					return atKey(err, key)
+				}
 				err = e.emitMapValue()
 				if err != nil {
 					return
 				}
-				err = e.Emit(value)
+				err = e.emit(value)
 				if err != nil {
-					return
+					return atPath(err, key)
 				}
 			}
-			return e.emitMapEnd()
+			return e.emitMapEnd(rv.Len())
+		} else if ty.Kind() == reflect.Struct {
+			members, err := structMembers(reflect.ValueOf(v))
+			if err != nil {
+				return err
+			}
+			return e.emitOrderedObject(&OrderedObject{Members: members})
+		} else if bv, ok := baseValue(reflect.ValueOf(v)); ok {
+			return e.emit(bv)
 		}
 	}
 	return fmt.Errorf("simple json: cannot emit unsupported type %T", v)
 }
 
+func (e *emitter) emitOrderedObject(obj *OrderedObject) (err error) {
+	if e.level >= maxEmitRecursion {
+		return e.emitDeep(obj)
+	}
+	e.level++
+	err = e.emitMapBegin(len(obj.Members))
+	if err != nil {
+		return
+	}
+	for i, member := range obj.Members {
+		if i > 0 {
+			err = e.emitMapNext()
+			if err != nil {
+				return
+			}
+		}
+		var value any
+		value, err = e.emitMemberComment(member.Value)
+		if err != nil {
+			return
+		}
+		err = e.emitString(member.Key)
+		if err != nil {
+			return atKey(err, member.Key)
+		}
+		err = e.emitMapValue()
+		if err != nil {
+			return
+		}
+		err = e.emit(value)
+		if err != nil {
+			return atPath(err, member.Key)
+		}
+	}
+	e.level--
+	return e.emitMapEnd(len(obj.Members))
+}
+
+// Returns the members of rv, a map with string keys, sorted by key, for
+// WithSortedKeys.
+func sortedMembers(rv reflect.Value) []Member {
+	members := make([]Member, 0, rv.Len())
+	for iter := rv.MapRange(); iter.Next(); {
+		members = append(members, Member{Key: iter.Key().String(), Value: iter.Value().Interface()})
+	}
+	slices.SortFunc(members, func(a, b Member) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+	return members
+}
+
 func align(n int, a int) int {
 	if (n % a) == 0 {
 		return n
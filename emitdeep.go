@@ -0,0 +1,225 @@
+package simplejsonext
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+)
+
+// Emitting values nested deeper than this continues with an explicit stack
+// rather than by recursion, so that no value is too deep to write.
+const maxEmitRecursion = 1000
+
+// An array or object being written by emitDeep. Frames are kept small, since
+// there may be a great many of them.
+type emitFrame struct {
+	elems   []any
+	members []Member
+	refl    *reflectList
+	n       int // number of elements
+	i       int // index of the next element
+	object  bool
+}
+
+// A slice, or a map with string keys, of some other type
+type reflectList struct {
+	rv   reflect.Value
+	iter *reflect.MapIter
+}
+
+// Returns the next key, for objects, and value in the frame, or false when it
+// has none left.
+func (f *emitFrame) next() (key string, val any, ok bool) {
+	if f.i >= f.n {
+		return "", nil, false
+	}
+	i := f.i
+	f.i++
+	switch {
+	case f.refl == nil && !f.object:
+		return "", f.elems[i], true
+	case f.refl == nil:
+		return f.members[i].Key, f.members[i].Value, true
+	case f.refl.iter != nil:
+		f.refl.iter.Next()
+		return f.refl.iter.Key().String(), f.refl.iter.Value().Interface(), true
+	default:
+		return "", f.refl.rv.Index(i).Interface(), true
+	}
+}
+
+// Returns the index or key of the element last returned by next, or nil if
+// there is none.
+func (f *emitFrame) pathElem() any {
+	switch {
+	case f.i == 0:
+		return nil
+	case f.refl == nil && !f.object:
+		return f.i - 1
+	case f.refl == nil:
+		return f.members[f.i-1].Key
+	case f.refl.iter != nil:
+		return f.refl.iter.Key().String()
+	default:
+		return f.i - 1
+	}
+}
+
+// Writes v just as emit does, but keeps the arrays and objects it is in on
+// the heap instead of the goroutine stack. Since emit continues here once it
+// is deep enough, this is also where a value that contains itself is found.
+func (e *emitter) emitDeep(v any) (err error) {
+	var stack []emitFrame
+	// What identifies each frame on the stack, if anything, as in
+	// CheckMarshalable.
+	var ids []any
+	onPath := make(map[any]bool)
+	defer func() {
+		if ue, ok := err.(*InvalidUTF8Error); ok {
+			var path []any
+			for i := range stack {
+				if elem := stack[i].pathElem(); elem != nil {
+					path = append(path, elem)
+				}
+			}
+			ue.Path = append(path, ue.Path...)
+		}
+	}()
+	for {
+		id := containerID(v)
+		if id != nil && onPath[id] {
+			return errCyclic
+		}
+		var f emitFrame
+		var isContainer bool
+		if f, isContainer, err = e.beginContainer(v); err != nil {
+			return
+		}
+		if isContainer {
+			stack = append(stack, f)
+			ids = append(ids, id)
+			if id != nil {
+				onPath[id] = true
+			}
+		}
+		// Find the next value to write, finishing any arrays and objects
+		// that have no more.
+		for {
+			if len(stack) == 0 {
+				return nil
+			}
+			top := &stack[len(stack)-1]
+			key, val, ok := top.next()
+			if !ok {
+				if top.object {
+					err = e.emitMapEnd(top.n)
+				} else {
+					err = e.emitArrayEnd(top.n)
+				}
+				if err != nil {
+					return
+				}
+				delete(onPath, ids[len(ids)-1])
+				stack, ids = stack[:len(stack)-1], ids[:len(ids)-1]
+				continue
+			}
+			if top.i > 1 {
+				if err = e.emitNext(); err != nil {
+					return
+				}
+			}
+			if top.object {
+				if val, err = e.emitMemberComment(val); err != nil {
+					return
+				}
+				if err = e.emitString(key); err != nil {
+					if ue, ok := err.(*InvalidUTF8Error); ok {
+						ue.Key = true
+					}
+					return
+				}
+				if err = e.emitMapValue(); err != nil {
+					return
+				}
+			}
+			v = val
+			break
+		}
+	}
+}
+
+// Writes the start of v and returns its frame if it is an array or object, or
+// writes all of it otherwise.
+func (e *emitter) beginContainer(v any) (f emitFrame, isContainer bool, err error) {
+	// Follow pointers and comments here, since emit would recurse through
+	// them. Errors, big numbers and ordered objects are written as such, just
+	// as emit does.
+Deref:
+	for {
+		if c, ok := v.(Commented); ok {
+			if err = e.emitComment(c.Comment); err != nil {
+				return
+			}
+			v = c.Value
+			continue
+		}
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Pointer || rv.IsNil() {
+			break
+		}
+		switch v.(type) {
+		case *OrderedObject, *big.Int, *big.Float, JSONExtAppender, error:
+			break Deref
+		}
+		v = rv.Elem().Interface()
+	}
+	switch vt := v.(type) {
+	case []any:
+		f = emitFrame{elems: vt, n: len(vt)}
+	case map[string]any:
+		rv := reflect.ValueOf(vt)
+		if e.cfg.sortKeys {
+			f = emitFrame{members: sortedMembers(rv), n: rv.Len(), object: true}
+		} else {
+			f = emitFrame{refl: &reflectList{rv: rv, iter: rv.MapRange()}, n: rv.Len(), object: true}
+		}
+	case *OrderedObject:
+		if vt == nil {
+			return f, false, e.emitNil()
+		}
+		f = emitFrame{members: vt.Members, n: len(vt.Members), object: true}
+	case OrderedObject:
+		f = emitFrame{members: vt.Members, n: len(vt.Members), object: true}
+	case RawMessage, []byte, JSONExtAppender, error:
+		return f, false, e.emit(v)
+	default:
+		rv := reflect.ValueOf(v)
+		switch rv.Kind() {
+		case reflect.Slice:
+			f = emitFrame{refl: &reflectList{rv: rv}, n: rv.Len()}
+		case reflect.Map:
+			if rv.Type().Key() != reflect.TypeOf("") {
+				return f, false, fmt.Errorf("simple json: cannot emit unsupported type %T", v)
+			}
+			if e.cfg.sortKeys {
+				f = emitFrame{members: sortedMembers(rv), n: rv.Len(), object: true}
+			} else {
+				f = emitFrame{refl: &reflectList{rv: rv, iter: rv.MapRange()}, n: rv.Len(), object: true}
+			}
+		case reflect.Struct:
+			members, err := structMembers(rv)
+			if err != nil {
+				return f, false, err
+			}
+			f = emitFrame{members: members, n: len(members), object: true}
+		default:
+			return f, false, e.emit(v)
+		}
+	}
+	if f.object {
+		err = e.emitMapBegin(f.n)
+	} else {
+		err = e.emitArrayBegin(f.n)
+	}
+	return f, true, err
+}
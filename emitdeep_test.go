@@ -0,0 +1,117 @@
+package simplejsonext
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Wraps v in depth containers, made by wrap in turn.
+func nestValue(v any, depth int, wraps ...func(any) any) any {
+	for i := 0; i < depth; i++ {
+		v = wraps[i%len(wraps)](v)
+	}
+	return v
+}
+
+var (
+	wrapSlice   = func(v any) any { return []any{v} }
+	wrapMap     = func(v any) any { return map[string]any{"a": v} }
+	wrapOrdered = func(v any) any { return &OrderedObject{Members: []Member{{Key: "a", Value: v}}} }
+	wrapTyped   = func(v any) any { return []map[string]any{{"a": v}} }
+	wrapPointer = func(v any) any { return &v }
+	wrapPair    = func(v any) any { return []any{int64(1), v, map[string]any{}} }
+)
+
+func TestEmitVeryDeep(t *testing.T) {
+	const depth = 1_000_000
+	var buf bytes.Buffer
+	require.NoError(t, NewEmitter(&buf).Emit(nestValue(nil, depth, wrapSlice)))
+	assert.Equal(t, strings.Repeat("[", depth)+"null"+strings.Repeat("]", depth), buf.String())
+
+	// Mixed containers and pointers, which are slower to write.
+	const mixedDepth = depth / 10
+	buf.Reset()
+	require.NoError(t, NewEmitter(&buf).Emit(nestValue("x", mixedDepth, wrapMap, wrapPointer, wrapOrdered)))
+	objects := mixedDepth - mixedDepth/3 // the rest are pointers
+	assert.Equal(t, objects*len(`{"a":}`)+len(`"x"`), buf.Len())
+
+	out, err := Marshal(nestValue(int64(7), depth, wrapTyped))
+	require.NoError(t, err)
+	assert.Equal(t, strings.Repeat(`[{"a":`, depth)+"7"+strings.Repeat("}]", depth), string(out))
+}
+
+// Past the depth where the emitter stops recursing, output is unchanged.
+func TestEmitDeepMatchesStd(t *testing.T) {
+	for _, test := range []struct {
+		wraps []func(any) any
+		// The same shape in types encoding/json knows, if they differ
+		stdWraps []func(any) any
+	}{
+		{wraps: []func(any) any{wrapSlice}},
+		{wraps: []func(any) any{wrapMap}},
+		{wraps: []func(any) any{wrapOrdered, wrapSlice}, stdWraps: []func(any) any{wrapMap, wrapSlice}},
+		{wraps: []func(any) any{wrapTyped, wrapPointer}},
+		{wraps: []func(any) any{wrapPair, wrapMap}},
+	} {
+		if test.stdWraps == nil {
+			test.stdWraps = test.wraps
+		}
+		for _, depth := range []int{maxEmitRecursion - 1, maxEmitRecursion, maxEmitRecursion + 1, 2*maxEmitRecursion + 1} {
+			// No raw messages, which encoding/json reindents.
+			leaf := []any{"x", 1.5, []byte("b"), nil, big.NewInt(7)}
+			v := nestValue(leaf, depth, test.wraps...)
+			stdV := nestValue(leaf, depth, test.stdWraps...)
+			expected, err := json.Marshal(stdV)
+			require.NoError(t, err)
+			out, err := Marshal(v)
+			require.NoError(t, err)
+			assert.Equal(t, string(expected), string(out))
+
+			expected, err = json.MarshalIndent(stdV, ">", "\t")
+			require.NoError(t, err)
+			var buf bytes.Buffer
+			require.NoError(t, NewEmitter(&buf, WithIndent(">", "\t")).Emit(v))
+			assert.Equal(t, string(expected), buf.String())
+		}
+	}
+}
+
+func TestEmitDeepErrors(t *testing.T) {
+	for _, bad := range []any{map[int]any{1: 2}, complex(1, 2)} {
+		for _, depth := range []int{10, 3 * maxEmitRecursion} {
+			_, err := Marshal(nestValue(bad, depth, wrapSlice, wrapMap))
+			assert.EqualError(t, err, fmt.Sprintf("simple json: cannot emit unsupported type %T", bad))
+		}
+	}
+
+	// Values that contain themselves are found once deep enough.
+	m := map[string]any{"a": int64(1)}
+	m["self"] = m
+	l := []any{nil, "x"}
+	l[0] = []any{&l}
+	for _, v := range []any{m, l, OrderedObject{Members: []Member{{Key: "m", Value: m}}}} {
+		_, err := Marshal(v)
+		assert.ErrorIs(t, err, errCyclic)
+		_, err = MarshalWithOptions(v, WithSortedKeys())
+		assert.ErrorIs(t, err, errCyclic)
+	}
+	// Values reached twice, but not inside themselves, are fine.
+	shared := nestValue(int64(1), 3*maxEmitRecursion, wrapSlice)
+	_, err := Marshal([]any{shared, shared})
+	require.NoError(t, err)
+
+	// The emitter can be used again after failing deep inside a value.
+	var buf bytes.Buffer
+	e := NewEmitter(&buf)
+	assert.Error(t, e.Emit(nestValue(complex(1, 2), 3*maxEmitRecursion, wrapSlice)))
+	buf.Reset()
+	require.NoError(t, e.Emit([]any{int64(1)}))
+	assert.Equal(t, "[1]", buf.String())
+}
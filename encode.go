@@ -2,24 +2,65 @@ package simplejsonext
 
 import (
 	"bytes"
+	"io"
 	"strings"
 )
 
 // Marshal writes the JSON representation of v to a byte slice returned in b.
+// The slice is allocated up front from a quick estimate of the size of v.
 func Marshal(v interface{}) (b []byte, err error) {
-	var buf bytes.Buffer
-	err = NewEmitter(&buf).Emit(v)
+	return MarshalWithCapacity(v, roughMarshalSize(v))
+}
+
+// MarshalWithCapacity is like Marshal, but starts with a buffer of n bytes,
+// which is enough to avoid any copying if the output is no longer than that.
+func MarshalWithCapacity(v interface{}, n int) (b []byte, err error) {
+	buf := bytes.NewBuffer(make([]byte, 0, max(n, 0)))
+	err = NewEmitter(buf).Emit(v)
 	if err != nil {
 		return
 	}
 	return buf.Bytes(), nil
 }
 
+// MarshalWithOptions is like Marshal, with an Emitter configured by opts.
+func MarshalWithOptions(v any, opts ...EmitOption) ([]byte, error) {
+	buf := bytes.NewBuffer(make([]byte, 0, roughMarshalSize(v)))
+	if err := NewEmitter(buf, opts...).Emit(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
 func MarshalToString(v interface{}) (s string, err error) {
 	var sb strings.Builder
+	sb.Grow(roughMarshalSize(v))
 	err = NewEmitter(&sb).Emit(v)
 	if err != nil {
 		return
 	}
 	return sb.String(), nil
 }
+
+// MarshalToStringWithOptions is like MarshalToString, with an Emitter
+// configured by opts.
+func MarshalToStringWithOptions(v any, opts ...EmitOption) (string, error) {
+	var sb strings.Builder
+	sb.Grow(roughMarshalSize(v))
+	if err := NewEmitter(&sb, opts...).Emit(v); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// MarshalWrite writes the JSON representation of v to w, in the manner of
+// encoding/json/v2. The output is produced in memory first and written with
+// a single call to w.Write, so nothing is written if v cannot be marshaled.
+func MarshalWrite(w io.Writer, v any, opts ...EmitOption) error {
+	buf := bytes.NewBuffer(make([]byte, 0, roughMarshalSize(v)))
+	if err := NewEmitter(buf, opts...).Emit(v); err != nil {
+		return err
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
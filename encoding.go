@@ -0,0 +1,190 @@
+package simplejsonext
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// The encodings WithEncodingDetection recognizes.
+type textEncoding int
+
+const (
+	encodingUTF8 textEncoding = iota
+	encodingUTF16BE
+	encodingUTF16LE
+	encodingUTF32BE
+	encodingUTF32LE
+)
+
+// How many bytes each code unit of an encoding takes.
+var unitSize = [...]int{
+	encodingUTF8:    1,
+	encodingUTF16BE: 2,
+	encodingUTF16LE: 2,
+	encodingUTF32BE: 4,
+	encodingUTF32LE: 4,
+}
+
+// Works out the encoding of data that starts with head, which should hold the
+// first four bytes if there are that many. It returns the encoding and the
+// length of the byte order mark to skip, if any.
+//
+// Without a byte order mark, it uses the heuristic from RFC 4627: the first
+// two characters of JSON text are ASCII, so the pattern of zero bytes among
+// the first four tells the encoding apart.
+func detectEncoding(head []byte) (enc textEncoding, bomLen int) {
+	switch {
+	case bytes.HasPrefix(head, []byte{0xef, 0xbb, 0xbf}):
+		return encodingUTF8, 3
+	case bytes.HasPrefix(head, []byte{0x00, 0x00, 0xfe, 0xff}):
+		return encodingUTF32BE, 4
+	case bytes.HasPrefix(head, []byte{0xff, 0xfe, 0x00, 0x00}):
+		return encodingUTF32LE, 4
+	case bytes.HasPrefix(head, []byte{0xfe, 0xff}):
+		return encodingUTF16BE, 2
+	case bytes.HasPrefix(head, []byte{0xff, 0xfe}):
+		return encodingUTF16LE, 2
+	}
+	if len(head) >= 4 {
+		switch {
+		case head[0] == 0 && head[1] == 0 && head[2] == 0 && head[3] != 0:
+			return encodingUTF32BE, 0
+		case head[0] != 0 && head[1] == 0 && head[2] == 0 && head[3] == 0:
+			return encodingUTF32LE, 0
+		}
+	}
+	if len(head) >= 2 {
+		switch {
+		case head[0] == 0 && head[1] != 0:
+			return encodingUTF16BE, 0
+		case head[0] != 0 && head[1] == 0:
+			return encodingUTF16LE, 0
+		}
+	}
+	return encodingUTF8, 0
+}
+
+// Appends the UTF-8 encoding of the whole code units at the start of src to
+// dst, returning the result and how many bytes of src were used. Unless atEOF,
+// a trailing partial code unit or unpaired high surrogate is left unused in
+// case the rest of it follows; otherwise it becomes U+FFFD, as do unpaired
+// surrogates and code points out of range, just as the parser does with
+// escapes.
+func transcodeToUTF8(dst, src []byte, enc textEncoding, atEOF bool) ([]byte, int) {
+	size := unitSize[enc]
+	unit := func(b []byte) rune {
+		switch enc {
+		case encodingUTF16BE:
+			return rune(binary.BigEndian.Uint16(b))
+		case encodingUTF16LE:
+			return rune(binary.LittleEndian.Uint16(b))
+		case encodingUTF32BE:
+			return rune(binary.BigEndian.Uint32(b))
+		default:
+			return rune(binary.LittleEndian.Uint32(b))
+		}
+	}
+	i := 0
+	for i+size <= len(src) {
+		r := unit(src[i:])
+		if size == 2 && utf16.IsSurrogate(r) && r < 0xdc00 {
+			// A high surrogate, which must be followed by a low one.
+			if i+2*size > len(src) {
+				if !atEOF {
+					break
+				}
+			} else if low := unit(src[i+size:]); low >= 0xdc00 && low <= 0xdfff {
+				dst = utf8.AppendRune(dst, utf16.DecodeRune(r, low))
+				i += 2 * size
+				continue
+			}
+		}
+		// AppendRune writes U+FFFD for surrogates and runes out of range.
+		dst = utf8.AppendRune(dst, r)
+		i += size
+	}
+	if atEOF && i < len(src) {
+		dst = utf8.AppendRune(dst, utf8.RuneError)
+		i = len(src)
+	}
+	return dst, i
+}
+
+// Returns data in UTF-8, without any byte order mark.
+func transcodeInput(data []byte) []byte {
+	enc, bomLen := detectEncoding(data[:min(len(data), 4)])
+	data = data[bomLen:]
+	if enc == encodingUTF8 {
+		return data
+	}
+	out, _ := transcodeToUTF8(make([]byte, 0, len(data)), data, enc, true)
+	return out
+}
+
+// Converts what it reads from r to UTF-8, working out the encoding from the
+// first bytes.
+type transcodingReader struct {
+	r        io.Reader
+	detected bool
+	enc      textEncoding
+	raw      [readBufferSize]byte
+	pending  []byte // bytes of raw not yet transcoded
+	out      []byte // transcoded bytes not yet returned
+	err      error  // the error from r, once it has returned one
+}
+
+func (t *transcodingReader) Read(b []byte) (int, error) {
+	if !t.detected {
+		t.detected = true
+		n, err := io.ReadFull(t.r, t.raw[:4])
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			err = io.EOF
+		}
+		t.err = err
+		var bomLen int
+		t.enc, bomLen = detectEncoding(t.raw[:n])
+		t.pending = t.raw[bomLen:n]
+	}
+	for len(t.out) == 0 {
+		if len(t.pending) == 0 && t.err != nil {
+			return 0, t.err
+		}
+		if t.enc == encodingUTF8 {
+			if len(t.pending) > 0 {
+				n := copy(b, t.pending)
+				t.pending = t.pending[n:]
+				return n, nil
+			}
+			return t.r.Read(b)
+		}
+		if t.err == nil {
+			// Move what is left to the front and read more after it.
+			n := copy(t.raw[:], t.pending)
+			var m int
+			m, t.err = t.r.Read(t.raw[n:])
+			t.pending = t.raw[:n+m]
+		}
+		var used int
+		t.out, used = transcodeToUTF8(t.out[:0], t.pending, t.enc, t.err != nil)
+		t.pending = t.pending[used:]
+	}
+	n := copy(b, t.out)
+	t.out = t.out[n:]
+	return n, nil
+}
+
+// Puts the input through transcoding if WithEncodingDetection was given.
+func (p *parser) detectEncoding() {
+	if !p.cfg.detectEncoding {
+		return
+	}
+	if p.reader != nil {
+		p.reader = &transcodingReader{r: p.reader}
+		return
+	}
+	p.readBuf = transcodeInput(p.readBuf[:p.size])
+	p.size = len(p.readBuf)
+}
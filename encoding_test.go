@@ -0,0 +1,126 @@
+package simplejsonext
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"unicode/utf16"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Encodes s, which must be valid UTF-8, in enc.
+func encodeText(s string, enc textEncoding) []byte {
+	var order binary.AppendByteOrder = binary.BigEndian
+	if enc == encodingUTF16LE || enc == encodingUTF32LE {
+		order = binary.LittleEndian
+	}
+	var out []byte
+	switch enc {
+	case encodingUTF16BE, encodingUTF16LE:
+		for _, u := range utf16.Encode([]rune(s)) {
+			out = order.AppendUint16(out, u)
+		}
+	case encodingUTF32BE, encodingUTF32LE:
+		for _, r := range s {
+			out = order.AppendUint32(out, uint32(r))
+		}
+	default:
+		out = []byte(s)
+	}
+	return out
+}
+
+func TestEncodingDetection(t *testing.T) {
+	const doc = `{"name": "grüße 💥", "n": [1, 2.5, NaN], "x": "é"}`
+	expected, err := UnmarshalString(doc)
+	require.NoError(t, err)
+
+	for _, enc := range []textEncoding{encodingUTF8, encodingUTF16BE, encodingUTF16LE, encodingUTF32BE, encodingUTF32LE} {
+		for _, bom := range []bool{false, true} {
+			text := doc
+			if bom {
+				text = "\ufeff" + doc
+			}
+			input := encodeText(text, enc)
+			detected, bomLen := detectEncoding(input[:4])
+			assert.Equal(t, enc, detected)
+			if bom {
+				assert.Equal(t, len(encodeText("\ufeff", enc)), bomLen)
+			}
+
+			for _, p := range []Parser{
+				NewParserFromSlice(input, WithEncodingDetection()),
+				NewParserFromString(string(input), WithEncodingDetection()),
+				NewParser(bytes.NewReader(input), WithEncodingDetection()),
+				NewParser(iotest.OneByteReader(bytes.NewReader(input)), WithEncodingDetection()),
+				GetParser(iotest.HalfReader(bytes.NewReader(input)), WithEncodingDetection()),
+			} {
+				v, err := p.UnmarshalFull()
+				if assert.NoError(t, err, "encoding %d, bom %v", enc, bom) {
+					assert.Equal(t, expected.(map[string]any)["name"], v.(map[string]any)["name"])
+					assert.Equal(t, expected.(map[string]any)["x"], v.(map[string]any)["x"])
+				}
+			}
+		}
+	}
+
+	// Short documents are detected too.
+	for _, enc := range []textEncoding{encodingUTF16BE, encodingUTF16LE} {
+		v, err := NewParserFromSlice(encodeText("7", enc), WithEncodingDetection()).UnmarshalFull()
+		require.NoError(t, err)
+		assert.Equal(t, int64(7), v)
+	}
+	v, err := NewParserFromString("", WithEncodingDetection()).Parse()
+	assert.Nil(t, v)
+	assert.Error(t, err)
+
+	// Without the option, nothing changes.
+	_, err = NewParserFromSlice(encodeText(doc, encodingUTF16LE)).Parse()
+	assert.Error(t, err)
+}
+
+func TestEncodingDetectionLargeInput(t *testing.T) {
+	// Longer than the read buffers, with surrogate pairs straddling them.
+	text := `["` + strings.Repeat("a💥", 3*readBufferSize) + `"]`
+	for _, enc := range []textEncoding{encodingUTF16LE, encodingUTF32BE} {
+		p := NewParser(iotest.HalfReader(bytes.NewReader(encodeText(text, enc))), WithEncodingDetection())
+		v, err := p.UnmarshalFull()
+		require.NoError(t, err)
+		assert.Equal(t, []any{strings.Repeat("a💥", 3*readBufferSize)}, v)
+	}
+}
+
+func TestEncodingLoneSurrogates(t *testing.T) {
+	// The transcoder replaces unpaired surrogates the same way the parser
+	// replaces escaped ones.
+	escaped, err := UnmarshalString(`["\ud800a", "b\udc00", "\ud800"]`)
+	require.NoError(t, err)
+
+	var input []byte
+	for _, u := range utf16.Encode([]rune(`["`)) {
+		input = binary.LittleEndian.AppendUint16(input, u)
+	}
+	for _, u := range []uint16{0xd800, 'a', '"', ',', '"', 'b', 0xdc00, '"', ',', '"', 0xd800, '"', ']'} {
+		input = binary.LittleEndian.AppendUint16(input, u)
+	}
+	for _, p := range []Parser{
+		NewParserFromSlice(input, WithEncodingDetection()),
+		NewParser(iotest.OneByteReader(bytes.NewReader(input)), WithEncodingDetection()),
+	} {
+		v, err := p.UnmarshalFull()
+		require.NoError(t, err)
+		assert.Equal(t, escaped, v)
+	}
+
+	// A truncated code unit at the end becomes U+FFFD too.
+	out, used := transcodeToUTF8(nil, []byte{'"', 0, 'x'}, encodingUTF16LE, true)
+	assert.Equal(t, "\"�", string(out))
+	assert.Equal(t, 3, used)
+	out, used = transcodeToUTF8(nil, []byte{'"', 0, 0x3d, 0xd8}, encodingUTF16LE, false)
+	assert.Equal(t, `"`, string(out))
+	assert.Equal(t, 2, used)
+}
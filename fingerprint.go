@@ -0,0 +1,174 @@
+package simplejsonext
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"math"
+	"reflect"
+	"sort"
+)
+
+// Type tags written before each value in the fingerprint stream
+const (
+	fpNull   = 'n'
+	fpFalse  = 'F'
+	fpTrue   = 'T'
+	fpInt    = 'i'
+	fpUint   = 'u' // only for unsigned values too large for int64
+	fpFloat  = 'f'
+	fpString = 's'
+	fpArray  = 'a'
+	fpObject = 'o'
+)
+
+// Canonical bits written for every NaN
+const canonicalNaN = 0x7ff8000000000001
+
+// Fingerprint writes a canonical encoding of v to h, so that equal values
+// always produce the same hash regardless of map iteration order. The encoding
+// is not JSON; it is streamed to h without being built in memory, and tags
+// every value with its type so that, for example, 1, 1.0 and "1" all hash
+// differently.
+//
+// Object keys are hashed in sorted order, so maps and ordered objects with the
+// same members produce the same fingerprint. All NaNs hash alike, while 0 and
+// -0.0 hash differently. Values of Go integer and float types other than int64
+// and float64, including named ones, hash the same as the int64 or float64 of
+// the same value, and a Number the same as the value the parser would give
+// for it without WithExactNumbers. Named bool and string types hash as bools
+// and strings. Any other type is an error.
+func Fingerprint(v any, h hash.Hash) error {
+	f := fingerprinter{h: h}
+	return f.write(v)
+}
+
+// FingerprintSHA256 returns the SHA-256 of the Fingerprint of v.
+func FingerprintSHA256(v any) (sum [32]byte, err error) {
+	h := sha256.New()
+	if err = Fingerprint(v, h); err != nil {
+		return
+	}
+	h.Sum(sum[:0])
+	return
+}
+
+type fingerprinter struct {
+	h hash.Hash
+	// Scratch space for encoding a tag and a number
+	buf [1 + binary.MaxVarintLen64]byte
+}
+
+func (f *fingerprinter) tag(t byte) {
+	f.buf[0] = t
+	f.h.Write(f.buf[:1])
+}
+
+func (f *fingerprinter) tagged(t byte, n uint64) {
+	f.buf[0] = t
+	f.h.Write(binary.AppendUvarint(f.buf[:1], n))
+}
+
+func (f *fingerprinter) fixed(t byte, n uint64) {
+	f.buf[0] = t
+	binary.BigEndian.PutUint64(f.buf[1:9], n)
+	f.h.Write(f.buf[:9])
+}
+
+func (f *fingerprinter) str(s string) {
+	f.tagged(fpString, uint64(len(s)))
+	f.h.Write(bytesNoCopy(s))
+}
+
+func (f *fingerprinter) float(n float64) {
+	if math.IsNaN(n) {
+		f.fixed(fpFloat, canonicalNaN)
+	} else {
+		f.fixed(fpFloat, math.Float64bits(n))
+	}
+}
+
+func (f *fingerprinter) write(v any) error {
+	switch tv := v.(type) {
+	case nil:
+		f.tag(fpNull)
+	case bool:
+		if tv {
+			f.tag(fpTrue)
+		} else {
+			f.tag(fpFalse)
+		}
+	case int64:
+		f.fixed(fpInt, uint64(tv))
+	case int:
+		f.fixed(fpInt, uint64(tv))
+	case int32:
+		f.fixed(fpInt, uint64(tv))
+	case uint64:
+		if tv > math.MaxInt64 {
+			f.fixed(fpUint, tv)
+		} else {
+			f.fixed(fpInt, tv)
+		}
+	case uint:
+		return f.write(uint64(tv))
+	case uint32:
+		f.fixed(fpInt, uint64(tv))
+	case float64:
+		f.float(tv)
+	case float32:
+		f.float(float64(tv))
+	case string:
+		f.str(tv)
+	case Number:
+		val, err := tv.Value()
+		if err != nil {
+			return err
+		}
+		return f.write(val)
+	case []any:
+		f.tagged(fpArray, uint64(len(tv)))
+		for _, child := range tv {
+			if err := f.write(child); err != nil {
+				return err
+			}
+		}
+	case map[string]any:
+		keys := make([]string, 0, len(tv))
+		for key := range tv {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		f.tagged(fpObject, uint64(len(keys)))
+		for _, key := range keys {
+			f.str(key)
+			if err := f.write(tv[key]); err != nil {
+				return err
+			}
+		}
+	case *OrderedObject:
+		if tv == nil {
+			f.tag(fpNull)
+			break
+		}
+		members := make([]*Member, len(tv.Members))
+		for i := range tv.Members {
+			members[i] = &tv.Members[i]
+		}
+		sort.Slice(members, func(i, j int) bool { return members[i].Key < members[j].Key })
+		f.tagged(fpObject, uint64(len(members)))
+		for _, member := range members {
+			f.str(member.Key)
+			if err := f.write(member.Value); err != nil {
+				return err
+			}
+		}
+	default:
+		if base, ok := baseValue(reflect.ValueOf(v)); ok {
+			return f.write(base)
+		}
+		return fmt.Errorf("simple json: cannot fingerprint unsupported type %T", v)
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package simplejsonext
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustFingerprint(t *testing.T, v any) [32]byte {
+	t.Helper()
+	sum, err := FingerprintSHA256(v)
+	require.NoError(t, err)
+	return sum
+}
+
+// Builds a random tree from a seeded source.
+func randomTree(r *rand.Rand, depth int) any {
+	kind := r.Intn(9)
+	if depth <= 0 {
+		kind %= 6
+	}
+	switch kind {
+	case 0:
+		return nil
+	case 1:
+		return r.Intn(2) == 0
+	case 2:
+		return int64(r.Intn(2000) - 1000)
+	case 3:
+		return []float64{0.5, math.NaN(), math.Inf(1), math.Inf(-1), 1e300}[r.Intn(5)]
+	case 4, 5:
+		return fmt.Sprintf("s%d", r.Intn(100))
+	case 6:
+		arr := make([]any, r.Intn(5))
+		for i := range arr {
+			arr[i] = randomTree(r, depth-1)
+		}
+		return arr
+	default:
+		obj := make(map[string]any)
+		for i := r.Intn(8); i > 0; i-- {
+			obj[fmt.Sprintf("k%d", r.Intn(20))] = randomTree(r, depth-1)
+		}
+		return obj
+	}
+}
+
+// Copies a tree, inserting map keys in a shuffled order.
+func shuffledCopy(r *rand.Rand, v any) any {
+	switch tv := v.(type) {
+	case []any:
+		res := make([]any, len(tv))
+		for i, child := range tv {
+			res[i] = shuffledCopy(r, child)
+		}
+		return res
+	case map[string]any:
+		keys := make([]string, 0, len(tv))
+		for key := range tv {
+			keys = append(keys, key)
+		}
+		r.Shuffle(len(keys), func(i, j int) { keys[i], keys[j] = keys[j], keys[i] })
+		res := make(map[string]any)
+		for _, key := range keys {
+			res[key] = shuffledCopy(r, tv[key])
+		}
+		return res
+	default:
+		return v
+	}
+}
+
+func TestFingerprintStable(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		tree := randomTree(r, 4)
+		expected := mustFingerprint(t, tree)
+		assert.Equal(t, expected, mustFingerprint(t, shuffledCopy(r, tree)))
+
+		// Re-parsing produces an equal tree, with the same fingerprint
+		b, err := Marshal(tree)
+		require.NoError(t, err)
+		parsed, err := Unmarshal(b)
+		require.NoError(t, err)
+		assert.Equal(t, expected, mustFingerprint(t, parsed), "%s", b)
+	}
+}
+
+func TestFingerprintDistinguishes(t *testing.T) {
+	values := []any{
+		nil,
+		false,
+		true,
+		int64(1),
+		1.0,
+		"1",
+		int64(0),
+		0.0,
+		math.Copysign(0, -1),
+		math.NaN(),
+		math.Inf(1),
+		math.Inf(-1),
+		"",
+		"NaN",
+		[]any{},
+		map[string]any{},
+		[]any{nil},
+		[]any{"a", "b"},
+		[]any{"ab"},
+		[]any{[]any{"a"}, "b"},
+		map[string]any{"a": "b"},
+		map[string]any{"ab": ""},
+		map[string]any{"a": map[string]any{}},
+		uint64(math.MaxUint64),
+	}
+	seen := make(map[[32]byte]any)
+	for _, v := range values {
+		sum := mustFingerprint(t, v)
+		if other, found := seen[sum]; found {
+			t.Errorf("%#v and %#v have the same fingerprint", v, other)
+		}
+		seen[sum] = v
+	}
+}
+
+func TestFingerprintEquivalences(t *testing.T) {
+	// All NaNs are alike
+	assert.Equal(t,
+		mustFingerprint(t, math.NaN()),
+		mustFingerprint(t, math.Float64frombits(0x7ff8dead00000000)))
+	// Go integer types hash like int64
+	assert.Equal(t, mustFingerprint(t, int64(7)), mustFingerprint(t, 7))
+	assert.Equal(t, mustFingerprint(t, int64(7)), mustFingerprint(t, uint32(7)))
+	assert.Equal(t, mustFingerprint(t, 0.5), mustFingerprint(t, float32(0.5)))
+	type level uint8
+	type label string
+	for _, v := range []any{int8(7), int16(7), uint8(7), uint16(7), uintptr(7), level(7)} {
+		assert.Equal(t, mustFingerprint(t, int64(7)), mustFingerprint(t, v), "%T", v)
+	}
+	assert.Equal(t, mustFingerprint(t, "x"), mustFingerprint(t, label("x")))
+	// Numbers hash like the values the parser gives without WithExactNumbers
+	assert.Equal(t, mustFingerprint(t, int64(7)), mustFingerprint(t, Number("7")))
+	assert.Equal(t, mustFingerprint(t, 0.5), mustFingerprint(t, Number("5e-1")))
+	assert.Equal(t, mustFingerprint(t, math.Inf(-1)), mustFingerprint(t, Number("-Infinity")))
+	exact, err := UnmarshalWithOptions([]byte(`{"a": [1, 2.5, 1e400]}`), WithExactNumbers())
+	require.NoError(t, err)
+	plain, err := UnmarshalString(`{"a": [1, 2.5, 1e400]}`)
+	require.NoError(t, err)
+	assert.Equal(t, mustFingerprint(t, plain), mustFingerprint(t, exact))
+	// Ordered objects hash like maps with the same members
+	ordered, err := NewParserFromString(`{"b": [1, {"d": 2, "c": 3}], "a": null}`, WithOrderedObjects()).Parse()
+	require.NoError(t, err)
+	unordered, err := UnmarshalString(`{"a": null, "b": [1, {"c": 3, "d": 2}]}`)
+	require.NoError(t, err)
+	assert.Equal(t, mustFingerprint(t, unordered), mustFingerprint(t, ordered))
+	// Nil and empty maps are both empty objects
+	assert.Equal(t, mustFingerprint(t, map[string]any{}), mustFingerprint(t, map[string]any(nil)))
+}
+
+func TestFingerprintErrors(t *testing.T) {
+	err := Fingerprint(map[string]any{"a": []any{struct{}{}}}, sha256.New())
+	assert.EqualError(t, err, "simple json: cannot fingerprint unsupported type struct {}")
+	err = Fingerprint([]any{Number("1x")}, sha256.New())
+	assert.EqualError(t, err, `simple json: invalid number "1x"`)
+}
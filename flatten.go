@@ -0,0 +1,286 @@
+package simplejsonext
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FlattenOptions controls how Flatten turns nested values into paths.
+type FlattenOptions struct {
+	// Separator is placed between the segments of each path. It must not be
+	// empty, and keys that contain it are rejected with an error rather than
+	// producing paths that cannot be told apart.
+	Separator string
+	// KeepArrays leaves arrays as values in the result instead of flattening
+	// them into one path per element, with the element's decimal index as the
+	// path segment.
+	KeepArrays bool
+	// DropEmpty omits empty objects and arrays from the result. By default they
+	// are kept as empty map[string]any{} or []any{} values, so that flattening
+	// loses no information.
+	DropEmpty bool
+}
+
+// Flatten converts a tree of nested objects and arrays into a single-level map
+// whose keys are the paths to each leaf value, joined by sep: for example
+// {"optimizer": {"lr": 0.1}, "layers": [{"units": 3}]} becomes
+// {"optimizer.lr": 0.1, "layers.0.units": 3}. Leaf values, including NaN and
+// infinities, are copied through unchanged.
+//
+// Object keys that contain sep are an error. Empty objects and arrays are kept
+// as values. See FlattenWithOptions for other behaviors.
+func Flatten(v any, sep string) (map[string]any, error) {
+	return FlattenWithOptions(v, FlattenOptions{Separator: sep})
+}
+
+// FlattenWithOptions is like Flatten, configured by opts.
+func FlattenWithOptions(v any, opts FlattenOptions) (map[string]any, error) {
+	if opts.Separator == "" {
+		return nil, errors.New("simple json: flatten separator must not be empty")
+	}
+	f := flattener{opts: opts, result: make(map[string]any)}
+	if !f.isContainer(v) {
+		return nil, fmt.Errorf("simple json: cannot flatten a top-level %T", v)
+	}
+	if err := f.flatten("", true, v); err != nil {
+		return nil, err
+	}
+	return f.result, nil
+}
+
+type flattener struct {
+	opts   FlattenOptions
+	result map[string]any
+}
+
+func (f *flattener) isContainer(v any) bool {
+	switch v.(type) {
+	case map[string]any, *OrderedObject:
+		return true
+	case []any:
+		return !f.opts.KeepArrays
+	}
+	return false
+}
+
+func (f *flattener) join(prefix string, root bool, segment string) string {
+	if root {
+		return segment
+	}
+	return prefix + f.opts.Separator + segment
+}
+
+// Flattens v, which is found at the path prefix unless it is the root.
+func (f *flattener) flatten(prefix string, root bool, v any) error {
+	var empty any
+	switch tv := v.(type) {
+	case map[string]any:
+		if len(tv) == 0 {
+			empty = map[string]any{}
+			break
+		}
+		for key, child := range tv {
+			if err := f.member(prefix, root, key, child); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *OrderedObject:
+		if tv == nil || len(tv.Members) == 0 {
+			empty = map[string]any{}
+			break
+		}
+		for _, member := range tv.Members {
+			if err := f.member(prefix, root, member.Key, member.Value); err != nil {
+				return err
+			}
+		}
+		return nil
+	case []any:
+		if len(tv) == 0 {
+			empty = []any{}
+			break
+		}
+		for i, child := range tv {
+			if err := f.child(f.join(prefix, root, strconv.Itoa(i)), child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if !root && !f.opts.DropEmpty {
+		f.result[prefix] = empty
+	}
+	return nil
+}
+
+func (f *flattener) member(prefix string, root bool, key string, v any) error {
+	if strings.Contains(key, f.opts.Separator) {
+		return fmt.Errorf("simple json: cannot flatten key %q containing separator %q", key, f.opts.Separator)
+	}
+	return f.child(f.join(prefix, root, key), v)
+}
+
+func (f *flattener) child(path string, v any) error {
+	if f.isContainer(v) {
+		return f.flatten(path, false, v)
+	}
+	if arr, ok := v.([]any); ok && len(arr) == 0 && f.opts.DropEmpty {
+		return nil
+	}
+	f.result[path] = v
+	return nil
+}
+
+// DefaultMaxUnflattenArrayLength is the default limit on the length of arrays
+// that Unflatten will build from numeric path segments.
+const DefaultMaxUnflattenArrayLength = 10000
+
+// UnflattenOptions controls how Unflatten rebuilds nested values from paths.
+type UnflattenOptions struct {
+	// Separator is found between the segments of each path. It must not be
+	// empty.
+	Separator string
+	// NoArrays builds objects for every path segment. By default, when all the
+	// segments found under some path are decimal integers without leading
+	// zeros, they are taken as the indices of an array. Indices missing from
+	// such an array are filled with nil.
+	NoArrays bool
+	// MaxArrayLength is the largest array Unflatten will build, so that a path
+	// like "a.999999999" cannot force a huge allocation. Zero means
+	// DefaultMaxUnflattenArrayLength.
+	MaxArrayLength int
+}
+
+// Unflatten is the inverse of Flatten: it splits each key of m on sep and
+// rebuilds the nested objects (and arrays, for numeric segments) that the
+// paths describe. For values that Flatten accepts, Unflatten(Flatten(v)) is
+// equal to v, except that objects whose keys are all decimal integers without
+// leading zeros, like {"0": 1}, come back as arrays, since their paths are the
+// same as an array's. With UnflattenOptions.NoArrays such objects round trip,
+// but arrays come back as objects instead.
+//
+// It is an error for any path to be both a value and a prefix of another path,
+// such as "a" and "a.b".
+func Unflatten(m map[string]any, sep string) (any, error) {
+	return UnflattenWithOptions(m, UnflattenOptions{Separator: sep})
+}
+
+// UnflattenWithOptions is like Unflatten, configured by opts.
+func UnflattenWithOptions(m map[string]any, opts UnflattenOptions) (any, error) {
+	if opts.Separator == "" {
+		return nil, errors.New("simple json: unflatten separator must not be empty")
+	}
+	if opts.MaxArrayLength == 0 {
+		opts.MaxArrayLength = DefaultMaxUnflattenArrayLength
+	}
+	// Insert keys in sorted order so any conflict is reported the same way
+	// every time.
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	root := &unflattenNode{}
+	for _, key := range keys {
+		node := root
+		for _, segment := range strings.Split(key, opts.Separator) {
+			if node.isLeaf {
+				return nil, fmt.Errorf(
+					"simple json: cannot unflatten %q: %q is already a value", key, node.key)
+			}
+			child := node.children[segment]
+			if child == nil {
+				if node.children == nil {
+					node.children = make(map[string]*unflattenNode)
+				}
+				child = &unflattenNode{key: key}
+				node.children[segment] = child
+			}
+			node = child
+		}
+		if node.isLeaf || node.children != nil {
+			return nil, fmt.Errorf(
+				"simple json: cannot unflatten %q: it is a prefix of %q", key, node.key)
+		}
+		node.isLeaf = true
+		node.key = key
+		node.leaf = m[key]
+	}
+	if root.children == nil {
+		return map[string]any{}, nil
+	}
+	return root.build(&opts)
+}
+
+type unflattenNode struct {
+	// For leaves, the key of the value; otherwise the first key found under
+	// this path
+	key      string
+	isLeaf   bool
+	leaf     any
+	children map[string]*unflattenNode
+}
+
+func (n *unflattenNode) build(opts *UnflattenOptions) (any, error) {
+	if n.isLeaf {
+		return n.leaf, nil
+	}
+	if !opts.NoArrays {
+		if length, last, ok := arrayLength(n.children); ok {
+			if length > opts.MaxArrayLength {
+				return nil, fmt.Errorf(
+					"simple json: cannot unflatten %q: array length %d exceeds the limit of %d",
+					last.key, length, opts.MaxArrayLength)
+			}
+			arr := make([]any, length)
+			for segment, child := range n.children {
+				i, _ := strconv.Atoi(segment)
+				val, err := child.build(opts)
+				if err != nil {
+					return nil, err
+				}
+				arr[i] = val
+			}
+			return arr, nil
+		}
+	}
+	obj := make(map[string]any, len(n.children))
+	for segment, child := range n.children {
+		val, err := child.build(opts)
+		if err != nil {
+			return nil, err
+		}
+		obj[segment] = val
+	}
+	return obj, nil
+}
+
+// Returns the length of the array described by the given path segments, and
+// the node at its last index, if they are all array indices.
+func arrayLength(children map[string]*unflattenNode) (length int, last *unflattenNode, ok bool) {
+	for segment, child := range children {
+		if segment == "" || len(segment) > 1 && segment[0] == '0' {
+			return 0, nil, false
+		}
+		for _, ch := range []byte(segment) {
+			if ch < '0' || ch > '9' {
+				return 0, nil, false
+			}
+		}
+		i, err := strconv.Atoi(segment)
+		if err != nil || i == math.MaxInt {
+			// Too big to be a length; certainly beyond any limit.
+			i = math.MaxInt - 1
+		}
+		if i >= length {
+			length, last = i+1, child
+		}
+	}
+	return length, last, true
+}
@@ -0,0 +1,171 @@
+package simplejsonext
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlatten(t *testing.T) {
+	tree, err := UnmarshalString(`{
+		"optimizer": {"lr": 0.1, "betas": [0.9, 0.999]},
+		"layers": [{"units": 3, "act": "relu"}, {"units": 1, "extra": {"deep": [[true]]}}],
+		"loss": NaN,
+		"empty_obj": {},
+		"empty_arr": [],
+		"": {"nameless": null}
+	}`)
+	require.NoError(t, err)
+
+	flat, err := Flatten(tree, ".")
+	require.NoError(t, err)
+	assert.True(t, math.IsNaN(flat["loss"].(float64)))
+	delete(flat, "loss")
+	assert.Equal(t, map[string]any{
+		"optimizer.lr":            0.1,
+		"optimizer.betas.0":       0.9,
+		"optimizer.betas.1":       0.999,
+		"layers.0.units":          int64(3),
+		"layers.0.act":            "relu",
+		"layers.1.units":          int64(1),
+		"layers.1.extra.deep.0.0": true,
+		"empty_obj":               map[string]any{},
+		"empty_arr":               []any{},
+		".nameless":               nil,
+	}, flat)
+
+	flat, err = FlattenWithOptions(tree, FlattenOptions{Separator: "/", KeepArrays: true, DropEmpty: true})
+	require.NoError(t, err)
+	assert.Equal(t, []any{0.9, 0.999}, flat["optimizer/betas"])
+	assert.Equal(t, tree.(map[string]any)["layers"], flat["layers"])
+	assert.NotContains(t, flat, "empty_obj")
+	assert.NotContains(t, flat, "empty_arr")
+	assert.Len(t, flat, 5)
+}
+
+func TestFlattenSeparatorInKey(t *testing.T) {
+	tree, err := UnmarshalString(`{"a": {"b.c": 1}}`)
+	require.NoError(t, err)
+	_, err = Flatten(tree, ".")
+	assert.EqualError(t, err, `simple json: cannot flatten key "b.c" containing separator "."`)
+
+	flat, err := Flatten(tree, "/")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"a/b.c": int64(1)}, flat)
+}
+
+func TestFlattenTopLevel(t *testing.T) {
+	flat, err := Flatten([]any{"x", []any{"y"}}, ".")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"0": "x", "1.0": "y"}, flat)
+
+	flat, err = Flatten(map[string]any{}, ".")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{}, flat)
+
+	_, err = Flatten("scalar", ".")
+	assert.EqualError(t, err, "simple json: cannot flatten a top-level string")
+	_, err = FlattenWithOptions([]any{}, FlattenOptions{Separator: ".", KeepArrays: true})
+	assert.EqualError(t, err, "simple json: cannot flatten a top-level []interface {}")
+	_, err = Flatten(map[string]any{}, "")
+	assert.EqualError(t, err, "simple json: flatten separator must not be empty")
+}
+
+func TestUnflatten(t *testing.T) {
+	tree, err := Unflatten(map[string]any{
+		"optimizer.lr":     0.1,
+		"layers.0.units":   int64(3),
+		"layers.2.units":   int64(1),
+		"mixed.0":          "zero",
+		"mixed.x":          "x",
+		"padded.01":        true,
+		"empty":            map[string]any{},
+		"deep.a.b.c.0.0.d": nil,
+	}, ".")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"optimizer": map[string]any{"lr": 0.1},
+		"layers": []any{
+			map[string]any{"units": int64(3)},
+			nil,
+			map[string]any{"units": int64(1)},
+		},
+		"mixed":  map[string]any{"0": "zero", "x": "x"},
+		"padded": map[string]any{"01": true},
+		"empty":  map[string]any{},
+		"deep": map[string]any{"a": map[string]any{"b": map[string]any{"c": []any{
+			[]any{map[string]any{"d": nil}},
+		}}}},
+	}, tree)
+
+	tree, err = UnflattenWithOptions(map[string]any{"a/0": 1, "a/1": 2}, UnflattenOptions{Separator: "/", NoArrays: true})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"a": map[string]any{"0": 1, "1": 2}}, tree)
+
+	tree, err = Unflatten(map[string]any{"1": "b", "0": "a"}, ".")
+	require.NoError(t, err)
+	assert.Equal(t, []any{"a", "b"}, tree)
+
+	tree, err = Unflatten(map[string]any{}, ".")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{}, tree)
+}
+
+func TestUnflattenConflicts(t *testing.T) {
+	_, err := Unflatten(map[string]any{"a": 1, "a.b": 2}, ".")
+	assert.EqualError(t, err, `simple json: cannot unflatten "a.b": "a" is already a value`)
+	_, err = Unflatten(map[string]any{"x.a": 1, "x.a.b.c": 2, "y": 3}, ".")
+	assert.EqualError(t, err, `simple json: cannot unflatten "x.a.b.c": "x.a" is already a value`)
+	_, err = Unflatten(map[string]any{"a": 1}, "")
+	assert.EqualError(t, err, "simple json: unflatten separator must not be empty")
+}
+
+func TestUnflattenArrayLimit(t *testing.T) {
+	_, err := Unflatten(map[string]any{"a.0": 1, "a.10000": 2}, ".")
+	assert.EqualError(t, err,
+		`simple json: cannot unflatten "a.10000": array length 10001 exceeds the limit of 10000`)
+	_, err = Unflatten(map[string]any{"a.99999999999999999999999": 1}, ".")
+	assert.ErrorContains(t, err, "exceeds the limit of 10000")
+
+	tree, err := UnflattenWithOptions(map[string]any{"3": 1}, UnflattenOptions{Separator: ".", MaxArrayLength: 4})
+	require.NoError(t, err)
+	assert.Equal(t, []any{nil, nil, nil, 1}, tree)
+	_, err = UnflattenWithOptions(map[string]any{"4": 1}, UnflattenOptions{Separator: ".", MaxArrayLength: 4})
+	assert.Error(t, err)
+}
+
+func TestFlattenRoundTrip(t *testing.T) {
+	for _, v := range []any{
+		map[string]any{"a": int64(1)},
+		map[string]any{
+			"optimizer": map[string]any{"lr": 0.1, "betas": []any{0.9, 0.999}},
+			"layers": []any{
+				map[string]any{"units": int64(3), "tags": []any{}},
+				map[string]any{"extra": map[string]any{}, "deep": []any{[]any{true, nil, "s"}}},
+			},
+			"": map[string]any{"": "empty keys"},
+		},
+		[]any{[]any{"nested"}, map[string]any{"x": false}},
+	} {
+		flat, err := Flatten(v, ".")
+		require.NoError(t, err)
+		res, err := Unflatten(flat, ".")
+		require.NoError(t, err)
+		assert.Equal(t, v, res)
+	}
+}
+
+func TestFlattenRoundTripNumericKeys(t *testing.T) {
+	v := map[string]any{"a": map[string]any{"0": int64(1)}, "b": map[string]any{"01": "x"}}
+	flat, err := Flatten(v, ".")
+	require.NoError(t, err)
+	res, err := Unflatten(flat, ".")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"a": []any{int64(1)}, "b": map[string]any{"01": "x"}}, res)
+
+	res, err = UnflattenWithOptions(flat, UnflattenOptions{Separator: ".", NoArrays: true})
+	require.NoError(t, err)
+	assert.Equal(t, v, res)
+}
@@ -0,0 +1,101 @@
+package simplejsonext
+
+// Exactly representable powers of ten.
+var float64Pow10 = [...]float64{
+	1e0, 1e1, 1e2, 1e3, 1e4, 1e5, 1e6, 1e7, 1e8, 1e9,
+	1e10, 1e11, 1e12, 1e13, 1e14, 1e15, 1e16, 1e17, 1e18, 1e19,
+	1e20, 1e21, 1e22,
+}
+
+// Parses a plain decimal number token, such as "-12.5e3", without going
+// through strconv, for the common case where the result can be computed
+// exactly: when the digits form an integer of at most 2^53 and the power of
+// ten is at most 22, both are exact float64 values, so a single correctly
+// rounded multiplication or division gives the same result strconv would.
+// Reports false for anything else, which must then be parsed by strconv.
+func parseFloatFast(b []byte) (f float64, ok bool) {
+	i := 0
+	neg := false
+	if i < len(b) && b[i] == '-' {
+		neg = true
+		i++
+	}
+
+	var mantissa uint64
+	digits := 0 // significant digits in mantissa
+	exp := 0    // power of ten to apply to mantissa
+	sawDigit := false
+	for ; i < len(b) && b[i] >= '0' && b[i] <= '9'; i++ {
+		sawDigit = true
+		if mantissa == 0 && b[i] == '0' {
+			continue // leading zeros are not significant
+		}
+		if digits == 19 {
+			return 0, false
+		}
+		mantissa = mantissa*10 + uint64(b[i]-'0')
+		digits++
+	}
+	if i < len(b) && b[i] == '.' {
+		i++
+		sawFraction := false
+		for ; i < len(b) && b[i] >= '0' && b[i] <= '9'; i++ {
+			sawFraction = true
+			if mantissa == 0 && b[i] == '0' {
+				exp--
+				continue
+			}
+			if digits == 19 {
+				return 0, false
+			}
+			mantissa = mantissa*10 + uint64(b[i]-'0')
+			digits++
+			exp--
+		}
+		sawDigit = sawDigit || sawFraction
+	}
+	if !sawDigit {
+		return 0, false
+	}
+	if i < len(b) && (b[i] == 'e' || b[i] == 'E') {
+		i++
+		expNeg := false
+		if i < len(b) && (b[i] == '+' || b[i] == '-') {
+			expNeg = b[i] == '-'
+			i++
+		}
+		if i == len(b) {
+			return 0, false
+		}
+		e := 0
+		for ; i < len(b) && b[i] >= '0' && b[i] <= '9'; i++ {
+			if e > 1000 {
+				return 0, false
+			}
+			e = e*10 + int(b[i]-'0')
+		}
+		if expNeg {
+			e = -e
+		}
+		exp += e
+	}
+	if i != len(b) || mantissa > 1<<53 {
+		return 0, false
+	}
+
+	f = float64(mantissa)
+	switch {
+	case mantissa == 0:
+		// Zero is zero at any scale
+	case exp < 0 && exp >= -22:
+		f /= float64Pow10[-exp]
+	case exp >= 0 && exp <= 22:
+		f *= float64Pow10[exp]
+	default:
+		return 0, false
+	}
+	if neg {
+		f = -f
+	}
+	return f, true
+}
@@ -0,0 +1,73 @@
+package simplejsonext
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFloatFast(t *testing.T) {
+	check := func(token string) {
+		t.Helper()
+		f, ok := parseFloatFast([]byte(token))
+		if !ok {
+			return
+		}
+		expected, err := strconv.ParseFloat(token, 64)
+		if assert.NoError(t, err, token) {
+			assert.Equal(t, math.Float64bits(expected), math.Float64bits(f), "%s: %v != %v", token, expected, f)
+		}
+	}
+
+	for _, token := range []string{
+		"0", "-0", "0.0", "-0.0", "00.5", "1.", "1.e1", "-.1", ".5", "1e0", "1E+2", "1e-2",
+		"9007199254740992", "9007199254740993", "9007199254740992e22", "9007199254740992e-22",
+		"1e22", "1e23", "1e-22", "1e-23", "0e99999", "1e99999999999999999999",
+		"1234567890123456789", "12345678901234567890", "0.0000000000000000000001",
+		"123456789012.5e-3", "4.9406564584124654e-324",
+	} {
+		check(token)
+	}
+
+	// Tokens the fast path must refuse, leaving them to strconv.
+	for _, token := range []string{
+		"", "-", ".", "-.", "e1", "1e", "1e+", "1.2.3", "1e1e1", "NaN", "Inf", "-Infinity",
+		"1_000", "0x1p3", "+1", "1e23", "9007199254740993", "12345678901234567890",
+	} {
+		_, ok := parseFloatFast([]byte(token))
+		assert.False(t, ok, token)
+	}
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 200000; i++ {
+		mantissa := r.Int63n(1 << uint(r.Intn(60)+1))
+		digits := strconv.FormatInt(mantissa, 10)
+		point := r.Intn(len(digits) + 1)
+		token := digits[:point] + "." + digits[point:]
+		if r.Intn(2) == 0 {
+			token = fmt.Sprintf("%se%d", token, r.Intn(60)-30)
+		}
+		if r.Intn(2) == 0 {
+			token = "-" + token
+		}
+		check(token)
+	}
+}
+
+func BenchmarkParseFloatToken(b *testing.B) {
+	tokens := [][]byte{[]byte("0.5"), []byte("-1234.5678"), []byte("3.14159e-5"), []byte("98765.4321")}
+	b.Run("fast", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, _ = parseFloatFast(tokens[i%len(tokens)])
+		}
+	})
+	b.Run("strconv", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, _ = strconv.ParseFloat(stringNoCopy(tokens[i%len(tokens)]), 64)
+		}
+	})
+}
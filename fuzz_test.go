@@ -0,0 +1,141 @@
+package simplejsonext_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+	"unicode/utf8"
+
+	"github.com/wandb/simplejsonext"
+)
+
+// Seeds a fuzz target with the inputs of the behavior tests.
+func addBehaviorCorpus(f *testing.F) {
+	for _, cases := range [][]jsonCase{
+		standardCases,
+		invalidUTF8Cases,
+		standardBehaviorForExtCases,
+		simpleCases,
+	} {
+		for _, c := range cases {
+			f.Add([]byte(c.s))
+		}
+	}
+}
+
+// Fails on errors that come from a recovered panic.
+func checkNoPanic(t *testing.T, err error) {
+	t.Helper()
+	var panicErr *simplejsonext.PanicError
+	if errors.As(err, &panicErr) {
+		t.Fatalf("%s\n%s", err, panicErr.Stack)
+	}
+}
+
+func FuzzUnmarshal(f *testing.F) {
+	addBehaviorCorpus(f)
+	f.Fuzz(func(t *testing.T, b []byte) {
+		v, err := simplejsonext.Unmarshal(b)
+		checkNoPanic(t, err)
+		_, objErr := simplejsonext.UnmarshalObject(b)
+		checkNoPanic(t, objErr)
+
+		// Collecting all the errors finds one exactly when there is one.
+		all, errs := simplejsonext.UnmarshalAllErrors(b, simplejsonext.MultiErrorOptions{})
+		if (err == nil) != (len(errs) == 0) {
+			t.Fatalf("Unmarshal error %v but UnmarshalAllErrors errors %v", err, errs)
+		}
+		if err == nil {
+			if err := equalImpl(v, all, options{}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+}
+
+func FuzzUnmarshalString(f *testing.F) {
+	addBehaviorCorpus(f)
+	f.Fuzz(func(t *testing.T, b []byte) {
+		s := string(b)
+		v1, err1 := simplejsonext.Unmarshal(b)
+		checkNoPanic(t, err1)
+		v2, err2 := simplejsonext.UnmarshalString(s)
+		checkNoPanic(t, err2)
+		if (err1 == nil) != (err2 == nil) {
+			t.Fatalf("Unmarshal error %v but UnmarshalString error %v", err1, err2)
+		}
+		if err1 == nil {
+			if err := equalImpl(v1, v2, options{}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+}
+
+func FuzzParser(f *testing.F) {
+	addBehaviorCorpus(f)
+	f.Fuzz(func(t *testing.T, b []byte) {
+		// Parsing a stream of values a byte at a time gives the same values
+		// and fails in the same places as parsing it all in memory.
+		inMemory := simplejsonext.NewParserFromSlice(b)
+		streamed := simplejsonext.NewParser(iotest.OneByteReader(bytes.NewReader(b)))
+		for {
+			v1, err1 := inMemory.Parse()
+			checkNoPanic(t, err1)
+			v2, err2 := streamed.Parse()
+			checkNoPanic(t, err2)
+			if (err1 == nil) != (err2 == nil) {
+				t.Fatalf("in-memory error %v but streamed error %v", err1, err2)
+			}
+			if err1 != nil {
+				if err1 != io.EOF && err2 == io.EOF {
+					t.Fatalf("in-memory error %v but streamed EOF", err1)
+				}
+				return
+			}
+			if err := equalImpl(v1, v2, options{}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+}
+
+func FuzzRoundTrip(f *testing.F) {
+	addBehaviorCorpus(f)
+	f.Fuzz(func(t *testing.T, b []byte) {
+		v1, err := simplejsonext.Unmarshal(b)
+		checkNoPanic(t, err)
+		if err != nil {
+			return
+		}
+		// Marshaling may turn integral floats into integers once, after
+		// which Marshal∘Unmarshal must change nothing.
+		m1, err := simplejsonext.Marshal(v1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v2, err := simplejsonext.Unmarshal(m1)
+		if err != nil {
+			t.Fatalf("cannot read back %q: %v", m1, err)
+		}
+		if err := equalImpl(v1, v2, options{tolerateFloatToIntRoundTrip: true}); err != nil {
+			t.Fatal(err)
+		}
+		m2, err := simplejsonext.Marshal(v2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v3, err := simplejsonext.Unmarshal(m2)
+		if err != nil {
+			t.Fatalf("cannot read back %q: %v", m2, err)
+		}
+		if err := equalImpl(v2, v3, options{}); err != nil {
+			t.Fatal(err)
+		}
+		if utf8.Valid(b) && !utf8.Valid(m1) {
+			t.Fatalf("valid UTF-8 %q written as invalid %q", b, m1)
+		}
+	})
+}
@@ -0,0 +1,214 @@
+package simplejsonext
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrPathNotFound is reported, wrapped in a *PathError, by the Get functions
+// when a key or array index on the path does not exist.
+var ErrPathNotFound = errors.New("not found")
+
+// PathError is returned by the Get functions, recording the part of the path
+// where the lookup failed. Path holds the requested path up to and including
+// the element that failed.
+type PathError struct {
+	Path []any
+	Err  error
+}
+
+func (e *PathError) Error() string {
+	msg := strings.TrimPrefix(e.Err.Error(), "simple json: ")
+	return fmt.Sprintf("simple json: at %s: %s", formatPath(e.Path), msg)
+}
+
+func (e *PathError) Unwrap() error {
+	return e.Err
+}
+
+// WrongTypeError is reported, wrapped in a *PathError, by the Get functions
+// when the value found on the path is not of the expected type.
+type WrongTypeError struct {
+	// Expected describes the type that was expected
+	Expected string
+	// Found is the value that was found instead
+	Found any
+}
+
+func (e *WrongTypeError) Error() string {
+	return fmt.Sprintf("expected %s but found %s", e.Expected, typeName(e.Found))
+}
+
+// Get returns the value found in v by following path, whose elements are
+// string object keys and int array indices. With an empty path, v itself is
+// returned. Both maps and ordered objects are traversed.
+//
+// If the path does not exist, the error wraps ErrPathNotFound; if a value on
+// the path is not the object or array needed to continue, it wraps a
+// *WrongTypeError.
+func Get(v any, path ...any) (any, error) {
+	for i, elem := range path {
+		var found bool
+		switch step := elem.(type) {
+		case string:
+			switch obj := v.(type) {
+			case map[string]any:
+				v, found = obj[step]
+			case *OrderedObject:
+				if obj == nil {
+					return nil, pathError(path[:i], &WrongTypeError{Expected: "object", Found: obj})
+				}
+				v, found = obj.Get(step)
+			default:
+				return nil, pathError(path[:i], &WrongTypeError{Expected: "object", Found: v})
+			}
+		case int:
+			arr, ok := v.([]any)
+			if !ok {
+				return nil, pathError(path[:i], &WrongTypeError{Expected: "array", Found: v})
+			}
+			if found = step >= 0 && step < len(arr); found {
+				v = arr[step]
+			}
+		default:
+			return nil, pathError(path[:i+1],
+				fmt.Errorf("path elements must be string or int, not %T", elem))
+		}
+		if !found {
+			return nil, pathError(path[:i+1], ErrPathNotFound)
+		}
+	}
+	return v, nil
+}
+
+// GetString returns the string found in v at path.
+func GetString(v any, path ...any) (string, error) {
+	found, err := Get(v, path...)
+	if err != nil {
+		return "", err
+	}
+	s, ok := found.(string)
+	if !ok {
+		return "", pathError(path, &WrongTypeError{Expected: "string", Found: found})
+	}
+	return s, nil
+}
+
+// GetBool returns the bool found in v at path.
+func GetBool(v any, path ...any) (bool, error) {
+	found, err := Get(v, path...)
+	if err != nil {
+		return false, err
+	}
+	b, ok := found.(bool)
+	if !ok {
+		return false, pathError(path, &WrongTypeError{Expected: "bool", Found: found})
+	}
+	return b, nil
+}
+
+// GetInt64 returns the integer found in v at path. A float64 is accepted only
+// if it has an integral value within the range of int64.
+func GetInt64(v any, path ...any) (int64, error) {
+	found, err := Get(v, path...)
+	if err != nil {
+		return 0, err
+	}
+	i, ok := AsInt64(found)
+	if !ok {
+		return 0, pathError(path, &WrongTypeError{Expected: "integer", Found: found})
+	}
+	return i, nil
+}
+
+// GetFloat64 returns the number found in v at path, converting an int64 to
+// float64 if necessary.
+func GetFloat64(v any, path ...any) (float64, error) {
+	found, err := Get(v, path...)
+	if err != nil {
+		return 0, err
+	}
+	f, ok := AsFloat64(found)
+	if !ok {
+		return 0, pathError(path, &WrongTypeError{Expected: "number", Found: found})
+	}
+	return f, nil
+}
+
+// GetObject returns the object found in v at path. An ordered object is
+// converted to a map with its ToMap method.
+func GetObject(v any, path ...any) (map[string]any, error) {
+	found, err := Get(v, path...)
+	if err != nil {
+		return nil, err
+	}
+	switch obj := found.(type) {
+	case map[string]any:
+		return obj, nil
+	case *OrderedObject:
+		if obj != nil {
+			return obj.ToMap(), nil
+		}
+	}
+	return nil, pathError(path, &WrongTypeError{Expected: "object", Found: found})
+}
+
+// GetArray returns the array found in v at path.
+func GetArray(v any, path ...any) ([]any, error) {
+	found, err := Get(v, path...)
+	if err != nil {
+		return nil, err
+	}
+	arr, ok := found.([]any)
+	if !ok {
+		return nil, pathError(path, &WrongTypeError{Expected: "array", Found: found})
+	}
+	return arr, nil
+}
+
+func pathError(path []any, err error) error {
+	return &PathError{Path: append([]any(nil), path...), Err: err}
+}
+
+// Formats a path of keys and indices like `$["a"][0]`.
+func formatPath(path []any) string {
+	var sb strings.Builder
+	sb.WriteByte('$')
+	for _, elem := range path {
+		switch step := elem.(type) {
+		case string:
+			fmt.Fprintf(&sb, "[%q]", step)
+		default:
+			fmt.Fprintf(&sb, "[%v]", step)
+		}
+	}
+	return sb.String()
+}
+
+// Names the type of a simple JSON value for error messages.
+func typeName(v any) string {
+	switch tv := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "bool"
+	case int64:
+		return "int64"
+	case float64:
+		return "float64"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	case *OrderedObject:
+		if tv == nil {
+			return "null"
+		}
+		return "object"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
@@ -0,0 +1,178 @@
+package simplejsonext
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const getDoc = `{
+	"name": "run-1",
+	"done": false,
+	"config": {"lr": 0.01, "epochs": 10, "steps": 1e3, "ratio": 2.5, "big": 1e19},
+	"layers": [{"units": 64}, null],
+	"nothing": null
+}`
+
+func TestGet(t *testing.T) {
+	v, err := UnmarshalString(getDoc)
+	require.NoError(t, err)
+
+	s, err := GetString(v, "name")
+	require.NoError(t, err)
+	assert.Equal(t, "run-1", s)
+
+	b, err := GetBool(v, "done")
+	require.NoError(t, err)
+	assert.False(t, b)
+
+	i, err := GetInt64(v, "layers", 0, "units")
+	require.NoError(t, err)
+	assert.Equal(t, int64(64), i)
+
+	i, err = GetInt64(v, "config", "steps")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1000), i)
+
+	f, err := GetFloat64(v, "config", "epochs")
+	require.NoError(t, err)
+	assert.Equal(t, 10.0, f)
+
+	obj, err := GetObject(v, "config")
+	require.NoError(t, err)
+	assert.Len(t, obj, 5)
+
+	arr, err := GetArray(v, "layers")
+	require.NoError(t, err)
+	assert.Len(t, arr, 2)
+
+	whole, err := Get(v)
+	require.NoError(t, err)
+	assert.Equal(t, v, whole)
+
+	nothing, err := Get(v, "nothing")
+	require.NoError(t, err)
+	assert.Nil(t, nothing)
+}
+
+func TestGetErrors(t *testing.T) {
+	v, err := UnmarshalString(getDoc)
+	require.NoError(t, err)
+
+	cases := []struct {
+		get      func() error
+		notFound bool
+		message  string
+	}{
+		{
+			get:      func() error { _, err := GetString(v, "missing"); return err },
+			notFound: true,
+			message:  `simple json: at $["missing"]: not found`,
+		},
+		{
+			get:      func() error { _, err := GetInt64(v, "layers", 2, "units"); return err },
+			notFound: true,
+			message:  `simple json: at $["layers"][2]: not found`,
+		},
+		{
+			get:      func() error { _, err := GetInt64(v, "layers", -1); return err },
+			notFound: true,
+			message:  `simple json: at $["layers"][-1]: not found`,
+		},
+		{
+			get:     func() error { _, err := GetString(v, "done"); return err },
+			message: `simple json: at $["done"]: expected string but found bool`,
+		},
+		{
+			get:     func() error { _, err := GetInt64(v, "config", "ratio"); return err },
+			message: `simple json: at $["config"]["ratio"]: expected integer but found float64`,
+		},
+		{
+			get:     func() error { _, err := GetInt64(v, "config", "big"); return err },
+			message: `simple json: at $["config"]["big"]: expected integer but found float64`,
+		},
+		{
+			get:     func() error { _, err := GetFloat64(v, "name"); return err },
+			message: `simple json: at $["name"]: expected number but found string`,
+		},
+		{
+			get:     func() error { _, err := GetObject(v, "layers", 1); return err },
+			message: `simple json: at $["layers"][1]: expected object but found null`,
+		},
+		{
+			get:     func() error { _, err := GetArray(v, "config"); return err },
+			message: `simple json: at $["config"]: expected array but found object`,
+		},
+		{
+			get:     func() error { _, err := GetBool(v, "layers", "units"); return err },
+			message: `simple json: at $["layers"]: expected object but found array`,
+		},
+		{
+			get:     func() error { _, err := GetBool(v, "name", 0); return err },
+			message: `simple json: at $["name"]: expected array but found string`,
+		},
+		{
+			get:     func() error { _, err := GetBool(v, "layers", 1, "x"); return err },
+			message: `simple json: at $["layers"][1]: expected object but found null`,
+		},
+		{
+			get:     func() error { _, err := Get(v, 1.5); return err },
+			message: `simple json: at $[1.5]: path elements must be string or int, not float64`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.message, func(t *testing.T) {
+			err := c.get()
+			assert.EqualError(t, err, c.message)
+			var pathErr *PathError
+			assert.ErrorAs(t, err, &pathErr)
+			if c.notFound {
+				assert.ErrorIs(t, err, ErrPathNotFound)
+			} else {
+				assert.NotErrorIs(t, err, ErrPathNotFound)
+			}
+		})
+	}
+}
+
+func TestGetWrongTypeError(t *testing.T) {
+	_, err := GetInt64(map[string]any{"a": []any{"x"}}, "a", 0)
+	var typeErr *WrongTypeError
+	require.ErrorAs(t, err, &typeErr)
+	assert.Equal(t, "integer", typeErr.Expected)
+	assert.Equal(t, "x", typeErr.Found)
+	var pathErr *PathError
+	require.ErrorAs(t, err, &pathErr)
+	assert.Equal(t, []any{"a", 0}, pathErr.Path)
+}
+
+func TestGetOrderedObject(t *testing.T) {
+	v, err := NewParserFromString(`{"a": {"b": [1, "x"]}}`, WithOrderedObjects()).Parse()
+	require.NoError(t, err)
+
+	s, err := GetString(v, "a", "b", 1)
+	require.NoError(t, err)
+	assert.Equal(t, "x", s)
+
+	obj, err := GetObject(v, "a")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"b": []any{int64(1), "x"}}, obj)
+}
+
+func TestGetNumericCoercion(t *testing.T) {
+	for _, f := range []float64{0, math.Copysign(0, -1), 1, -1, 1 << 53, -(1 << 62), math.MinInt64} {
+		i, err := GetInt64(f)
+		require.NoError(t, err, f)
+		assert.Equal(t, int64(f), i)
+	}
+	for _, f := range []float64{0.5, -1e-9, math.NaN(), math.Inf(1), math.Inf(-1), 1 << 63, 1e300} {
+		_, err := GetInt64(f)
+		assert.Error(t, err, f)
+	}
+
+	f, err := GetFloat64(int64(math.MaxInt64))
+	require.NoError(t, err)
+	assert.Equal(t, float64(math.MaxInt64), f)
+}
@@ -0,0 +1,51 @@
+package simplejsonext
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+// The first two bytes of every gzip stream
+var gzipMagic = [...]byte{0x1f, 0x8b}
+
+// NewParserAuto creates a new parser for r, which may be plain JSON or gzip
+// compressed JSON. It reads the first two bytes of r, and if they are the
+// gzip magic number it parses the decompressed stream instead.
+//
+// An error is returned only if r is compressed and its gzip header cannot be
+// read. Errors decompressing the rest of the stream are wrapped in a
+// "simple json: reading gzip input" error and returned from the parser.
+// Nothing needs to be closed other than r itself, which remains the caller's
+// responsibility.
+func NewParserAuto(r io.Reader, opts ...ParseOption) (Parser, error) {
+	var head [len(gzipMagic)]byte
+	n, err := io.ReadFull(r, head[:])
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	r = io.MultiReader(bytes.NewReader(head[:n]), r)
+	if n < len(head) || head != gzipMagic {
+		return NewParser(r, opts...), nil
+	}
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("simple json: reading gzip header: %w", err)
+	}
+	return NewParser(gzipErrorReader{zr}, opts...), nil
+}
+
+// Wraps errors from a gzip.Reader, so that they can be told apart from
+// syntax errors in what it decompresses.
+type gzipErrorReader struct {
+	r *gzip.Reader
+}
+
+func (g gzipErrorReader) Read(b []byte) (int, error) {
+	n, err := g.r.Read(b)
+	if err != nil && err != io.EOF {
+		err = fmt.Errorf("simple json: reading gzip input: %w", err)
+	}
+	return n, err
+}
@@ -0,0 +1,93 @@
+package simplejsonext
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const gzipTestLines = "{\"step\": 1, \"loss\": NaN}\n{\"step\": 2, \"loss\": 0.5}\n"
+
+func gzipped(t *testing.T, s string) []byte {
+	var b bytes.Buffer
+	zw := gzip.NewWriter(&b)
+	_, err := zw.Write([]byte(s))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	return b.Bytes()
+}
+
+func readAllLines(p Parser) (lines []any, err error) {
+	p.IterLines()(func(v any, lineErr error) bool {
+		if lineErr != nil {
+			err = lineErr
+			return false
+		}
+		lines = append(lines, v)
+		return true
+	})
+	return
+}
+
+func TestNewParserAuto(t *testing.T) {
+	plain := []byte(gzipTestLines)
+	compressed := gzipped(t, gzipTestLines)
+	for name, input := range map[string][]byte{"plain": plain, "gzip": compressed} {
+		p, err := NewParserAuto(iotest.HalfReader(bytes.NewReader(input)), WithOrderedObjects())
+		require.NoError(t, err, name)
+		lines, err := readAllLines(p)
+		require.NoError(t, err, name)
+		require.Len(t, lines, 2, name)
+		step, _ := lines[1].(*OrderedObject).Get("step")
+		assert.Equal(t, int64(2), step, name)
+	}
+
+	// Inputs too short to hold the magic number are parsed as they are.
+	for input, expected := range map[string]any{"7": int64(7), "": io.EOF, "\x1f": nil} {
+		p, err := NewParserAuto(strings.NewReader(input))
+		require.NoError(t, err)
+		v, err := p.Parse()
+		if expected == io.EOF {
+			assert.Equal(t, io.EOF, err)
+		} else if expected == nil {
+			assert.Error(t, err)
+		} else {
+			assert.Equal(t, expected, v)
+		}
+	}
+}
+
+func TestNewParserAutoErrors(t *testing.T) {
+	compressed := gzipped(t, gzipTestLines)
+
+	// A truncated stream fails while parsing, with an error that says why.
+	p, err := NewParserAuto(bytes.NewReader(compressed[:len(compressed)-10]))
+	require.NoError(t, err)
+	_, err = readAllLines(p)
+	assert.ErrorContains(t, err, "simple json: reading gzip input")
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+	// So does one with a bad checksum.
+	corrupt := bytes.Clone(compressed)
+	corrupt[len(corrupt)-6] ^= 0xff
+	p, err = NewParserAuto(bytes.NewReader(corrupt))
+	require.NoError(t, err)
+	_, err = readAllLines(p)
+	assert.ErrorIs(t, err, gzip.ErrChecksum)
+
+	// A broken header fails straight away.
+	_, err = NewParserAuto(bytes.NewReader(compressed[:5]))
+	assert.ErrorContains(t, err, "simple json: reading gzip header")
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+	readErr := errors.New("disk on fire")
+	_, err = NewParserAuto(iotest.ErrReader(readErr))
+	assert.Equal(t, readErr, err)
+}
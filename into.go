@@ -0,0 +1,410 @@
+package simplejsonext
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var errNotPointer = errors.New("simple json: destination must be a non-nil pointer")
+
+var anyType = reflect.TypeFor[any]()
+
+// UnmarshalTypeError is returned by UnmarshalInto and Parser.ParseInto when a
+// JSON value cannot be stored in the Go value it is decoded into.
+type UnmarshalTypeError struct {
+	// Value describes the JSON value: "string", "array", or for numbers that
+	// do not fit, the number itself, as in "number 300".
+	Value string
+	// Type is the type of the Go value it was to be stored in.
+	Type reflect.Type
+	// Struct is the name of the outermost struct type, if it has one, and
+	// Field the Go name of the field, dotted through nested structs, that the
+	// value was for. Both are empty if the value was not in a struct.
+	Struct string
+	Field  string
+}
+
+func (e *UnmarshalTypeError) Error() string {
+	if e.Field == "" {
+		return "simple json: cannot decode " + e.Value + " into " + e.Type.String()
+	}
+	field := e.Field
+	if e.Struct != "" {
+		field = e.Struct + "." + field
+	}
+	return "simple json: cannot decode " + e.Value + " into Go struct field " + field + " of type " + e.Type.String()
+}
+
+// UnmarshalInto decodes the single JSON value in b into the value that dest
+// points to, which may be an any, a RawMessage, a bool, string, integer or
+// float, a struct, or a map with string keys, slice, or pointer whose
+// elements are any of these.
+//
+// Structs are decoded as encoding/json decodes them: members are matched to
+// exported fields by the name in their json tag, or else the field name,
+// ignoring case if there is no exact match; fields tagged `json:"-"` are
+// skipped; and the fields of embedded structs are promoted. Members without
+// a field are skipped, and fields without a member are left as they were, as
+// are bools, strings, numbers and structs decoded from null.
+//
+// Numbers written with a fraction or exponent, such as 2.0, 1e3 or -0, are
+// decoded into integers if their value is exactly an integer. A value that
+// does not fit its destination, such as a string for an int field or a
+// number too big for it, is an *UnmarshalTypeError.
+func UnmarshalInto(b []byte, dest any) error {
+	p := NewParserFromSlice(b)
+	if err := p.ParseInto(dest); err != nil {
+		return err
+	}
+	return p.CheckEmpty()
+}
+
+func (p *parser) ParseInto(dest any) error {
+	rv := reflect.ValueOf(dest)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return errNotPointer
+	}
+	return p.decodeInto(rv.Elem(), p.maxDepth())
+}
+
+// Decodes the next value into rv, which must be settable.
+func (p *parser) decodeInto(rv reflect.Value, remainingDepth int) error {
+	if remainingDepth < 0 {
+		return p.depthError(remainingDepth)
+	}
+	if rv.Type() == rawMessageType {
+		raw, err := p.readRaw()
+		if err != nil {
+			return err
+		}
+		rv.SetBytes(append(RawMessage(nil), raw...))
+		return nil
+	}
+	ty, err := p.parseType()
+	if err != nil {
+		return err
+	}
+
+	switch rv.Kind() {
+	case reflect.Interface:
+		if rv.NumMethod() != 0 {
+			break
+		}
+		val, err := p.doParse(remainingDepth)
+		if err != nil {
+			return err
+		}
+		if val == nil {
+			rv.SetZero()
+		} else {
+			rv.Set(reflect.ValueOf(val))
+		}
+		return nil
+	case reflect.Pointer:
+		if ty == nilTy {
+			rv.SetZero()
+			return p.consumeNull()
+		}
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		return p.decodeInto(rv.Elem(), remainingDepth-1)
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			break
+		}
+		if ty == nilTy {
+			rv.SetZero()
+			return p.consumeNull()
+		} else if ty != objectTy {
+			return p.errCannotDecode(ty, rv.Type())
+		}
+		if rv.IsNil() {
+			rv.Set(reflect.MakeMap(rv.Type()))
+		}
+		elemType := rv.Type().Elem()
+		var collected map[string]bool
+		return p.eachMember('{', '}', func(key []byte) error {
+			k := reflect.ValueOf(p.makeKey(key)).Convert(rv.Type().Key())
+			var existing reflect.Value
+			if p.cfg.duplicateKeys != DuplicateKeysLastWins {
+				existing = rv.MapIndex(k)
+			}
+			if existing.IsValid() {
+				switch p.cfg.duplicateKeys {
+				case DuplicateKeysError:
+					return errDuplicateKey(k.String())
+				case DuplicateKeysCollect:
+					if elemType != anyType {
+						return fmt.Errorf("simple json: cannot collect the values of duplicate key %q in a %v", k.String(), rv.Type())
+					}
+				default:
+					return p.Skip() // first wins
+				}
+			}
+			elem := reflect.New(elemType).Elem()
+			if err := p.decodeInto(elem, remainingDepth-1); err != nil {
+				return err
+			}
+			if existing.IsValid() {
+				elem = reflect.ValueOf(collectDuplicate(&collected, k.String(), existing.Interface(), elem.Interface()))
+			}
+			rv.SetMapIndex(k, elem)
+			return nil
+		})
+	case reflect.Struct:
+		if ty == nilTy {
+			return p.consumeNull()
+		} else if ty != objectTy {
+			return p.errCannotDecode(ty, rv.Type())
+		}
+		return p.decodeStruct(rv, remainingDepth)
+	case reflect.Bool:
+		if ty == nilTy {
+			return p.consumeNull()
+		} else if ty != boolTy {
+			return p.errCannotDecode(ty, rv.Type())
+		}
+		b, err := p.parseBool()
+		if err == nil {
+			rv.SetBool(b)
+		}
+		return err
+	case reflect.String:
+		if ty == nilTy {
+			return p.consumeNull()
+		} else if ty != stringTy {
+			return p.errCannotDecode(ty, rv.Type())
+		}
+		str, err := p.parseString()
+		if err == nil {
+			rv.SetString(string(str))
+		}
+		return err
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		if ty == nilTy {
+			return p.consumeNull()
+		} else if ty != numberTy {
+			return p.errCannotDecode(ty, rv.Type())
+		}
+		return p.decodeNumber(rv)
+	case reflect.Slice:
+		if ty == nilTy {
+			rv.SetZero()
+			return p.consumeNull()
+		} else if ty != arrayTy {
+			return p.errCannotDecode(ty, rv.Type())
+		}
+		rv.SetLen(0)
+		elemType := rv.Type().Elem()
+		err := p.eachMember('[', ']', func([]byte) error {
+			elem := reflect.New(elemType).Elem()
+			if err := p.decodeInto(elem, remainingDepth-1); err != nil {
+				return err
+			}
+			rv.Set(reflect.Append(rv, elem))
+			return nil
+		})
+		if err == nil && rv.IsNil() {
+			rv.Set(reflect.MakeSlice(rv.Type(), 0, 0))
+		}
+		return err
+	}
+	return fmt.Errorf("simple json: cannot decode into unsupported type %s", rv.Type())
+}
+
+var valTypeNames = [...]string{
+	nilTy:    "null",
+	boolTy:   "boolean",
+	numberTy: "number",
+	stringTy: "string",
+	arrayTy:  "array",
+	objectTy: "object",
+}
+
+func (p *parser) errCannotDecode(ty valType, dest reflect.Type) error {
+	switch ty {
+	case commaSym:
+		return errUnexpectedComma
+	case endGroupSym:
+		return errUnexpectedEnd
+	}
+	return &UnmarshalTypeError{Value: valTypeNames[ty], Type: dest}
+}
+
+// Decodes a number into rv, which is of an integer or float kind.
+func (p *parser) decodeNumber(rv reflect.Value) error {
+	view, isFloat, err := p.scanNumberText()
+	if err != nil {
+		return err
+	}
+	// The text is only needed for errors, which are rare, but is gone once
+	// the number is converted.
+	text := string(view)
+	i, f, isFloat, err := p.convertNumber(view, isFloat)
+	if err != nil {
+		return err
+	}
+	fits := true
+	switch rv.Kind() {
+	case reflect.Float32, reflect.Float64:
+		if !isFloat {
+			f = float64(i)
+		}
+		if fits = !rv.OverflowFloat(f) || math.IsInf(f, 0); fits {
+			rv.SetFloat(f)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u, ok := uint64(i), !isFloat && i >= 0
+		if isFloat {
+			u, err = strconv.ParseUint(integralText(text), 10, 64)
+			ok = err == nil
+		}
+		if fits = ok && !rv.OverflowUint(u); fits {
+			rv.SetUint(u)
+		}
+	default:
+		ok := !isFloat
+		if isFloat {
+			i, err = strconv.ParseInt(integralText(text), 10, 64)
+			ok = err == nil
+		}
+		if fits = ok && !rv.OverflowInt(i); fits {
+			rv.SetInt(i)
+		}
+	}
+	if !fits {
+		return &UnmarshalTypeError{Value: "number " + text, Type: rv.Type()}
+	}
+	return nil
+}
+
+// Returns the number text, with a fraction or exponent, as plain integer
+// digits if its value is exactly an integer of at most 20 digits, or else "".
+// The text is used rather than the float it parses to, which may have been
+// rounded to an integer.
+func integralText(text string) string {
+	sign := ""
+	if rest, ok := strings.CutPrefix(text, "-"); ok {
+		sign, text = "-", rest
+	}
+	mantissa, exponent, hasExponent := strings.Cut(strings.ToLower(text), "e")
+	e := 0
+	if hasExponent {
+		var err error
+		if e, err = strconv.Atoi(exponent); err != nil {
+			return ""
+		}
+	}
+	whole, fraction, _ := strings.Cut(mantissa, ".")
+	digits := strings.TrimLeft(whole+fraction, "0")
+	point := len(whole) + e - (len(whole) + len(fraction) - len(digits))
+	digits = strings.TrimRight(digits, "0")
+	switch {
+	case digits == "":
+		return "0"
+	case point < len(digits) || point > 20:
+		return ""
+	}
+	return sign + digits + strings.Repeat("0", point-len(digits))
+}
+
+// Decodes the members of an object into the fields of the struct rv.
+func (p *parser) decodeStruct(rv reflect.Value, remainingDepth int) error {
+	info := cachedStructInfo(rv.Type())
+	return p.eachMember('{', '}', func(key []byte) error {
+		f := info.field(key)
+		if f == nil {
+			if p.cfg.disallowUnknownFields {
+				return fmt.Errorf("simple json: unknown field %q for %s", key, rv.Type())
+			}
+			return p.doVisit(remainingDepth-1, NopVisitor{})
+		}
+		fv, err := structFieldValue(rv, f)
+		if err != nil {
+			return err
+		}
+		err = p.decodeInto(fv, remainingDepth-1)
+		if typeErr, ok := err.(*UnmarshalTypeError); ok {
+			if typeErr.Field == "" {
+				typeErr.Field = f.goName
+			} else {
+				typeErr.Field = f.goName + "." + typeErr.Field
+			}
+			typeErr.Struct = rv.Type().Name()
+		}
+		return err
+	})
+}
+
+// Returns the field f of the struct rv, allocating any nil embedded structs
+// it is promoted from.
+func structFieldValue(rv reflect.Value, f *structField) (reflect.Value, error) {
+	for i, x := range f.index {
+		if i > 0 && rv.Kind() == reflect.Pointer {
+			if rv.IsNil() {
+				if !rv.CanSet() {
+					return reflect.Value{}, fmt.Errorf("simple json: cannot set embedded pointer to unexported struct %v", rv.Type().Elem())
+				}
+				rv.Set(reflect.New(rv.Type().Elem()))
+			}
+			rv = rv.Elem()
+		}
+		rv = rv.Field(x)
+	}
+	return rv, nil
+}
+
+// Consumes an array or object, calling each with the key of every member, or
+// nil for arrays, when the data is positioned at its value. each must consume
+// the value.
+func (p *parser) eachMember(open, close byte, each func(key []byte) error) (err error) {
+	if err = p.readByte(open); err != nil {
+		return
+	}
+	first := true
+	for n := 0; ; n++ {
+		var ty valType
+		ty, err = p.parseType()
+		if err != nil {
+			return
+		}
+		if ty == endGroupSym {
+			return p.readByte(close)
+		} else if first {
+			if ty == commaSym {
+				return errUnexpectedComma
+			}
+			first = false
+		} else if err = p.consumeComma(ty); err != nil {
+			return
+		}
+		if err = p.checkCount(n, open == '{'); err != nil {
+			return
+		}
+		var key []byte
+		if open == '{' {
+			if err = p.skipSpaces(); err != nil {
+				return
+			}
+			if key, err = p.parseString(); err != nil {
+				return
+			}
+			if err = p.skipSpaces(); err != nil {
+				return
+			}
+			if err = p.readByte(':'); err != nil {
+				return
+			}
+		}
+		if err = each(key); err != nil {
+			return
+		}
+	}
+}
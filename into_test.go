@@ -0,0 +1,203 @@
+package simplejsonext
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type intoServer struct {
+	Host string `json:"host"`
+	Port uint16 `json:"port"`
+}
+
+type intoBase struct {
+	ID      int64  `json:"id"`
+	Kind    string `json:"type"`
+	private int
+}
+
+type intoConfig struct {
+	intoBase
+	Name     string                 `json:"name"`
+	Kind     string                 `json:"type"` // shadows intoBase.Kind
+	Skipped  string                 `json:"-"`
+	Dash     string                 `json:"-,"`
+	Untagged bool                   //
+	Ratio    float32                `json:"ratio,omitempty"`
+	Count    int8                   `json:"count"`
+	Server   intoServer             `json:"server"`
+	Backup   *intoServer            `json:"backup"`
+	Replicas []intoServer           `json:"replicas"`
+	Limits   map[string]int         `json:"limits"`
+	Extra    map[string]any         `json:"extra"`
+	Raw      RawMessage             `json:"raw"`
+	Named    map[string]*intoServer `json:"named"`
+	internal string
+}
+
+func TestUnmarshalIntoStruct(t *testing.T) {
+	const doc = `{
+		"id": 7, "kind": "base", "type": "config", "name": "run",
+		"-": "dash", "Skipped": "no", "untagged": true, "ratio": 0.5, "count": -3,
+		"server": {"host": "a", "port": 80},
+		"backup": {"host": "b", "port": 81, "unknown": [1, {"x": null}]},
+		"replicas": [{"host": "c"}, {"port": 83}],
+		"limits": {"cpu": 2},
+		"extra": {"x": [1, 2.5]},
+		"raw": {"kept": true},
+		"named": {"n": {"host": "d"}, "none": null},
+		"internal": "ignored", "private": 1
+	}`
+	var cfg intoConfig
+	require.NoError(t, UnmarshalInto([]byte(doc), &cfg))
+	expected := intoConfig{
+		intoBase: intoBase{ID: 7},
+		Name:     "run",
+		Kind:     "config",
+		Dash:     "dash",
+		Untagged: true,
+		Ratio:    0.5,
+		Count:    -3,
+		Server:   intoServer{Host: "a", Port: 80},
+		Backup:   &intoServer{Host: "b", Port: 81},
+		Replicas: []intoServer{{Host: "c"}, {Port: 83}},
+		Limits:   map[string]int{"cpu": 2},
+		Extra:    map[string]any{"x": []any{int64(1), 2.5}},
+		Raw:      RawMessage(`{"kept": true}`),
+		Named:    map[string]*intoServer{"n": {Host: "d"}, "none": nil},
+	}
+	assert.Equal(t, expected, cfg)
+
+	// The same as encoding/json, apart from the generic values it decodes.
+	var std intoConfig
+	require.NoError(t, json.Unmarshal([]byte(doc), &std))
+	std.Extra = expected.Extra
+	assert.Equal(t, expected, std)
+
+	// Members that are missing or null leave fields as they were.
+	require.NoError(t, UnmarshalInto([]byte(`{"name": null, "server": null, "count": null, "backup": null}`), &cfg))
+	assert.Equal(t, "run", cfg.Name)
+	assert.Equal(t, intoServer{Host: "a", Port: 80}, cfg.Server)
+	assert.Equal(t, int8(-3), cfg.Count)
+	assert.Nil(t, cfg.Backup)
+}
+
+func TestUnmarshalIntoEmbeddedPointer(t *testing.T) {
+	type Inner struct{ A, B int }
+	type outer struct {
+		*Inner
+		B string
+	}
+	var v outer
+	require.NoError(t, UnmarshalInto([]byte(`{"a": 1, "b": "x"}`), &v))
+	assert.Equal(t, outer{Inner: &Inner{A: 1}, B: "x"}, v)
+
+	type hidden struct {
+		*intoBase
+	}
+	var h hidden
+	assert.EqualError(t, UnmarshalInto([]byte(`{"id": 1}`), &h), "simple json: cannot set embedded pointer to unexported struct simplejsonext.intoBase")
+}
+
+func TestUnmarshalIntoNumbers(t *testing.T) {
+	var v struct {
+		I   int
+		I8  int8
+		I64 int64
+		U   uint
+		U8  uint8
+		F32 float32
+		F64 float64
+	}
+	require.NoError(t, UnmarshalInto([]byte(`{"i": -1, "i8": 127, "i64": -9223372036854775808, "u": 5, "u8": 255, "f32": 3, "f64": Infinity}`), &v))
+	assert.Equal(t, -1, v.I)
+	assert.Equal(t, int8(127), v.I8)
+	assert.Equal(t, int64(math.MinInt64), v.I64)
+	assert.Equal(t, uint(5), v.U)
+	assert.Equal(t, uint8(255), v.U8)
+	assert.Equal(t, float32(3), v.F32)
+	assert.True(t, math.IsInf(v.F64, 1))
+
+	for input, expected := range map[string]string{
+		`{"i8": 128}`:                    "simple json: cannot decode number 128 into Go struct field I8 of type int8",
+		`{"u8": -1}`:                     "simple json: cannot decode number -1 into Go struct field U8 of type uint8",
+		`{"u": 1.5}`:                     "simple json: cannot decode number 1.5 into Go struct field U of type uint",
+		`{"u": -1.0}`:                    "simple json: cannot decode number -1.0 into Go struct field U of type uint",
+		`{"i": 2e-3}`:                    "simple json: cannot decode number 2e-3 into Go struct field I of type int",
+		`{"i8": 1.28e2}`:                 "simple json: cannot decode number 1.28e2 into Go struct field I8 of type int8",
+		`{"i64": 9223372036854775807.5}`: "simple json: cannot decode number 9223372036854775807.5 into Go struct field I64 of type int64",
+		`{"i64": 1e19}`:                  "simple json: cannot decode number 1e19 into Go struct field I64 of type int64",
+		`{"i": NaN}`:                     "simple json: cannot decode number NaN into Go struct field I of type int",
+		`{"f32": 1e39}`:                  "simple json: cannot decode number 1e39 into Go struct field F32 of type float32",
+		`{"i": "1"}`:                     "simple json: cannot decode string into Go struct field I of type int",
+	} {
+		assert.EqualError(t, UnmarshalInto([]byte(input), &v), expected, input)
+	}
+
+	// Numbers with a fraction or exponent whose value is an integer, exactly.
+	require.NoError(t, UnmarshalInto([]byte(`{"i": -0, "i8": -1.28e2, "i64": 9223372036854775807.0, "u": 2e3, "u8": 0.00255e5}`), &v))
+	assert.Equal(t, 0, v.I)
+	assert.Equal(t, int8(-128), v.I8)
+	assert.Equal(t, int64(math.MaxInt64), v.I64)
+	assert.Equal(t, uint(2000), v.U)
+	assert.Equal(t, uint8(255), v.U8)
+	var u64 uint64
+	require.NoError(t, UnmarshalInto([]byte(`18446744073709551615`), &u64))
+	assert.Equal(t, uint64(math.MaxUint64), u64)
+	var z struct{ X int }
+	require.NoError(t, UnmarshalInto([]byte(`{"X": -0}`), &z))
+	assert.Equal(t, 0, z.X)
+
+	var i8 int8
+	var typeErr *UnmarshalTypeError
+	require.ErrorAs(t, UnmarshalInto([]byte(`-129`), &i8), &typeErr)
+	assert.Equal(t, UnmarshalTypeError{Value: "number -129", Type: typeErr.Type}, *typeErr)
+	assert.Equal(t, "simple json: cannot decode number -129 into int8", typeErr.Error())
+}
+
+func TestUnmarshalIntoFieldErrors(t *testing.T) {
+	var cfg intoConfig
+	for input, expected := range map[string]string{
+		`{"name": 5}`:                        "simple json: cannot decode number into Go struct field intoConfig.Name of type string",
+		`{"id": "x"}`:                        "simple json: cannot decode string into Go struct field intoConfig.intoBase.ID of type int64",
+		`{"server": {"port": 70000}}`:        "simple json: cannot decode number 70000 into Go struct field intoConfig.Server.Port of type uint16",
+		`{"replicas": [{}, {"host": true}]}`: "simple json: cannot decode boolean into Go struct field intoConfig.Replicas.Host of type string",
+		`{"named": {"n": {"host": []}}}`:     "simple json: cannot decode array into Go struct field intoConfig.Named.Host of type string",
+		`{"limits": {"cpu": 0.5}}`:           "simple json: cannot decode number 0.5 into Go struct field intoConfig.Limits of type int",
+		`{"untagged": "yes"}`:                "simple json: cannot decode string into Go struct field intoConfig.Untagged of type bool",
+		`{"server": [1]}`:                    "simple json: cannot decode array into Go struct field intoConfig.Server of type simplejsonext.intoServer",
+		`{"name": "a" "count": 1}`:           "simple json: expected ',' but found '\"'",
+	} {
+		assert.EqualError(t, UnmarshalInto([]byte(input), &cfg), expected, input)
+	}
+}
+
+func TestDecoderDisallowUnknownFields(t *testing.T) {
+	const input = `{"host": "a", "port": 1} {"host": "b", "proto": "udp"}`
+	for _, strict := range []bool{false, true} {
+		d := NewDecoder(strings.NewReader(input))
+		std := json.NewDecoder(strings.NewReader(input))
+		if strict {
+			d.DisallowUnknownFields()
+			std.DisallowUnknownFields()
+		}
+		var a, b, stdA, stdB intoServer
+		require.NoError(t, d.Decode(&a))
+		require.NoError(t, std.Decode(&stdA))
+		assert.Equal(t, intoServer{Host: "a", Port: 1}, a)
+		assert.Equal(t, stdA, a)
+		err, stdErr := d.Decode(&b), std.Decode(&stdB)
+		if strict {
+			assert.EqualError(t, err, `simple json: unknown field "proto" for simplejsonext.intoServer`)
+			assert.Error(t, stdErr)
+		} else {
+			require.NoError(t, err)
+			assert.Equal(t, stdB, b)
+		}
+	}
+}
@@ -1,14 +1,22 @@
 package simplejsonext
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"math"
+	"math/big"
 	"reflect"
+	"runtime"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"testing/iotest"
+	"unsafe"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -60,6 +68,21 @@ func TestUnicode(t *testing.T) {
 			if err != nil {
 				res = err
 			}
+			unquoted, err := UnquoteString([]byte(test.in))
+			if err != nil {
+				assert.Equal(t, res, err)
+			} else {
+				assert.Equal(t, res, unquoted)
+			}
+			// Errors here may differ for truncated input, which is no longer
+			// truncated once the suffix is added.
+			prefix, n, err := UnquoteStringPrefix([]byte(test.in + `, "next"`))
+			if _, ok := res.(error); ok {
+				assert.Error(t, err)
+			} else {
+				assert.Equal(t, res, prefix)
+				assert.Equal(t, len(test.in), n)
+			}
 
 			switch v := test.out.(type) {
 			case string:
@@ -77,6 +100,28 @@ func TestUnicode(t *testing.T) {
 	}
 }
 
+func TestUnquoteStringTrailingData(t *testing.T) {
+	s, err := UnquoteString([]byte(" \"a\\tb\" \n"))
+	require.NoError(t, err)
+	assert.Equal(t, "a\tb", s)
+
+	_, err = UnquoteString([]byte(`"a" "b"`))
+	assert.Equal(t, ErrTrailingData, err)
+	_, err = UnquoteString([]byte(`"abc`))
+	assert.ErrorIs(t, err, io.EOF)
+	_, err = UnquoteString(nil)
+	assert.ErrorIs(t, err, io.EOF)
+	_, err = UnquoteString([]byte(`123`))
+	assert.EqualError(t, err, `simple json: expected '"' but found '1'`)
+
+	_, _, err = UnquoteStringPrefix([]byte(` "a"`))
+	assert.EqualError(t, err, `simple json: expected '"' but found ' '`)
+	s, n, err := UnquoteStringPrefix([]byte(`"\u00e9"garbage`))
+	require.NoError(t, err)
+	assert.Equal(t, "\u00e9", s)
+	assert.Equal(t, 8, n)
+}
+
 func TestParseImpossibleFloats(t *testing.T) {
 	cases := []struct {
 		raw   string
@@ -130,6 +175,37 @@ func TestEmitImpossibleFloats(t *testing.T) {
 	}
 }
 
+func TestEmitNegativeZero(t *testing.T) {
+	negZero := math.Copysign(0, -1)
+	cases := []struct {
+		value any
+		out   string
+	}{
+		{negZero, `-0.0`},
+		{float32(negZero), `-0.0`},
+		{0.0, `0`},
+		{[]any{negZero, 0.0, []float64{negZero}}, `[-0.0,0,[-0.0]]`},
+		{map[string]any{"z": negZero}, `{"z":-0.0}`},
+		{map[string]float32{"z": float32(negZero)}, `{"z":-0.0}`},
+		{&OrderedObject{Members: []Member{{Key: "z", Value: negZero}}}, `{"z":-0.0}`},
+	}
+	for _, test := range cases {
+		out, err := MarshalToString(test.value)
+		require.NoError(t, err)
+		assert.Equal(t, test.out, out)
+		assert.Equal(t, len(out), EstimateMarshalSize(test.value), out)
+	}
+
+	// The sign survives a round trip, however deeply it is nested.
+	out, err := Marshal(map[string]any{"list": []any{map[string]any{"z": negZero}}})
+	require.NoError(t, err)
+	back, err := Unmarshal(out)
+	require.NoError(t, err)
+	z := back.(map[string]any)["list"].([]any)[0].(map[string]any)["z"]
+	require.IsType(t, float64(0), z)
+	assert.True(t, math.Signbit(z.(float64)))
+}
+
 func TestParseBigInts(t *testing.T) {
 	cases := []struct {
 		raw   string
@@ -156,17 +232,6 @@ func TestParseBigInts(t *testing.T) {
 	}
 }
 
-var _ json.Marshaler = &jsonextMarshaled{}
-
-type jsonextMarshaled struct {
-	value interface{}
-}
-
-func (jm jsonextMarshaled) MarshalJSON() ([]byte, error) {
-	b, e := Marshal(jm.value)
-	return b, e
-}
-
 func TestControlCharacters(t *testing.T) {
 	var sb strings.Builder
 	for r := rune(0); r <= '"'; r++ {
@@ -183,6 +248,12 @@ func TestControlCharacters(t *testing.T) {
 		` !\""`
 	assert.Equal(t, expected, marshaled)
 
+	assert.Equal(t, expected, string(AppendQuote(nil, testString)))
+	assert.Equal(t, "prefix"+expected, string(AppendQuote([]byte("prefix"), testString)))
+	assert.Equal(t, expected[1:len(expected)-1], EscapeString(testString))
+	assert.Equal(t, expected[1:len(expected)-1], string(AppendEscaped(nil, testString)))
+	assert.Equal(t, len(expected), quotedLen(testString))
+
 	unmarshaled, err := UnmarshalString(marshaled)
 	require.NoError(t, err)
 	assert.Equal(t, testString, unmarshaled)
@@ -196,12 +267,12 @@ func TestControlCharacters(t *testing.T) {
 			testString, unmarshaled)
 	}
 
-	marshaledViaInterface, err := json.Marshal(jsonextMarshaled{value: testString})
+	marshaledViaInterface, err := json.Marshal(Value{testString})
 	if err != nil {
 		t.Error("encoding/json did not validate the encoding", err)
 	}
 	if !bytes.Equal([]byte(marshaled), marshaledViaInterface) {
-		t.Fatalf("sanity check: jsonextMarshaled didn't work: expected %#v, but got %#v",
+		t.Fatalf("sanity check: Value didn't work: expected %#v, but got %#v",
 			marshaled, marshaledViaInterface)
 	}
 
@@ -217,7 +288,99 @@ func TestParseObject(t *testing.T) {
 	assert.Equal(t, val, map[string]any{"a": int64(1)})
 
 	_, err = UnmarshalObjectString(`1`)
-	assert.ErrorContains(t, err, "simple json: expected '{' but found '1'")
+	assert.EqualError(t, err, "simple json: expected an object but found number")
+}
+
+func TestNotObject(t *testing.T) {
+	unmarshalers := map[string]func(string) (map[string]any, error){
+		"slice":  func(s string) (map[string]any, error) { return UnmarshalObject([]byte(s)) },
+		"string": UnmarshalObjectString,
+		"reader": func(s string) (map[string]any, error) {
+			return UnmarshalObjectReader(iotest.OneByteReader(strings.NewReader(s)))
+		},
+	}
+	cases := []struct {
+		in   string
+		kind Kind
+	}{
+		{`null`, KindNull},
+		{`true`, KindBool},
+		{` false `, KindBool},
+		{`-1.5`, KindNumber},
+		{`NaN`, KindNumber},
+		{`"foo"`, KindString},
+		{"\n[1, 2, 3]\n", KindArray},
+	}
+	for name, unmarshal := range unmarshalers {
+		for _, test := range cases {
+			val, err := unmarshal(test.in)
+			assert.Nil(t, val)
+			assert.ErrorIs(t, err, ErrNotObject, name+" "+test.in)
+			var notObject *NotObjectError
+			if assert.ErrorAs(t, err, &notObject, name+" "+test.in) {
+				assert.Equal(t, test.kind, notObject.Found)
+			}
+		}
+		// Input that is not valid JSON is not reported as a non-object.
+		for _, bad := range []string{`[1,`, `"foo`, `nul`, `x`, `]`, `,`, `[1] x`, `1 2`, ``} {
+			_, err := unmarshal(bad)
+			assert.Error(t, err, name+" "+bad)
+			assert.NotErrorIs(t, err, ErrNotObject, name+" "+bad)
+		}
+	}
+
+	// A streaming parser moves past a value that is not an object.
+	p := NewParserFromString(`[{"a": 1}] {"b": 2}`)
+	_, err := p.ParseObject()
+	assert.Equal(t, &NotObjectError{Found: KindArray}, err)
+	val, err := p.ParseObject()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"b": int64(2)}, val)
+	_, err = NewParserFromString(`"x"`).ParseOrderedObject()
+	assert.Equal(t, &NotObjectError{Found: KindString}, err)
+}
+
+func TestEmptyObjectIsMutable(t *testing.T) {
+	obj, err := UnmarshalObjectString(`{}`)
+	require.NoError(t, err)
+	require.NotNil(t, obj)
+	obj["added"] = true
+
+	val, err := UnmarshalString(`{"outer": {}, "list": [{}]}`)
+	require.NoError(t, err)
+	outer := val.(map[string]any)["outer"].(map[string]any)
+	require.NotNil(t, outer)
+	outer["added"] = true
+	inner := val.(map[string]any)["list"].([]any)[0].(map[string]any)
+	require.NotNil(t, inner)
+	inner["added"] = true
+}
+
+// The streaming parsers must agree with each other and with Unmarshal about
+// objects, whatever follows them.
+func TestObjectEntryPointsAgree(t *testing.T) {
+	parsers := map[string]func(string) Parser{
+		"slice":  func(s string) Parser { return NewParserFromSlice([]byte(s)) },
+		"string": func(s string) Parser { return NewParserFromString(s) },
+		"reader": func(s string) Parser { return NewParser(strings.NewReader(s)) },
+		"one byte reader": func(s string) Parser {
+			return NewParser(iotest.OneByteReader(strings.NewReader(s)))
+		},
+	}
+	for _, doc := range []string{`{}`, `{"a": 1}`, `{"a": {}}`} {
+		expected, err := UnmarshalString(doc)
+		require.NoError(t, err)
+		for _, trailer := range []string{"", "foobar", " {}", "]"} {
+			for name, newParser := range parsers {
+				t.Run(name+" "+doc+trailer, func(t *testing.T) {
+					val, err := newParser(doc + trailer).Parse()
+					require.NoError(t, err)
+					assert.Equal(t, expected, val)
+					assert.NotNil(t, val.(map[string]any))
+				})
+			}
+		}
+	}
 }
 
 func TestWhitespaceSkipping(t *testing.T) {
@@ -246,3 +409,1164 @@ func TestReflectedEmit(t *testing.T) {
 	_, err = MarshalToString([]map[string]map[int]int{{"foo": {1: 2}}})
 	assert.ErrorContains(t, err, "simple json: cannot emit unsupported type map[int]int")
 }
+
+func TestUnmarshalReader(t *testing.T) {
+	// Padding longer than the read buffer pushes trailing data across a read
+	// boundary.
+	padding := strings.Repeat(" ", 3000)
+	cases := []struct {
+		in     string
+		out    any
+		offset int64 // of trailing data, if any
+	}{
+		{in: `{"a": 1}`, out: map[string]any{"a": int64(1)}},
+		{in: `{"a": 1}   `, out: map[string]any{"a": int64(1)}},
+		{in: "{\"a\": 1}\n", out: map[string]any{"a": int64(1)}},
+		{in: `[1, 2]` + padding + "\r\n\t", out: []any{int64(1), int64(2)}},
+		{in: `123`, out: int64(123)},
+		{in: `123 4`, offset: 4},
+		{in: `{"a": 1}x`, offset: 8},
+		{in: `{"a": 1}` + padding + `junk`, offset: 3008},
+		{in: `{"a": 1}{}`, offset: 8},
+		{in: "\"s\"\n\n\"t\"\n", offset: 5},
+	}
+	readers := map[string]func(string) io.Reader{
+		"whole": func(s string) io.Reader { return strings.NewReader(s) },
+		"one byte": func(s string) io.Reader {
+			return iotest.OneByteReader(strings.NewReader(s))
+		},
+		"half": func(s string) io.Reader {
+			return iotest.HalfReader(strings.NewReader(s))
+		},
+	}
+	for name, mkReader := range readers {
+		for _, test := range cases {
+			t.Run(name+" "+strings.TrimSpace(test.in), func(t *testing.T) {
+				val, err := UnmarshalReader(mkReader(test.in))
+				if test.offset != 0 {
+					assert.Equal(t, &TrailingDataError{Offset: test.offset}, err)
+					assert.ErrorIs(t, err, ErrTrailingData)
+					return
+				}
+				require.NoError(t, err)
+				assert.Equal(t, test.out, val)
+			})
+		}
+	}
+
+	// Trailing garbage is not read past its first byte.
+	r := &endlessReader{prefix: "[1]\n ", repeat: "garbage"}
+	_, err := UnmarshalReader(r)
+	assert.EqualError(t, err, "simple json: remainder of buffer not empty at offset 5")
+}
+
+func TestUnmarshalObjectReader(t *testing.T) {
+	val, err := UnmarshalObjectReader(strings.NewReader(" {\"a\": \"b\"}\n"))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"a": "b"}, val)
+
+	_, err = UnmarshalObjectReader(strings.NewReader(`[1]`))
+	assert.EqualError(t, err, "simple json: expected an object but found array")
+
+	_, err = UnmarshalObjectReader(iotest.OneByteReader(strings.NewReader(`{} {}`)))
+	assert.Equal(t, &TrailingDataError{Offset: 3}, err)
+
+	_, err = UnmarshalObjectReader(strings.NewReader(``))
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestUnmarshalRead(t *testing.T) {
+	var v []int
+	require.NoError(t, UnmarshalRead(strings.NewReader(" [1, 2]\n"), &v))
+	assert.Equal(t, []int{1, 2}, v)
+
+	err := UnmarshalRead(iotest.OneByteReader(strings.NewReader(`[3] x`)), &v)
+	assert.Equal(t, &TrailingDataError{Offset: 4}, err)
+	assert.ErrorIs(t, err, ErrTrailingData)
+}
+
+func TestZeroCopyStrings(t *testing.T) {
+	const doc = `{"plain": "value", "esc\u0061ped": "line\nbreak", "list": ["a", ""]}`
+	expected := map[string]any{
+		"plain":   "value",
+		"escaped": "line\nbreak",
+		"list":    []any{"a", ""},
+	}
+	within := func(s string, input []byte) bool {
+		at := uintptr(unsafe.Pointer(unsafe.StringData(s)))
+		start := uintptr(unsafe.Pointer(unsafe.SliceData(input)))
+		return len(s) > 0 && at >= start && at < start+uintptr(len(input))
+	}
+
+	for _, zeroCopy := range []bool{false, true} {
+		var opts []ParseOption
+		if zeroCopy {
+			opts = append(opts, WithZeroCopyStrings())
+		}
+		input := []byte(doc)
+		v, err := NewParserFromSlice(input, opts...).Parse()
+		require.NoError(t, err)
+		assert.Equal(t, expected, v)
+
+		// Escape-free strings and keys alias the input; decoded ones never do.
+		obj := v.(map[string]any)
+		for key, value := range obj {
+			if key == "escaped" {
+				assert.False(t, within(key, input))
+				assert.False(t, within(value.(string), input))
+			} else {
+				assert.Equal(t, zeroCopy, within(key, input), key)
+			}
+		}
+		assert.Equal(t, zeroCopy, within(obj["plain"].(string), input))
+		assert.Equal(t, zeroCopy, within(obj["list"].([]any)[0].(string), input))
+	}
+
+	// Strings from a reader are always copied out of the read buffer.
+	input := []byte(doc)
+	p := NewParser(bytes.NewReader(input), WithZeroCopyStrings())
+	v, err := p.Parse()
+	require.NoError(t, err)
+	assert.Equal(t, expected, v)
+	assert.False(t, within(v.(map[string]any)["plain"].(string), p.(*parser).readBuf))
+
+	str := doc
+	v, err = NewParserFromString(str, WithZeroCopyStrings()).Parse()
+	require.NoError(t, err)
+	assert.Equal(t, expected, v)
+	assert.True(t, within(v.(map[string]any)["plain"].(string), bytesNoCopy(str)))
+}
+
+func TestKeyInterning(t *testing.T) {
+	long := strings.Repeat("k", maxInternedKeyLen+1)
+	doc := `[{"a": 1, "bc": 2, "` + long + `": 3}, {"\u0061": 4, "bc": 5, "` + long + `": 6}]`
+	expected := []any{
+		map[string]any{"a": int64(1), "bc": int64(2), long: int64(3)},
+		map[string]any{"a": int64(4), "bc": int64(5), long: int64(6)},
+	}
+	keyData := func(obj any, want string) *byte {
+		for key := range obj.(map[string]any) {
+			if key == want {
+				return unsafe.StringData(key)
+			}
+		}
+		t.Fatalf("key %q not found", want)
+		return nil
+	}
+
+	for _, opts := range [][]ParseOption{
+		{WithKeyInterning()},
+		{WithKeyInterning(), WithZeroCopyStrings()},
+	} {
+		p := NewParserFromString(doc, opts...)
+		v, err := p.Parse()
+		require.NoError(t, err)
+		assert.Equal(t, expected, v)
+		arr := v.([]any)
+		// Short keys are shared whether or not they were escaped.
+		assert.Same(t, keyData(arr[0], "a"), keyData(arr[1], "a"))
+		assert.Same(t, keyData(arr[0], "bc"), keyData(arr[1], "bc"))
+		assert.NotSame(t, keyData(arr[0], long), keyData(arr[1], long))
+		// Interned keys never alias the input, even with zero copy strings.
+		assert.NotSame(t, unsafe.StringData(doc[3:]), keyData(arr[0], "a"))
+
+		// The table is kept across resets.
+		p.ResetString(`{"a": null}`)
+		v2, err := p.Parse()
+		require.NoError(t, err)
+		assert.Same(t, keyData(arr[0], "a"), keyData(v2, "a"))
+	}
+
+	// The table stops growing when it is full.
+	var sb strings.Builder
+	sb.WriteByte('{')
+	for i := 0; i < maxInternedKeys+10; i++ {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		fmt.Fprintf(&sb, `"key%d": %d`, i, i)
+	}
+	sb.WriteByte('}')
+	p := NewParserFromString(sb.String(), WithKeyInterning())
+	v, err := p.Parse()
+	require.NoError(t, err)
+	assert.Len(t, v, maxInternedKeys+10)
+	assert.Len(t, p.(*parser).keys, maxInternedKeys)
+}
+
+// Values built in the parser's scratch buffer must not change when the buffer
+// is reused for later values.
+func TestScratchBufferReuse(t *testing.T) {
+	long := strings.Repeat("1234567890", 200)
+	docs := []string{
+		`"esc\taped"`,
+		`"💥 and more"`,
+		long,
+		`{"k\ney": "val\"ue", "n": -` + long + `}`,
+		`["` + strings.Repeat(`\n`, 3*readBufferSize) + `"]`,
+		`"\u0000"`,
+		`0.` + long,
+	}
+	var expected []any
+	for _, doc := range docs {
+		v, err := UnmarshalString(doc)
+		require.NoError(t, err)
+		expected = append(expected, v)
+	}
+
+	for _, fromReader := range []bool{false, true} {
+		var p Parser
+		if fromReader {
+			p = NewParser(strings.NewReader(""))
+		} else {
+			p = NewParserFromString("")
+		}
+		var results []any
+		for round := 0; round < 2; round++ {
+			for _, doc := range docs {
+				if fromReader {
+					p.Reset(strings.NewReader(doc))
+				} else {
+					p.ResetString(doc)
+				}
+				v, err := p.UnmarshalFull()
+				require.NoError(t, err)
+				results = append(results, v)
+			}
+		}
+		assert.Equal(t, append(expected, expected...), results)
+	}
+}
+
+func TestOrdinaryPrefixLen(t *testing.T) {
+	naive := func(b []byte) int {
+		for i, c := range b {
+			if c < ' ' || c == '"' || c == '\\' {
+				return i
+			}
+		}
+		return len(b)
+	}
+	buf := []byte(strings.Repeat("abcdé\x7f\xff", 4))
+	for c := 0; c < 256; c++ {
+		for pos := 0; pos < len(buf); pos++ {
+			b := append([]byte(nil), buf...)
+			b[pos] = byte(c)
+			require.Equal(t, naive(b), ordinaryPrefixLen(b), "byte %#x at %d", c, pos)
+		}
+	}
+
+	// Control characters are rejected wherever they appear in long strings.
+	for pos := 0; pos < 20; pos++ {
+		s := []byte(`"` + strings.Repeat("x", 20) + `"`)
+		s[1+pos] = '\x1f'
+		_, err := Unmarshal(s)
+		assert.Equal(t, errControlChar, err)
+		s = []byte(`"\n` + strings.Repeat("x", 20) + `"`)
+		s[3+pos] = '\x1f'
+		_, err = Unmarshal(s)
+		assert.Equal(t, errControlChar, err)
+	}
+}
+
+func TestWhitespace(t *testing.T) {
+	for _, ws := range []string{"\v", "\f", " ", "\x00"} {
+		_, err := UnmarshalString("[1," + ws + "2]")
+		assert.Error(t, err, "%q", ws)
+	}
+
+	// Runs of whitespace spanning many reads are skipped, and the next token
+	// is found wherever it lands in the read buffer.
+	for _, n := range []int{readBufferSize - 1, readBufferSize, readBufferSize + 1, 3 * readBufferSize} {
+		pad := strings.Repeat(" \t\r\n", n/4+1)[:n]
+		doc := pad + "[" + pad + "1" + pad + "," + pad + `{"a"` + pad + ":" + pad + "true" + pad + "}" + pad + "]" + pad
+		for _, p := range []Parser{NewParserFromString(doc), NewParser(iotest.HalfReader(strings.NewReader(doc)))} {
+			v, err := p.UnmarshalFull()
+			require.NoError(t, err)
+			assert.Equal(t, []any{int64(1), map[string]any{"a": true}}, v)
+		}
+		_, err := NewParser(strings.NewReader(pad)).Parse()
+		assert.Equal(t, io.EOF, err)
+	}
+}
+
+func TestArrayCapacity(t *testing.T) {
+	big := "[" + strings.TrimSuffix(strings.Repeat("0,", 2*oversizedElems), ",") + "]"
+	p := NewParserFromString("").(*parser)
+	for _, doc := range []string{
+		`[1, [2, 3, [4]], [], 5]`,
+		`{"a": [1, 2], "b": [[3], [4, 5, 6]]}`,
+		big,
+		`[1, [2, 3], ` + big + `, 4]`,
+	} {
+		p.ResetString(doc)
+		v, err := p.UnmarshalFull()
+		require.NoError(t, err)
+		expected, err := UnmarshalString(doc)
+		require.NoError(t, err)
+		assert.Equal(t, expected, v)
+		assert.Empty(t, p.elems, "the element stack is left empty")
+
+		// Every array is exactly sized, and empty arrays are nil.
+		_, err = Walk(v, func(path []string, v any) (any, error) {
+			if arr, ok := v.([]any); ok {
+				assert.Equal(t, len(arr), cap(arr), "%v", path)
+				if len(arr) == 0 {
+					assert.Nil(t, arr)
+				}
+			}
+			return v, nil
+		})
+		require.NoError(t, err)
+	}
+
+	// Errors part way through nested arrays leave nothing behind.
+	for _, doc := range []string{`[1, [2, [3, oops]]]`, `[1, 2`, `[[1, 2], ,]`} {
+		p.ResetString(doc)
+		_, err := p.Parse()
+		assert.Error(t, err)
+		assert.Empty(t, p.elems)
+		for _, v := range p.elems[:cap(p.elems)] {
+			assert.Nil(t, v, "scratch does not keep parsed values alive")
+		}
+	}
+}
+
+func TestUnsafeStrings(t *testing.T) {
+	const doc = `{"plain": "value", "escaped": "line\nbreak", "list": ["a", ""]}`
+	expected := map[string]any{
+		"plain":   "value",
+		"escaped": "line\nbreak",
+		"list":    []any{"a", ""},
+	}
+	within := func(s string, input []byte) bool {
+		at := uintptr(unsafe.Pointer(unsafe.StringData(s)))
+		start := uintptr(unsafe.Pointer(unsafe.SliceData(input)))
+		return len(s) > 0 && at >= start && at < start+uintptr(len(input))
+	}
+
+	input := []byte(doc)
+	p := NewParserFromSlice(input, WithUnsafeStrings())
+	v, err := p.Parse()
+	require.NoError(t, err)
+	assert.Equal(t, expected, v)
+	obj := v.(map[string]any)
+	assert.True(t, within(obj["plain"].(string), input))
+	assert.True(t, within(obj["list"].([]any)[0].(string), input))
+	for key := range obj {
+		assert.False(t, within(key, input), key)
+	}
+
+	// Keys survive the input being overwritten, and so do strings copied
+	// into chunks, even as the parser goes on to fill them further.
+	for i := range input {
+		input[i] = ' '
+	}
+	for i := 0; i < unsafeStringChunk; i++ {
+		p.ResetString(`{"other key": "more\ttext"}`)
+		_, err = p.Parse()
+		require.NoError(t, err)
+	}
+	assert.Equal(t, "line\nbreak", obj["escaped"])
+	assert.Contains(t, obj, "plain")
+	assert.Contains(t, obj, "list")
+	assert.Contains(t, obj, "escaped")
+
+	// Long strings get their own memory.
+	long := strings.Repeat("k", unsafeStringChunk)
+	v, err = NewParserFromString(`{"`+long+`": 1}`, WithUnsafeStrings()).Parse()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{long: int64(1)}, v)
+}
+
+func TestUnsafeStringsConcurrent(t *testing.T) {
+	// Values from different parsers share no memory, so they can be used
+	// from different goroutines while the parsers carry on.
+	const doc = `{"name": "run\t1", "tags": ["a\nb", "c"]}`
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p := NewParserFromString(doc, WithUnsafeStrings())
+			results := make(chan any, 16)
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				for v := range results {
+					obj := v.(map[string]any)
+					assert.Equal(t, "run\t1", obj["name"])
+					assert.Equal(t, []any{"a\nb", "c"}, obj["tags"])
+				}
+			}()
+			for i := 0; i < 200; i++ {
+				p.ResetString(doc)
+				v, err := p.Parse()
+				if !assert.NoError(t, err) {
+					break
+				}
+				results <- v
+			}
+			close(results)
+			<-done
+		}()
+	}
+	wg.Wait()
+}
+
+func TestMarshalWriteIndent(t *testing.T) {
+	ordered := &OrderedObject{Members: []Member{
+		{Key: "b", Value: []any{int64(1), "two", []any{}, map[string]any{}}},
+		{Key: "a", Value: map[string]any{"x": []int{3}}},
+	}}
+	type stdShape struct {
+		B []any          `json:"b"`
+		A map[string]any `json:"a"`
+	}
+	std := stdShape{B: []any{1, "two", []any{}, map[string]any{}}, A: map[string]any{"x": []int{3}}}
+	for _, indent := range [][2]string{{"", "  "}, {">", "\t"}, {"", ""}} {
+		expected, err := json.MarshalIndent(std, indent[0], indent[1])
+		require.NoError(t, err)
+		var b bytes.Buffer
+		require.NoError(t, MarshalWrite(&b, ordered, WithIndent(indent[0], indent[1])))
+		assert.Equal(t, string(expected), b.String())
+	}
+
+	// Indentation does not carry over from a failed value.
+	var b bytes.Buffer
+	e := NewEmitter(&b, WithIndent("", " "))
+	assert.Error(t, e.Emit([]any{[]any{complex(1, 2)}}))
+	b.Reset()
+	e.Reset(&b)
+	require.NoError(t, e.Emit([]any{int64(1)}))
+	assert.Equal(t, "[\n 1\n]", b.String())
+
+	// Nor without resetting the emitter.
+	b.Reset()
+	e = NewEmitter(&b, WithIndent("", "  "), WithStrictFloats())
+	assert.Error(t, e.Emit([]any{[]any{math.NaN()}}))
+	b.Reset()
+	require.NoError(t, e.Emit([]any{int64(1)}))
+	assert.Equal(t, "[\n  1\n]", b.String())
+}
+
+func TestStrictFloats(t *testing.T) {
+	for _, f := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		var b bytes.Buffer
+		err := MarshalWrite(&b, []any{1.5, f}, WithStrictFloats())
+		assert.ErrorContains(t, err, "simple json: cannot emit non-finite float")
+		assert.Zero(t, b.Len(), "nothing is written on error")
+	}
+	var b bytes.Buffer
+	require.NoError(t, MarshalWrite(&b, []any{1.5, float32(2)}, WithStrictFloats()))
+	assert.Equal(t, "[1.5,2]", b.String())
+}
+
+func TestStrictNumbers(t *testing.T) {
+	for _, ok := range []string{`0`, `-0`, `12`, `-1.5`, `1e5`, `1E+5`, `0.5e-3`, `9223372036854775808`} {
+		var v any
+		assert.NoError(t, UnmarshalRead(strings.NewReader(ok), &v, WithStrictNumbers()), ok)
+		expected, err := UnmarshalString(ok)
+		require.NoError(t, err)
+		assert.Equal(t, expected, v, ok)
+	}
+	for _, bad := range []string{`NaN`, `Infinity`, `-Infinity`, `+1`, `01`, `-01`, `.5`, `1.`, `1e`, `1e999`, `[1, NaN]`} {
+		var v any
+		assert.Error(t, UnmarshalRead(strings.NewReader(bad), &v, WithStrictNumbers()), bad)
+	}
+}
+
+func TestMaxDepthOption(t *testing.T) {
+	nested := func(n int) string { return nestedJSON("[", n) }
+	tooDeep := &DepthError{Depth: 11, Offset: 11}
+	var v any
+	assert.NoError(t, UnmarshalRead(strings.NewReader(nested(10)), &v, WithMaxDepth(10)))
+	assert.Equal(t, tooDeep, UnmarshalRead(strings.NewReader(nested(11)), &v, WithMaxDepth(10)))
+	p := NewParserFromString(nested(11), WithMaxDepth(10))
+	assert.Equal(t, tooDeep, p.Skip())
+	p = NewParserFromString(nested(maxDepth), WithMaxDepth(0))
+	_, err := p.Parse()
+	assert.NoError(t, err)
+	p = NewParserFromString(nested(maxDepth + 1))
+	_, err = p.Parse()
+	assert.Equal(t, &DepthError{Depth: maxDepth + 1, Offset: maxDepth + 1}, err)
+}
+
+// Returns a value nested depth levels deep in containers that each open with
+// one of the given brackets in turn, '[' or '{'.
+func nestedJSON(opens string, depth int) string {
+	var sb strings.Builder
+	for i := 0; i < depth; i++ {
+		if opens[i%len(opens)] == '[' {
+			sb.WriteByte('[')
+		} else {
+			sb.WriteString(`{"a":`)
+		}
+	}
+	sb.WriteString("null")
+	for i := depth - 1; i >= 0; i-- {
+		if opens[i%len(opens)] == '[' {
+			sb.WriteByte(']')
+		} else {
+			sb.WriteByte('}')
+		}
+	}
+	return sb.String()
+}
+
+func TestDepthLimit(t *testing.T) {
+	parsers := map[string]func(string) Parser{
+		"slice":  func(s string) Parser { return NewParserFromSlice([]byte(s)) },
+		"string": func(s string) Parser { return NewParserFromString(s) },
+		"one byte reader": func(s string) Parser {
+			return NewParser(iotest.OneByteReader(strings.NewReader(s)))
+		},
+	}
+	reads := map[string]func(Parser) error{
+		"parse": func(p Parser) error { _, err := p.Parse(); return err },
+		"skip":  func(p Parser) error { return p.Skip() },
+		"into": func(p Parser) error {
+			var v any
+			return p.ParseInto(&v)
+		},
+		"tokens": func(p Parser) error {
+			for {
+				if _, err := p.StdToken(); err == io.EOF {
+					return nil
+				} else if err != nil {
+					return err
+				}
+			}
+		},
+	}
+	for _, opens := range []string{"[", "{", "[{", "{["} {
+		ok := nestedJSON(opens, maxDepth)
+		tooDeep := nestedJSON(opens, maxDepth+1)
+		// The innermost null is the value that is too deep.
+		expected := &DepthError{Depth: maxDepth + 1, Offset: int64(strings.Index(tooDeep, "null"))}
+		for parserName, newParser := range parsers {
+			for readName, read := range reads {
+				t.Run(opens+" "+parserName+" "+readName, func(t *testing.T) {
+					assert.NoError(t, read(newParser(ok)))
+					assert.Equal(t, expected, read(newParser(tooDeep)))
+				})
+			}
+		}
+	}
+
+	// Space before the value does not count towards its offset.
+	_, err := NewParserFromString("[[ \n null]]", WithMaxDepth(1)).Parse()
+	assert.EqualError(t, err, "simple json: maximum nesting depth exceeded: depth 2 at offset 5")
+}
+
+func TestStrictNumberEnds(t *testing.T) {
+	cases := []struct {
+		in  string
+		out any
+		err string
+	}{
+		{in: `1`, out: int64(1)},
+		{in: "1 2", out: int64(1)},
+		{in: "[1,\t2.5\n]", out: []any{int64(1), 2.5}},
+		{in: `{"a": -Infinity}`, out: map[string]any{"a": math.Inf(-1)}},
+		{in: `[1x]`, err: "simple json: expected end of number but found 'x'"},
+		{in: `{"a": 1"b"}`, err: "simple json: expected end of number but found '\"'"},
+		{in: `1{}`, err: "simple json: expected end of number but found '{'"},
+		{in: `[1.5:2]`, err: "simple json: expected end of number but found ':'"},
+		{in: `[NaN, Infinity, -Infinity]`, out: []any{math.NaN(), math.Inf(1), math.Inf(-1)}},
+		{in: `NaNvvvvv`, err: `simple json: invalid token "NaNvvvvv"`},
+		{in: `[Infrared]`, err: `simple json: invalid token "Infrared"`},
+		{in: `-Infinityx2 `, err: `simple json: invalid token "-Infinityx2"`},
+		{in: `{"a": NaN_}`, err: "simple json: expected end of number but found '_'"},
+	}
+	parsers := map[string]func(string) Parser{
+		"slice": func(s string) Parser { return NewParserFromSlice([]byte(s), WithStrictNumberEnds()) },
+		"one byte reader": func(s string) Parser {
+			return NewParser(iotest.OneByteReader(strings.NewReader(s)), WithStrictNumberEnds())
+		},
+		"exact numbers": func(s string) Parser {
+			return NewParserFromString(s, WithStrictNumberEnds(), WithExactNumbers())
+		},
+	}
+	for name, newParser := range parsers {
+		for _, test := range cases {
+			t.Run(name+" "+test.in, func(t *testing.T) {
+				val, err := newParser(test.in).Parse()
+				if test.err != "" {
+					assert.EqualError(t, err, test.err)
+					assert.EqualError(t, newParser(test.in).Skip(), test.err)
+					return
+				}
+				require.NoError(t, err)
+				if name == "exact numbers" {
+					return
+				}
+				assert.Equal(t, Dump(test.out), Dump(val))
+			})
+		}
+	}
+
+	// The default is unchanged.
+	val, err := NewParserFromString(`[1x]`).Parse()
+	assert.Nil(t, val)
+	assert.Error(t, err)
+	val, err = NewParserFromString(`12z`).Parse()
+	require.NoError(t, err)
+	assert.Equal(t, int64(12), val)
+}
+
+func TestNegativeZero(t *testing.T) {
+	for _, in := range []string{`-0`, `-00`, `-0.0`, `-0e3`, `-0E-1`} {
+		for _, p := range []Parser{
+			NewParserFromString(in),
+			NewParserFromSlice([]byte(in + " ")),
+			NewParser(iotest.OneByteReader(strings.NewReader(in + "]"))),
+		} {
+			v, err := p.Parse()
+			require.NoError(t, err, in)
+			require.IsType(t, float64(0), v, in)
+			assert.True(t, math.Signbit(v.(float64)), in)
+
+			// Whatever it is written as parses back the same.
+			out, err := Marshal(v)
+			require.NoError(t, err)
+			back, err := Unmarshal(out)
+			require.NoError(t, err)
+			require.IsType(t, float64(0), back, string(out))
+			assert.True(t, math.Signbit(back.(float64)), string(out))
+		}
+	}
+	// Positive zeros are still integers.
+	for _, in := range []string{`0`, `00`} {
+		v, err := UnmarshalString(in)
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), v)
+	}
+}
+
+func TestMemberSizes(t *testing.T) {
+	members := []struct{ key, value string }{
+		{"a", `1`},
+		{"esc\\u00e9\\\"", `"x\"y\\zé\n"`},
+		{"nested", `{"b": [1, 2, {"c": "}]"}], "d": {}}`},
+		{"long", `"` + strings.Repeat("é", 3*readBufferSize) + `"`},
+		{"num", `-1.5e-3`},
+		{"ws", `[ 1 , 2 ]`},
+		{"last", `NaN`},
+	}
+	var sb strings.Builder
+	sb.WriteString(" {")
+	expected := make(map[string]int64)
+	for i, m := range members {
+		if i > 0 {
+			sb.WriteString(" ,\n")
+		}
+		fmt.Fprintf(&sb, "\"%s\" :\t %s", m.key, m.value)
+		key, err := UnquoteString([]byte(`"` + m.key + `"`))
+		require.NoError(t, err)
+		expected[key] = int64(len(m.value))
+	}
+	sb.WriteString(" } ")
+	doc := sb.String()
+
+	for name, newParser := range map[string]func(opts ...ParseOption) Parser{
+		"slice": func(opts ...ParseOption) Parser { return NewParserFromString(doc, opts...) },
+		"one byte": func(opts ...ParseOption) Parser {
+			return NewParser(iotest.OneByteReader(strings.NewReader(doc)), opts...)
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			sizes := make(map[string]int64)
+			p := newParser(WithMemberSizes(func(key string, size int64) error {
+				sizes[key] = size
+				return nil
+			}))
+			_, err := p.Parse()
+			require.NoError(t, err)
+			assert.Equal(t, expected, sizes)
+
+			// A limit names the member that broke it, and stops parsing.
+			var seen []string
+			p = newParser(WithOrderedObjects(), WithMemberSizes(func(key string, size int64) error {
+				seen = append(seen, key)
+				if size > 1000 {
+					return fmt.Errorf("member %q is %d bytes", key, size)
+				}
+				return nil
+			}))
+			_, err = p.ParseObject()
+			assert.EqualError(t, err, fmt.Sprintf("member \"long\" is %d bytes", expected["long"]))
+			assert.Equal(t, []string{"a", "escé\"", "nested", "long"}, seen)
+		})
+	}
+
+	// Only members of top-level objects are measured.
+	var keys []string
+	_, err := UnmarshalWithOptions([]byte(`[{"a": {"b": 1}}, 2]`), WithMemberSizes(func(key string, _ int64) error {
+		keys = append(keys, key)
+		return nil
+	}))
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+func TestPanicRecovery(t *testing.T) {
+	p := NewParserFromString(`[1, 2]`).(*parser)
+	p.cfg.onNumber = func(view []byte, f float64, isFloat bool) {
+		var m map[string]int
+		m["x"] = 1
+	}
+	v, err := p.Parse()
+	assert.Nil(t, v)
+	var panicErr *PanicError
+	require.ErrorAs(t, err, &panicErr)
+	assert.EqualError(t, err, "simple json: internal error: assignment to entry in nil map")
+	assert.Contains(t, string(panicErr.Stack), "TestPanicRecovery")
+	var runtimeErr runtime.Error
+	assert.ErrorAs(t, err, &runtimeErr)
+
+	p.ResetString(`1`)
+	assert.ErrorAs(t, p.Skip(), &panicErr)
+	p.ResetString(`{"a": 1}`)
+	_, err = p.ParseObject()
+	assert.ErrorAs(t, err, &panicErr)
+}
+
+func TestByteStrings(t *testing.T) {
+	const doc = `{"plain": "value", "escaped": "line\nbreaké", "list": ["a", "", 1, NaN], "bin": "\u0000ÿ"}`
+	expected := map[string]any{
+		"plain":   []byte("value"),
+		"escaped": []byte("line\nbreaké"),
+		"list":    []any{[]byte("a"), []byte{}, int64(1), []byte("NaN")},
+		"bin":     []byte("\x00ÿ"),
+	}
+	for _, zeroCopy := range []bool{false, true} {
+		opts := []ParseOption{WithByteStrings(), WithDeNaN(DeNaNToString)}
+		if zeroCopy {
+			opts = append(opts, WithZeroCopyStrings())
+		}
+		input := []byte(doc)
+		v, err := NewParserFromSlice(input, opts...).Parse()
+		require.NoError(t, err)
+		assert.Equal(t, expected, v)
+
+		// Values are copies unless zero-copy strings were requested, and
+		// appending to them never overwrites the input.
+		plain := v.(map[string]any)["plain"].([]byte)
+		plain[0] = 'V'
+		assert.Equal(t, zeroCopy, strings.Contains(string(input), `"Value"`))
+		_ = append(plain, "!!!"...)
+		assert.Contains(t, string(input), `alue", "esc`)
+		v.(map[string]any)["escaped"].([]byte)[0] = 'L'
+		assert.Equal(t, doc[:10], string(input[:10]))
+	}
+
+	// Written back with WithBytesAsStrings, the values have the same bytes.
+	var out bytes.Buffer
+	v, err := UnmarshalWithOptions([]byte(doc), WithByteStrings())
+	require.NoError(t, err)
+	require.NoError(t, MarshalWrite(&out, v, WithBytesAsStrings()))
+	roundTripped, err := UnmarshalWithOptions(out.Bytes(), WithByteStrings())
+	require.NoError(t, err)
+	assert.Equal(t, v.(map[string]any)["escaped"], roundTripped.(map[string]any)["escaped"])
+	assert.Equal(t, v.(map[string]any)["bin"], roundTripped.(map[string]any)["bin"])
+	asStrings, err := Unmarshal(out.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, "line\nbreaké", asStrings.(map[string]any)["escaped"])
+
+	// Without it, []byte is still written in base64.
+	b, err := Marshal([]any{[]byte("hi")})
+	require.NoError(t, err)
+	assert.Equal(t, `["aGk="]`, string(b))
+	out.Reset()
+	require.NoError(t, MarshalWrite(&out, []any{[]byte("hi"), RawMessage(`{}`)}, WithBytesAsStrings()))
+	assert.Equal(t, `["hi",{}]`, out.String())
+}
+
+func TestEmitBig(t *testing.T) {
+	huge, _ := new(big.Int).SetString("-123456789012345678901234567890123456789012345678901234567890", 10)
+	third := new(big.Float).SetPrec(200).Quo(big.NewFloat(1).SetPrec(200), big.NewFloat(3))
+	tiny := new(big.Float).SetMantExp(big.NewFloat(1), -1000)
+	large := new(big.Float).SetMantExp(big.NewFloat(1.5), 10000)
+	negZero := new(big.Float).Neg(new(big.Float))
+	inf := new(big.Float).SetInf(false)
+	var nilInt *big.Int
+	var nilFloat *big.Float
+
+	for _, test := range []struct {
+		value any
+		out   string
+	}{
+		{big.NewInt(42), `42`},
+		{huge, huge.String()},
+		{nilInt, `null`},
+		{nilFloat, `null`},
+		{big.NewFloat(1.5), `1.5`},
+		{big.NewFloat(1e100), `1e+100`},
+		{big.NewFloat(-0.001), `-0.001`},
+		{new(big.Float), `0`},
+		{negZero, `-0.0`},
+		{inf, `Infinity`},
+		{new(big.Float).Neg(inf), `-Infinity`},
+		{third, third.Text('g', -1)},
+		{[]any{big.NewInt(1), map[string]any{"f": big.NewFloat(2.5)}}, `[1,{"f":2.5}]`},
+	} {
+		out, err := MarshalToString(test.value)
+		require.NoError(t, err)
+		assert.Equal(t, test.out, out)
+		assert.Equal(t, len(out), EstimateMarshalSize(test.value), out)
+	}
+
+	// Values read back exactly as Numbers, and as the nearest int64 or
+	// float64 otherwise. Numbers beyond the range of float64 read back as
+	// infinities.
+	for _, f := range []*big.Float{third, tiny, large, big.NewFloat(-2.25)} {
+		out, err := Marshal(f)
+		require.NoError(t, err)
+		assert.NotContains(t, string(out), "p")
+		exact, err := UnmarshalWithOptions(out, WithExactNumbers())
+		require.NoError(t, err)
+		back, _, err := new(big.Float).SetPrec(f.Prec()).Parse(string(exact.(Number)), 10)
+		require.NoError(t, err)
+		assert.Zero(t, f.Cmp(back), string(out))
+		approx, err := Unmarshal(out)
+		require.NoError(t, err)
+		f64, _ := f.Float64()
+		assert.Equal(t, f64, approx, string(out))
+	}
+	out, err := Marshal(huge)
+	require.NoError(t, err)
+	exact, err := UnmarshalWithOptions(out, WithExactNumbers())
+	require.NoError(t, err)
+	back, ok := new(big.Int).SetString(string(exact.(Number)), 10)
+	require.True(t, ok)
+	assert.Zero(t, huge.Cmp(back))
+
+	// Exponents too large to write out in decimal are an error.
+	tooLarge := new(big.Float).SetMantExp(big.NewFloat(1), 1<<20)
+	_, err = Marshal(tooLarge)
+	assert.EqualError(t, err, "simple json: cannot emit *big.Float with binary exponent 1048577")
+	assert.Equal(t, -1, EstimateMarshalSize(tooLarge))
+	_, err = Marshal(new(big.Float).SetMantExp(big.NewFloat(1), -1<<20))
+	assert.Error(t, err)
+	var sb strings.Builder
+	assert.EqualError(t, NewEmitter(&sb, WithStrictFloats()).Emit(inf), "simple json: cannot emit non-finite float +Inf")
+}
+
+type appendPoint struct{ X, Y float64 }
+
+func (p appendPoint) AppendJSONExt(dst []byte) ([]byte, error) {
+	dst = append(dst, '[')
+	dst = strconv.AppendFloat(dst, p.X, 'g', -1, 64)
+	dst = append(dst, ',')
+	dst = strconv.AppendFloat(dst, p.Y, 'g', -1, 64)
+	return append(dst, ']'), nil
+}
+
+// Appends whatever it holds, and is an error too.
+type appendText string
+
+func (a appendText) AppendJSONExt(dst []byte) ([]byte, error) {
+	if a == "fail" {
+		return dst, errors.New("cannot append")
+	}
+	return append(dst, a...), nil
+}
+
+func (a appendText) Error() string { return string(a) }
+
+// A slice that writes itself, rather than being written as an array
+type appendCount []int
+
+func (c *appendCount) AppendJSONExt(dst []byte) ([]byte, error) {
+	return strconv.AppendInt(dst, int64(len(*c)), 10), nil
+}
+
+func TestEmitAppender(t *testing.T) {
+	var nilPoint *appendPoint
+	var nilCount *appendCount
+	count := appendCount{1, 2, 3}
+	for _, test := range []struct {
+		value any
+		out   string
+	}{
+		{appendPoint{1.5, math.Inf(-1)}, `[1.5,-Inf]`},
+		{&appendPoint{1, 2}, `[1,2]`},
+		{nilPoint, `null`},
+		{nilCount, `null`},
+		{appendText(`{"a":NaN}`), `{"a":NaN}`},
+		{&count, `3`},
+		{[]any{appendPoint{}, map[string]any{"p": appendPoint{3, 4}}}, `[[0,0],{"p":[3,4]}]`},
+		{[]appendPoint{{1, 2}}, `[[1,2]]`},
+	} {
+		out, err := MarshalToString(test.value)
+		require.NoError(t, err)
+		assert.Equal(t, test.out, out)
+		assert.Equal(t, len(out), EstimateMarshalSize(test.value), out)
+
+		// Appenders are leaves when emitted with an explicit stack too.
+		var sb strings.Builder
+		require.NoError(t, NewEmitter(&sb).(*emitter).emitDeep(test.value))
+		assert.Equal(t, test.out, sb.String())
+	}
+
+	_, err := Marshal([]any{appendText("fail")})
+	assert.EqualError(t, err, "cannot append")
+	assert.Equal(t, -1, EstimateMarshalSize(appendText("fail")))
+
+	for _, invalid := range []string{``, `1 2`, ` 1`, `[1`, `}`} {
+		_, err = Marshal(appendText(invalid))
+		if emitDebug {
+			assert.ErrorContains(t, err, "simple json: invalid output from AppendJSONExt of simplejsonext.appendText: ", invalid)
+		} else {
+			assert.NoError(t, err, invalid)
+		}
+	}
+}
+
+func TestEmitterStats(t *testing.T) {
+	corpus := []any{
+		nil,
+		true,
+		int64(-12),
+		math.Inf(1),
+		"a \"quoted\"\n string",
+		[]any{1.5, []byte("bytes"), map[string]any{"k": []any{}}},
+		map[string]any{"nested": map[string]any{"a": []string{"x", "y"}}},
+		strings.Repeat("long ", 100),
+	}
+	var expected int64
+	for _, v := range corpus {
+		b, err := Marshal(v)
+		require.NoError(t, err)
+		expected += int64(len(b))
+	}
+
+	var buf bytes.Buffer
+	e := NewEmitter(&buf)
+	for _, v := range corpus {
+		require.NoError(t, e.Emit(v))
+	}
+	assert.Equal(t, EmitterStats{BytesWritten: expected, Values: int64(len(corpus))}, e.Stats())
+	assert.Equal(t, int64(buf.Len()), e.Stats().BytesWritten)
+
+	// A failed value is not counted, but what was written of it is.
+	require.Error(t, e.Emit([]any{1, make(chan int)}))
+	assert.Equal(t, EmitterStats{BytesWritten: expected + 3, Values: int64(len(corpus))}, e.Stats())
+
+	// Bytes are counted as they are handed to the writer, so through a
+	// buffered writer they are all there to be flushed.
+	var out bytes.Buffer
+	bw := bufio.NewWriterSize(&out, 16)
+	e.Reset(bw)
+	assert.Equal(t, EmitterStats{}, e.Stats())
+	for _, v := range corpus {
+		require.NoError(t, e.Emit(v))
+		assert.Equal(t, e.Stats().BytesWritten, int64(out.Len()+bw.Buffered()))
+	}
+	require.NoError(t, bw.Flush())
+	assert.Equal(t, EmitterStats{BytesWritten: expected, Values: int64(len(corpus))}, e.Stats())
+	assert.Equal(t, buf.String()[:expected], out.String())
+
+	// Indentation is counted too.
+	buf.Reset()
+	e = NewEmitter(&buf, WithIndent("", "  "))
+	require.NoError(t, e.Emit(corpus[6]))
+	assert.Equal(t, int64(buf.Len()), e.Stats().BytesWritten)
+}
+
+func TestReplaceInvalidUTF8(t *testing.T) {
+	long := strings.Repeat("é", readBufferSize)
+	for _, test := range []struct {
+		in  string
+		out any
+	}{
+		{`"ok é 世界 😀"`, "ok é 世界 😀"},
+		{"\"a\xffb\"", "a�b"},
+		// A truncated sequence is one replacement per byte, as in
+		// encoding/json.
+		{"\"\xe4\xb8\"", "��"},
+		// Invalid bytes next to escapes
+		{"\"\xc3\\u00e9\xa9\\n\"", "�é�\n"},
+		{"\"\xed\xa0\x80\\ud83d\\ude00\"", "���😀"},
+		// Keys are replaced too
+		{"{\"k\xff\": \"v\xfe\"}", map[string]any{"k�": "v�"}},
+		// Across refills of the read buffer
+		{"\"" + long + "\xff" + long + "\"", long + "�" + long},
+	} {
+		for _, mode := range [][]ParseOption{nil, {WithZeroCopyStrings()}, {WithUnsafeStrings()}} {
+			opts := append([]ParseOption{WithReplaceInvalidUTF8()}, mode...)
+			for _, p := range []Parser{
+				NewParserFromString(test.in, opts...),
+				NewParser(iotest.OneByteReader(strings.NewReader(test.in)), opts...),
+			} {
+				v, err := p.Parse()
+				require.NoError(t, err, test.in)
+				assert.Equal(t, test.out, v, test.in)
+			}
+		}
+	}
+}
+
+func TestWithOptionsHelpers(t *testing.T) {
+	const doc = `{"b": [1, {"d": 2, "c": 3}], "a": {"z": null, "y": "x"}}`
+	v, err := UnmarshalStringWithOptions(doc, WithMaxDepth(3))
+	require.NoError(t, err)
+	expected, err := UnmarshalWithOptions([]byte(doc), WithMaxDepth(3))
+	require.NoError(t, err)
+	assert.Equal(t, expected, v)
+	_, err = UnmarshalStringWithOptions(doc, WithMaxDepth(2))
+	assert.Equal(t, &DepthError{Depth: 3, Offset: 16}, err)
+	_, err = UnmarshalStringWithOptions(doc+"x", WithMaxDepth(3))
+	assert.Equal(t, ErrTrailingData, err)
+
+	// Sorted keys are written as encoding/json writes them.
+	v = map[string]any{
+		"b":  v,
+		"a":  map[string]int{"2": 2, "10": 10, "1": 1},
+		"é":  []map[string]bool{{"y": true, "x": false}},
+		"A":  nil,
+		"":   "empty",
+		"aa": []any{},
+	}
+	std, err := json.Marshal(v)
+	require.NoError(t, err)
+	out, err := MarshalWithOptions(v, WithSortedKeys())
+	require.NoError(t, err)
+	assert.Equal(t, string(std), string(out))
+	std, err = json.MarshalIndent(v, ">", "\t")
+	require.NoError(t, err)
+	s, err := MarshalToStringWithOptions(v, WithSortedKeys(), WithIndent(">", "\t"))
+	require.NoError(t, err)
+	assert.Equal(t, string(std), s)
+
+	// Ordered objects keep their order.
+	s, err = MarshalToStringWithOptions(map[string]any{"o": &OrderedObject{Members: []Member{{Key: "z"}, {Key: "a"}}}}, WithSortedKeys())
+	require.NoError(t, err)
+	assert.Equal(t, `{"o":{"z":null,"a":null}}`, s)
+
+	// Keys are sorted at any depth.
+	deep := any(map[string]any{"b": 1, "a": 2})
+	for i := 0; i < 2000; i++ {
+		deep = map[string]any{"y": deep, "x": i}
+	}
+	out, err = MarshalWithOptions(deep, WithSortedKeys())
+	require.NoError(t, err)
+	assert.Equal(t, `{"x":1999,`, string(out[:10]))
+	assert.Equal(t, `{"a":2,"b":1}`, string(out[len(out)-2000-13:len(out)-2000]))
+
+	out, err = MarshalWithOptions([]any{make(chan int)})
+	assert.Error(t, err)
+	assert.Nil(t, out)
+}
+
+func TestWithComments(t *testing.T) {
+	doc := "// set by sweep agent\n{\"a\": /* one */ 1, // the next\n \"b\": [1, /**/ 2, /* a * / b **/ \"// not /* a comment */\"]} // end"
+	v, err := parseAllWays(t, doc, WithComments())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"a": int64(1),
+		"b": []any{int64(1), int64(2), "// not /* a comment */"},
+	}, v)
+	_, err = parseAllWays(t, doc)
+	assert.Error(t, err)
+
+	for _, doc := range []string{"1 /", "/ 1", "1 /x", "[1 /* two ]", "/* unterminated", "1 /*/"} {
+		_, err = parseAllWays(t, doc, WithComments())
+		assert.Error(t, err, doc)
+	}
+	_, err = parseAllWays(t, "[1, /* two", WithComments())
+	assert.Equal(t, errUnterminatedComment, err)
+	v, err = parseAllWays(t, "/* a */ /* b *//*c*/ 7 //", WithComments())
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), v)
+}
+
+func TestEmitterComment(t *testing.T) {
+	var buf bytes.Buffer
+	e := NewEmitter(&buf, WithIndent("  ", "\t"))
+	require.NoError(t, e.Comment("set by sweep agent"))
+	require.NoError(t, e.Emit(map[string]any{"lr": 0.5, "layers": []any{int64(2), int64(3)}}))
+	buf.WriteByte('\n')
+	require.NoError(t, e.Comment("line one\nline two\r\n\r\nthree */ four\rfive\u2028six"))
+	require.NoError(t, e.Emit([]any{true}))
+	buf.WriteByte('\n')
+	require.NoError(t, e.Comment(""))
+	assert.Equal(t, EmitterStats{BytesWritten: int64(buf.Len() - 2), Values: 2}, e.Stats())
+	out := buf.String()
+	assert.Equal(t, "// set by sweep agent\n  {\n", out[:len("// set by sweep agent\n  {\n")])
+	assert.Contains(t, out, "\n  }\n// line one\n  // line two\n  //\n  // three */ four\n  // five\n  // six\n  [\n  \ttrue\n  ]\n//\n  ")
+
+	// The values survive being read back.
+	p := NewParserFromString(out, WithComments())
+	v, err := p.Parse()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"lr": 0.5, "layers": []any{int64(2), int64(3)}}, v)
+	v, err = p.Parse()
+	require.NoError(t, err)
+	assert.Equal(t, []any{true}, v)
+	_, err = p.Parse()
+	assert.Equal(t, io.EOF, err)
+
+	// Comments on members go before their keys, and on elements before them.
+	tree := &OrderedObject{Members: []Member{
+		{"lr", Commented{"learning rate", 0.5}},
+		{"layers", []any{Commented{"input", int64(2)}, int64(3), Commented{"a\nb", []any{int64(4)}}}},
+		{"opts", map[string]any{"seed": Commented{"fixed", int64(1)}}},
+	}}
+	buf.Reset()
+	e = NewEmitter(&buf, WithIndent("", "  "))
+	require.NoError(t, e.Emit(tree))
+	assert.Equal(t, `{
+  // learning rate
+  "lr": 0.5,
+  "layers": [
+    // input
+    2,
+    3,
+    // a
+    // b
+    [
+      4
+    ]
+  ],
+  "opts": {
+    // fixed
+    "seed": 1
+  }
+}`, buf.String())
+	v, err = NewParserFromString(buf.String(), WithComments()).Parse()
+	require.NoError(t, err)
+	plain := map[string]any{
+		"lr":     0.5,
+		"layers": []any{int64(2), int64(3), []any{int64(4)}},
+		"opts":   map[string]any{"seed": int64(1)},
+	}
+	assert.Equal(t, plain, v)
+
+	// Past the depth where emitting stops recursing, too.
+	var deep any = Commented{"bottom", "x"}
+	for i := 0; i < 2*maxEmitRecursion; i++ {
+		deep = map[string]any{"k": Commented{"c", []any{deep}}}
+	}
+	buf.Reset()
+	require.NoError(t, e.Emit(deep))
+	assert.Contains(t, buf.String(), "// bottom\n")
+	v, err = NewParserFromString(buf.String(), WithComments(), WithMaxDepth(8*maxEmitRecursion)).Parse()
+	require.NoError(t, err)
+	read, err := Marshal(v)
+	require.NoError(t, err)
+	compact, err := Marshal(deep)
+	require.NoError(t, err)
+	assert.Equal(t, compact, read)
+
+	// Compact output gets no comments.
+	compact, err = Marshal(tree)
+	require.NoError(t, err)
+	assert.Equal(t, `{"lr":0.5,"layers":[2,3,[4]],"opts":{"seed":1}}`, string(compact))
+	assert.Equal(t, len(compact), EstimateMarshalSize(tree))
+	buf.Reset()
+	e = NewEmitter(&buf)
+	assert.Error(t, e.Comment("x"))
+	assert.Zero(t, buf.Len())
+}
@@ -0,0 +1,132 @@
+package simplejsonext
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// Kind is the kind of a JSON value.
+type Kind int
+
+const (
+	KindInvalid Kind = iota
+	KindNull
+	KindBool
+	KindNumber
+	KindString
+	KindArray
+	KindObject
+	// KindNonFinite is NaN or an infinity, which are numbers that only
+	// extended JSON has. Only KindOf reports it; elsewhere they are numbers.
+	KindNonFinite
+)
+
+var kindNames = [...]string{
+	KindInvalid:   "invalid",
+	KindNull:      "null",
+	KindBool:      "boolean",
+	KindNumber:    "number",
+	KindString:    "string",
+	KindArray:     "array",
+	KindObject:    "object",
+	KindNonFinite: "non-finite number",
+}
+
+func (k Kind) String() string {
+	if k < 0 || int(k) >= len(kindNames) {
+		return fmt.Sprintf("Kind(%d)", int(k))
+	}
+	return kindNames[k]
+}
+
+// Returns the kind of value that starts with a byte of type ty.
+func kindOf(ty valType) Kind {
+	switch ty {
+	case nilTy:
+		return KindNull
+	case boolTy:
+		return KindBool
+	case numberTy:
+		return KindNumber
+	case stringTy:
+		return KindString
+	case arrayTy:
+		return KindArray
+	case objectTy:
+		return KindObject
+	}
+	return KindInvalid
+}
+
+// ErrNotObject is matched by every *NotObjectError.
+var ErrNotObject = errors.New("simple json: not an object")
+
+// NotObjectError is returned when an object is required but the input holds
+// some other valid JSON value, so that callers can tell it apart from input
+// that is not valid JSON at all.
+type NotObjectError struct {
+	// Found is the kind of value that was found instead
+	Found Kind
+}
+
+func (e *NotObjectError) Error() string {
+	return "simple json: expected an object but found " + e.Found.String()
+}
+
+func (e *NotObjectError) Is(target error) bool {
+	return target == ErrNotObject
+}
+
+// KindOfError is returned by KindOf when b does not show what kind of value
+// it holds.
+type KindOfError struct {
+	// Offset is where the value starts, or len(b) if there is none.
+	Offset int
+	// Prefix is what KindOf examined of the value, if anything.
+	Prefix string
+}
+
+func (e *KindOfError) Error() string {
+	if e.Prefix == "" {
+		return fmt.Sprintf("simple json: no value at offset %d", e.Offset)
+	}
+	return fmt.Sprintf("simple json: cannot tell the kind of value starting %q at offset %d", e.Prefix, e.Offset)
+}
+
+// KindOf classifies the value at the start of b, after any whitespace and
+// UTF-8 byte order mark, by looking at no more than its first two bytes. The
+// rest of b is not examined, so the value may still be invalid. If b holds no
+// value, or its start is not that of any value, a *KindOfError is returned.
+func KindOf(b []byte) (Kind, error) {
+	start := 0
+	if bytes.HasPrefix(b, []byte{0xef, 0xbb, 0xbf}) {
+		start = 3
+	}
+	for start < len(b) && spaceTable[b[start]] {
+		start++
+	}
+	if start == len(b) {
+		return KindInvalid, &KindOfError{Offset: start}
+	}
+	switch c := b[start]; {
+	case c == 'N' || c == 'I':
+		return KindNonFinite, nil
+	case c == '-':
+		// Either a number or an infinity
+		if start+1 < len(b) {
+			switch next := b[start+1]; {
+			case next == 'I':
+				return KindNonFinite, nil
+			case next >= '0' && next <= '9' || next == '.':
+				return KindNumber, nil
+			}
+		}
+		return KindInvalid, &KindOfError{Offset: start, Prefix: string(b[start:min(start+2, len(b))])}
+	default:
+		if kind := kindOf(valType(typeTable[c])); kind != KindInvalid {
+			return kind, nil
+		}
+		return KindInvalid, &KindOfError{Offset: start, Prefix: string(b[start : start+1])}
+	}
+}
@@ -0,0 +1,63 @@
+package simplejsonext
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKindOf(t *testing.T) {
+	for _, test := range []struct {
+		value string
+		kind  Kind
+		// How many bytes KindOf needs to see
+		needs int
+	}{
+		{`null`, KindNull, 1},
+		{`true`, KindBool, 1},
+		{`false`, KindBool, 1},
+		{`12.5`, KindNumber, 1},
+		{`-3`, KindNumber, 2},
+		{`-.5`, KindNumber, 2},
+		{`0`, KindNumber, 1},
+		{`"NaN"`, KindString, 1},
+		{`[1]`, KindArray, 1},
+		{`{"a": 1}`, KindObject, 1},
+		{`NaN`, KindNonFinite, 1},
+		{`Infinity`, KindNonFinite, 1},
+		{`-Infinity`, KindNonFinite, 2},
+	} {
+		for _, prefix := range []string{"", " \t\r\n ", "\xef\xbb\xbf", "\xef\xbb\xbf\n"} {
+			for _, value := range []string{test.value, test.value[:test.needs]} {
+				kind, err := KindOf([]byte(prefix + value))
+				require.NoError(t, err, "%q", prefix+value)
+				assert.Equal(t, test.kind, kind, "%q", prefix+value)
+			}
+		}
+	}
+}
+
+func TestKindOfErrors(t *testing.T) {
+	for _, test := range []struct {
+		in  string
+		err KindOfError
+		msg string
+	}{
+		{``, KindOfError{Offset: 0}, "simple json: no value at offset 0"},
+		{" \n\t", KindOfError{Offset: 3}, "simple json: no value at offset 3"},
+		{"\xef\xbb\xbf ", KindOfError{Offset: 4}, "simple json: no value at offset 4"},
+		{`  -`, KindOfError{Offset: 2, Prefix: "-"}, `simple json: cannot tell the kind of value starting "-" at offset 2`},
+		{`-x1`, KindOfError{Offset: 0, Prefix: "-x"}, `simple json: cannot tell the kind of value starting "-x" at offset 0`},
+		{` ]`, KindOfError{Offset: 1, Prefix: "]"}, `simple json: cannot tell the kind of value starting "]" at offset 1`},
+		{`'a'`, KindOfError{Offset: 0, Prefix: "'"}, `simple json: cannot tell the kind of value starting "'" at offset 0`},
+		{"\xef\xbb", KindOfError{Offset: 0, Prefix: "\xef"}, `simple json: cannot tell the kind of value starting "\xef" at offset 0`},
+	} {
+		kind, err := KindOf([]byte(test.in))
+		assert.Equal(t, KindInvalid, kind)
+		var kindErr *KindOfError
+		require.ErrorAs(t, err, &kindErr, "%q", test.in)
+		assert.Equal(t, test.err, *kindErr, "%q", test.in)
+		assert.EqualError(t, err, test.msg)
+	}
+}
@@ -0,0 +1,123 @@
+package simplejsonext
+
+import "fmt"
+
+// SafeLimits bounds the resources a parser will spend on its input, so that
+// input from untrusted sources cannot make it use unbounded memory or time.
+// A field of zero or less means no limit, except for MaxDepth, which then
+// keeps the parser's depth limit as it is.
+type SafeLimits struct {
+	// MaxDepth is how deeply arrays and objects may be nested, as with
+	// WithMaxDepth.
+	MaxDepth int
+	// MaxStringBytes is the longest a string or object key may be once
+	// unescaped.
+	MaxStringBytes int
+	// MaxNumberBytes is the longest the text of a number may be.
+	MaxNumberBytes int
+	// MaxArrayElements is the most elements an array may have.
+	MaxArrayElements int
+	// MaxObjectMembers is the most members an object may have, counting
+	// repeated keys each time.
+	MaxObjectMembers int
+	// MaxInputBytes is the most bytes the parser will read in all, from when
+	// it is created or reset.
+	MaxInputBytes int64
+}
+
+// DefaultSafeLimits are the limits applied by WithUntrustedInput. They are
+// meant to be generous for requests and documents sent by clients, and
+// conservative for anything else; copy and adjust them for other needs.
+var DefaultSafeLimits = SafeLimits{
+	MaxDepth:         64,
+	MaxStringBytes:   1 << 20,
+	MaxNumberBytes:   64,
+	MaxArrayElements: 1 << 20,
+	MaxObjectMembers: 10000,
+	MaxInputBytes:    16 << 20,
+}
+
+// WithLimits makes the parser fail on input that exceeds any of the given
+// limits. Nesting too deep fails with a *DepthError, and exceeding any other
+// limit fails with a *LimitError, as soon as the parser has read enough to
+// tell, so that memory use stays bounded whatever the input. Options given
+// after it, such as WithMaxDepth, override its limits. StdToken does not count
+// the elements of arrays and objects, since it keeps none of them.
+func WithLimits(limits SafeLimits) ParseOption {
+	return func(c *parseConfig) {
+		c.limits = limits
+		if limits.MaxDepth > 0 {
+			c.maxDepth = limits.MaxDepth
+		}
+	}
+}
+
+// WithUntrustedInput applies DefaultSafeLimits, for parsing input from
+// sources that cannot be trusted, such as the internet.
+func WithUntrustedInput() ParseOption {
+	return WithLimits(DefaultSafeLimits)
+}
+
+// A LimitError is returned when the input exceeds one of the limits set with
+// WithLimits or WithUntrustedInput.
+type LimitError struct {
+	// Limit is the name of the SafeLimits field that was exceeded, such as
+	// "MaxStringBytes".
+	Limit string
+	// Max is the value of that limit.
+	Max int64
+	// Offset is the position in the input, in bytes, of the value that
+	// exceeded the limit, or for MaxInputBytes, of the limit itself.
+	Offset int64
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("simple json: input exceeds %s of %d at offset %d", e.Limit, e.Max, e.Offset)
+}
+
+// Returns a *LimitError if an array or object that has n elements or
+// members so far may not have another, which is next in the data.
+func (p *parser) checkCount(n int, object bool) error {
+	limit, lim := "MaxArrayElements", p.cfg.limits.MaxArrayElements
+	if object {
+		limit, lim = "MaxObjectMembers", p.cfg.limits.MaxObjectMembers
+	}
+	if lim <= 0 || n < lim {
+		return nil
+	}
+	_ = p.skipSpaces()
+	return &LimitError{Limit: limit, Max: int64(lim), Offset: p.InputOffset()}
+}
+
+// Returns a *LimitError if a string n bytes long so far is too long. start is
+// the offset of the string.
+func (p *parser) checkStringLen(n int, start int64) error {
+	if lim := p.cfg.limits.MaxStringBytes; lim > 0 && n > lim {
+		return &LimitError{Limit: "MaxStringBytes", Max: int64(lim), Offset: start}
+	}
+	return nil
+}
+
+// Returns a *LimitError if the text of a number n bytes long so far is too
+// long. start is the offset of the number.
+func (p *parser) checkNumberLen(n int, start int64) error {
+	if lim := p.cfg.limits.MaxNumberBytes; lim > 0 && n > lim {
+		return &LimitError{Limit: "MaxNumberBytes", Max: int64(lim), Offset: start}
+	}
+	return nil
+}
+
+// Cuts the input in the read buffer off at MaxInputBytes, so that reading
+// past it fails with a *LimitError rather than ending the input.
+func (p *parser) limitInput() {
+	p.inputCut = false
+	if lim := p.cfg.limits.MaxInputBytes; lim > 0 && p.consumed+int64(p.size) > lim {
+		p.size = int(lim - p.consumed)
+		p.inputCut = true
+	}
+}
+
+func (p *parser) inputLimitError() error {
+	lim := p.cfg.limits.MaxInputBytes
+	return &LimitError{Limit: "MaxInputBytes", Max: lim, Offset: lim}
+}
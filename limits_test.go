@@ -0,0 +1,184 @@
+package simplejsonext
+
+import (
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Reads prefix, then repeat forever.
+type endlessReader struct {
+	prefix, repeat string
+	pos            int
+}
+
+func (r *endlessReader) Read(b []byte) (int, error) {
+	n := 0
+	for n < len(b) {
+		if r.prefix != "" {
+			c := copy(b[n:], r.prefix)
+			r.prefix = r.prefix[c:]
+			n += c
+			continue
+		}
+		c := copy(b[n:], r.repeat[r.pos:])
+		r.pos = (r.pos + c) % len(r.repeat)
+		n += c
+	}
+	return n, nil
+}
+
+var adversarialInputs = []struct {
+	name, prefix, repeat string
+	limit                string // "" for a *DepthError
+}{
+	{"deep arrays", "", "[", ""},
+	{"deep objects", "", `{"a":`, ""},
+	{"deep mixed", "", `[{"":`, ""},
+	{"giant string", `"`, "a", "MaxStringBytes"},
+	{"giant escaped string", `["`, `\né`, "MaxStringBytes"},
+	{"giant key", `{"`, "key", "MaxStringBytes"},
+	{"giant integer", "[", "9", "MaxNumberBytes"},
+	{"giant fraction", "-0.", "0", "MaxNumberBytes"},
+	{"giant exponent", "1e", "0", "MaxNumberBytes"},
+	{"flat array", "[", "0,", "MaxArrayElements"},
+	{"flat array of arrays", "[", "[],", "MaxArrayElements"},
+	{"flat object", "{", `"a":null,`, "MaxObjectMembers"},
+	{"whitespace", "", " \t\r\n", "MaxInputBytes"},
+	{"array of long strings", "[", `"` + strings.Repeat("a", 50000) + `",`, "MaxInputBytes"},
+	{"array of long arrays", "[", "[" + strings.Repeat("1,", 5000) + "1],", "MaxInputBytes"},
+}
+
+func checkLimitError(t *testing.T, err error, limit, name string) {
+	t.Helper()
+	if limit == "" {
+		var depthErr *DepthError
+		require.ErrorAs(t, err, &depthErr, name)
+		return
+	}
+	var limitErr *LimitError
+	require.ErrorAs(t, err, &limitErr, name)
+	assert.Equal(t, limit, limitErr.Limit, name)
+}
+
+// Limits smaller than the defaults, to keep tests quick
+var testLimits = SafeLimits{
+	MaxDepth:         32,
+	MaxStringBytes:   100 << 10,
+	MaxNumberBytes:   100,
+	MaxArrayElements: 10000,
+	MaxObjectMembers: 1000,
+	MaxInputBytes:    4 << 20,
+}
+
+func TestUntrustedInput(t *testing.T) {
+	// Endless input must fail with the right error, which shows that the
+	// parser neither waits for the end nor keeps all it has read.
+	for _, test := range adversarialInputs {
+		_, err := NewParser(&endlessReader{prefix: test.prefix, repeat: test.repeat}, WithUntrustedInput()).Parse()
+		checkLimitError(t, err, test.limit, test.name)
+	}
+
+	limits := testLimits
+	for _, test := range adversarialInputs {
+		read := func() io.Reader { return &endlessReader{prefix: test.prefix, repeat: test.repeat} }
+
+		_, err := NewParser(read(), WithLimits(limits)).Parse()
+		checkLimitError(t, err, test.limit, test.name)
+		_, err = NewParser(read(), WithLimits(limits), WithOrderedObjects()).Parse()
+		checkLimitError(t, err, test.limit, test.name)
+		err = NewParser(read(), WithLimits(limits)).Skip()
+		checkLimitError(t, err, test.limit, test.name)
+		var dest any
+		err = NewParser(read(), WithLimits(limits)).ParseInto(&dest)
+		checkLimitError(t, err, test.limit, test.name)
+		err = Transcode(io.Discard, read(), TranscodeOptions{ParseOptions: []ParseOption{WithLimits(limits)}})
+		checkLimitError(t, err, test.limit, test.name)
+
+		// In-memory input is cut off at MaxInputBytes just the same.
+		b := make([]byte, limits.MaxInputBytes+1000)
+		_, _ = io.ReadFull(read(), b)
+		_, err = NewParserFromSlice(b, WithLimits(limits)).Parse()
+		checkLimitError(t, err, test.limit, test.name)
+	}
+}
+
+func TestUntrustedInputMemory(t *testing.T) {
+	for _, test := range adversarialInputs {
+		var before, after runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+		_, err := NewParser(&endlessReader{prefix: test.prefix, repeat: test.repeat}, WithLimits(testLimits)).Parse()
+		runtime.ReadMemStats(&after)
+		checkLimitError(t, err, test.limit, test.name)
+		// What is allocated is bounded by the limits, rather than by the
+		// endless input.
+		assert.Less(t, after.TotalAlloc-before.TotalAlloc, uint64(16*testLimits.MaxInputBytes), test.name)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	limits := SafeLimits{
+		MaxStringBytes:   3,
+		MaxNumberBytes:   3,
+		MaxArrayElements: 2,
+		MaxObjectMembers: 2,
+		MaxInputBytes:    20,
+	}
+	for _, test := range []struct {
+		in  string
+		err string
+	}{
+		{`"abc"`, ""},
+		{`"abcd"`, "simple json: input exceeds MaxStringBytes of 3 at offset 0"},
+		{`"é"`, ""},
+		{`"é\t\n"`, "simple json: input exceeds MaxStringBytes of 3 at offset 0"},
+		{`{"abcd": 1}`, "simple json: input exceeds MaxStringBytes of 3 at offset 1"},
+		{`[123, -12]`, ""},
+		{`[1, 1234]`, "simple json: input exceeds MaxNumberBytes of 3 at offset 4"},
+		{`-1.0`, "simple json: input exceeds MaxNumberBytes of 3 at offset 0"},
+		{`[[1, 2], {}]`, ""},
+		{`[1, 2, 3]`, "simple json: input exceeds MaxArrayElements of 2 at offset 7"},
+		{`{"a":1,"a":2, "a":3}`, "simple json: input exceeds MaxObjectMembers of 2 at offset 14"},
+		{`  [1, 2]     [1, 2]`, ""},
+		{`  [1, 2]     [1, 2]  `, "simple json: input exceeds MaxInputBytes of 20 at offset 20"},
+		{`  [1, 2]     [1,   2]`, "simple json: input exceeds MaxInputBytes of 20 at offset 20"},
+	} {
+		for _, p := range []Parser{
+			NewParserFromString(test.in, WithLimits(limits)),
+			NewParser(strings.NewReader(test.in), WithLimits(limits)),
+			NewParser(iotest.OneByteReader(strings.NewReader(test.in)), WithLimits(limits)),
+		} {
+			var err error
+			for err == nil {
+				_, err = p.Parse()
+			}
+			if test.err == "" {
+				assert.Equal(t, io.EOF, err, test.in)
+			} else {
+				assert.EqualError(t, err, test.err, test.in)
+			}
+		}
+	}
+
+	// Later options override the limits, and parsers that are reset count
+	// their input afresh.
+	p := NewParserFromString(`[[[1]]]`, WithUntrustedInput(), WithMaxDepth(2))
+	_, err := p.Parse()
+	assert.ErrorAs(t, err, new(*DepthError))
+	p = NewParserFromString(`1234`, WithLimits(SafeLimits{MaxInputBytes: 4}))
+	for i := 0; i < 3; i++ {
+		v, err := p.Parse()
+		require.NoError(t, err)
+		assert.Equal(t, int64(1234), v)
+		p.ResetString(`12345`)
+		_, err = p.Parse()
+		assert.ErrorAs(t, err, new(*LimitError))
+		p.Reset(strings.NewReader(`1234`))
+	}
+}
@@ -0,0 +1,77 @@
+package simplejsonext
+
+import "io"
+
+// LineIndexOptions controls BuildLineIndexWithOptions.
+type LineIndexOptions struct {
+	// Every is how many records apart the indexed offsets are. Values less
+	// than 1 mean 1.
+	Every int
+	// MaxErrors is the most invalid records that will be collected, as in
+	// ValidateLinesOptions.
+	MaxErrors int
+	// StopOnError makes building the index fail at the first invalid record,
+	// returning its *LineError, rather than carrying on.
+	StopOnError bool
+}
+
+// LineIndex holds the byte offsets of some of the records of a stream of
+// newline-delimited JSON, for random access to them. Each line is a record,
+// including blank and invalid lines, and records are numbered from 0; the
+// Line of each LineError counts from 1, as with ValidateLines.
+type LineIndex struct {
+	Every        int         // how many records apart the offsets are
+	Offsets      []int64     // Offsets[i] is the offset of record i*Every
+	Records      int         // total number of records
+	Bytes        int64       // total number of bytes read
+	InvalidLines int         // number of invalid records, collected or not
+	Errors       []LineError // the first invalid records, in order
+}
+
+// OffsetOf returns the indexed offset nearest before the given record, and
+// how many records to skip from there to reach it. Records past the end are
+// looked up as if the stream went on.
+func (ix *LineIndex) OffsetOf(record int) (offset int64, skip int) {
+	if len(ix.Offsets) == 0 || record < 0 {
+		return 0, max(record, 0)
+	}
+	i := min(record/ix.Every, len(ix.Offsets)-1)
+	return ix.Offsets[i], record - i*ix.Every
+}
+
+// BuildLineIndex reads newline-delimited JSON from r and indexes the offset
+// of every nth record. Each record is validated without being built, and the
+// first DefaultMaxLineErrors invalid ones are collected in the index. The
+// returned error is only for failures reading from r.
+func BuildLineIndex(r io.Reader, every int) (LineIndex, error) {
+	return BuildLineIndexWithOptions(r, LineIndexOptions{Every: every})
+}
+
+// BuildLineIndexWithOptions is like BuildLineIndex, configured by opts. The
+// index returned with an error covers the records read before it.
+func BuildLineIndexWithOptions(r io.Reader, opts LineIndexOptions) (LineIndex, error) {
+	if opts.MaxErrors == 0 {
+		opts.MaxErrors = DefaultMaxLineErrors
+	}
+	ix := LineIndex{Every: max(opts.Every, 1)}
+	var err error
+	ix.Bytes, err = validateEachLine(r, func(offset int64, lineErr error) error {
+		if ix.Records%ix.Every == 0 {
+			ix.Offsets = append(ix.Offsets, offset)
+		}
+		ix.Records++
+		if lineErr == nil {
+			return nil
+		}
+		ix.InvalidLines++
+		lineError := LineError{Line: ix.Records, Offset: offset, Err: lineErr}
+		if opts.StopOnError {
+			return &lineError
+		}
+		if opts.MaxErrors < 0 || len(ix.Errors) < opts.MaxErrors {
+			ix.Errors = append(ix.Errors, lineError)
+		}
+		return nil
+	})
+	return ix, err
+}
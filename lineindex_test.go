@@ -0,0 +1,81 @@
+package simplejsonext
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildLineIndex(t *testing.T) {
+	const records = 10_000
+	r := rand.New(rand.NewSource(1))
+	var lines []string
+	for i := 0; i < records; i++ {
+		switch {
+		case i%1000 == 999:
+			lines = append(lines, fmt.Sprintf(`{"record": %d, "broken": `, i))
+		case i%2500 == 1:
+			lines = append(lines, "")
+		default:
+			lines = append(lines, fmt.Sprintf(`{"record": %d, "pad": %q}`, i, strings.Repeat("x", r.Intn(100))))
+		}
+	}
+	data := []byte(strings.Join(lines, "\n") + "\n")
+
+	ix, err := BuildLineIndex(iotest.HalfReader(bytes.NewReader(data)), 64)
+	require.NoError(t, err)
+	assert.Equal(t, records, ix.Records)
+	assert.Equal(t, int64(len(data)), ix.Bytes)
+	assert.Len(t, ix.Offsets, (records+63)/64)
+	assert.Equal(t, 10, ix.InvalidLines)
+	require.Len(t, ix.Errors, 10)
+	for i, lineErr := range ix.Errors {
+		assert.Equal(t, 1000*(i+1), lineErr.Line)
+		assert.Equal(t, int64(bytes.Index(data, []byte(fmt.Sprintf(`{"record": %d,`, lineErr.Line-1)))), lineErr.Offset)
+	}
+
+	for _, record := range append([]int{0, 1, 63, 64, 65, records - 1}, r.Perm(records)[:200]...) {
+		offset, skip := ix.OffsetOf(record)
+		assert.Less(t, skip, 64)
+		scanner := bufio.NewScanner(bytes.NewReader(data[offset:]))
+		for i := 0; i <= skip; i++ {
+			require.True(t, scanner.Scan())
+		}
+		assert.Equal(t, lines[record], scanner.Text(), "record %d", record)
+	}
+
+	offset, skip := ix.OffsetOf(records + 10)
+	assert.Equal(t, ix.Offsets[len(ix.Offsets)-1], offset)
+	assert.Equal(t, records+10-64*(len(ix.Offsets)-1), skip)
+}
+
+func TestBuildLineIndexOptions(t *testing.T) {
+	input := "{}\n[1]\nbad\n2\nworse\n"
+	ix, err := BuildLineIndexWithOptions(strings.NewReader(input), LineIndexOptions{StopOnError: true})
+	var lineErr *LineError
+	require.ErrorAs(t, err, &lineErr)
+	assert.Equal(t, 3, lineErr.Line)
+	assert.Equal(t, int64(7), lineErr.Offset)
+	assert.Equal(t, []int64{0, 3, 7}, ix.Offsets)
+	assert.Equal(t, 3, ix.Records)
+
+	ix, err = BuildLineIndexWithOptions(strings.NewReader(input), LineIndexOptions{MaxErrors: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{0, 3, 7, 11, 13}, ix.Offsets)
+	assert.Equal(t, 2, ix.InvalidLines)
+	assert.Len(t, ix.Errors, 1)
+
+	ix, err = BuildLineIndex(strings.NewReader(""), 0)
+	require.NoError(t, err)
+	assert.Equal(t, LineIndex{Every: 1}, ix)
+	offset, skip := ix.OffsetOf(5)
+	assert.Equal(t, int64(0), offset)
+	assert.Equal(t, 5, skip)
+}
@@ -0,0 +1,162 @@
+package simplejsonext
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// DefaultMaxLineErrors is the default number of errors ValidateLines collects.
+const DefaultMaxLineErrors = 100
+
+// LineError describes an invalid line found by ValidateLines.
+type LineError struct {
+	Line   int   // 1-based line number
+	Offset int64 // byte offset of the start of the line in the stream
+	Err    error // the error from the parser
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("simple json: line %d (offset %d): %v", e.Line, e.Offset, e.Err)
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// ValidateLinesOptions controls ValidateLinesWithOptions.
+type ValidateLinesOptions struct {
+	// MaxErrors is the most errors that will be collected; any further
+	// invalid lines are only counted. Zero means DefaultMaxLineErrors, and a
+	// negative value means there is no limit.
+	MaxErrors int
+}
+
+// LinesSummary is the result of ValidateLinesWithOptions.
+type LinesSummary struct {
+	Lines        int         // total number of lines, including blank lines
+	Bytes        int64       // total number of bytes read
+	InvalidLines int         // number of invalid lines, collected or not
+	Errors       []LineError // the first invalid lines, in order
+}
+
+// ValidateLines reads newline-delimited JSON from r and checks that each
+// non-blank line holds exactly one value, returning up to
+// DefaultMaxLineErrors errors for the lines that do not. Values are checked
+// without being built, and lines may be of any length. The returned error is
+// only for failures reading from r.
+func ValidateLines(r io.Reader) ([]LineError, error) {
+	summary, err := ValidateLinesWithOptions(r, ValidateLinesOptions{})
+	return summary.Errors, err
+}
+
+// ValidateLinesWithOptions is like ValidateLines, but with options, and also
+// returns the line and byte counts of the stream.
+func ValidateLinesWithOptions(r io.Reader, opts ValidateLinesOptions) (LinesSummary, error) {
+	if opts.MaxErrors == 0 {
+		opts.MaxErrors = DefaultMaxLineErrors
+	}
+	var summary LinesSummary
+	var err error
+	summary.Bytes, err = validateEachLine(r, func(offset int64, lineErr error) error {
+		summary.Lines++
+		if lineErr != nil {
+			summary.InvalidLines++
+			if opts.MaxErrors < 0 || len(summary.Errors) < opts.MaxErrors {
+				summary.Errors = append(summary.Errors, LineError{
+					Line:   summary.Lines,
+					Offset: offset,
+					Err:    lineErr,
+				})
+			}
+		}
+		return nil
+	})
+	return summary, err
+}
+
+// Checks each line of r as ValidateLines does, calling fn with the offset of
+// the line and the error that makes it invalid, if any. It stops early if fn
+// returns an error, and returns the number of bytes read.
+func validateEachLine(r io.Reader, fn func(offset int64, lineErr error) error) (n int64, err error) {
+	lr := &lineReader{br: bufio.NewReaderSize(r, readBufferSize)}
+	p := &parser{readBuf: make([]byte, readBufferSize), reader: lr}
+	for {
+		// Give the parser a reader that ends at the next newline, so that a
+		// broken line can never consume any of the lines after it.
+		offset := n
+		lr.next()
+		p.Reset(lr)
+		_, lineErr := p.parseType()
+		if lineErr == nil {
+			if lineErr = p.Skip(); lineErr == nil {
+				lineErr = p.CheckEmpty()
+			} else if lineErr == io.EOF {
+				lineErr = io.ErrUnexpectedEOF // the line ended inside the value
+			}
+		} else if lineErr == io.EOF {
+			lineErr = nil // blank line
+		}
+		lr.discard()
+		if lr.err != nil {
+			return n + lr.n, lr.err
+		}
+		if lr.n == 0 {
+			return n, nil // end of stream
+		}
+		n += lr.n
+		if err = fn(offset, lineErr); err != nil {
+			return
+		}
+	}
+}
+
+// lineReader reads from br up to and including the next newline, then
+// reports io.EOF.
+type lineReader struct {
+	br   *bufio.Reader
+	n    int64 // bytes read from the current line
+	done bool  // whether the line has ended
+	err  error // the first error from br other than io.EOF
+}
+
+// Starts reading the next line.
+func (lr *lineReader) next() {
+	lr.n = 0
+	lr.done = lr.err != nil
+}
+
+func (lr *lineReader) Read(b []byte) (int, error) {
+	if lr.done {
+		return 0, io.EOF
+	}
+	if lr.br.Buffered() == 0 {
+		if _, err := lr.br.Peek(1); err != nil {
+			lr.done = true
+			if err != io.EOF {
+				lr.err = err
+			}
+			return 0, io.EOF
+		}
+	}
+	buf, _ := lr.br.Peek(min(len(b), lr.br.Buffered()))
+	for i, c := range buf {
+		if c == '\n' {
+			buf = buf[:i+1]
+			lr.done = true
+			break
+		}
+	}
+	n := copy(b, buf)
+	_, _ = lr.br.Discard(n)
+	lr.n += int64(n)
+	return n, nil
+}
+
+// Consumes the rest of the current line.
+func (lr *lineReader) discard() {
+	var buf [readBufferSize]byte
+	for !lr.done {
+		_, _ = lr.Read(buf[:])
+	}
+}
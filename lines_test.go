@@ -0,0 +1,96 @@
+package simplejsonext
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateLines(t *testing.T) {
+	long := `{"a": "` + strings.Repeat("x", 5*readBufferSize) + `"}`
+	input := strings.Join([]string{
+		`{"ok": 1}`,
+		``,
+		`  [1, 2, NaN]  `,
+		`{"unterminated": "`,
+		`{"next": "line is fine"}`,
+		`1 2`,
+		long,
+		long + `,`,
+		"\r",
+		`"no final newline"`,
+	}, "\n")
+
+	for name, r := range map[string]io.Reader{
+		"reader":     strings.NewReader(input),
+		"one byte":   iotest.OneByteReader(strings.NewReader(input)),
+		"half reads": iotest.HalfReader(strings.NewReader(input)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			summary, err := ValidateLinesWithOptions(r, ValidateLinesOptions{})
+			require.NoError(t, err)
+			assert.Equal(t, 10, summary.Lines)
+			assert.Equal(t, int64(len(input)), summary.Bytes)
+			assert.Equal(t, 3, summary.InvalidLines)
+			require.Len(t, summary.Errors, 3)
+
+			assert.Equal(t, 4, summary.Errors[0].Line)
+			assert.Equal(t, int64(strings.Index(input, `{"unterminated"`)), summary.Errors[0].Offset)
+			assert.Equal(t, errControlChar, summary.Errors[0].Err)
+			assert.Equal(t, 6, summary.Errors[1].Line)
+			assert.Equal(t, ErrTrailingData, summary.Errors[1].Err)
+			assert.Equal(t, 8, summary.Errors[2].Line)
+			assert.EqualError(t, &summary.Errors[2], fmt.Sprintf(
+				"simple json: line 8 (offset %d): simple json: remainder of buffer not empty",
+				strings.Index(input, long+",")))
+		})
+	}
+}
+
+func TestValidateLinesMaxErrors(t *testing.T) {
+	input := strings.Repeat("bad\n", 10)
+
+	errs, err := ValidateLines(strings.NewReader(input))
+	require.NoError(t, err)
+	assert.Len(t, errs, 10)
+
+	summary, err := ValidateLinesWithOptions(strings.NewReader(input), ValidateLinesOptions{MaxErrors: 3})
+	require.NoError(t, err)
+	assert.Len(t, summary.Errors, 3)
+	assert.Equal(t, 10, summary.InvalidLines)
+	assert.Equal(t, 10, summary.Lines)
+}
+
+func TestValidateLinesEmpty(t *testing.T) {
+	summary, err := ValidateLinesWithOptions(strings.NewReader(""), ValidateLinesOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, LinesSummary{}, summary)
+
+	summary, err = ValidateLinesWithOptions(strings.NewReader("\n\n"), ValidateLinesOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, LinesSummary{Lines: 2, Bytes: 2}, summary)
+}
+
+func TestValidateLinesReadError(t *testing.T) {
+	readErr := errors.New("disk on fire")
+	r := io.MultiReader(strings.NewReader("1\n2\n3"), iotest.ErrReader(readErr))
+	summary, err := ValidateLinesWithOptions(r, ValidateLinesOptions{})
+	assert.Equal(t, readErr, err)
+	assert.Equal(t, 2, summary.Lines)
+	assert.Equal(t, int64(5), summary.Bytes)
+}
+
+func TestValidateLinesUnfinished(t *testing.T) {
+	errs, err := ValidateLines(strings.NewReader("[1, \n{\"a\": \n \n\"x\"\n"))
+	require.NoError(t, err)
+	require.Len(t, errs, 2)
+	assert.Equal(t, 1, errs[0].Line)
+	assert.Equal(t, io.ErrUnexpectedEOF, errs[0].Err)
+	assert.Equal(t, 2, errs[1].Line)
+}
@@ -0,0 +1,295 @@
+package simplejsonext
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// DefaultMaxSyntaxErrors is the default number of errors UnmarshalAllErrors
+// collects.
+const DefaultMaxSyntaxErrors = 20
+
+// SyntaxError describes one of the problems found by UnmarshalAllErrors, or
+// the error that stopped Reformat.
+type SyntaxError struct {
+	Line   int   // 1-based line number, or 0 if not known
+	Column int   // 1-based column, in bytes, or 0 if not known
+	Offset int64 // byte offset in the input
+	Err    error // the error from the parser
+}
+
+func (e *SyntaxError) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("simple json: offset %d: %v", e.Offset, e.Err)
+	}
+	return fmt.Sprintf("simple json: line %d, column %d (offset %d): %v", e.Line, e.Column, e.Offset, e.Err)
+}
+
+func (e *SyntaxError) Unwrap() error {
+	return e.Err
+}
+
+// MultiErrorOptions controls UnmarshalAllErrors.
+type MultiErrorOptions struct {
+	// MaxErrors is the most errors that will be collected, after which
+	// parsing stops. Zero means DefaultMaxSyntaxErrors, and a negative value
+	// means there is no limit.
+	MaxErrors int
+	// ParseOptions configure the parser, as for UnmarshalWithOptions.
+	ParseOptions []ParseOption
+}
+
+// UnmarshalAllErrors decodes b like UnmarshalWithOptions, but rather than
+// stopping at the first error, it collects every error it can find, such as
+// for showing a user all the problems in a file at once.
+//
+// After an error, parsing carries on from the next comma or closing bracket
+// or brace of the array or object the error is in, skipping over the rest of
+// the faulty value, and leaving it out of the result. A comma missing between
+// elements is reported and parsing goes on as if it were there. Recovery is
+// only a guess at what was meant, so one mistake may cause others to be
+// reported after it. The value returned is whatever could be parsed, and is
+// only complete if there are no errors.
+func UnmarshalAllErrors(b []byte, opts MultiErrorOptions) (any, []SyntaxError) {
+	if opts.MaxErrors == 0 {
+		opts.MaxErrors = DefaultMaxSyntaxErrors
+	}
+	r := recoverer{p: NewParserFromSlice(b, opts.ParseOptions...).(*parser), max: opts.MaxErrors}
+	v, ok := r.value(r.p.maxDepth())
+	if r.more() {
+		if err := r.p.CheckEmpty(); err != nil && err != io.EOF {
+			r.fail(r.p.InputOffset(), err)
+		}
+	}
+	if !ok {
+		v = nil
+	}
+	// Work out the lines and columns of the errors, which are in order.
+	line, lineStart := 1, 0
+	i := 0
+	for n := range r.errs {
+		e := &r.errs[n]
+		for ; i < int(e.Offset) && i < len(r.p.readBuf); i++ {
+			if r.p.readBuf[i] == '\n' {
+				line, lineStart = line+1, i+1
+			}
+		}
+		e.Line, e.Column = line, int(e.Offset)-lineStart+1
+	}
+	return v, r.errs
+}
+
+// Parses values, collecting errors and recovering from them.
+type recoverer struct {
+	p    *parser
+	errs []SyntaxError
+	max  int
+	// Whether the last faulty value skipped was taken to end at the end of
+	// its line, so that a comma is not expected after it.
+	skippedLine bool
+}
+
+// Reports whether parsing should go on, rather than stop because enough
+// errors were collected.
+func (r *recoverer) more() bool {
+	return r.max < 0 || len(r.errs) < r.max
+}
+
+// Collects an error, unless there is one at the same offset already, which
+// is likely the cause of this one.
+func (r *recoverer) fail(offset int64, err error) {
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if len(r.errs) > 0 && r.errs[len(r.errs)-1].Offset == offset {
+		return
+	}
+	if r.more() {
+		r.errs = append(r.errs, SyntaxError{Offset: offset, Err: err})
+	}
+}
+
+// Parses a value, reporting whether it was parsed. If it was not, the error is
+// collected and the rest of the value is skipped.
+func (r *recoverer) value(remainingDepth int) (v any, ok bool) {
+	p := r.p
+	ty, err := p.parseType()
+	start := p.InputOffset()
+	if err == nil && remainingDepth < 0 {
+		err = p.depthError(remainingDepth)
+	}
+	if err == nil {
+		switch ty {
+		case arrayTy:
+			return r.array(remainingDepth), true
+		case objectTy:
+			return r.object(remainingDepth), true
+		case commaSym, endGroupSym:
+			// The value is missing; leave the delimiter to the caller.
+			r.fail(start, errors.New("simple json: expected a value"))
+			return nil, false
+		}
+		if v, err = p.doParse(remainingDepth); err == nil {
+			return v, true
+		}
+	}
+	r.fail(start, err)
+	r.skipRest(start)
+	return nil, false
+}
+
+// Skips a faulty value starting at offset, up to the next comma, closing
+// bracket or brace, or newline outside of any nested array, object or string.
+// A string is taken to end at the end of its line, in case its closing quote
+// is missing.
+func (r *recoverer) skipRest(offset int64) {
+	p := r.p
+	p.begin = int(offset) // the input is all in memory
+	depth := 0
+	inString, escaped := false, false
+	for ; p.begin < p.size; p.begin++ {
+		b := p.readBuf[p.begin]
+		switch {
+		case b == '\n' && depth == 0:
+			r.skippedLine = true
+			return
+		case inString:
+			if escaped {
+				escaped = false
+			} else if b == '\\' {
+				escaped = true
+			} else if b == '"' || b == '\n' {
+				inString = false
+			}
+		case b == '"':
+			inString = true
+		case b == '[' || b == '{':
+			depth++
+		case b == ']' || b == '}':
+			if depth == 0 {
+				return
+			}
+			depth--
+		case b == ',' && depth == 0:
+			return
+		}
+	}
+}
+
+// Parses the elements of an array, with the parser at its opening bracket.
+func (r *recoverer) array(remainingDepth int) []any {
+	arr := []any{}
+	r.elements(']', func() {
+		if v, ok := r.value(remainingDepth - 1); ok {
+			arr = append(arr, v)
+		}
+	})
+	return arr
+}
+
+// Parses the members of an object, with the parser at its opening brace.
+func (r *recoverer) object(remainingDepth int) any {
+	p := r.p
+	var obj map[string]any
+	var ordered *OrderedObject
+	if p.cfg.orderedObjects {
+		ordered = &OrderedObject{}
+	} else {
+		obj = make(map[string]any)
+	}
+	var collected map[string]bool
+	r.elements('}', func() {
+		ty, err := p.parseType()
+		start := p.InputOffset()
+		var key []byte
+		if err == nil && ty != stringTy {
+			err = errors.New("simple json: expected a string for an object key")
+		}
+		if err == nil {
+			key, err = p.parseString()
+		}
+		if err == nil {
+			if err = p.skipSpaces(); err == nil {
+				err = p.readByte(':')
+			}
+		}
+		if err != nil {
+			r.fail(start, err)
+			r.skipRest(start)
+			return
+		}
+		k := p.makeKey(key)
+		if p.cfg.keyFunc != nil {
+			k = p.cfg.keyFunc(k)
+		}
+		v, ok := r.value(remainingDepth - 1)
+		if !ok {
+			return
+		}
+		var existing any
+		var found bool
+		if ordered != nil {
+			existing, found = ordered.Get(k)
+		} else {
+			existing, found = obj[k]
+		}
+		switch {
+		case !found || p.cfg.duplicateKeys == DuplicateKeysLastWins:
+		case p.cfg.duplicateKeys == DuplicateKeysError:
+			r.fail(start, errDuplicateKey(k))
+			return
+		case p.cfg.duplicateKeys == DuplicateKeysCollect:
+			v = collectDuplicate(&collected, k, existing, v)
+		default:
+			return // first wins
+		}
+		if ordered != nil {
+			ordered.Set(k, v)
+		} else {
+			obj[k] = v
+		}
+	})
+	if ordered != nil {
+		return ordered
+	}
+	return obj
+}
+
+// Reads the delimiters of an array or object, up to close, calling each at
+// each of its elements or members, which must be consumed.
+func (r *recoverer) elements(close byte, each func()) {
+	p := r.p
+	p.begin++ // the parser is at the opening bracket or brace
+	first := true
+	for r.more() {
+		skippedLine := r.skippedLine
+		r.skippedLine = false
+		ty, err := p.parseType()
+		start := p.InputOffset()
+		if err != nil {
+			if err == io.EOF {
+				r.fail(start, fmt.Errorf("simple json: expected '%c' but found the end of the input", close))
+				return
+			}
+			// Not a token at all; let each report it.
+		} else if ty == endGroupSym {
+			if b := p.readBuf[p.begin]; b != close {
+				r.fail(start, fmt.Errorf("simple json: expected '%c' but found '%c'", close, b))
+			}
+			p.begin++
+			return
+		} else if ty == commaSym {
+			p.begin++
+			if first {
+				r.fail(start, errUnexpectedComma)
+				continue
+			}
+			// A trailing comma is left to each, as a missing value.
+		} else if !first && !skippedLine {
+			r.fail(start, fmt.Errorf("simple json: expected ',' or '%c' but found '%c'", close, p.readBuf[p.begin]))
+		}
+		first = false
+		each()
+	}
+}
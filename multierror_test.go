@@ -0,0 +1,106 @@
+package simplejsonext
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func errorStrings(errs []SyntaxError) []string {
+	var out []string
+	for _, err := range errs {
+		out = append(out, err.Error())
+	}
+	return out
+}
+
+func TestUnmarshalAllErrors(t *testing.T) {
+	for _, test := range []struct {
+		in   string
+		val  any
+		errs []string
+	}{
+		{
+			`{"a": [1, 2], "b": null}`,
+			map[string]any{"a": []any{int64(1), int64(2)}, "b": nil},
+			nil,
+		},
+		{
+			"{\n  \"name\": \"run\",\n  \"epochs\": 1O,\n  \"tags\": [\"a\", \"b\",],\n  \"lr\": 0.1\n}",
+			map[string]any{"name": "run", "epochs": int64(1), "tags": []any{"a", "b"}, "lr": 0.1},
+			[]string{
+				"simple json: line 3, column 14 (offset 32): simple json: expected token but found 'O'",
+				"simple json: line 4, column 21 (offset 55): simple json: expected a value",
+			},
+		},
+		{
+			"[1 2, tru, \"ok\", {\"k\" 3, \"j\": 4}]",
+			[]any{int64(1), int64(2), "ok", map[string]any{"j": int64(4)}},
+			[]string{
+				"simple json: line 1, column 4 (offset 3): simple json: expected ',' or ']' but found '2'",
+				`simple json: line 1, column 7 (offset 6): simple json: expected "true" but found "tru,"`,
+				"simple json: line 1, column 19 (offset 18): simple json: expected ':' but found '3'",
+			},
+		},
+		{
+			"{\"a\": \"unterminated,\n \"b\": [1, }, c: 2, \"d\": 3}",
+			map[string]any{"b": []any{int64(1)}, "d": int64(3)},
+			[]string{
+				"simple json: line 1, column 7 (offset 6): simple json: control character, tab, or newline in string value",
+				"simple json: line 2, column 11 (offset 31): simple json: expected a value",
+				"simple json: line 2, column 14 (offset 34): simple json: expected token but found 'c'",
+			},
+		},
+		{
+			`[[1, [2`,
+			[]any{[]any{int64(1), []any{int64(2)}}},
+			[]string{"simple json: line 1, column 8 (offset 7): simple json: expected ']' but found the end of the input"},
+		},
+		{
+			`[1] [2]`,
+			[]any{int64(1)},
+			[]string{"simple json: line 1, column 5 (offset 4): simple json: remainder of buffer not empty"},
+		},
+		{
+			`,`,
+			nil,
+			[]string{"simple json: line 1, column 1 (offset 0): simple json: expected a value"},
+		},
+		{
+			``,
+			nil,
+			[]string{"simple json: line 1, column 1 (offset 0): " + io.ErrUnexpectedEOF.Error()},
+		},
+	} {
+		val, errs := UnmarshalAllErrors([]byte(test.in), MultiErrorOptions{})
+		assert.Equal(t, test.val, val, test.in)
+		assert.Equal(t, test.errs, errorStrings(errs), test.in)
+		if len(errs) == 0 {
+			expected, err := UnmarshalString(test.in)
+			require.NoError(t, err)
+			assert.Equal(t, expected, val)
+		}
+	}
+}
+
+func TestUnmarshalAllErrorsOptions(t *testing.T) {
+	const doc = `[x, {"a": 1, "a": 2}, [[[0]]], y, z]`
+	_, errs := UnmarshalAllErrors([]byte(doc), MultiErrorOptions{MaxErrors: 2})
+	assert.Len(t, errs, 2)
+
+	val, errs := UnmarshalAllErrors([]byte(doc), MultiErrorOptions{
+		MaxErrors:    -1,
+		ParseOptions: []ParseOption{WithDuplicateKeys(DuplicateKeysError), WithMaxDepth(2), WithOrderedObjects()},
+	})
+	assert.Equal(t, []any{&OrderedObject{Members: []Member{{Key: "a", Value: int64(1)}}}, []any{[]any{}}}, val)
+	assert.Equal(t, []string{
+		"simple json: line 1, column 2 (offset 1): simple json: expected token but found 'x'",
+		"simple json: line 1, column 14 (offset 13): simple json: duplicate object key \"a\"",
+		"simple json: line 1, column 25 (offset 24): simple json: maximum nesting depth exceeded: depth 3 at offset 24",
+		"simple json: line 1, column 32 (offset 31): simple json: expected token but found 'y'",
+		"simple json: line 1, column 35 (offset 34): simple json: expected token but found 'z'",
+	}, errorStrings(errs))
+	assert.ErrorIs(t, &errs[0], errs[0].Err)
+}
@@ -1,35 +1,305 @@
 package simplejsonext
 
 import (
+	"maps"
 	"math"
 )
 
-// WalkDeNaN recursively traverses a simple JSON value, recursively replacing
-// NaN and Infinity values with a corresponding string value. This modifies
-// objects and arrays in-place.
+// WalkDeNaN recursively traverses a simple JSON value, replacing NaN and
+// Infinity values with a corresponding string value. The input is not
+// modified; objects and arrays containing replaced values are copied, and all
+// others are shared with the result.
+//
+// Besides float64 values, float32 values and the elements of []float64,
+// []float32 and map[string]float64 are replaced. A typed container is copied
+// as the same type if its replacements fit in it, and as a []any or
+// map[string]any if not.
 func WalkDeNaN(obj interface{}) interface{} {
-	switch tobj := obj.(type) {
-	case map[string]interface{}:
-		for k, v := range tobj {
-			tobj[k] = WalkDeNaN(v)
+	res, _ := WalkDeNaNChanged(obj)
+	return res
+}
+
+// WalkDeNaNChanged is like WalkDeNaN, and also reports whether any value was
+// replaced. If not, the result is obj itself.
+func WalkDeNaNChanged(obj any) (res any, changed bool) {
+	d := deNaNer{fn: func(_ []string, f float64) any {
+		return deNaN(f)
+	}}
+	return d.walk(obj)
+}
+
+// WalkDeNaNToNull is like WalkDeNaN, but replaces NaN and Infinity values with
+// null rather than strings, for consumers that expect numbers or nothing.
+func WalkDeNaNToNull(obj any) any {
+	return WalkDeNaNFunc(obj, func([]string, float64) any {
+		return nil
+	})
+}
+
+// WalkDeNaNFunc is like WalkDeNaN, but replaces each NaN and Infinity value
+// with whatever fn returns for it. fn receives the path of keys and array
+// indices to the value, as with Walk, and the path slice is likewise reused
+// between calls. float32 values are passed to fn as float64; returning the
+// value unchanged leaves it as it was.
+func WalkDeNaNFunc(obj any, fn func(path []string, f float64) any) any {
+	d := deNaNer{fn: fn}
+	res, _ := d.walk(obj)
+	return res
+}
+
+// ReNaNOptions configures WalkReNaNWithOptions.
+type ReNaNOptions struct {
+	// Paths, if not empty, limits the strings replaced to those at one of
+	// these paths, given as keys and array indices as WalkDeNaNFunc passes
+	// them. A "*" element matches any key or index.
+	Paths [][]string
+}
+
+func (o *ReNaNOptions) matches(path []string) bool {
+	if len(o.Paths) == 0 {
+		return true
+	}
+Paths:
+	for _, p := range o.Paths {
+		if len(p) != len(path) {
+			continue
+		}
+		for i, elem := range p {
+			if elem != "*" && elem != path[i] {
+				continue Paths
+			}
 		}
-		return tobj
-	case []interface{}:
-		for i, v := range tobj {
-			tobj[i] = WalkDeNaN(v)
+		return true
+	}
+	return false
+}
+
+// WalkReNaN undoes WalkDeNaN, replacing the strings "NaN", "Infinity" and
+// "-Infinity" with the float64 values they stand for. Other strings are left
+// alone, even ones that contain those words. As with WalkDeNaN, the input is
+// not modified and only the containers holding replaced values are copied.
+//
+// Any string that is exactly one of the three is replaced, even if it was
+// never a number; WalkReNaNWithOptions can limit the replacement to the
+// places numbers are expected.
+func WalkReNaN(obj any) any {
+	return WalkReNaNWithOptions(obj, ReNaNOptions{})
+}
+
+// WalkReNaNWithOptions is like WalkReNaN, replacing only the strings opts
+// allows.
+func WalkReNaNWithOptions(obj any, opts ReNaNOptions) any {
+	d := deNaNer{reNaN: &opts}
+	res, _ := d.walk(obj)
+	return res
+}
+
+// ClampOptions are the numbers WalkClamp puts in place of non-finite values.
+type ClampOptions struct {
+	NaN    float64 // replaces NaN
+	PosInf float64 // replaces positive infinity
+	NegInf float64 // replaces negative infinity
+}
+
+// DefaultClampOptions replace NaN with 0 and the infinities with the largest
+// finite numbers of the same sign.
+var DefaultClampOptions = ClampOptions{
+	PosInf: math.MaxFloat64,
+	NegInf: -math.MaxFloat64,
+}
+
+// WalkClamp is like WalkDeNaN, but replaces NaN and Infinity values with the
+// finite numbers in opts, for consumers that only take numbers. A []float32
+// keeps its type unless a replacement is out of the range of float32, as the
+// defaults are, in which case it is copied as a []any.
+func WalkClamp(obj any, opts ClampOptions) any {
+	return WalkDeNaNFunc(obj, func(_ []string, f float64) any {
+		if math.IsNaN(f) {
+			return opts.NaN
+		} else if f > 0 {
+			return opts.PosInf
 		}
-		return tobj
+		return opts.NegInf
+	})
+}
+
+// The callback that Walk calls for WalkDeNaN and its variants.
+type deNaNer struct {
+	walker
+	fn    func(path []string, f float64) any
+	reNaN *ReNaNOptions // if not nil, strings are replaced instead of floats
+}
+
+// Returns v with its non-finite values replaced, and whether there were any
+// the callback changed.
+func (d *deNaNer) walk(v any) (any, bool) {
+	d.visitor = d
+	res, _ := d.walker.walk(v) // visit never fails
+	return res, !identical(res, v)
+}
+
+func (d *deNaNer) visit(w *walker, v any) (any, error) {
+	switch tv := v.(type) {
+	case float64, float32, string:
+		return d.leaf(w, v), nil
+	case []float64:
+		return deNaNFloats(d, w, tv), nil
+	case []float32:
+		return deNaNFloats(d, w, tv), nil
+	case map[string]float64:
+		return d.floatMap(w, tv), nil
+	}
+	return v, nil
+}
+
+// Replaces v if it is a non-finite number, or for WalkReNaN, a string that
+// stands for one.
+func (d *deNaNer) leaf(w *walker, v any) any {
+	var f float64
+	switch tv := v.(type) {
 	case float64:
-		if math.IsNaN(tobj) {
-			return "NaN"
-		} else if math.IsInf(tobj, +1) {
-			return "Infinity"
-		} else if math.IsInf(tobj, -1) {
-			return "-Infinity"
-		} else {
-			return tobj
+		f = tv
+	case float32:
+		f = float64(tv)
+	case string:
+		if d.reNaN == nil {
+			return v
+		}
+		f, ok := reNaN(tv)
+		if !ok || !d.reNaN.matches(w.pathStrings()) {
+			return v
+		}
+		return f
+	default:
+		return v
+	}
+	if d.fn == nil || !math.IsNaN(f) && !math.IsInf(f, 0) {
+		return v
+	}
+	res := d.fn(w.pathStrings(), f)
+	if identical(res, f) {
+		return v
+	}
+	return res
+}
+
+// Replaces the elements of a typed slice of floats, copying it as the same
+// type if it can.
+func deNaNFloats[F float32 | float64](d *deNaNer, w *walker, s []F) any {
+	var rebuilt []F
+	var generic []any // used instead if a replacement is not a float
+	for i, f := range s {
+		if !math.IsNaN(float64(f)) && !math.IsInf(float64(f), 0) {
+			continue
+		}
+		w.push(pathStep{index: i})
+		res := d.leaf(w, f)
+		w.pop()
+		if identical(res, f) {
+			continue
+		}
+		if generic == nil {
+			if r, ok := asFloat[F](res); ok {
+				if rebuilt == nil {
+					rebuilt = append([]F(nil), s...)
+				}
+				rebuilt[i] = r
+				continue
+			}
+			if rebuilt != nil {
+				s = rebuilt
+			}
+			generic = make([]any, len(s))
+			for j, f := range s {
+				generic[j] = f
+			}
+		}
+		generic[i] = res
+	}
+	if generic != nil {
+		return generic
+	} else if rebuilt != nil {
+		return rebuilt
+	}
+	return s
+}
+
+// Replaces the values of a map of floats, copying it as the same type if it
+// can.
+func (d *deNaNer) floatMap(w *walker, m map[string]float64) any {
+	var rebuilt map[string]float64
+	var generic map[string]any // used instead if a replacement is not a float64
+	for key, f := range m {
+		if !math.IsNaN(f) && !math.IsInf(f, 0) {
+			continue
+		}
+		w.push(pathStep{key: key, index: -1})
+		res := d.leaf(w, f)
+		w.pop()
+		if identical(res, f) {
+			continue
+		}
+		if generic == nil {
+			if r, ok := asFloat[float64](res); ok {
+				if rebuilt == nil {
+					rebuilt = maps.Clone(m)
+				}
+				rebuilt[key] = r
+				continue
+			}
+			generic = make(map[string]any, len(m))
+			for k, f := range m {
+				generic[k] = f
+			}
+			for k, f := range rebuilt {
+				generic[k] = f
+			}
 		}
+		generic[key] = res
+	}
+	if generic != nil {
+		return generic
+	} else if rebuilt != nil {
+		return rebuilt
+	}
+	return m
+}
+
+// Converts a float32 or float64 to F, if it is not too big for it.
+func asFloat[F float32 | float64](v any) (F, bool) {
+	var f float64
+	switch tv := v.(type) {
+	case float64:
+		f = tv
+	case float32:
+		f = float64(tv)
 	default:
-		return tobj
+		return 0, false
+	}
+	return F(f), !math.IsInf(float64(F(f)), 0) || math.IsInf(f, 0)
+}
+
+// Returns the number s stands for, if it is one that deNaN replaces.
+func reNaN(s string) (float64, bool) {
+	switch s {
+	case "NaN":
+		return math.NaN(), true
+	case "Infinity":
+		return math.Inf(1), true
+	case "-Infinity":
+		return math.Inf(-1), true
+	}
+	return 0, false
+}
+
+func deNaN(f float64) any {
+	if math.IsNaN(f) {
+		return "NaN"
+	} else if math.IsInf(f, +1) {
+		return "Infinity"
+	} else if math.IsInf(f, -1) {
+		return "-Infinity"
+	} else {
+		return f
 	}
 }
@@ -1,9 +1,14 @@
 package simplejsonext
 
 import (
+	"encoding/json"
+	"io"
+	"math"
 	"reflect"
+	"strings"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -31,3 +36,317 @@ func TestDeNaN(t *testing.T) {
 		t.Errorf(">>> %+v", expected)
 	}
 }
+
+func TestDeNaNToNull(t *testing.T) {
+	var expected = map[string]interface{}{
+		"a": int64(1),
+		"b": 1.2,
+		"c": -1e-3,
+		"d": nil,
+		"e": nil,
+		"f": nil,
+		"g": "str",
+		"h": "abc Infinity",
+	}
+
+	dirty, err := UnmarshalString(raw)
+	require.NoError(t, err)
+	cleaned, ok := WalkDeNaNToNull(dirty).(map[string]any)
+	require.True(t, ok)
+
+	if !reflect.DeepEqual(cleaned, expected) {
+		t.Errorf("<<< %+v", cleaned)
+		t.Errorf(">>> %+v", expected)
+	}
+}
+
+func TestDeNaNFunc(t *testing.T) {
+	dirty, err := UnmarshalString(`{"a": [1, NaN, {"b": Infinity, "c": [NaN]}], "d": -Infinity, "e": "NaN"}`)
+	require.NoError(t, err)
+
+	counts := make(map[string]int)
+	kept := WalkDeNaNFunc(dirty, func(path []string, f float64) any {
+		counts[strings.Join(path, ".")]++
+		return f
+	})
+	assert.Equal(t, map[string]int{"a.1": 1, "a.2.b": 1, "a.2.c.0": 1, "d": 1}, counts)
+	assert.Equal(t, Dump(dirty), Dump(kept))
+
+	columnMax := map[string]float64{"b": 10, "d": 20}
+	clamped := WalkDeNaNFunc(dirty, func(path []string, f float64) any {
+		key := path[len(path)-1]
+		switch {
+		case math.IsInf(f, 1):
+			return columnMax[key]
+		case math.IsInf(f, -1):
+			return -columnMax[key]
+		}
+		return nil
+	})
+	assert.Equal(t, map[string]any{
+		"a": []any{int64(1), nil, map[string]any{"b": 10.0, "c": []any{nil}}},
+		"d": -20.0,
+		"e": "NaN",
+	}, clamped)
+}
+
+func TestDeNaNChanged(t *testing.T) {
+	clean, err := UnmarshalString(`{"a": [1, 2.5, {"b": "Infinity"}], "c": {"d": null}}`)
+	require.NoError(t, err)
+	res, changed := WalkDeNaNChanged(clean)
+	assert.False(t, changed)
+	assert.True(t, identical(clean, res))
+
+	dirty, err := UnmarshalString(`{"a": [1, {"b": NaN}], "c": {"d": [2.5]}, "e": [{"f": 1}]}`)
+	require.NoError(t, err)
+	before := Dump(dirty)
+	res, changed = WalkDeNaNChanged(dirty)
+	assert.True(t, changed)
+	assert.Equal(t, map[string]any{
+		"a": []any{int64(1), map[string]any{"b": "NaN"}},
+		"c": map[string]any{"d": []any{2.5}},
+		"e": []any{map[string]any{"f": int64(1)}},
+	}, res)
+
+	// The input is untouched, and only the containers on the path to the
+	// replaced value are copied.
+	assert.Equal(t, before, Dump(dirty))
+	in, out := dirty.(map[string]any), res.(map[string]any)
+	assert.False(t, identical(in["a"], out["a"]))
+	assert.True(t, identical(in["a"].([]any)[0], out["a"].([]any)[0]))
+	assert.True(t, identical(in["c"], out["c"]))
+	assert.True(t, identical(in["e"], out["e"]))
+}
+
+func TestDeNaNVeryDeep(t *testing.T) {
+	// As deep as the parser allows, with non-finite values at the bottom.
+	leaf := []any{math.NaN(), 1.5, math.Inf(-1)}
+	res, changed := WalkDeNaNChanged(nestValue(leaf, 500, wrapSlice))
+	assert.True(t, changed)
+	assert.Equal(t, nestValue([]any{"NaN", 1.5, "-Infinity"}, 500, wrapSlice), res)
+
+	// Far deeper, through every kind of container, with a path long enough
+	// to check.
+	const depth = 300_000
+	deep := nestValue(map[string]any{"x": math.Inf(1), "y": "z"}, depth, wrapSlice, wrapMap, wrapOrdered)
+	var pathLen int
+	cleaned := WalkDeNaNFunc(deep, func(path []string, f float64) any {
+		pathLen = len(path)
+		assert.Equal(t, []string{"a", "0", "x"}, path[len(path)-3:])
+		return nil
+	})
+	assert.Equal(t, depth+1, pathLen)
+	res = cleaned
+	for i := 0; i < depth; i++ {
+		switch v := res.(type) {
+		case []any:
+			res = v[0]
+		case map[string]any:
+			res = v["a"]
+		case *OrderedObject:
+			res = v.Members[0].Value
+		}
+	}
+	assert.Equal(t, map[string]any{"x": nil, "y": "z"}, res)
+
+	// A deep tree with nothing to replace is returned as it is.
+	res, changed = WalkDeNaNChanged(cleaned)
+	assert.False(t, changed)
+	assert.True(t, identical(cleaned, res))
+}
+
+func TestDeNaNTypedFloats(t *testing.T) {
+	nan32, inf32 := float32(math.NaN()), float32(math.Inf(1))
+	tree := map[string]any{
+		"f32":     nan32,
+		"finite":  float32(1.5),
+		"f64s":    []float64{1, math.NaN(), math.Inf(-1)},
+		"f32s":    []any{[]float32{inf32, 2}, []float32{3}},
+		"columns": map[string]float64{"a": math.Inf(1), "b": 4},
+		"clean":   map[string]float64{"c": 5},
+	}
+	before := Dump(tree)
+
+	var paths []string
+	WalkDeNaNFunc(tree, func(path []string, f float64) any {
+		paths = append(paths, strings.Join(path, "."))
+		return f
+	})
+	assert.ElementsMatch(t, []string{"f32", "f64s.1", "f64s.2", "f32s.0.0", "columns.a"}, paths)
+
+	// Replacements that fit keep the containers' types.
+	assert.Equal(t, map[string]any{
+		"f32":     100.0,
+		"finite":  float32(1.5),
+		"f64s":    []float64{1, 100, -100},
+		"f32s":    []any{[]float32{100, 2}, []float32{3}},
+		"columns": map[string]float64{"a": 100, "b": 4},
+		"clean":   map[string]float64{"c": 5},
+	}, WalkDeNaNFunc(tree, func(_ []string, f float64) any {
+		return math.Copysign(100, f)
+	}))
+
+	// Others do not.
+	assert.Equal(t, map[string]any{
+		"f32":     "NaN",
+		"finite":  float32(1.5),
+		"f64s":    []any{1.0, "NaN", "-Infinity"},
+		"f32s":    []any{[]any{"Infinity", float32(2)}, []float32{3}},
+		"columns": map[string]any{"a": "Infinity", "b": 4.0},
+		"clean":   map[string]float64{"c": 5},
+	}, WalkDeNaN(tree))
+	assert.Equal(t, before, Dump(tree))
+
+	nullNaN := func(_ []string, f float64) any {
+		if math.IsNaN(f) {
+			return nil
+		}
+		return math.Copysign(100, f)
+	}
+	assert.Equal(t, []any{100.0, 1.0, nil}, WalkDeNaNFunc([]float64{math.Inf(1), 1, math.NaN()}, nullNaN))
+	assert.Equal(t, map[string]any{"a": 100.0, "b": nil}, WalkDeNaNFunc(map[string]float64{"a": math.Inf(1), "b": math.NaN()}, nullNaN))
+
+	clean := map[string]any{"a": []float32{1}, "b": float32(2), "c": map[string]float64{"d": 3}}
+	res, changed := WalkDeNaNChanged(clean)
+	assert.False(t, changed)
+	assert.True(t, identical(clean, res))
+}
+
+// Records the scalars it visits.
+type scalarRecorder struct {
+	NopVisitor
+	values []any
+}
+
+func (r *scalarRecorder) OnNull() error           { r.values = append(r.values, nil); return nil }
+func (r *scalarRecorder) OnFloat(v float64) error { r.values = append(r.values, v); return nil }
+func (r *scalarRecorder) OnString(v []byte) error { r.values = append(r.values, string(v)); return nil }
+func (r *scalarRecorder) OnInt(v int64) error     { r.values = append(r.values, v); return nil }
+
+func TestDeNaNWhileParsing(t *testing.T) {
+	cleaned, err := UnmarshalWithOptions([]byte(raw), WithDeNaN(DeNaNToString))
+	require.NoError(t, err)
+	dirty, err := UnmarshalString(raw)
+	require.NoError(t, err)
+	assert.Equal(t, WalkDeNaN(dirty), cleaned)
+
+	const overflowing = `[1e999, -1e999, 1.5, 2, NaN]`
+	for _, test := range []struct {
+		policy   DeNaNPolicy
+		expected []any
+	}{
+		{DeNaNToString, []any{"Infinity", "-Infinity", 1.5, int64(2), "NaN"}},
+		{DeNaNToNull, []any{nil, nil, 1.5, int64(2), nil}},
+	} {
+		v, err := UnmarshalWithOptions([]byte(overflowing), WithDeNaN(test.policy))
+		require.NoError(t, err)
+		assert.Equal(t, test.expected, v)
+
+		p := NewParserFromString(overflowing, WithDeNaN(test.policy), WithExactNumbers())
+		v, err = p.Parse()
+		require.NoError(t, err)
+		assert.Equal(t, []any{test.expected[0], test.expected[1], Number("1.5"), Number("2"), test.expected[4]}, v)
+
+		r := &scalarRecorder{}
+		require.NoError(t, NewParserFromString(overflowing, WithDeNaN(test.policy)).Visit(r))
+		assert.Equal(t, test.expected, r.values)
+
+		var tokens []any
+		p = NewParserFromString(overflowing, WithDeNaN(test.policy))
+		for {
+			tok, err := p.StdToken()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			tokens = append(tokens, tok)
+		}
+		assert.Equal(t, []any{json.Delim('['), test.expected[0], test.expected[1], 1.5, 2.0, test.expected[4], json.Delim(']')}, tokens)
+	}
+
+	for _, doc := range []string{`NaN`, `[1, -Infinity]`, `{"a": 1e400}`} {
+		_, err := UnmarshalWithOptions([]byte(doc), WithDeNaN(DeNaNError))
+		assert.ErrorContains(t, err, "simple json: non-finite number")
+		err = NewParserFromString(doc, WithDeNaN(DeNaNError)).Visit(NopVisitor{})
+		assert.ErrorContains(t, err, "simple json: non-finite number")
+	}
+	v, err := UnmarshalWithOptions([]byte(`[1e300, -0.5]`), WithDeNaN(DeNaNError))
+	require.NoError(t, err)
+	assert.Equal(t, []any{1e300, -0.5}, v)
+}
+
+func TestWalkClamp(t *testing.T) {
+	dirty, err := UnmarshalString(`{"a": [NaN, Infinity, -Infinity, 1.5, 2], "b": {"c": "NaN", "d": true, "e": null}, "f": 1e999}`)
+	require.NoError(t, err)
+	before := Dump(dirty)
+
+	clamped := WalkClamp(dirty, DefaultClampOptions)
+	assert.Equal(t, map[string]any{
+		"a": []any{0.0, math.MaxFloat64, -math.MaxFloat64, 1.5, int64(2)},
+		"b": map[string]any{"c": "NaN", "d": true, "e": nil},
+		"f": math.MaxFloat64,
+	}, clamped)
+	assert.Equal(t, before, Dump(dirty))
+	// The object with nothing to replace is shared.
+	assert.True(t, identical(dirty.(map[string]any)["b"], clamped.(map[string]any)["b"]))
+
+	// The result is standard JSON.
+	b, err := Marshal(clamped)
+	require.NoError(t, err)
+	var std any
+	require.NoError(t, json.Unmarshal(b, &std))
+	assert.Equal(t, map[string]any{
+		"a": []any{0.0, math.MaxFloat64, -math.MaxFloat64, 1.5, 2.0},
+		"b": map[string]any{"c": "NaN", "d": true, "e": nil},
+		"f": math.MaxFloat64,
+	}, std)
+
+	sentinels := ClampOptions{NaN: -1, PosInf: 1000, NegInf: -1000}
+	assert.Equal(t, []any{-1.0, 1000.0, -1000.0, 1.5, int64(2)}, WalkClamp(dirty.(map[string]any)["a"], sentinels))
+	assert.Equal(t, []float32{-1, 1000, 3}, WalkClamp([]float32{float32(math.NaN()), float32(math.Inf(1)), 3}, sentinels))
+	assert.Equal(t, []any{float32(0), math.MaxFloat64, float32(3)}, WalkClamp([]float32{float32(math.NaN()), float32(math.Inf(1)), 3}, DefaultClampOptions))
+	assert.Equal(t, ClampOptions{}.NaN, WalkClamp(math.NaN(), ClampOptions{}))
+}
+
+func TestReNaN(t *testing.T) {
+	dirty, err := UnmarshalString(raw)
+	require.NoError(t, err)
+	cleaned := WalkDeNaN(dirty)
+	restored := WalkReNaN(cleaned)
+	text := func(v any) string {
+		b, err := MarshalWithOptions(v, WithSortedKeys())
+		require.NoError(t, err)
+		return string(b)
+	}
+	assert.Equal(t, text(dirty), text(restored))
+	assert.Equal(t, "abc Infinity", restored.(map[string]any)["h"])
+	assert.Equal(t, "Infinity", cleaned.(map[string]any)["d"], "input is not modified")
+
+	// Every kind of container, deep enough to leave the goroutine stack.
+	leaf := &OrderedObject{Members: []Member{
+		{Key: "n", Value: math.NaN()},
+		{Key: "list", Value: []any{"x", math.Inf(1), int64(2), []any{math.Inf(-1)}}},
+	}}
+	tree := nestValue(leaf, 2*maxWalkRecursion, wrapSlice, wrapMap, wrapOrdered)
+	assert.Equal(t, text(tree), text(WalkReNaN(WalkDeNaN(tree))))
+
+	// Nothing to replace leaves the tree as it was.
+	plain := map[string]any{"a": []any{"NaNs", "infinity", 1.5}, "b": math.NaN()}
+	assert.True(t, identical(plain, WalkReNaN(plain)))
+}
+
+func TestReNaNPaths(t *testing.T) {
+	v := map[string]any{
+		"loss":    "NaN",
+		"name":    "NaN",
+		"history": []any{1.5, "Infinity", map[string]any{"val": "-Infinity", "tag": "NaN"}},
+	}
+	opts := ReNaNOptions{Paths: [][]string{{"loss"}, {"history", "*"}, {"history", "*", "val"}}}
+	res := WalkReNaNWithOptions(v, opts).(map[string]any)
+	assert.True(t, math.IsNaN(res["loss"].(float64)))
+	assert.Equal(t, "NaN", res["name"])
+	history := res["history"].([]any)
+	assert.Equal(t, math.Inf(1), history[1])
+	assert.Equal(t, map[string]any{"val": math.Inf(-1), "tag": "NaN"}, history[2])
+	assert.Equal(t, "NaN", v["loss"], "input is not modified")
+}
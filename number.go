@@ -0,0 +1,96 @@
+package simplejsonext
+
+import (
+	"fmt"
+	"math"
+)
+
+// Number is the exact text of a JSON number, as produced by parsers with
+// WithExactNumbers. The Emitter writes it back verbatim, byte for byte, after
+// checking that it is still a number the parser accepts, so that numbers
+// survive decoding and encoding unchanged: 1.10 stays 1.10 and 5e3 stays 5e3.
+type Number string
+
+// Int64 returns the number as an int64, failing if it is not an integer that
+// fits in one.
+func (n Number) Int64() (int64, error) {
+	i, _, isFloat, err := n.parse()
+	if err != nil {
+		return 0, err
+	}
+	if isFloat {
+		return 0, fmt.Errorf("simple json: number %s is not an int64", string(n))
+	}
+	return i, nil
+}
+
+// Float64 returns the number as a float64, rounding it if need be. NaN and
+// infinities are returned as such.
+func (n Number) Float64() (float64, error) {
+	i, f, isFloat, err := n.parse()
+	if err != nil {
+		return 0, err
+	}
+	if !isFloat {
+		f = float64(i)
+	}
+	return f, nil
+}
+
+// Value returns the number as the parser would without WithExactNumbers: an
+// int64 if it is an integer that fits in one, otherwise a float64.
+func (n Number) Value() (any, error) {
+	i, f, isFloat, err := n.parse()
+	if err != nil {
+		return nil, err
+	} else if isFloat {
+		return f, nil
+	}
+	return i, nil
+}
+
+func (n Number) String() string {
+	return string(n)
+}
+
+// Parses n exactly as the parser parses a number.
+func (n Number) parse() (i int64, f float64, isFloat bool, err error) {
+	if len(n) == 0 || valType(typeTable[n[0]]) != numberTy {
+		return 0, 0, false, fmt.Errorf("simple json: invalid number %q", string(n))
+	}
+	p := parser{readBuf: bytesNoCopy(string(n)), size: len(n)}
+	i, f, isFloat, err = p.scanNumber()
+	if err == nil && p.begin < p.size {
+		err = fmt.Errorf("simple json: invalid number %q", string(n))
+	}
+	return
+}
+
+func (p *parser) parseNumberText() (any, error) {
+	view, isFloat, err := p.scanNumberText()
+	if err != nil {
+		return nil, err
+	}
+	// Check it just as if it were being converted.
+	_, f, isFloat, err := p.convertNumber(view, isFloat)
+	if err != nil {
+		return nil, err
+	} else if isFloat && p.replacesNaN(f) {
+		return p.deNaNValue(f), nil
+	}
+	return Number(p.makeString(view)), nil
+}
+
+func (e *emitter) emitNumber(n Number) (err error) {
+	_, f, isFloat, err := n.parse()
+	if err != nil {
+		return
+	}
+	if isFloat && e.cfg.strictFloats && (math.IsNaN(f) || math.IsInf(f, 0)) {
+		return fmt.Errorf("simple json: cannot emit non-finite number %s", string(n))
+	}
+	s := append(e.s[:0], n...)
+	e.s = s[:0]
+	err = e.write(s)
+	return
+}
@@ -0,0 +1,228 @@
+package simplejsonext
+
+import (
+	"bytes"
+	"math"
+	"math/big"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExactNumbersRoundTrip(t *testing.T) {
+	// Compact documents, so that decoding and encoding them should change
+	// nothing at all.
+	corpus := []string{
+		`1.10`,
+		`[5e3,5E3,5e+3,5E-3,0.0,-0.0,-0,1.000000000000000000001,100]`,
+		`{"big":123456789012345678901234567890,"neg":-9223372036854775809,"tiny":1e-400}`,
+		`{"ext":[NaN,Infinity,-Infinity],"mixed":[1,"1.0",true,null,{"x":2.50}]}`,
+		`[` + strings.Repeat(`0.1000,`, 1000) + `7]`,
+	}
+	for _, doc := range corpus {
+		for _, p := range []Parser{
+			NewParserFromString(doc, WithExactNumbers(), WithOrderedObjects()),
+			NewParser(iotest.OneByteReader(strings.NewReader(doc)), WithExactNumbers(), WithOrderedObjects()),
+		} {
+			v, err := p.UnmarshalFull()
+			require.NoError(t, err, doc)
+			out, err := Marshal(v)
+			require.NoError(t, err, doc)
+			assert.Equal(t, doc, string(out))
+			assert.Equal(t, len(doc), EstimateMarshalSize(v))
+			assert.Equal(t, len(doc), Stats(v).MarshaledSize)
+		}
+	}
+
+	v, err := NewParserFromString(`[1.10, 2]`, WithExactNumbers()).Parse()
+	require.NoError(t, err)
+	assert.Equal(t, []any{Number("1.10"), Number("2")}, v)
+
+	// Non-finite numbers are written as they were, unless they must be
+	// finite.
+	v, err = NewParserFromString(`[0.1000000000000000000001, NaN, -Infinity]`, WithExactNumbers()).Parse()
+	require.NoError(t, err)
+	out, err := MarshalWithOptions(v, WithStrictFloats())
+	assert.EqualError(t, err, "simple json: cannot emit non-finite number NaN")
+	assert.Nil(t, out)
+	out, err = MarshalWithOptions(v.([]any)[:1], WithStrictFloats())
+	require.NoError(t, err)
+	assert.Equal(t, `[0.1000000000000000000001]`, string(out))
+
+	// Bad numbers are still rejected.
+	for _, bad := range []string{`1e`, `--1`, `1.2.3`, `NaNa`} {
+		_, err = NewParserFromString(bad, WithExactNumbers()).UnmarshalFull()
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestNumberAccessors(t *testing.T) {
+	i, err := Number("42").Int64()
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), i)
+	_, err = Number("4.2").Int64()
+	assert.EqualError(t, err, "simple json: number 4.2 is not an int64")
+	_, err = Number("9223372036854775808").Int64()
+	assert.Error(t, err, "promoted to float like the parser does")
+
+	f, err := Number("5e3").Float64()
+	require.NoError(t, err)
+	assert.Equal(t, 5000.0, f)
+	f, err = Number("-7").Float64()
+	require.NoError(t, err)
+	assert.Equal(t, -7.0, f)
+	f, err = Number("-Infinity").Float64()
+	require.NoError(t, err)
+	assert.True(t, math.IsInf(f, -1))
+
+	v, err := Number("12").Value()
+	require.NoError(t, err)
+	assert.Equal(t, int64(12), v)
+	v, err = Number("1.5").Value()
+	require.NoError(t, err)
+	assert.Equal(t, 1.5, v)
+
+	for _, bad := range []Number{"", " 1", "1 ", "x", "1x", "\"1\"", "1e"} {
+		_, err = bad.Value()
+		assert.Error(t, err, string(bad))
+		_, err = Marshal(bad)
+		assert.Error(t, err, string(bad))
+		assert.Equal(t, -1, EstimateMarshalSize(bad))
+	}
+
+	var b bytes.Buffer
+	require.NoError(t, NewEmitter(&b).Emit(map[string]any{"n": Number("1.50")}))
+	assert.Equal(t, `{"n":1.50}`, b.String())
+	assert.Equal(t, "Number(1.50)", Dump(Number("1.50")))
+	assert.Equal(t, "1.50", Number("1.50").String())
+}
+
+func TestAppendNumbersRoundTrip(t *testing.T) {
+	for _, i := range []int64{0, 1, -1, math.MaxInt64, math.MinInt64, 1<<53 - 1, 1 << 53, 1<<53 + 1, -(1<<53 + 1)} {
+		b := AppendInt([]byte("x"), i)
+		assert.Equal(t, "x", string(b[:1]))
+		v, err := Unmarshal(b[1:])
+		require.NoError(t, err, i)
+		assert.Equal(t, i, v)
+		out, err := Marshal(i)
+		require.NoError(t, err)
+		assert.Equal(t, out, b[1:])
+	}
+
+	for _, f := range []float64{
+		0, math.Copysign(0, -1), 0.5, -2.25, 1e300, 5e-324, math.MaxFloat64,
+		1<<53 - 1, 1 << 53, 1<<53 + 2, -(1 << 53), 9007199254740993.5,
+		math.NaN(), math.Inf(1), math.Inf(-1),
+	} {
+		b := AppendFloat(nil, f, FloatFormat{})
+		v, err := Unmarshal(b)
+		require.NoError(t, err, string(b))
+		var got float64
+		switch v := v.(type) {
+		case float64:
+			got = v
+		case int64:
+			// Integral values are written without a decimal point.
+			got = float64(v)
+		default:
+			t.Fatalf("%s parsed as %T", b, v)
+		}
+		if math.IsNaN(f) {
+			assert.True(t, math.IsNaN(got), string(b))
+		} else {
+			assert.Equal(t, f, got, string(b))
+			assert.Equal(t, math.Signbit(f), math.Signbit(got), string(b))
+		}
+		out, err := Marshal(f)
+		require.NoError(t, err)
+		assert.Equal(t, out, b)
+		assert.Equal(t, len(b), EstimateMarshalSize(f))
+	}
+	assert.Equal(t, "-0.0", string(AppendFloat(nil, math.Copysign(0, -1), FloatFormat{})))
+	b := []byte("[")
+	for _, f := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		b = append(AppendFloat(b, f, FloatFormat{}), ',')
+	}
+	assert.Equal(t, "[NaN,Infinity,-Infinity,", string(b))
+
+	for _, f := range []float32{0.1, -3.4028235e38, 1e-45, 16777217} {
+		b := AppendFloat(nil, float64(f), FloatFormat{BitSize: 32})
+		out, err := Marshal(f)
+		require.NoError(t, err)
+		assert.Equal(t, out, b)
+		v, err := Unmarshal(b)
+		require.NoError(t, err, string(b))
+		switch v := v.(type) {
+		case float64:
+			assert.Equal(t, f, float32(v), string(b))
+		case int64:
+			assert.Equal(t, f, float32(v), string(b))
+		}
+	}
+}
+
+func TestWithBigIntegers(t *testing.T) {
+	huge := strings.Repeat("9", 400)
+	doc := `[1, -2, 9223372036854775807, 9223372036854775808, -9223372036854775808, -9223372036854775809, ` +
+		huge + `, -` + huge + `, 1.5, 1e30, -0, 00000000000000000000042, NaN]`
+	bigOf := func(s string) *big.Int {
+		n, ok := new(big.Int).SetString(s, 10)
+		require.True(t, ok, s)
+		return n
+	}
+	for _, p := range []Parser{
+		NewParserFromString(doc, WithBigIntegers()),
+		NewParser(iotest.OneByteReader(strings.NewReader(doc)), WithBigIntegers()),
+	} {
+		v, err := p.UnmarshalFull()
+		require.NoError(t, err)
+		arr := v.([]any)
+		require.Len(t, arr, 13)
+		assert.Equal(t, []any{int64(1), int64(-2), int64(math.MaxInt64)}, arr[:3])
+		assert.Equal(t, bigOf("9223372036854775808"), arr[3])
+		assert.Equal(t, int64(math.MinInt64), arr[4])
+		assert.Equal(t, bigOf("-9223372036854775809"), arr[5])
+		assert.Equal(t, bigOf(huge), arr[6])
+		assert.Equal(t, bigOf("-"+huge), arr[7])
+		// Only integers are affected.
+		assert.Equal(t, []any{1.5, 1e30}, arr[8:10])
+		assert.True(t, math.Signbit(arr[10].(float64)))
+		assert.Equal(t, int64(42), arr[11])
+		assert.True(t, math.IsNaN(arr[12].(float64)))
+
+		out, err := Marshal(arr[2:8])
+		require.NoError(t, err)
+		assert.Equal(t, `[9223372036854775807,9223372036854775808,-9223372036854775808,-9223372036854775809,`+
+			huge+`,-`+huge+`]`, string(out))
+	}
+
+	// Without the option, they are floats as before.
+	v, err := UnmarshalString(`[9223372036854775808, ` + huge + `]`)
+	require.NoError(t, err)
+	assert.Equal(t, float64(9223372036854775808), v.([]any)[0])
+	assert.True(t, math.IsInf(v.([]any)[1].(float64), 1))
+
+	// Big integers are not floats out of range, but are still checked.
+	for _, opt := range []ParseOption{WithStrictNumbers(), WithDeNaN(DeNaNError), WithStrictNumberEnds()} {
+		v, err = UnmarshalStringWithOptions(`[`+huge+`, -9223372036854775809]`, WithBigIntegers(), opt)
+		require.NoError(t, err)
+		assert.Equal(t, []any{bigOf(huge), bigOf("-9223372036854775809")}, v)
+	}
+	_, err = UnmarshalStringWithOptions(`0`+huge, WithBigIntegers(), WithStrictNumbers())
+	assert.EqualError(t, err, `simple json: invalid number "0`+huge+`"`)
+	_, err = UnmarshalStringWithOptions(`[`+huge+`x]`, WithBigIntegers(), WithStrictNumberEnds())
+	assert.Error(t, err)
+	_, err = UnmarshalStringWithOptions(`1e400`, WithBigIntegers(), WithStrictNumbers())
+	assert.EqualError(t, err, `simple json: number "1e400" out of range`)
+
+	// Exact numbers take precedence, and limits still apply.
+	v, err = UnmarshalStringWithOptions(`{"n": 9223372036854775808}`, WithBigIntegers(), WithExactNumbers())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"n": Number("9223372036854775808")}, v)
+	_, err = UnmarshalStringWithOptions(huge, WithBigIntegers(), WithLimits(SafeLimits{MaxNumberBytes: 100}))
+	var limitErr *LimitError
+	assert.ErrorAs(t, err, &limitErr)
+}
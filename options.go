@@ -0,0 +1,482 @@
+package simplejsonext
+
+// DuplicateKeyPolicy determines what the parser does when an object contains
+// the same key more than once.
+type DuplicateKeyPolicy int
+
+const (
+	// DuplicateKeysLastWins keeps the value of the last occurrence of a key.
+	// This is the default, and matches encoding/json.
+	DuplicateKeysLastWins DuplicateKeyPolicy = iota
+	// DuplicateKeysFirstWins keeps the value of the first occurrence of a key
+	// and ignores the rest.
+	DuplicateKeysFirstWins
+	// DuplicateKeysError fails parsing when a key is repeated.
+	DuplicateKeysError
+	// DuplicateKeysCollect gathers the values of a repeated key into a []any,
+	// in the order they appear. A key that appears once keeps its value as it
+	// is, and only when it appears again is the first value put in a []any
+	// with the second; the []any is made even if the values are themselves
+	// arrays. Objects nested in the values are handled separately.
+	//
+	// Writing the result does not give back the repeated keys, as there is no
+	// way to tell a collected []any from an array that was in the input.
+	DuplicateKeysCollect
+)
+
+// DeNaNPolicy determines what the parser produces for numbers that are NaN or
+// infinite, whether written as NaN or Infinity or too big for a float64.
+type DeNaNPolicy int
+
+const (
+	// DeNaNKeep produces them as float64 values. This is the default.
+	DeNaNKeep DeNaNPolicy = iota
+	// DeNaNToString produces them as the strings "NaN", "Infinity" and
+	// "-Infinity", as WalkDeNaN does.
+	DeNaNToString
+	// DeNaNToNull produces them as null, as WalkDeNaNToNull does.
+	DeNaNToNull
+	// DeNaNError fails parsing when one is found.
+	DeNaNError
+)
+
+type parseConfig struct {
+	// Produce *OrderedObject values instead of map[string]any
+	orderedObjects bool
+	// What to do about repeated object keys
+	duplicateKeys DuplicateKeyPolicy
+	// Return escape-free strings that alias in-memory input
+	zeroCopyStrings bool
+	// Reuse the strings of repeated object keys
+	internKeys bool
+	// Alias in-memory input and batch the allocation of all other strings
+	unsafeStrings bool
+	// Deepest nesting allowed, or 0 for the default
+	maxDepth int
+	// Only accept numbers in the grammar of standard JSON
+	strictNumbers bool
+	// Transcode UTF-16 and UTF-32 input to UTF-8
+	detectEncoding bool
+	// Produce numbers as Number values
+	exactNumbers bool
+	// Fail on numbers followed by anything but whitespace, ',', ']' or '}'
+	strictNumberEnds bool
+	// What to produce for non-finite numbers
+	deNaN DeNaNPolicy
+	// Called with the size of each member of a top-level object
+	memberSizes func(key string, size int64) error
+	// Called with the text and value of each number, for Check
+	onNumber func(view []byte, f float64, isFloat bool)
+	// Bounds on the size of the input and the values in it
+	limits SafeLimits
+	// Produce string values as []byte
+	byteStrings bool
+	// Transforms each object key before it is added to its object
+	keyFunc func(key string) string
+	// Replace invalid UTF-8 in strings with U+FFFD
+	replaceInvalidUTF8 bool
+	// Skip one of these at the start of the input
+	prefixes [][]byte
+	// Accept // and /* */ comments as whitespace
+	comments bool
+	// Called with the input offset every progressEvery bytes and at the end
+	progress      func(bytesConsumed int64)
+	progressEvery int64
+	// Leave the escapes in string values
+	rawStrings bool
+	// Fail on object members with no struct field to decode into
+	disallowUnknownFields bool
+	// Produce integers too big for an int64 as *big.Int
+	bigIntegers bool
+}
+
+// ParseOption configures optional behavior of a Parser.
+type ParseOption func(*parseConfig)
+
+// WithOrderedObjects makes the parser produce JSON objects as *OrderedObject
+// values, which remember the order of their keys, instead of map[string]any.
+func WithOrderedObjects() ParseOption {
+	return func(c *parseConfig) {
+		c.orderedObjects = true
+	}
+}
+
+// WithDuplicateKeys sets the policy for objects that contain the same key more
+// than once.
+func WithDuplicateKeys(policy DuplicateKeyPolicy) ParseOption {
+	return func(c *parseConfig) {
+		c.duplicateKeys = policy
+	}
+}
+
+// WithZeroCopyStrings makes parsers created with NewParserFromSlice or
+// NewParserFromString return strings and object keys that contain no escapes
+// as views of the input itself, rather than as copies. Strings with escapes
+// are still decoded into new memory, and parsers reading from an io.Reader
+// are unaffected.
+//
+// WARNING: The parsed values then alias the input. They keep all of it from
+// being garbage collected for as long as any of them is alive, and if the
+// input is a []byte, modifying it afterwards changes the values of strings
+// that are supposed to be immutable, with undefined results. Only use this
+// option when the input is never modified and lives at least as long as the
+// values parsed from it.
+func WithZeroCopyStrings() ParseOption {
+	return func(c *parseConfig) {
+		c.zeroCopyStrings = true
+	}
+}
+
+// WithKeyInterning makes the parser reuse one string for each distinct short
+// object key it sees, rather than allocating a new copy of the key for every
+// object. This helps most with arrays of objects that share their keys. The
+// table of keys belongs to the Parser and is kept when it is reset, so that
+// it is shared by all the values the Parser produces; it is bounded in size,
+// and keys that do not fit in it are allocated as usual.
+func WithKeyInterning() ParseOption {
+	return func(c *parseConfig) {
+		c.internKeys = true
+	}
+}
+
+// WithUnsafeStrings goes further than WithZeroCopyStrings to avoid allocating
+// strings. Escape-free string values alias in-memory input just as they do
+// with WithZeroCopyStrings, while all other strings, including every object
+// key, are copied into chunks of memory owned by the parser and shared by many
+// strings, so that they do not need an allocation each.
+//
+// Object keys never alias the input, so maps stay valid even if it changes.
+//
+// WARNING: The aliasing contract of WithZeroCopyStrings applies to string
+// values. In addition, any string copied into a chunk keeps the whole chunk
+// from being garbage collected, so holding on to a few strings of a large
+// document may keep tens of kilobytes alive per string.
+func WithUnsafeStrings() ParseOption {
+	return func(c *parseConfig) {
+		c.unsafeStrings = true
+	}
+}
+
+// WithMaxDepth limits how deeply arrays and objects may be nested, in place of
+// the default limit of 500. Values nested deeper fail to parse. A limit of 0
+// or less keeps the default.
+func WithMaxDepth(n int) ParseOption {
+	return func(c *parseConfig) {
+		c.maxDepth = n
+	}
+}
+
+// WithStrictNumbers makes the parser accept only numbers that standard JSON
+// allows: no NaN or infinities, no leading '+' or zeros, and nothing too big
+// for a float64.
+func WithStrictNumbers() ParseOption {
+	return func(c *parseConfig) {
+		c.strictNumbers = true
+	}
+}
+
+// WithEncodingDetection makes the parser work out whether its input is UTF-8,
+// UTF-16, or UTF-32, big or little endian, from a byte order mark or, failing
+// that, from the pattern of zero bytes at its start, and transcode it to UTF-8
+// as it is parsed. A UTF-8 byte order mark is skipped. Unpaired surrogates and
+// other invalid code units become U+FFFD, as escaped ones do.
+//
+// Detection happens when the parser is given its input, so this option only
+// affects readers and data passed to the parser after it is set. Input in
+// memory that is not UTF-8 is transcoded into a new buffer all at once.
+func WithEncodingDetection() ParseOption {
+	return func(c *parseConfig) {
+		c.detectEncoding = true
+	}
+}
+
+// WithExactNumbers makes the parser produce every number as a Number holding
+// its exact text, rather than as an int64 or float64, so that it can be
+// written back unchanged. The numbers are still checked just as strictly.
+func WithExactNumbers() ParseOption {
+	return func(c *parseConfig) {
+		c.exactNumbers = true
+	}
+}
+
+// WithBigIntegers makes the parser produce integers too big for an int64 as
+// *big.Int values, which the Emitter writes back digit for digit, rather than
+// as float64 values that may lose precision or overflow to an infinity.
+// Integers that fit are still produced as int64 values. WithExactNumbers
+// takes precedence, and like it, this only affects Parse and the functions
+// built on it.
+func WithBigIntegers() ParseOption {
+	return func(c *parseConfig) {
+		c.bigIntegers = true
+	}
+}
+
+// WithStrictNumberEnds makes the parser fail on a number that is followed by
+// anything other than whitespace, ',', ']', '}', or the end of the input, such
+// as the "z" in 123z, rather than ending the number there. NaN and Infinity
+// must end the same way, so that a word such as Infrared is reported as an
+// invalid token rather than read as Inf. Without this option, a streaming
+// parser reading a top-level number leaves whatever follows for the next call
+// to Parse, which suits concatenated values but hides corrupted numbers.
+func WithStrictNumberEnds() ParseOption {
+	return func(c *parseConfig) {
+		c.strictNumberEnds = true
+	}
+}
+
+func (c *parseConfig) apply(opts []ParseOption) {
+	for _, opt := range opts {
+		opt(c)
+	}
+}
+
+type emitConfig struct {
+	// Put each element on a new line, starting with prefix and then indent
+	// once per level
+	indented       bool
+	prefix, indent string
+	// Fail on NaN and infinities instead of writing them
+	strictFloats bool
+	// Escape <, >, & in strings
+	escapeHTML bool
+	// Escape U+2028 and U+2029 in strings
+	escapeSeparators bool
+	// Write []byte as a string of its bytes rather than in base64
+	bytesAsStrings bool
+	// What to do with strings that are not valid UTF-8
+	invalidUTF8 InvalidUTF8Policy
+	// Write the members of maps in order of their keys
+	sortKeys bool
+	// Write string values as they are, between quotes
+	escapedStrings bool
+}
+
+// EmitOption configures optional behavior of an Emitter.
+type EmitOption func(*emitConfig)
+
+// WithIndent makes the Emitter write each element of an array or object on
+// its own line, starting with prefix followed by one copy of indent for each
+// level of nesting, like json.MarshalIndent. Empty arrays and objects are
+// still written as [] and {}.
+func WithIndent(prefix, indent string) EmitOption {
+	return func(c *emitConfig) {
+		c.indented = true
+		c.prefix = prefix
+		c.indent = indent
+	}
+}
+
+// WithStrictFloats makes the Emitter fail on NaN and infinite floats, which
+// standard JSON cannot represent, rather than writing them as extended JSON.
+// Number values holding them fail too.
+func WithStrictFloats() EmitOption {
+	return func(c *emitConfig) {
+		c.strictFloats = true
+	}
+}
+
+// WithEscapeHTML makes the Emitter escape the characters <, >, and & in strings
+// as \u003c, \u003e, and \u0026, like encoding/json does by default, so that
+// the output can be embedded in HTML. The line and paragraph separators U+2028
+// and U+2029 are escaped too, for the sake of JavaScript.
+func WithEscapeHTML() EmitOption {
+	return func(c *emitConfig) {
+		c.escapeHTML = true
+		c.escapeSeparators = true
+	}
+}
+
+// WithBytesAsStrings makes the Emitter write []byte values as JSON strings of
+// their bytes, escaped like any other string, rather than encoded in base64 as
+// encoding/json does. This writes back the values parsed with
+// WithByteStrings. RawMessage values are still written verbatim.
+func WithBytesAsStrings() EmitOption {
+	return func(c *emitConfig) {
+		c.bytesAsStrings = true
+	}
+}
+
+// WithSortedKeys makes the Emitter write the members of maps in order of their
+// keys, compared byte by byte, like encoding/json, so that equal values are
+// always written the same way. The members of ordered objects are still
+// written in their stored order.
+func WithSortedKeys() EmitOption {
+	return func(c *emitConfig) {
+		c.sortKeys = true
+	}
+}
+
+// InvalidUTF8Policy determines what the Emitter does with strings, including
+// object keys, that are not valid UTF-8.
+type InvalidUTF8Policy int
+
+const (
+	// InvalidUTF8PassThrough writes the bytes as they are, so that the output
+	// is not valid UTF-8 either. This is the default.
+	InvalidUTF8PassThrough InvalidUTF8Policy = iota
+	// InvalidUTF8Replace writes each byte of invalid UTF-8 as the
+	// replacement character U+FFFD, as encoding/json does, leaving the valid
+	// runes around it as they are.
+	InvalidUTF8Replace
+	// InvalidUTF8Fail makes Emit fail with an *InvalidUTF8Error.
+	InvalidUTF8Fail
+)
+
+// WithInvalidUTF8 sets what the Emitter does with strings that are not valid
+// UTF-8. Bytes of overlong encodings and of encoded surrogate halves are
+// invalid, as are bytes that never appear in UTF-8. A string's validity is
+// judged before it is escaped, so it applies with WithEscapeHTML too.
+func WithInvalidUTF8(policy InvalidUTF8Policy) EmitOption {
+	return func(c *emitConfig) {
+		c.invalidUTF8 = policy
+	}
+}
+
+func (c *emitConfig) apply(opts []EmitOption) {
+	for _, opt := range opts {
+		opt(c)
+	}
+}
+
+// WithDeNaN sets the policy for numbers that are NaN or infinite, replacing
+// them as they are parsed so that the result needs no WalkDeNaN afterwards.
+// Visitors are given the replacement strings and nulls in their place too.
+func WithDeNaN(policy DeNaNPolicy) ParseOption {
+	return func(c *parseConfig) {
+		c.deNaN = policy
+	}
+}
+
+// WithMemberSizes makes the parser call fn with the key of each member of a
+// top-level object and the size in bytes of the member's value as written in
+// the input, from its first byte to its last, once the value is parsed. If fn
+// returns an error, parsing stops and returns it, so that fn can enforce
+// limits on the sizes of members. Objects nested in other values are not
+// measured, nor are values read with Skip, Visit or StdToken.
+func WithMemberSizes(fn func(key string, size int64) error) ParseOption {
+	return func(c *parseConfig) {
+		c.memberSizes = fn
+	}
+}
+
+// WithByteStrings makes the parser produce string values as []byte rather
+// than string, including the strings that WithDeNaN produces. Object keys are
+// still strings, so that they can be map keys. Write such values back as
+// strings with WithBytesAsStrings, since an Emitter otherwise writes []byte in
+// base64.
+//
+// Each []byte is a new copy that belongs to the caller and may be modified,
+// and never aliases the parser's buffers. With WithZeroCopyStrings or
+// WithUnsafeStrings, however, values of in-memory input that contain no
+// escapes are views of the input, as their strings would be: modifying them
+// modifies the input, though appending to them never does. Visitors and
+// StdToken are unaffected.
+func WithByteStrings() ParseOption {
+	return func(c *parseConfig) {
+		c.byteStrings = true
+	}
+}
+
+// WithKeyFunc makes the parser replace each object key, once unescaped, with
+// fn(key), in objects at every depth. Keys that fn maps to the same string are
+// duplicates, handled as WithDuplicateKeys says. A nil fn transforms nothing.
+// Visitors and StdToken are given the keys as written.
+func WithKeyFunc(fn func(key string) string) ParseOption {
+	return func(c *parseConfig) {
+		c.keyFunc = fn
+	}
+}
+
+// WithReplaceInvalidUTF8 makes the parser replace each byte of invalid UTF-8
+// in strings and object keys with the replacement character U+FFFD, as
+// encoding/json does, so that every string it produces is valid UTF-8. Bytes
+// of overlong encodings and of encoded surrogate halves are invalid, and each
+// one is replaced. Without it, such bytes are kept as they are.
+func WithReplaceInvalidUTF8() ParseOption {
+	return func(c *parseConfig) {
+		c.replaceInvalidUTF8 = true
+	}
+}
+
+// XSSIPrefixes are the guards that some APIs put before JSON responses to
+// defeat cross-site script inclusion, which WithPrefixSkip skips by default.
+var XSSIPrefixes = [][]byte{
+	[]byte(")]}'"),
+	[]byte(")]}',"),
+	[]byte("while(1);"),
+	[]byte("for(;;);"),
+}
+
+// WithPrefixSkip makes the parser skip one of prefixes, the longest that
+// matches, if the input starts with it, possibly after a UTF-8 byte order
+// mark; whitespace after it is skipped as usual. With no prefixes, those in
+// XSSIPrefixes are skipped. The same bytes are not skipped anywhere else,
+// including before the second of several concatenated values, and input that
+// does not start with a prefix is parsed as usual.
+//
+// Like WithEncodingDetection, the prefix is skipped when the parser is given
+// its input, so this option only affects readers and data passed to the
+// parser after it is set. Offsets in errors still count the prefix.
+func WithPrefixSkip(prefixes ...[]byte) ParseOption {
+	if len(prefixes) == 0 {
+		prefixes = XSSIPrefixes
+	}
+	return func(c *parseConfig) {
+		c.prefixes = prefixes
+	}
+}
+
+// WithComments makes the parser accept comments wherever whitespace may go
+// between tokens, as in JSONC: // comments, which run to the end of the line,
+// and /* */ comments, which do not nest. They are skipped like whitespace, and
+// kept by none of the parsed values. NextLine still only accepts whitespace
+// before the newline.
+func WithComments() ParseOption {
+	return func(c *parseConfig) {
+		c.comments = true
+	}
+}
+
+// WithProgress makes the parser call fn with the number of bytes of input
+// consumed so far, as InputOffset would report it, each time at least everyN
+// more bytes have been consumed since the last call, and once more at the end
+// of the input. The calls are made from the goroutine doing the parsing,
+// whenever the parser reads more of its input, so for a parser reading from
+// an io.Reader they are at most as frequent as its reads; a parser of a slice
+// or string only calls fn at the end. If fn panics, parsing fails with an
+// error rather than the panic continuing.
+func WithProgress(fn func(bytesConsumed int64), everyN int64) ParseOption {
+	return func(c *parseConfig) {
+		c.progress = fn
+		c.progressEvery = everyN
+	}
+}
+
+// WithRawStrings makes the parser produce string values with the text they
+// have in the input, escapes and all, rather than decoding them, which saves
+// the work for data that is passed on without being looked at. The strings
+// are checked just as they would be otherwise, so they hold no unescaped
+// quotes or control characters, and an Emitter configured with
+// WithEscapedStrings writes them back out unchanged.
+//
+// Object keys are still decoded, so that they compare as they would without
+// this option. Only Parse and the functions built on it are affected, not
+// other ways of reading, such as Visit or the token and stream readers.
+func WithRawStrings() ParseOption {
+	return func(c *parseConfig) {
+		c.rawStrings = true
+	}
+}
+
+// WithEscapedStrings makes the Emitter write string values as they are,
+// without escaping them, taking them to be already escaped, as WithRawStrings
+// parses them. Such a string must be valid between quotes, with escapes that
+// the parser accepts and no unescaped quotes or control characters, or the
+// Emitter fails. Object keys, and strings written for values of other types,
+// are escaped as usual, and WithEscapeHTML does not apply to the strings this
+// option writes as they are.
+func WithEscapedStrings() EmitOption {
+	return func(c *emitConfig) {
+		c.escapedStrings = true
+	}
+}
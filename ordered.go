@@ -0,0 +1,80 @@
+package simplejsonext
+
+// Member is a single key and value of an OrderedObject.
+type Member struct {
+	Key   string
+	Value any
+}
+
+// OrderedObject is a JSON object that remembers the order of its keys. The
+// parser produces *OrderedObject values when WithOrderedObjects is used, and
+// the Emitter writes their members in stored order.
+//
+// Lookups are linear in the number of members, which is fine for the small
+// objects found in typical config files. Members may be manipulated directly,
+// but then it is up to the caller to keep keys unique.
+type OrderedObject struct {
+	Members []Member
+}
+
+// Len returns the number of members in the object.
+func (o *OrderedObject) Len() int {
+	return len(o.Members)
+}
+
+// Get returns the value stored under key, and whether it was found.
+func (o *OrderedObject) Get(key string) (any, bool) {
+	if i := o.index(key); i >= 0 {
+		return o.Members[i].Value, true
+	}
+	return nil, false
+}
+
+// Set stores value under key. An existing key keeps its position; a new key is
+// appended at the end.
+func (o *OrderedObject) Set(key string, value any) {
+	if i := o.index(key); i >= 0 {
+		o.Members[i].Value = value
+		return
+	}
+	o.Members = append(o.Members, Member{Key: key, Value: value})
+}
+
+// Delete removes key from the object, preserving the order of the remaining
+// members. It returns whether the key was present.
+func (o *OrderedObject) Delete(key string) bool {
+	i := o.index(key)
+	if i < 0 {
+		return false
+	}
+	o.Members = append(o.Members[:i], o.Members[i+1:]...)
+	return true
+}
+
+// Keys returns the keys of the object in order.
+func (o *OrderedObject) Keys() []string {
+	keys := make([]string, len(o.Members))
+	for i, m := range o.Members {
+		keys[i] = m.Key
+	}
+	return keys
+}
+
+// ToMap returns the members of the object as a map, discarding their order.
+// Nested ordered objects are left as they are.
+func (o *OrderedObject) ToMap() map[string]any {
+	m := make(map[string]any, len(o.Members))
+	for _, member := range o.Members {
+		m[member.Key] = member.Value
+	}
+	return m
+}
+
+func (o *OrderedObject) index(key string) int {
+	for i := range o.Members {
+		if o.Members[i].Key == key {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,274 @@
+package simplejsonext_test
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wandb/simplejsonext"
+)
+
+func parseOrdered(t *testing.T, data string, opts ...simplejsonext.ParseOption) (any, error) {
+	t.Helper()
+	opts = append([]simplejsonext.ParseOption{simplejsonext.WithOrderedObjects()}, opts...)
+	p := simplejsonext.NewParserFromString(data, opts...)
+	val, err := p.Parse()
+	if err != nil {
+		return nil, err
+	}
+	return val, p.CheckEmpty()
+}
+
+func TestOrderedObjectRoundTrip(t *testing.T) {
+	for _, doc := range []string{
+		`{}`,
+		`{"z":1,"a":2,"m":3}`,
+		`{"zeta":{"b":true,"a":null},"alpha":[{"y":"1","x":"2"},{}],"mid":1.5}`,
+		`[{"9":9,"1":1},{"b":NaN,"a":-Infinity}]`,
+	} {
+		t.Run(doc, func(t *testing.T) {
+			val, err := parseOrdered(t, doc)
+			require.NoError(t, err)
+			out, err := simplejsonext.MarshalToString(val)
+			require.NoError(t, err)
+			assert.Equal(t, doc, out)
+		})
+	}
+}
+
+func TestOrderedObjectValues(t *testing.T) {
+	val, err := parseOrdered(t, ` { "b" : 1, "a" : [ {"d": 2, "c": 3} ] } `)
+	require.NoError(t, err)
+	assertEqual(t, &simplejsonext.OrderedObject{Members: []simplejsonext.Member{
+		{Key: "b", Value: int64(1)},
+		{Key: "a", Value: []any{&simplejsonext.OrderedObject{Members: []simplejsonext.Member{
+			{Key: "d", Value: int64(2)},
+			{Key: "c", Value: int64(3)},
+		}}}},
+	}}, val, options{})
+
+	obj, err := simplejsonext.NewParserFromString(`{"y": {"q": 1, "p": 2}, "x": 0}`,
+		simplejsonext.WithOrderedObjects()).ParseOrderedObject()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"y", "x"}, obj.Keys())
+	nested, ok := obj.Get("y")
+	require.True(t, ok)
+	assert.Equal(t, []string{"q", "p"}, nested.(*simplejsonext.OrderedObject).Keys())
+
+	// Without the option, only the top level is ordered
+	obj, err = simplejsonext.NewParserFromString(`{"y": {"q": 1}}`).ParseOrderedObject()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"q": int64(1)}, obj.Members[0].Value)
+}
+
+func TestOrderedObjectAccessors(t *testing.T) {
+	var obj simplejsonext.OrderedObject
+	assert.Equal(t, 0, obj.Len())
+	_, ok := obj.Get("a")
+	assert.False(t, ok)
+
+	obj.Set("b", 1)
+	obj.Set("a", 2)
+	obj.Set("c", 3)
+	obj.Set("b", 4) // keeps its position
+	assert.Equal(t, 3, obj.Len())
+	assert.Equal(t, []string{"b", "a", "c"}, obj.Keys())
+	v, ok := obj.Get("b")
+	assert.True(t, ok)
+	assert.Equal(t, 4, v)
+
+	assert.True(t, obj.Delete("a"))
+	assert.False(t, obj.Delete("a"))
+	assert.Equal(t, []string{"b", "c"}, obj.Keys())
+	assert.Equal(t, map[string]any{"b": 4, "c": 3}, obj.ToMap())
+
+	out, err := simplejsonext.MarshalToString(obj)
+	require.NoError(t, err)
+	assert.Equal(t, `{"b":4,"c":3}`, out)
+	out, err = simplejsonext.MarshalToString((*simplejsonext.OrderedObject)(nil))
+	require.NoError(t, err)
+	assert.Equal(t, `null`, out)
+}
+
+func TestOrderedObjectDuplicateKeys(t *testing.T) {
+	const doc = `{"a": 1, "b": 2, "a": 3}`
+
+	val, err := parseOrdered(t, doc)
+	require.NoError(t, err)
+	out, err := simplejsonext.MarshalToString(val)
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":3,"b":2}`, out)
+
+	val, err = parseOrdered(t, doc, simplejsonext.WithDuplicateKeys(simplejsonext.DuplicateKeysFirstWins))
+	require.NoError(t, err)
+	out, err = simplejsonext.MarshalToString(val)
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":1,"b":2}`, out)
+
+	_, err = parseOrdered(t, doc, simplejsonext.WithDuplicateKeys(simplejsonext.DuplicateKeysError))
+	assert.EqualError(t, err, `simple json: duplicate object key "a"`)
+}
+
+func TestOrderedObjectLarge(t *testing.T) {
+	const n = 200000
+	var sb strings.Builder
+	sb.WriteString("{")
+	for i := range n {
+		fmt.Fprintf(&sb, `"k%d": %d, `, i, i)
+	}
+	// Duplicates of the first and a middle key, found through the index.
+	sb.WriteString(`"k0": "first", "k100000": "middle"}`)
+
+	start := time.Now()
+	for _, policy := range []simplejsonext.DuplicateKeyPolicy{simplejsonext.DuplicateKeysLastWins, simplejsonext.DuplicateKeysFirstWins} {
+		val, err := parseOrdered(t, sb.String(), simplejsonext.WithDuplicateKeys(policy))
+		require.NoError(t, err)
+		obj := val.(*simplejsonext.OrderedObject)
+		require.Equal(t, n, obj.Len())
+		assert.Equal(t, "k100000", obj.Members[100000].Key)
+		v, _ := obj.Get("k100000")
+		if policy == simplejsonext.DuplicateKeysFirstWins {
+			assert.Equal(t, int64(100000), v)
+		} else {
+			assert.Equal(t, "middle", v)
+		}
+	}
+	_, err := parseOrdered(t, sb.String(), simplejsonext.WithDuplicateKeys(simplejsonext.DuplicateKeysError))
+	assert.EqualError(t, err, `simple json: duplicate object key "k0"`)
+	// Searching every member for every key would take minutes.
+	assert.Less(t, time.Since(start), 10*time.Second)
+}
+
+func TestDuplicateKeys(t *testing.T) {
+	const doc = `{"a": 1, "b": 2, "a": 3}`
+	unmarshal := func(opts ...simplejsonext.ParseOption) (any, error) {
+		return simplejsonext.NewParserFromString(doc, opts...).Parse()
+	}
+
+	val, err := unmarshal()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"a": int64(3), "b": int64(2)}, val)
+
+	val, err = unmarshal(simplejsonext.WithDuplicateKeys(simplejsonext.DuplicateKeysFirstWins))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"a": int64(1), "b": int64(2)}, val)
+
+	_, err = unmarshal(simplejsonext.WithDuplicateKeys(simplejsonext.DuplicateKeysError))
+	assert.EqualError(t, err, `simple json: duplicate object key "a"`)
+}
+
+func TestDuplicateKeysCollect(t *testing.T) {
+	const doc = `{"tag": "a", "n": 1, "tag": ["b"], "o": {"x": 1, "x": {"x": 2, "y": 3}}, "tag": "c", "list": [{"k": 1, "k": 2}, {"k": 3}]}`
+	collect := simplejsonext.WithDuplicateKeys(simplejsonext.DuplicateKeysCollect)
+	expected := map[string]any{
+		// Values that were arrays are still collected into a new one.
+		"tag":  []any{"a", []any{"b"}, "c"},
+		"n":    int64(1),
+		"o":    map[string]any{"x": []any{int64(1), map[string]any{"x": int64(2), "y": int64(3)}}},
+		"list": []any{map[string]any{"k": []any{int64(1), int64(2)}}, map[string]any{"k": int64(3)}},
+	}
+	val, err := simplejsonext.UnmarshalWithOptions([]byte(doc), collect)
+	require.NoError(t, err)
+	assert.Equal(t, expected, val)
+	val, err = simplejsonext.UnmarshalStringWithOptions(doc, collect)
+	require.NoError(t, err)
+	assert.Equal(t, expected, val)
+
+	// Repeated keys keep the place they first appeared.
+	ordered, err := parseOrdered(t, doc, collect)
+	require.NoError(t, err)
+	out, err := simplejsonext.MarshalToString(ordered)
+	require.NoError(t, err)
+	assert.Equal(t, `{"tag":["a",["b"],"c"],"n":1,"o":{"x":[1,{"x":2,"y":3}]},"list":[{"k":[1,2]},{"k":3}]}`, out)
+
+	// Collecting is one-way: parsing that output gives the same tree, not
+	// the original keys.
+	again, err := simplejsonext.UnmarshalStringWithOptions(out, collect)
+	require.NoError(t, err)
+	assert.Equal(t, expected, again)
+
+	// Decoding into maps of any collects too; other maps cannot.
+	var m map[string]any
+	p := simplejsonext.NewParserFromString(doc, collect)
+	require.NoError(t, p.ParseInto(&m))
+	assert.Equal(t, expected, m)
+	var tags map[string]simplejsonext.RawMessage
+	p = simplejsonext.NewParserFromString(`{"tag": "a", "tag": "b"}`, collect)
+	assert.EqualError(t, p.ParseInto(&tags), `simple json: cannot collect the values of duplicate key "tag" in a map[string]simplejsonext.RawMessage`)
+
+	// As do the parsers that recover from errors.
+	val, errs := simplejsonext.UnmarshalAllErrors([]byte(`{"tag": "a", "tag": "b", "bad": x}`), simplejsonext.MultiErrorOptions{
+		ParseOptions: []simplejsonext.ParseOption{collect},
+	})
+	assert.Len(t, errs, 1)
+	assert.Equal(t, []any{"a", "b"}, val.(map[string]any)["tag"])
+}
+
+func TestKeyFunc(t *testing.T) {
+	const doc = `{"Name": 1, " tags ": {"A": [{"B": 2}]}, "NAME": 3}`
+	normalize := simplejsonext.WithKeyFunc(func(key string) string {
+		return strings.ToLower(strings.TrimSpace(key))
+	})
+	for _, test := range []struct {
+		policy simplejsonext.DuplicateKeyPolicy
+		name   any
+	}{
+		{simplejsonext.DuplicateKeysLastWins, int64(3)},
+		{simplejsonext.DuplicateKeysFirstWins, int64(1)},
+		{simplejsonext.DuplicateKeysError, nil},
+	} {
+		dups := simplejsonext.WithDuplicateKeys(test.policy)
+		val, err := simplejsonext.NewParserFromString(doc, normalize, dups).Parse()
+		ordered, orderedErr := parseOrdered(t, doc, normalize, dups)
+		if test.name == nil {
+			assert.EqualError(t, err, `simple json: duplicate object key "name"`)
+			assert.EqualError(t, orderedErr, `simple json: duplicate object key "name"`)
+			continue
+		}
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{
+			"name": test.name,
+			"tags": map[string]any{"a": []any{map[string]any{"b": int64(2)}}},
+		}, val)
+		require.NoError(t, orderedErr)
+		out, err := simplejsonext.MarshalToString(ordered)
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf(`{"name":%d,"tags":{"a":[{"b":2}]}}`, test.name), out)
+	}
+
+	// A rename table can bind values to entirely different keys.
+	renames := map[string]string{"Name": "id", "NAME": "label"}
+	val, err := simplejsonext.NewParserFromString(doc, simplejsonext.WithKeyFunc(func(key string) string {
+		if renamed, ok := renames[key]; ok {
+			return renamed
+		}
+		return key
+	}), simplejsonext.WithDuplicateKeys(simplejsonext.DuplicateKeysError)).Parse()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), val.(map[string]any)["id"])
+	assert.Equal(t, int64(3), val.(map[string]any)["label"])
+	assert.Contains(t, val, " tags ")
+
+	val, err = simplejsonext.NewParserFromString(doc, simplejsonext.WithKeyFunc(nil)).Parse()
+	require.NoError(t, err)
+	assert.Len(t, val, 3)
+}
+
+func TestOrderedObjectDeNaN(t *testing.T) {
+	val, err := parseOrdered(t, `{"b": NaN, "a": [Infinity], "c": 1}`)
+	require.NoError(t, err)
+	out, err := simplejsonext.MarshalToString(simplejsonext.WalkDeNaN(val))
+	require.NoError(t, err)
+	assert.Equal(t, `{"b":"NaN","a":["Infinity"],"c":1}`, out)
+
+	obj := val.(*simplejsonext.OrderedObject)
+	obj.Set("d", math.Inf(-1))
+	obj = simplejsonext.WalkDeNaN(obj).(*simplejsonext.OrderedObject)
+	d, _ := obj.Get("d")
+	assert.Equal(t, "-Infinity", d)
+}
@@ -0,0 +1,172 @@
+package simplejsonext
+
+import (
+	"fmt"
+	"io"
+)
+
+// OverlayNullPolicy determines what a null member of an object does when
+// documents are overlaid.
+type OverlayNullPolicy int
+
+const (
+	// OverlayNullReplaces sets the member to null, like any other value. This
+	// is the default.
+	OverlayNullReplaces OverlayNullPolicy = iota
+	// OverlayNullDeletes removes the member, as in a JSON merge patch
+	// (RFC 7396).
+	OverlayNullDeletes
+	// OverlayNullIgnored leaves the member as it was.
+	OverlayNullIgnored
+)
+
+// OverlayOptions controls UnmarshalOverlayWithOptions and
+// UnmarshalOverlayReader.
+type OverlayOptions struct {
+	// Nulls is what null members of the documents after the first do.
+	Nulls OverlayNullPolicy
+	// ParseOptions configure the parser reading the documents.
+	ParseOptions []ParseOption
+}
+
+// OverlayError is returned when one of the documents being overlaid cannot be
+// parsed or is not an object.
+type OverlayError struct {
+	// Index is the position of the document among those given, or in the
+	// stream, from 0.
+	Index int
+	// Err is the error from parsing it.
+	Err error
+}
+
+func (e *OverlayError) Error() string {
+	return fmt.Sprintf("simple json: document %d: %v", e.Index, e.Err)
+}
+
+func (e *OverlayError) Unwrap() error {
+	return e.Err
+}
+
+// UnmarshalOverlay parses each of docs, which must all be objects, and
+// overlays them from left to right, for layered configuration. Where two
+// documents have objects for the same member, the objects are merged in the
+// same way, at any depth; any other value, including an array, replaces what
+// was there. Nulls replace what was there too; see UnmarshalOverlayWithOptions
+// for other behaviors. With no documents, the result is an empty map.
+func UnmarshalOverlay(docs ...[]byte) (map[string]any, error) {
+	return UnmarshalOverlayWithOptions(docs, OverlayOptions{})
+}
+
+// UnmarshalOverlayWithOptions is like UnmarshalOverlay, configured by opts.
+// Nulls in the first document are kept whatever opts.Nulls says.
+func UnmarshalOverlayWithOptions(docs [][]byte, opts OverlayOptions) (map[string]any, error) {
+	o := overlayer{nulls: opts.Nulls}
+	var p Parser
+	for i, doc := range docs {
+		if p == nil {
+			p = NewParserFromSlice(doc, opts.ParseOptions...)
+		} else {
+			p.ResetSlice(doc)
+		}
+		obj, err := p.ParseObject()
+		if err == nil {
+			err = p.CheckEmpty()
+		}
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return nil, &OverlayError{Index: i, Err: err}
+		}
+		o.add(obj)
+	}
+	return o.result(), nil
+}
+
+// UnmarshalOverlayReader is like UnmarshalOverlayWithOptions, but reads the
+// documents from r, one after the other, until its end. They may be separated
+// by whitespace, such as when they are newline-delimited.
+func UnmarshalOverlayReader(r io.Reader, opts OverlayOptions) (map[string]any, error) {
+	o := overlayer{nulls: opts.Nulls}
+	p := NewParser(r, opts.ParseOptions...).(*parser)
+	for i := 0; ; i++ {
+		// Only the end of the input before a document ends the stream.
+		if _, err := p.parseType(); err == io.EOF {
+			return o.result(), nil
+		} else if err != nil {
+			return nil, &OverlayError{Index: i, Err: err}
+		}
+		obj, err := p.ParseObject()
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return nil, &OverlayError{Index: i, Err: err}
+		}
+		o.add(obj)
+	}
+}
+
+// Accumulates overlaid documents.
+type overlayer struct {
+	nulls  OverlayNullPolicy
+	merged map[string]any
+}
+
+func (o *overlayer) add(obj map[string]any) {
+	if o.merged == nil {
+		o.merged = obj
+		return
+	}
+	o.mergeMap(o.merged, obj)
+}
+
+func (o *overlayer) result() map[string]any {
+	if o.merged == nil {
+		return map[string]any{}
+	}
+	return o.merged
+}
+
+// Returns src overlaid on dst, merging dst in place if both are objects of
+// the same kind.
+func (o *overlayer) overlay(dst, src any) any {
+	switch s := src.(type) {
+	case map[string]any:
+		d, ok := dst.(map[string]any)
+		if !ok {
+			d = make(map[string]any, len(s))
+		}
+		o.mergeMap(d, s)
+		return d
+	case *OrderedObject:
+		d, ok := dst.(*OrderedObject)
+		if !ok || d == nil {
+			d = &OrderedObject{Members: make([]Member, 0, len(s.Members))}
+		}
+		for _, m := range s.Members {
+			if m.Value == nil && o.nulls != OverlayNullReplaces {
+				if o.nulls == OverlayNullDeletes {
+					d.Delete(m.Key)
+				}
+				continue
+			}
+			old, _ := d.Get(m.Key)
+			d.Set(m.Key, o.overlay(old, m.Value))
+		}
+		return d
+	}
+	return src
+}
+
+func (o *overlayer) mergeMap(dst, src map[string]any) {
+	for key, value := range src {
+		if value == nil && o.nulls != OverlayNullReplaces {
+			if o.nulls == OverlayNullDeletes {
+				delete(dst, key)
+			}
+			continue
+		}
+		dst[key] = o.overlay(dst[key], value)
+	}
+}
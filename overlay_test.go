@@ -0,0 +1,116 @@
+package simplejsonext
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var overlayDocs = []string{
+	`{"name": "base", "db": {"host": "localhost", "port": 5432, "opts": {"ssl": false}}, "tags": ["a", "b"], "debug": true}`,
+	`{"db": {"port": 6543, "opts": {"timeout": 30}}, "tags": ["c"], "debug": null}`,
+	`{"db": {"opts": {"ssl": true, "timeout": null}}, "name": {"first": "x"}, "extra": {"k": null, "l": 1}}`,
+}
+
+func TestUnmarshalOverlay(t *testing.T) {
+	var docs [][]byte
+	for _, doc := range overlayDocs {
+		docs = append(docs, []byte(doc))
+	}
+	merged, err := UnmarshalOverlay(docs...)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"name":  map[string]any{"first": "x"},
+		"db":    map[string]any{"host": "localhost", "port": int64(6543), "opts": map[string]any{"ssl": true, "timeout": nil}},
+		"tags":  []any{"c"},
+		"debug": nil,
+		"extra": map[string]any{"k": nil, "l": int64(1)},
+	}, merged)
+
+	merged, err = UnmarshalOverlayWithOptions(docs, OverlayOptions{Nulls: OverlayNullDeletes})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"name":  map[string]any{"first": "x"},
+		"db":    map[string]any{"host": "localhost", "port": int64(6543), "opts": map[string]any{"ssl": true}},
+		"tags":  []any{"c"},
+		"extra": map[string]any{"l": int64(1)},
+	}, merged)
+
+	merged, err = UnmarshalOverlayWithOptions(docs, OverlayOptions{Nulls: OverlayNullIgnored})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"name":  map[string]any{"first": "x"},
+		"db":    map[string]any{"host": "localhost", "port": int64(6543), "opts": map[string]any{"ssl": true, "timeout": int64(30)}},
+		"tags":  []any{"c"},
+		"debug": true,
+		"extra": map[string]any{"l": int64(1)},
+	}, merged)
+
+	// Ordered objects merge in the same way, keeping the order in which keys
+	// first appeared.
+	merged, err = UnmarshalOverlayWithOptions(docs, OverlayOptions{
+		Nulls:        OverlayNullDeletes,
+		ParseOptions: []ParseOption{WithOrderedObjects()},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &OrderedObject{Members: []Member{
+		{Key: "host", Value: "localhost"},
+		{Key: "port", Value: int64(6543)},
+		{Key: "opts", Value: &OrderedObject{Members: []Member{{Key: "ssl", Value: true}}}},
+	}}, merged["db"])
+
+	merged, err = UnmarshalOverlay()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{}, merged)
+}
+
+func TestUnmarshalOverlayErrors(t *testing.T) {
+	for _, test := range []struct {
+		docs []string
+		err  string
+	}{
+		{[]string{`{}`, `[1]`}, "simple json: document 1: simple json: expected an object but found array"},
+		{[]string{`{"a": 1`}, "simple json: document 0: " + io.ErrUnexpectedEOF.Error()},
+		{[]string{`{}`, `{}`, ``}, "simple json: document 2: " + io.ErrUnexpectedEOF.Error()},
+		{[]string{`{} {}`}, "simple json: document 0: " + ErrTrailingData.Error()},
+		{[]string{`{}`, `{"a": [1,]}`}, "simple json: document 1: simple json: unexpected end of array or object"},
+	} {
+		var docs [][]byte
+		for _, doc := range test.docs {
+			docs = append(docs, []byte(doc))
+		}
+		_, err := UnmarshalOverlay(docs...)
+		var overlayErr *OverlayError
+		if assert.ErrorAs(t, err, &overlayErr, test.docs) {
+			assert.EqualError(t, err, test.err, test.docs)
+		}
+	}
+	_, err := UnmarshalOverlay([]byte(`{}`), []byte(`"x"`))
+	assert.ErrorIs(t, err, ErrNotObject)
+}
+
+func TestUnmarshalOverlayReader(t *testing.T) {
+	var docs [][]byte
+	for _, doc := range overlayDocs {
+		docs = append(docs, []byte(doc))
+	}
+	expected, err := UnmarshalOverlayWithOptions(docs, OverlayOptions{Nulls: OverlayNullDeletes})
+	require.NoError(t, err)
+	stream := strings.Join(overlayDocs, "\n") + "\n"
+	merged, err := UnmarshalOverlayReader(iotest.OneByteReader(strings.NewReader(stream)), OverlayOptions{Nulls: OverlayNullDeletes})
+	require.NoError(t, err)
+	assert.Equal(t, expected, merged)
+
+	merged, err = UnmarshalOverlayReader(strings.NewReader(" \n"), OverlayOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{}, merged)
+
+	_, err = UnmarshalOverlayReader(strings.NewReader(`{"a": 1}{"b": 2} 3`), OverlayOptions{})
+	assert.EqualError(t, err, "simple json: document 2: simple json: expected an object but found number")
+	_, err = UnmarshalOverlayReader(strings.NewReader(`{"a": 1}{"b": `), OverlayOptions{})
+	assert.Equal(t, &OverlayError{Index: 1, Err: io.ErrUnexpectedEOF}, err)
+}
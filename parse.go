@@ -2,10 +2,14 @@ package simplejsonext
 
 import (
 	"bytes"
+	"encoding/binary"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"math"
+	"math/big"
+	"runtime/debug"
 	"strconv"
 	"unicode/utf16"
 	"unicode/utf8"
@@ -17,8 +21,16 @@ const (
 	readBufferSize = 1024
 	// We opportunistically release buffers larger than this many bytes
 	oversizedBuffer = 64 * 1024
-	// Maximum recursion depth for nested values
+	// ...and element stacks larger than this many values
+	oversizedElems = 4 * 1024
+	// Default maximum recursion depth for nested values
 	maxDepth = 500
+	// Longest object key that is interned
+	maxInternedKeyLen = 64
+	// Most keys held in the intern table
+	maxInternedKeys = 1024
+	// Size of the chunks that WithUnsafeStrings copies strings into
+	unsafeStringChunk = 4 * 1024
 )
 
 type valType int
@@ -35,28 +47,144 @@ const (
 	endGroupSym
 )
 
+// ErrTrailingData is returned by CheckEmpty when something other than
+// whitespace follows a value, and is matched by every *TrailingDataError.
+var ErrTrailingData = errors.New("simple json: remainder of buffer not empty")
+
 var (
-	errUnexpectedComma = errors.New("simple json: unexpected comma")
-	errUnexpectedEnd   = errors.New("simple json: unexpected end of array or object")
-	errLineNotEmpty    = errors.New("simple json: non-whitespace found before newline")
-	errBufferNotEmpty  = errors.New("simple json: remainder of buffer not empty")
-	errControlChar     = errors.New("simple json: control character, tab, or newline in string value")
-	errTruncatedHex    = errors.New(
+	errUnexpectedComma     = errors.New("simple json: unexpected comma")
+	errUnexpectedEnd       = errors.New("simple json: unexpected end of array or object")
+	errLineNotEmpty        = errors.New("simple json: non-whitespace found before newline")
+	errControlChar         = errors.New("simple json: control character, tab, or newline in string value")
+	errUnterminatedComment = errors.New("simple json: unterminated comment")
+	errTruncatedHex        = errors.New(
 		"simple json: expected a unicode hexadecimal codepoint but json is truncated",
 	)
-	errMaxDepth = errors.New("simple json: maximum nesting depth exceeded")
 )
 
+// DepthError is returned when values are nested more deeply than the parser
+// allows.
+type DepthError struct {
+	// Depth is the nesting depth of the value that exceeded the limit, where
+	// the top-level value is at depth 0.
+	Depth int
+	// Offset is the position of that value in the input, in bytes.
+	Offset int64
+}
+
+func (e *DepthError) Error() string {
+	return fmt.Sprintf("simple json: maximum nesting depth exceeded: depth %d at offset %d", e.Depth, e.Offset)
+}
+
+// A PanicError is returned in place of a panic while parsing, which would be a
+// bug in this package, so that no input can bring down the program. The
+// Parser must not be used again afterwards.
+type PanicError struct {
+	// Value is the value the code panicked with.
+	Value any
+	// Stack is the stack trace of the panic, for reporting the bug.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("simple json: internal error: %v", e.Value)
+}
+
+// Unwrap returns Value if it is an error, such as a runtime.Error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// Turns a panic into a *PanicError in *err. This must be deferred directly.
+func recoverPanic(err *error) {
+	if r := recover(); r != nil {
+		*err = &PanicError{Value: r, Stack: debug.Stack()}
+	}
+}
+
+func errDuplicateKey(key string) error {
+	return fmt.Errorf("simple json: duplicate object key %q", key)
+}
+
+// Returns what a repeated key has for its value once val is added, for
+// DuplicateKeysCollect, given what it had. collected holds the keys whose
+// values are already gathered, and is made when first needed.
+func collectDuplicate(collected *map[string]bool, key string, existing, val any) any {
+	if (*collected)[key] {
+		return append(existing.([]any), val)
+	}
+	if *collected == nil {
+		*collected = make(map[string]bool)
+	}
+	(*collected)[key] = true
+	return []any{existing, val}
+}
+
 type Parser interface {
 	// Parse JSON from the front of the contained data as a simply-typed value
 	// and return it. If the data is empty, the exact error io.EOF will be
 	// returned.
 	Parse() (any, error)
 	// ParseObject parses JSON from the front of the contained data as a
-	// simply-typed JSON object and return it. If the JSON is a value of a type
-	// other than object, an error will be returned. If the data is empty, the
-	// exact error io.EOF will be returned.
+	// simply-typed JSON object and return it. If the JSON is a valid value of
+	// a type other than object, it is consumed and a *NotObjectError returned.
+	// If the data is empty, the exact error io.EOF will be returned.
+	//
+	// The top-level object is always returned as a map, even when the parser
+	// produces ordered objects; use ParseOrderedObject to keep its key order.
 	ParseObject() (map[string]any, error)
+	// ParseOrderedObject is like ParseObject, but returns the object with its
+	// keys in the order they appeared.
+	ParseOrderedObject() (*OrderedObject, error)
+	// ParseFloatArray parses an array of numbers from the front of the
+	// contained data straight into a []float64, which is much cheaper than
+	// Parse for large arrays. Numbers are read just as Parse reads them, and
+	// integers converted to float64; non-finite numbers are kept unless the
+	// DeNaNPolicy is DeNaNError. An element that is not a number is an error
+	// naming its index. If the value is not an array, it is consumed and an
+	// error returned. If the data is empty, the exact error io.EOF will be
+	// returned.
+	ParseFloatArray() ([]float64, error)
+	// Skip consumes the next value from the front of the contained data,
+	// checking that it is well formed without building it. If the data is
+	// empty, the exact error io.EOF will be returned. Duplicate object keys
+	// are not detected, whatever the parser's DuplicateKeyPolicy.
+	Skip() error
+	// ReadRaw consumes the next value like Skip, returning its exact bytes,
+	// including any whitespace inside it but none around it. For a parser
+	// made from a slice or string the bytes alias the input, and must not be
+	// modified; otherwise they are a new copy. If the data is empty, the exact
+	// error io.EOF will be returned.
+	ReadRaw() ([]byte, error)
+	// ParseInArena is like Parse, but allocates the strings and arrays of the
+	// value from the given Arena. The value is only valid until the Arena is
+	// reset.
+	ParseInArena(a *Arena) (any, error)
+	// Visit parses the next value from the front of the contained data,
+	// passing each of its parts to v in order rather than building it. If the
+	// data is empty, the exact error io.EOF will be returned; if v returns an
+	// error, parsing stops and that error is returned.
+	Visit(v Visitor) error
+	// StdToken returns the next token of the contained data in the vocabulary
+	// of json.Decoder.Token, so that code written against that can read
+	// extended JSON: json.Delim for the brackets and braces of arrays and
+	// objects, string for object keys and strings, float64 for all numbers,
+	// including NaN and infinities, bool, and nil for null. Commas and colons
+	// are consumed silently. At the end of the data, the exact error io.EOF
+	// will be returned.
+	//
+	// Integers are converted to float64 just as encoding/json would, so those
+	// beyond ±2^53 lose precision. Calls to StdToken should not be mixed with
+	// the other parsing methods except between top-level values.
+	StdToken() (json.Token, error)
+	// More reports whether there is another element in the array or object
+	// that StdToken is reading, or another top-level value.
+	More() bool
+	// ParseInto is like UnmarshalInto, but decodes the next value from the
+	// front of the contained data. If the data is empty, the exact error
+	// io.EOF will be returned.
+	ParseInto(dest any) error
 	// NextLine consumes whitespace up to the next newline, returning an error
 	// if something other than whitespace exists before the next newline, or
 	// returning the exact error io.EOF if the end of data is found first. This
@@ -90,6 +218,10 @@ type Parser interface {
 	// be returned but there will be a "not empty" error. If the data is empty,
 	// the exact error io.EOF will be returned.
 	UnmarshalFull() (any, error)
+	// InputOffset returns the number of bytes of input read so far, not
+	// counting any that are buffered but not yet parsed. When the input is
+	// transcoded by WithEncodingDetection, it counts bytes of UTF-8.
+	InputOffset() int64
 	// Reset the parser with a new io.Reader.
 	Reset(io.Reader)
 	// ResetSlice resets the parser with a new byte slice.
@@ -103,64 +235,153 @@ type parser struct {
 	// the existing in-memory slice we are parsing if we do not have a Reader.
 	// This allows us to parse from a generic io.Reader just as easily as from
 	// a single in-memory slice or string.
-	readBuf []byte
-	strBuf  bytes.Buffer // buffer used for building strings
-	reader  io.Reader    // reader to load bytes from
-	begin   int          // position of first unread byte in readBuf
-	size    int          // position after the last byte written in readBuf
+	readBuf    []byte
+	buf        []byte            // our own read buffer, kept while parsing slices
+	strBuf     bytes.Buffer      // scratch for unescaped strings and long numbers, kept across values
+	reader     io.Reader         // reader to load bytes from
+	begin      int               // position of first unread byte in readBuf
+	size       int               // position after the last byte written in readBuf
+	cfg        parseConfig       // optional behaviors, retained across resets
+	keys       map[string]string // interned object keys, retained across resets
+	elems      []any             // stack of elements of the arrays being parsed
+	arena      *Arena            // where to allocate values, if anywhere
+	tokens     []byte            // open brackets and braces of the containers StdToken is in
+	tokenState tokenState        // what StdToken expects next
+	strs       []byte            // unused end of the chunk that unsafe strings are copied into
+	// While capturing, the bytes of readBuf from captureStart on are added to
+	// captured before it is refilled.
+	capturing    bool
+	captureStart int
+	captured     []byte
+	consumed     int64  // bytes of input before readBuf
+	inputCut     bool   // whether readBuf stops at MaxInputBytes
+	fixed        []byte // scratch for strings with invalid UTF-8 replaced
+	// Whether to skip a prefix given by WithPrefixSkip at the start of the
+	// next read
+	prefixPending bool
+	// The offset last reported to WithProgress, and whether it was the end of
+	// the input
+	progressLast int64
+	progressDone bool
+	// For EstimateDecodedSize, kept here so that it is not allocated
+	sizeVisitor decodedSizeVisitor
 }
 
 // NewParser creates a new parser that parses the given reader.
-func NewParser(r io.Reader) Parser {
-	return &parser{readBuf: make([]byte, readBufferSize), reader: r}
+func NewParser(r io.Reader, opts ...ParseOption) Parser {
+	buf := make([]byte, readBufferSize)
+	p := &parser{readBuf: buf, buf: buf, reader: r}
+	p.cfg.apply(opts)
+	p.detectEncoding()
+	p.skipPrefix()
+	p.limitInput()
+	return p
 }
 
 // NewParserFromSlice creates a new parser for the given slice.
-func NewParserFromSlice(data []byte) Parser {
-	return &parser{readBuf: data, size: len(data)}
+func NewParserFromSlice(data []byte, opts ...ParseOption) Parser {
+	p := &parser{readBuf: data, size: len(data)}
+	p.cfg.apply(opts)
+	p.detectEncoding()
+	p.skipPrefix()
+	p.limitInput()
+	return p
 }
 
 // NewParserFromString creates a new parser for the given string.
-func NewParserFromString(data string) Parser {
+func NewParserFromString(data string, opts ...ParseOption) Parser {
 	// We unsafe-cast the string to a byte slice because we are confident that
 	// nothing in our call stack will ever modify the referenced bytes.
-	return &parser{
+	p := &parser{
 		readBuf: unsafe.Slice(unsafe.StringData(data), len(data)),
 		size:    len(data),
 	}
+	p.cfg.apply(opts)
+	p.detectEncoding()
+	p.skipPrefix()
+	p.limitInput()
+	return p
 }
 
 func (p *parser) Reset(r io.Reader) {
-	if p.reader == nil {
+	if p.buf == nil {
 		// Big brain: Allocate a read buffer only if we don't already have one
-		p.readBuf = make([]byte, readBufferSize)
+		p.buf = make([]byte, readBufferSize)
 	}
+	p.readBuf = p.buf
 	p.reader = r
 	p.begin = 0
+	p.consumed = 0
+	p.progressLast, p.progressDone = 0, false
 	p.size = 0
-	if p.strBuf.Cap() > oversizedBuffer {
-		p.strBuf = bytes.Buffer{}
-	}
+	p.resetTokens()
+	p.releaseOversized()
+	p.detectEncoding()
+	p.skipPrefix()
+	p.limitInput()
 }
 
 func (p *parser) ResetSlice(data []byte) {
 	p.reader = nil
 	p.readBuf = data
 	p.begin = 0
+	p.consumed = 0
+	p.progressLast, p.progressDone = 0, false
 	p.size = len(data)
-	if p.strBuf.Cap() > oversizedBuffer {
-		p.strBuf = bytes.Buffer{}
-	}
+	p.resetTokens()
+	p.releaseOversized()
+	p.detectEncoding()
+	p.skipPrefix()
+	p.limitInput()
 }
 
 func (p *parser) ResetString(data string) {
 	p.reader = nil
 	p.readBuf = unsafe.Slice(unsafe.StringData(data), len(data))
 	p.begin = 0
+	p.consumed = 0
+	p.progressLast, p.progressDone = 0, false
 	p.size = len(data)
+	p.resetTokens()
+	p.releaseOversized()
+	p.detectEncoding()
+	p.skipPrefix()
+	p.limitInput()
+}
+
+// The whitespace characters allowed between tokens.
+var spaceTable = [256]bool{' ': true, '\t': true, '\n': true, '\r': true}
+
+// The ASCII letters and digits.
+var wordTable = func() (t [256]bool) {
+	for ch := '0'; ch <= '9'; ch++ {
+		t[ch] = true
+	}
+	for ch := 'a'; ch <= 'z'; ch++ {
+		t[ch] = true
+		t[ch-'a'+'A'] = true
+	}
+	return
+}()
+
+// The characters that may follow a number with WithStrictNumberEnds.
+var numberEndTable = [256]bool{
+	' ': true, '\t': true, '\n': true, '\r': true,
+	',': true, ']': true, '}': true,
+}
+
+// Drops scratch buffers that have grown large, so that one huge value does not
+// pin their memory for the life of the parser.
+func (p *parser) releaseOversized() {
 	if p.strBuf.Cap() > oversizedBuffer {
 		p.strBuf = bytes.Buffer{}
 	}
+	if cap(p.elems) > oversizedElems {
+		p.elems = nil
+	}
+	if cap(p.fixed) > oversizedBuffer {
+		p.fixed = nil
+	}
 }
 
 var typeTable = [256]byte{
@@ -220,6 +441,44 @@ var numberCharTable = [256]byte{
 	'a': floatNumber,
 }
 
+// Bytes that end the fast scan through a string: the closing quote, the start
+// of an escape, and the control characters that are not allowed in strings.
+var stringSpecialTable = [256]bool{
+	0x00: true, 0x01: true, 0x02: true, 0x03: true, 0x04: true, 0x05: true, 0x06: true, 0x07: true,
+	0x08: true, 0x09: true, 0x0a: true, 0x0b: true, 0x0c: true, 0x0d: true, 0x0e: true, 0x0f: true,
+	0x10: true, 0x11: true, 0x12: true, 0x13: true, 0x14: true, 0x15: true, 0x16: true, 0x17: true,
+	0x18: true, 0x19: true, 0x1a: true, 0x1b: true, 0x1c: true, 0x1d: true, 0x1e: true, 0x1f: true,
+	'"':  true,
+	'\\': true,
+}
+
+// Returns the number of bytes at the start of b that are not in
+// stringSpecialTable, checking eight bytes at a time where possible.
+func ordinaryPrefixLen(b []byte) int {
+	const (
+		ones  = 0x0101010101010101
+		highs = 0x8080808080808080
+	)
+	i := 0
+	for ; i+8 <= len(b); i += 8 {
+		x := binary.LittleEndian.Uint64(b[i:])
+		// Each term has a high bit set in some byte exactly when some byte of
+		// x is, respectively, a control character, a quote, or a backslash.
+		control := (x - 0x20*ones) &^ x
+		quote := x ^ '"'*ones
+		quote = (quote - ones) &^ quote
+		backslash := x ^ '\\'*ones
+		backslash = (backslash - ones) &^ backslash
+		if (control|quote|backslash)&highs != 0 {
+			break
+		}
+	}
+	for i < len(b) && !stringSpecialTable[b[i]] {
+		i++
+	}
+	return i
+}
+
 var escapeTable = [256]byte{
 	'b':  '\b',
 	't':  '\t',
@@ -276,26 +535,21 @@ func parseHexToRune(chunk [4]byte) (r rune, err error) {
 }
 
 func (p *parser) parseType() (t valType, err error) {
-	var chunk []byte
-
 	if err = p.skipSpaces(); err != nil {
 		return
 	}
-
-	chunk, err = p.take()
-	if err != nil {
-		return
+	// After skipping spaces, the next byte is buffered unless we are at the end
+	// of the data.
+	if p.begin >= p.size {
+		return unknownTy, io.EOF
 	}
 
-	t = valType(typeTable[chunk[0]])
-	switch t {
-	case unknownTy:
-		err = fmt.Errorf("simple json: expected token but found '%c'", chunk[0])
-	default:
-		// For all other types we don't consume anything from the stream.
-		p.rewind(len(chunk))
+	// We don't consume anything from the stream.
+	b := p.readBuf[p.begin]
+	t = valType(typeTable[b])
+	if t == unknownTy {
+		err = fmt.Errorf("simple json: expected token but found '%c'", b)
 	}
-
 	return
 }
 
@@ -325,13 +579,100 @@ func (p *parser) parseBool() (v bool, err error) {
 }
 
 func (p *parser) parseNumber() (v any, err error) {
+	view, isFloat, err := p.scanNumberText()
+	if err != nil {
+		return nil, err
+	}
+	if p.cfg.bigIntegers && isFloat && isBigInteger(view) {
+		// Not a float after all, so none of the checks on the range of
+		// floats apply.
+		if err = p.checkBigInteger(view); err != nil {
+			return nil, err
+		}
+		return parseBigInteger(view), nil
+	}
+	i, f, isFloat, err := p.convertNumber(view, isFloat)
+	if err != nil {
+		return nil, err
+	}
+	if isFloat {
+		if p.replacesNaN(f) {
+			return p.deNaNValue(f), nil
+		}
+		return boxFloat(f), nil
+	}
+	return boxInt(i), nil
+}
+
+// Reports whether view, the text of a number that is to be parsed as a
+// float, is an integer too big for an int64.
+func isBigInteger(view []byte) bool {
+	digits := view
+	if len(digits) > 0 && digits[0] == '-' {
+		digits = digits[1:]
+	}
+	for _, ch := range digits {
+		if ch < '0' || ch > '9' {
+			return false
+		}
+	}
+	return len(digits) > 0 && checkPromoteToFloat(view) && !isNegativeZero(view)
+}
+
+// Checks the text of an integer too big for an int64 as convertNumber checks
+// the text of other numbers.
+func (p *parser) checkBigInteger(view []byte) error {
+	if p.cfg.strictNumbers && !isStandardNumber(view) {
+		return fmt.Errorf("simple json: invalid number %q", view)
+	}
+	if p.cfg.strictNumberEnds {
+		return p.checkNumberEnd(view)
+	}
+	return nil
+}
+
+// Parses the text of an integer for WithBigIntegers, as an int64 if it fits
+// after all, having leading zeros, or else as a *big.Int.
+func parseBigInteger(view []byte) any {
+	n, _ := new(big.Int).SetString(string(view), 10)
+	if n.IsInt64() {
+		return boxInt(n.Int64())
+	}
+	return n
+}
+
+// Reports whether the float f is to be replaced by the DeNaN policy.
+func (p *parser) replacesNaN(f float64) bool {
+	return p.cfg.deNaN != DeNaNKeep && (math.IsNaN(f) || math.IsInf(f, 0))
+}
+
+// Returns the replacement for the non-finite number f.
+func (p *parser) deNaNValue(f float64) any {
+	if p.cfg.deNaN == DeNaNToNull {
+		return nil
+	}
+	return deNaN(f)
+}
+
+// Parses a number, returning it as an int64 or, if isFloat, as a float64.
+func (p *parser) scanNumber() (i int64, f float64, isFloat bool, err error) {
+	view, isFloat, err := p.scanNumberText()
+	if err != nil {
+		return
+	}
+	return p.convertNumber(view, isFloat)
+}
+
+// Reads the text of a number, reporting whether it needs to be parsed as a
+// float. The text is only valid until the parser is next used.
+func (p *parser) scanNumberText() (view []byte, isFloat bool, err error) {
 	p.strBuf.Reset()
 	ty := integralNumber // Which kind of number we are parsing
 	buffered := false    // Whether the value we're parsing is buffered
 	var chunk []byte     // Current chunk we are reading
-	// End result of bytes we will parse; either from a single chunk or from the
-	// strBuf buffer
-	var view []byte
+	// The result, view, is the bytes we will parse; either from a single chunk
+	// or from the strBuf buffer
+	start := p.InputOffset()
 
 	chunk, err = p.take()
 	if err != nil {
@@ -358,38 +699,146 @@ ReadingChunks:
 			}
 		}
 		// We've gotten to the end of a chunk without finding a non-number
-		// byte; get a new chunk
+		// byte. When parsing a slice, that is the end of the data, and the
+		// number needs no copying.
+		if !buffered && p.reader == nil && !p.inputCut {
+			view = chunk
+			break ReadingChunks
+		}
+		// Otherwise get a new chunk
 		p.strBuf.Write(chunk)
 		buffered = true
+		if err = p.checkNumberLen(p.strBuf.Len(), start); err != nil {
+			return
+		}
 		chunk, err = p.take()
 		if err != nil {
 			if err == io.EOF {
+				// The data ends with this number
 				view = p.strBuf.Bytes()
+				err = nil
 				break ReadingChunks
 			}
 			return
 		}
 	}
 
-	if ty == floatNumber || checkPromoteToFloat(view) {
+	if err = p.checkNumberLen(len(view), start); err != nil {
+		return nil, false, err
+	}
+	isFloat = ty == floatNumber || checkPromoteToFloat(view) || isNegativeZero(view)
+	return
+}
+
+// Parses the text of a number from scanNumberText.
+func (p *parser) convertNumber(view []byte, isFloat bool) (i int64, f float64, _ bool, err error) {
+	if isFloat {
 		// strconv.ParseFloat will work with both decimal and hexadecimal floats,
 		// but we don't accept some of the characters that are required to spell a
 		// hexadecimal float so effectively we only parse decimal here. We also, via
 		// strconv, accept the symbols "Inf", "Infinity", and "NaN" (and negative
 		// infinities).
-		v, err = strconv.ParseFloat(stringNoCopy(view), 64)
-		if err != nil {
-			if errors.Is(err, strconv.ErrRange) {
-				err = nil // When very big values overflow to infinite, we keep them
+		var ok bool
+		f, ok = parseFloatFast(view)
+		if !ok {
+			f, err = strconv.ParseFloat(stringNoCopy(view), 64)
+			if err != nil {
+				if errors.Is(err, strconv.ErrRange) {
+					err = nil // When very big values overflow to infinite, we keep them
+				}
 			}
 		}
 	} else {
-		return strconv.ParseInt(stringNoCopy(view), 10, 0)
+		i, err = strconv.ParseInt(stringNoCopy(view), 10, 0)
 	}
-	return
+	if err == nil && p.cfg.strictNumbers {
+		if !isStandardNumber(view) {
+			err = fmt.Errorf("simple json: invalid number %q", view)
+		} else if isFloat && math.IsInf(f, 0) {
+			err = fmt.Errorf("simple json: number %q out of range", view)
+		}
+	}
+	if err == nil && p.cfg.strictNumberEnds {
+		err = p.checkNumberEnd(view)
+	}
+	if err == nil && isFloat && p.cfg.deNaN == DeNaNError && (math.IsNaN(f) || math.IsInf(f, 0)) {
+		err = fmt.Errorf("simple json: non-finite number %q", view)
+	}
+	if err == nil && p.cfg.onNumber != nil {
+		p.cfg.onNumber(view, f, isFloat)
+	}
+	return i, f, isFloat, err
+}
+
+// Checks what follows a number for WithStrictNumberEnds. The scanner leaves
+// the byte that ended the number unread, so it is still in the buffer unless
+// the input ended.
+func (p *parser) checkNumberEnd(view []byte) error {
+	if p.begin >= p.size || numberEndTable[p.readBuf[p.begin]] {
+		return nil
+	}
+	if wordTable[p.readBuf[p.begin]] && bytes.ContainsAny(view, "IN") {
+		// NaN or Infinity ran into more letters, as in Infrared; name the
+		// whole word.
+		word := append([]byte(nil), view...)
+		for {
+			chunk, err := p.take()
+			if err != nil {
+				break
+			}
+			n := 0
+			for n < len(chunk) && wordTable[chunk[n]] {
+				n++
+			}
+			word = append(word, chunk[:n]...)
+			if n < len(chunk) {
+				p.rewind(len(chunk) - n)
+				break
+			}
+		}
+		return fmt.Errorf("simple json: invalid token %q", word)
+	}
+	return fmt.Errorf("simple json: expected end of number but found '%c'", p.readBuf[p.begin])
+}
+
+// Reports whether b is a number in the grammar of standard JSON.
+func isStandardNumber(b []byte) bool {
+	i := 0
+	if i < len(b) && b[i] == '-' {
+		i++
+	}
+	digits := func() int {
+		start := i
+		for i < len(b) && b[i] >= '0' && b[i] <= '9' {
+			i++
+		}
+		return i - start
+	}
+	if i < len(b) && b[i] == '0' {
+		i++
+	} else if digits() == 0 {
+		return false
+	}
+	if i < len(b) && b[i] == '.' {
+		i++
+		if digits() == 0 {
+			return false
+		}
+	}
+	if i < len(b) && (b[i] == 'e' || b[i] == 'E') {
+		i++
+		if i < len(b) && (b[i] == '+' || b[i] == '-') {
+			i++
+		}
+		if digits() == 0 {
+			return false
+		}
+	}
+	return i == len(b)
 }
 
 func (p *parser) parseString() (v []byte, err error) {
+	start := p.InputOffset()
 	var chunk []byte
 	chunk, err = p.take()
 	if err != nil {
@@ -414,11 +863,20 @@ func (p *parser) parseString() (v []byte, err error) {
 
 	// Fast path: look for an unescaped string in the read buffer, returning a
 	// slice without copying any data if possible.
-	for pos, b := range chunk {
-		if b == '"' {
+	for pos := ordinaryPrefixLen(chunk); pos < len(chunk); pos++ {
+		b := chunk[pos]
+		if !stringSpecialTable[b] {
+			continue
+		} else if b == '"' {
 			// We reached the end of the string
 			v = chunk[:pos]                   // Value is everything until this quote
 			p.rewind(len(chunk) - len(v) - 1) // consume the string and end quote
+			if err = p.checkStringLen(len(v), start); err != nil {
+				return nil, err
+			}
+			if p.cfg.replaceInvalidUTF8 {
+				v = p.replaceInvalidUTF8(v)
+			}
 			return
 		} else if b == '\\' {
 			// The string has escapes in it; copy what we passed so far into
@@ -448,13 +906,35 @@ func (p *parser) parseString() (v []byte, err error) {
 
 ReadingChunks:
 	for {
+		// Checking the length once per chunk bounds the memory used
+		if err = p.checkStringLen(p.strBuf.Len(), start); err != nil {
+			return nil, err
+		}
 		chunk, err = p.take()
 		if err != nil {
 			return nil, err
 		}
 	ReadingBytes:
-		for pos, b := range chunk {
-			if b < ' ' {
+		for pos := 0; pos < len(chunk); pos++ {
+			b := chunk[pos]
+			if !escaped && !stringSpecialTable[b] {
+				if openSurrogate != 0 {
+					p.strBuf.WriteRune(utf8.RuneError)
+					openSurrogate = 0
+				}
+				// Copy any longer run of ordinary bytes all at once.
+				if pos+1 < len(chunk) && !stringSpecialTable[chunk[pos+1]] {
+					n := 2 + ordinaryPrefixLen(chunk[pos+2:])
+					p.strBuf.Write(chunk[pos : pos+n])
+					pos += n - 1
+					if err = p.checkStringLen(p.strBuf.Len(), start); err != nil {
+						return nil, err
+					}
+				} else {
+					p.strBuf.WriteByte(b)
+				}
+				continue ReadingBytes
+			} else if b < ' ' {
 				return nil, errControlChar
 			} else if escaped {
 				escaped = false
@@ -541,24 +1021,96 @@ ReadingChunks:
 		}
 	}
 
+	if err = p.checkStringLen(p.strBuf.Len(), start); err != nil {
+		return nil, err
+	}
+	if p.cfg.replaceInvalidUTF8 {
+		return p.replaceInvalidUTF8(p.strBuf.Bytes()), nil
+	}
 	return p.strBuf.Bytes(), nil
 }
 
+// Returns v with each byte of invalid UTF-8 replaced by U+FFFD, for
+// WithReplaceInvalidUTF8. Escapes always decode to whole, valid runes, so
+// checking the unescaped string finds the same bytes as checking the input.
+func (p *parser) replaceInvalidUTF8(v []byte) []byte {
+	if utf8.Valid(v) {
+		return v
+	}
+	p.fixed = appendValidUTF8(p.fixed[:0], v)
+	return p.fixed
+}
+
+// Appends v to dst with each byte of invalid UTF-8 replaced by U+FFFD.
+func appendValidUTF8(dst, v []byte) []byte {
+	for len(v) > 0 {
+		r, n := utf8.DecodeRune(v)
+		if r == utf8.RuneError && n == 1 {
+			dst = utf8.AppendRune(dst, utf8.RuneError)
+		} else {
+			dst = append(dst, v[:n]...)
+		}
+		v = v[n:]
+	}
+	return dst
+}
+
+// Returns the deepest nesting allowed in values.
+func (p *parser) maxDepth() int {
+	if p.cfg.maxDepth > 0 {
+		return p.cfg.maxDepth
+	}
+	return maxDepth
+}
+
 func (p *parser) Parse() (val any, err error) {
-	return p.doParse(maxDepth)
+	defer recoverPanic(&err)
+	return p.doParse(p.maxDepth())
 }
 
-func (p *parser) ParseObject() (map[string]any, error) {
-	err := p.skipSpaces()
-	if err != nil {
+func (p *parser) ParseInArena(a *Arena) (val any, err error) {
+	p.arena = a
+	defer func() { p.arena = nil }()
+	defer recoverPanic(&err)
+	return p.doParse(p.maxDepth())
+}
+
+func (p *parser) ParseObject() (obj map[string]any, err error) {
+	defer recoverPanic(&err)
+	if err := p.expectObject(); err != nil {
 		return nil, err
 	}
-	return p.doParseObject(maxDepth)
+	return p.doParseObject(p.maxDepth())
+}
+
+func (p *parser) ParseOrderedObject() (obj *OrderedObject, err error) {
+	defer recoverPanic(&err)
+	if err := p.expectObject(); err != nil {
+		return nil, err
+	}
+	return p.doParseOrderedObject(p.maxDepth())
+}
+
+// Checks that the next value is an object. If it is some other valid value,
+// it is consumed and a *NotObjectError returned.
+func (p *parser) expectObject() error {
+	ty, err := p.parseType()
+	if err != nil || ty == objectTy {
+		return err
+	}
+	if kind := kindOf(ty); kind != KindInvalid {
+		if err = p.Skip(); err != nil {
+			return err
+		}
+		return &NotObjectError{Found: kind}
+	}
+	// A stray comma or closing bracket
+	return p.readByte('{')
 }
 
 func (p *parser) doParse(remainingDepth int) (val any, err error) {
 	if remainingDepth < 0 {
-		return nil, errMaxDepth
+		return nil, p.depthError(remainingDepth)
 	}
 	var ty valType
 	ty, err = p.parseType()
@@ -571,17 +1123,34 @@ func (p *parser) doParse(remainingDepth int) (val any, err error) {
 	case boolTy:
 		val, err = p.parseBool()
 	case numberTy:
-		val, err = p.parseNumber()
+		if p.cfg.exactNumbers {
+			val, err = p.parseNumberText()
+		} else {
+			val, err = p.parseNumber()
+		}
+		if s, ok := val.(string); ok && p.cfg.byteStrings {
+			val = []byte(s) // from WithDeNaN
+		}
 	case stringTy:
 		var str []byte
-		str, err = p.parseString()
-		// After reading strings, we always copy the bytes out as they may not
-		// refer to bytes in the original buffer.
-		val = string(str)
+		if p.cfg.rawStrings {
+			str, err = p.parseRawString()
+		} else {
+			str, err = p.parseString()
+		}
+		if p.cfg.byteStrings {
+			val = p.makeBytes(str)
+		} else {
+			val = p.makeString(str)
+		}
 	case arrayTy:
 		val, err = p.doParseArray(remainingDepth)
 	case objectTy:
-		val, err = p.doParseObject(remainingDepth)
+		if p.cfg.orderedObjects {
+			val, err = p.doParseOrderedObject(remainingDepth)
+		} else {
+			val, err = p.doParseObject(remainingDepth)
+		}
 	case commaSym:
 		return nil, errUnexpectedComma
 	case endGroupSym:
@@ -595,12 +1164,51 @@ func (p *parser) doParse(remainingDepth int) (val any, err error) {
 	return
 }
 
+func (p *parser) Skip() (err error) {
+	defer recoverPanic(&err)
+	return p.doVisit(p.maxDepth(), NopVisitor{})
+}
+
+func (p *parser) ReadRaw() (raw []byte, err error) {
+	defer recoverPanic(&err)
+	return p.readRaw()
+}
+
+func (p *parser) Visit(v Visitor) error {
+	return p.doVisit(p.maxDepth(), v)
+}
+
 func (p *parser) doParseArray(remainingDepth int) (arr []any, err error) {
 	// Consume the opening bracket
 	err = p.readByte('[')
 	if err != nil {
 		return
 	}
+	// Elements are gathered on the parser's stack, which is shared with any
+	// nested arrays, and copied out once we know how many there are. This
+	// way the scratch space is reused and the result is exactly sized,
+	// without ever trusting the input to say how big an array will be.
+	base := len(p.elems)
+	defer func() {
+		n := len(p.elems) - base
+		if n > 0 && err == nil {
+			if base == 0 && cap(p.elems) > oversizedElems {
+				// The stack holds just this array, and is too big to keep
+				// for reuse anyway: hand it over rather than copying it.
+				arr = p.elems[:n:n]
+				p.elems = nil
+				return
+			}
+			if p.arena != nil {
+				arr = p.arena.array(n)
+			} else {
+				arr = make([]any, n)
+			}
+			copy(arr, p.elems[base:])
+		}
+		clear(p.elems[base:]) // don't keep parsed values alive
+		p.elems = p.elems[:base]
+	}()
 	for {
 		var ty valType
 		ty, err = p.parseType()
@@ -610,12 +1218,8 @@ func (p *parser) doParseArray(remainingDepth int) (arr []any, err error) {
 		if ty == endGroupSym {
 			// Found an ending brace/bracket immediately after the start of
 			// the array or one of its values, cleanly ending the array
-			err = p.readByte(']')
-			if err != nil {
-				return
-			}
-			break
-		} else if len(arr) == 0 {
+			return nil, p.readByte(']')
+		} else if len(p.elems) == base {
 			if ty == commaSym {
 				// Found a comma with no previous value
 				return nil, errUnexpectedComma
@@ -623,30 +1227,109 @@ func (p *parser) doParseArray(remainingDepth int) (arr []any, err error) {
 		} else {
 			// We just read a value and the array hasn't ended. We MUST find
 			// a comma next, and we have already skipped whitespace.
-			err = p.readByte(',')
+			err = p.consumeComma(ty)
 			if err != nil {
 				return
 			}
 		}
 		// We now have a regular following value, not an errant comma or the
 		// end of the array.
+		if err = p.checkCount(len(p.elems)-base, false); err != nil {
+			return
+		}
 		var arrVal any
 		arrVal, err = p.doParse(remainingDepth - 1)
 		if err != nil {
 			return
 		}
-		arr = append(arr, arrVal)
+		p.elems = append(p.elems, arrVal)
 	}
-	return
 }
 
 func (p *parser) doParseObject(remainingDepth int) (obj map[string]any, err error) {
+	// Even an empty object gets a map, as with encoding/json, so that callers
+	// can add to it.
+	obj = make(map[string]any)
+	var collected map[string]bool
+	err = p.parseMembers(remainingDepth, func(key string, val any) error {
+		if p.cfg.duplicateKeys != DuplicateKeysLastWins {
+			if existing, found := obj[key]; found {
+				switch p.cfg.duplicateKeys {
+				case DuplicateKeysError:
+					return errDuplicateKey(key)
+				case DuplicateKeysCollect:
+					obj[key] = collectDuplicate(&collected, key, existing, val)
+				}
+				return nil // first wins, unless collected
+			}
+		}
+		obj[key] = val
+		return nil
+	})
+	if err != nil {
+		obj = nil
+	}
+	return
+}
+
+// Ordered objects with more members than this keep a map of their keys while
+// they are parsed, so that finding duplicates does not take quadratic time.
+const maxOrderedSearch = 8
+
+func (p *parser) doParseOrderedObject(remainingDepth int) (obj *OrderedObject, err error) {
+	obj = &OrderedObject{}
+	var collected map[string]bool
+	// The index of each key, once there are too many members to search them
+	// all for every key.
+	var byKey map[string]int
+	err = p.parseMembers(remainingDepth, func(key string, val any) error {
+		i := -1
+		if byKey == nil {
+			i = obj.index(key)
+		} else if j, ok := byKey[key]; ok {
+			i = j
+		}
+		if i >= 0 {
+			switch p.cfg.duplicateKeys {
+			case DuplicateKeysError:
+				return errDuplicateKey(key)
+			case DuplicateKeysFirstWins:
+				// keep the existing value
+			case DuplicateKeysCollect:
+				obj.Members[i].Value = collectDuplicate(&collected, key, obj.Members[i].Value, val)
+			default:
+				// The key keeps the position where it first appeared
+				obj.Members[i].Value = val
+			}
+			return nil
+		}
+		obj.Members = append(obj.Members, Member{Key: key, Value: val})
+		if byKey != nil {
+			byKey[key] = len(obj.Members) - 1
+		} else if len(obj.Members) > maxOrderedSearch {
+			byKey = make(map[string]int, 2*len(obj.Members))
+			for i := range obj.Members {
+				byKey[obj.Members[i].Key] = i
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		obj = nil
+	}
+	return
+}
+
+// Parses an object from the data, calling add with each of its keys and values
+// in the order they appear.
+func (p *parser) parseMembers(remainingDepth int, add func(key string, val any) error) (err error) {
 	// Consume the beginning of the object
 	err = p.readByte('{')
 	if err != nil {
-		return nil, err
+		return err
 	}
-	for {
+	first := true
+	for n := 0; ; n++ {
 		var ty valType
 		ty, err = p.parseType()
 		if err != nil {
@@ -655,22 +1338,17 @@ func (p *parser) doParseObject(remainingDepth int) (obj map[string]any, err erro
 		if ty == endGroupSym {
 			// Found an ending brace/bracket immediately after the start of
 			// the object or one of its items, cleanly ending the object
-			err = p.readByte('}')
-			if err != nil {
-				return
-			}
-			break
-		} else if obj == nil {
+			return p.readByte('}')
+		} else if first {
 			if ty == commaSym {
 				// Found a comma with no previous value
-				return nil, errUnexpectedComma
+				return errUnexpectedComma
 			}
-			// Initialize the object's map
-			obj = make(map[string]any)
+			first = false
 		} else {
 			// We just parsed an item and the object hasn't ended. We MUST
 			// find a comma next, and we have already skipped whitespace.
-			err = p.readByte(',')
+			err = p.consumeComma(ty)
 			if err != nil {
 				return
 			}
@@ -683,12 +1361,18 @@ func (p *parser) doParseObject(remainingDepth int) (obj map[string]any, err erro
 		if err != nil {
 			return
 		}
+		if err = p.checkCount(n, true); err != nil {
+			return
+		}
 		// Read the map key, which MUST be a string.
 		objKeyBytes, err = p.parseString()
 		if err != nil {
 			return
 		}
-		objKey := string(objKeyBytes)
+		objKey := p.makeKey(objKeyBytes)
+		if p.cfg.keyFunc != nil {
+			objKey = p.cfg.keyFunc(objKey)
+		}
 		// Consume the ':' separating the key and value
 		err = p.skipSpaces()
 		if err != nil {
@@ -699,13 +1383,27 @@ func (p *parser) doParseObject(remainingDepth int) (obj map[string]any, err erro
 			return
 		}
 		// Read the value, which may be of any type.
+		measure := p.cfg.memberSizes != nil && remainingDepth == p.maxDepth()
+		var start int64
+		if measure {
+			if err = p.skipSpaces(); err != nil {
+				return
+			}
+			start = p.InputOffset()
+		}
 		objVal, err = p.doParse(remainingDepth - 1)
 		if err != nil {
 			return
 		}
-		obj[objKey] = objVal
+		if measure {
+			if err = p.cfg.memberSizes(objKey, p.InputOffset()-start); err != nil {
+				return
+			}
+		}
+		if err = add(objKey, objVal); err != nil {
+			return
+		}
 	}
-	return
 }
 
 func (p *parser) NextLine() (err error) {
@@ -786,7 +1484,7 @@ func (p *parser) CheckEmpty() error {
 	// (begin == size) unless non-whitespace characters were found later in the
 	// data.
 	if p.begin < p.size {
-		return errBufferNotEmpty
+		return ErrTrailingData
 	}
 	return nil
 }
@@ -818,10 +1516,38 @@ func (p *parser) take() (buf []byte, err error) {
 
 // Fetches the next chunk into the parser. You don't want to call this function.
 func (p *parser) refreshInternal() (buf []byte, err error) {
+	if p.inputCut {
+		return nil, p.inputLimitError()
+	}
 	if p.reader == nil {
+		if p.cfg.progress != nil {
+			if err = p.reportProgress(p.consumed+int64(p.size), true); err != nil {
+				return nil, err
+			}
+		}
 		return nil, io.EOF
 	}
+	if p.capturing {
+		p.captured = append(p.captured, p.readBuf[p.captureStart:p.size]...)
+		p.captureStart = 0
+	}
+	p.consumed += int64(p.size)
 	p.size, err = io.ReadFull(p.reader, p.readBuf)
+	if p.prefixPending {
+		p.skipPrefixRead()
+		if p.size == 0 {
+			err = io.EOF
+		}
+	}
+	if p.cfg.progress != nil {
+		if perr := p.reportProgress(p.consumed, p.size == 0 && err == io.EOF); perr != nil {
+			return nil, perr
+		}
+	}
+	p.limitInput()
+	if p.inputCut && p.size == 0 {
+		return nil, p.inputLimitError()
+	}
 	if p.size > 0 {
 		err = nil
 	} else if err == nil {
@@ -831,6 +1557,18 @@ func (p *parser) refreshInternal() (buf []byte, err error) {
 	return
 }
 
+func (p *parser) InputOffset() int64 {
+	return p.consumed + int64(p.begin)
+}
+
+// Returns the error for a value nested too deeply, which has remainingDepth
+// below zero. The offset reported is that of the value itself rather than of
+// any space before it.
+func (p *parser) depthError(remainingDepth int) error {
+	_ = p.skipSpaces()
+	return &DepthError{Depth: p.maxDepth() - remainingDepth, Offset: p.InputOffset()}
+}
+
 // Puts n bytes from the last call to take() back to be read again by the next
 // call to take().
 func (p *parser) rewind(n int) {
@@ -876,10 +1614,22 @@ func (p *parser) read(fillThis []byte) error {
 	}
 }
 
+// Consumes the comma that must follow a value in an array or object, given the
+// type that parseType just found for the next byte.
+func (p *parser) consumeComma(ty valType) error {
+	if ty == commaSym {
+		// parseType left the comma buffered; no need to look at it again.
+		p.begin++
+		return nil
+	}
+	return p.readByte(',')
+}
+
 // Consumes an exact token from the parser, returning an error if anything else
 // was found.
 func (p *parser) readToken(token []byte) error {
-	actual := make([]byte, len(token))
+	var buf [len(falseBytes)]byte // long enough for any literal token
+	actual := buf[:len(token)]
 	err := p.read(actual)
 	if err != nil {
 		return err
@@ -891,29 +1641,152 @@ func (p *parser) readToken(token []byte) error {
 }
 
 func (p *parser) skipSpaces() (err error) {
-	var chunk []byte
+Scan:
 	for {
-		chunk, err = p.take()
+		// Scan the buffered bytes directly rather than through take() and
+		// rewind(), since this is the hottest loop for pretty-printed data.
+		for i, ch := range p.readBuf[p.begin:p.size] {
+			if !spaceTable[ch] {
+				p.begin += i
+				if ch == '/' && p.cfg.comments {
+					if err = p.skipComment(); err != nil {
+						return
+					}
+					continue Scan
+				}
+				return nil
+			}
+		}
+		p.begin = p.size
+		_, err = p.refreshInternal()
 		if err != nil {
+			p.begin = p.size
 			if err == io.EOF {
 				return nil
 			}
 			return
 		}
-		for offset, ch := range chunk {
-			switch ch {
-			case ' ', '\n', '\t', '\r':
-				// skip
-			default:
-				// Non-whitespace character: give back everything except the
-				// whitespace we saw so far.
-				p.rewind(len(chunk) - offset)
+		p.begin = 0
+	}
+}
+
+// Skips the comment at the front of the data, for WithComments. Line comments
+// end with a newline or the end of the data; block comments must be closed.
+func (p *parser) skipComment() error {
+	if err := p.readByte('/'); err != nil {
+		return err
+	}
+	b, err := p.peekOneByte()
+	if err != nil && err != io.EOF {
+		return err
+	} else if err != nil || (b != '/' && b != '*') {
+		return errors.New("simple json: expected token but found '/'")
+	}
+	p.begin++
+	block := b == '*'
+	star := false // whether the last byte of a block comment was '*'
+	for {
+		chunk, err := p.take()
+		if err == io.EOF && !block {
+			return nil
+		} else if err == io.EOF {
+			return errUnterminatedComment
+		} else if err != nil {
+			return err
+		}
+		for pos, ch := range chunk {
+			if !block && ch == '\n' {
+				p.rewind(len(chunk) - pos)
+				return nil
+			} else if block && star && ch == '/' {
+				p.rewind(len(chunk) - pos - 1)
 				return nil
 			}
+			star = ch == '*'
 		}
 	}
 }
 
+// Returns the string value of bytes returned by parseString. Unless zero-copy
+// strings were requested, we always copy the bytes out, as they may refer to
+// the parser's buffers rather than to the original input.
+func (p *parser) makeString(b []byte) string {
+	if p.mayAlias(b) {
+		return stringNoCopy(b)
+	}
+	return p.copyString(b)
+}
+
+// Returns the value of bytes returned by parseString as a []byte, for
+// WithByteStrings, following the same rules as makeString.
+func (p *parser) makeBytes(b []byte) []byte {
+	if p.mayAlias(b) {
+		// Appending to the value must not overwrite the rest of the input.
+		return b[:len(b):len(b)]
+	}
+	v := make([]byte, len(b))
+	copy(v, b)
+	return v
+}
+
+// Reports whether zero-copy strings were requested and b, from parseString,
+// is part of the in-memory input.
+func (p *parser) mayAlias(b []byte) bool {
+	if (p.cfg.zeroCopyStrings || p.cfg.unsafeStrings) && p.reader == nil && len(b) > 0 {
+		// The bytes are only part of the input if they were not unescaped
+		// into strBuf.
+		start := uintptr(unsafe.Pointer(unsafe.SliceData(p.readBuf)))
+		at := uintptr(unsafe.Pointer(unsafe.SliceData(b)))
+		return at >= start && at < start+uintptr(len(p.readBuf))
+	}
+	return false
+}
+
+// Returns a string with the value of b that does not alias the input.
+func (p *parser) copyString(b []byte) string {
+	if p.arena != nil {
+		return p.arena.string(b)
+	}
+	if p.cfg.unsafeStrings && len(b) > 0 && len(b) <= unsafeStringChunk/4 {
+		// Carve the string from a chunk shared with the strings before it.
+		// Chunks are only ever appended to, so the bytes of a string handed
+		// out never change, and the garbage collector keeps the chunk alive
+		// for as long as any of its strings is.
+		if cap(p.strs) < len(b) {
+			p.strs = make([]byte, 0, unsafeStringChunk)
+		}
+		s := append(p.strs, b...)
+		p.strs = s[len(s):]
+		return unsafe.String(unsafe.SliceData(s), len(s))
+	}
+	return string(b)
+}
+
+// Returns the string value of object key bytes returned by parseString.
+func (p *parser) makeKey(b []byte) string {
+	if !p.cfg.internKeys || len(b) > maxInternedKeyLen {
+		if p.cfg.unsafeStrings {
+			// Maps keep their keys for as long as they like, so keys never
+			// alias the caller's input.
+			return p.copyString(b)
+		}
+		return p.makeString(b)
+	}
+	if key, ok := p.keys[string(b)]; ok {
+		return key
+	}
+	// Interned keys always own their bytes, because the table outlives the
+	// input.
+	key := string(b)
+	if len(p.keys) < maxInternedKeys {
+		if p.keys == nil {
+			p.keys = make(map[string]string)
+		}
+		p.keys[key] = key
+	}
+	return key
+}
+
 // Unsafely casts a byte slice to a string. Only used internally, when we are
 // confident that the bytes will not be modified while the string value is in
 // use.
@@ -924,12 +1797,32 @@ func stringNoCopy(b []byte) (view string) {
 	return unsafe.String(&b[0], len(b))
 }
 
+// Unsafely casts a string to a byte slice. Only used internally, when we are
+// confident that the bytes will never be modified.
+func bytesNoCopy(s string) []byte {
+	return unsafe.Slice(unsafe.StringData(s), len(s))
+}
+
 // []byte("9223372036854775807"), int64_max as text
 var int64MaxTextBytes = []byte(strconv.Itoa(math.MaxInt64))
 
 // []byte("-9223372036854775808"), int64_min as text
 var int64MinTextBytes = []byte(strconv.Itoa(math.MinInt64))
 
+// Reports whether b is an integer that is zero with a minus sign, such as -0,
+// which is parsed as a float64 so that it keeps its sign.
+func isNegativeZero(b []byte) bool {
+	if len(b) < 2 || b[0] != '-' {
+		return false
+	}
+	for _, ch := range b[1:] {
+		if ch != '0' {
+			return false
+		}
+	}
+	return true
+}
+
 func checkPromoteToFloat(b []byte) bool {
 	if len(b) == 0 {
 		return false
@@ -0,0 +1,96 @@
+package simplejsonext
+
+import (
+	"io"
+	"sync"
+)
+
+// Parsers and emitters can be shared through a package-wide pool with
+// GetParser and PutParser, or GetEmitter and PutEmitter, rather than each
+// caller keeping its own sync.Pool.
+//
+// A parser or emitter that is put back keeps only its own internal buffers,
+// and only while they are of modest size. Everything else is dropped: the
+// reader, writer, or input data, any unread input, the options, and the table
+// of interned keys. A value must not be used after it is put back, and
+// nothing parsed from a pooled parser refers to its buffers, except for
+// strings from WithZeroCopyStrings, which refer only to the caller's input.
+
+var (
+	parserPool  = sync.Pool{New: func() any { return &parser{} }}
+	emitterPool = sync.Pool{New: func() any {
+		e := &emitter{}
+		e.s = e.a[:0]
+		return e
+	}}
+)
+
+// GetParser is like NewParser, but takes the parser from the pool.
+func GetParser(r io.Reader, opts ...ParseOption) Parser {
+	p := parserPool.Get().(*parser)
+	p.cfg.apply(opts)
+	p.Reset(r)
+	return p
+}
+
+// GetParserFromSlice is like NewParserFromSlice, but takes the parser from the
+// pool.
+func GetParserFromSlice(data []byte, opts ...ParseOption) Parser {
+	p := parserPool.Get().(*parser)
+	p.cfg.apply(opts)
+	p.ResetSlice(data)
+	return p
+}
+
+// GetParserFromString is like NewParserFromString, but takes the parser from
+// the pool.
+func GetParserFromString(data string, opts ...ParseOption) Parser {
+	p := parserPool.Get().(*parser)
+	p.cfg.apply(opts)
+	p.ResetString(data)
+	return p
+}
+
+// PutParser returns a parser to the pool. It may be any parser created by this
+// package, whether or not it came from GetParser, and must not be used again
+// afterwards.
+func PutParser(p Parser) {
+	pp, ok := p.(*parser)
+	if !ok || pp == nil {
+		return
+	}
+	pp.reader = nil
+	pp.readBuf = nil
+	pp.begin = 0
+	pp.size = 0
+	pp.cfg = parseConfig{}
+	pp.keys = nil
+	pp.strs = nil
+	pp.resetTokens()
+	pp.strBuf.Reset()
+	clear(pp.elems[:cap(pp.elems)])
+	pp.elems = pp.elems[:0]
+	pp.releaseOversized()
+	parserPool.Put(pp)
+}
+
+// GetEmitter is like NewEmitter, but takes the emitter from the pool.
+func GetEmitter(w io.Writer, opts ...EmitOption) Emitter {
+	e := emitterPool.Get().(*emitter)
+	e.Reset(w)
+	e.cfg.apply(opts)
+	return e
+}
+
+// PutEmitter returns an emitter to the pool. It may be any emitter created by
+// this package, whether or not it came from GetEmitter, and must not be used
+// again afterwards.
+func PutEmitter(e Emitter) {
+	ee, ok := e.(*emitter)
+	if !ok || ee == nil {
+		return
+	}
+	ee.Reset(nil)
+	ee.cfg = emitConfig{}
+	emitterPool.Put(ee)
+}
@@ -0,0 +1,87 @@
+package simplejsonext
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPutParserDropsState(t *testing.T) {
+	input := []byte(`{"a": "b"} {"c": 1}`)
+	p := GetParserFromSlice(input, WithKeyInterning(), WithOrderedObjects())
+	_, err := p.Parse()
+	require.NoError(t, err)
+	pp := p.(*parser)
+	require.NotNil(t, pp.keys)
+
+	PutParser(p)
+	assert.Nil(t, pp.reader)
+	assert.Nil(t, pp.readBuf)
+	assert.Zero(t, pp.begin)
+	assert.Zero(t, pp.size)
+	assert.Equal(t, parseConfig{}, pp.cfg)
+	assert.Nil(t, pp.keys)
+	assert.Zero(t, pp.strBuf.Len())
+
+	// A parser from a reader keeps its own read buffer for the next user.
+	p = NewParser(strings.NewReader(`[1, 2] [3`))
+	_, err = p.Parse()
+	require.NoError(t, err)
+	buf := p.(*parser).buf
+	PutParser(p)
+	assert.Equal(t, buf, p.(*parser).buf)
+
+	// Parsers that are not ours are ignored.
+	PutParser(nil)
+	PutEmitter(nil)
+}
+
+func TestPoolsConcurrently(t *testing.T) {
+	var wg sync.WaitGroup
+	for g := 0; g < 16; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				doc := fmt.Sprintf(`{"g": %d, "i": %d, "s": "%s\n"}`, g, i, strings.Repeat("x", i))
+				expected := map[string]any{"g": int64(g), "i": int64(i), "s": strings.Repeat("x", i) + "\n"}
+
+				var p Parser
+				switch i % 3 {
+				case 0:
+					p = GetParser(strings.NewReader(doc), WithKeyInterning())
+				case 1:
+					p = GetParserFromSlice([]byte(doc))
+				default:
+					p = GetParserFromString(doc, WithOrderedObjects())
+				}
+				v, err := p.UnmarshalFull()
+				PutParser(p)
+				if !assert.NoError(t, err) {
+					return
+				}
+				if obj, ok := v.(*OrderedObject); ok {
+					v = obj.ToMap()
+				}
+				assert.Equal(t, expected, v)
+
+				var out bytes.Buffer
+				e := GetEmitter(&out)
+				err = e.Emit(v)
+				PutEmitter(e)
+				if !assert.NoError(t, err) {
+					return
+				}
+				v2, err := Unmarshal(out.Bytes())
+				assert.NoError(t, err)
+				assert.Equal(t, expected, v2)
+			}
+		}(g)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,50 @@
+package simplejsonext
+
+import (
+	"bytes"
+)
+
+var utf8BOM = []byte{0xef, 0xbb, 0xbf}
+
+// Skips any prefix given by WithPrefixSkip at the start of new input. For a
+// reader, this waits for the first read.
+func (p *parser) skipPrefix() {
+	p.prefixPending = false
+	if len(p.cfg.prefixes) == 0 {
+		return
+	}
+	if p.reader != nil {
+		p.prefixPending = true
+		return
+	}
+	p.begin += matchPrefix(p.readBuf[p.begin:p.size], p.cfg.prefixes)
+}
+
+// Skips any prefix at the start of the first chunk read, moving the rest of
+// the chunk down over it.
+func (p *parser) skipPrefixRead() {
+	p.prefixPending = false
+	if n := matchPrefix(p.readBuf[:p.size], p.cfg.prefixes); n > 0 {
+		p.size = copy(p.readBuf, p.readBuf[n:p.size])
+		p.consumed += int64(n)
+	}
+}
+
+// Returns the length of the longest of prefixes that b starts with, including
+// any byte order mark before it, or 0 if there is none.
+func matchPrefix(b []byte, prefixes [][]byte) int {
+	bom := 0
+	if bytes.HasPrefix(b, utf8BOM) {
+		bom = len(utf8BOM)
+	}
+	longest := 0
+	for _, prefix := range prefixes {
+		if len(prefix) > longest && bytes.HasPrefix(b[bom:], prefix) {
+			longest = len(prefix)
+		}
+	}
+	if longest == 0 {
+		return 0
+	}
+	return bom + longest
+}
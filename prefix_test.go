@@ -0,0 +1,139 @@
+package simplejsonext
+
+import (
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Parses doc with the given options from a slice, a string, and a reader one
+// byte at a time, checking that they agree.
+func parseAllWays(t *testing.T, doc string, opts ...ParseOption) (any, error) {
+	t.Helper()
+	v, err := NewParserFromSlice([]byte(doc), opts...).UnmarshalFull()
+	v2, err2 := NewParserFromString(doc, opts...).UnmarshalFull()
+	v3, err3 := NewParser(iotest.OneByteReader(strings.NewReader(doc)), opts...).UnmarshalFull()
+	assert.Equal(t, v, v2, doc)
+	assert.Equal(t, v, v3, doc)
+	assert.Equal(t, err, err2, doc)
+	assert.Equal(t, err, err3, doc)
+	return v, err
+}
+
+func TestWithPrefixSkip(t *testing.T) {
+	for _, prefix := range XSSIPrefixes {
+		for _, doc := range []string{
+			string(prefix) + `{"a": [1, 2]}`,
+			string(prefix) + "\n{\"a\": [1, 2]}\n",
+			string(prefix) + "\r\n  {\"a\": [1, 2]}",
+		} {
+			v, err := parseAllWays(t, doc, WithPrefixSkip())
+			require.NoError(t, err, doc)
+			assert.Equal(t, map[string]any{"a": []any{int64(1), int64(2)}}, v, doc)
+			_, err = parseAllWays(t, doc)
+			assert.Error(t, err, doc)
+		}
+	}
+
+	// The longest matching prefix wins.
+	v, err := parseAllWays(t, ")]}',\n[true]", WithPrefixSkip())
+	require.NoError(t, err)
+	assert.Equal(t, []any{true}, v)
+
+	// Custom prefixes replace the default ones.
+	v, err = parseAllWays(t, "GUARD\n42", WithPrefixSkip([]byte("GUARD")))
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), v)
+	_, err = parseAllWays(t, ")]}'\n42", WithPrefixSkip([]byte("GUARD")))
+	assert.Error(t, err)
+
+	// Input without a prefix is parsed as usual.
+	v, err = parseAllWays(t, `{"x": null}`, WithPrefixSkip())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"x": nil}, v)
+	_, err = parseAllWays(t, ``, WithPrefixSkip())
+	assert.Error(t, err)
+	_, err = parseAllWays(t, `)]}'`, WithPrefixSkip())
+	assert.Error(t, err)
+}
+
+func TestWithPrefixSkipOnlyAtStart(t *testing.T) {
+	// Prefix-like bytes inside the document are left alone.
+	doc := `)]}'` + "\n" + `{"guard": ")]}'", "loop": "while(1);"}`
+	v, err := parseAllWays(t, doc, WithPrefixSkip())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"guard": ")]}'", "loop": "while(1);"}, v)
+
+	// A prefix is not skipped twice, nor before later values.
+	_, err = parseAllWays(t, ")]}'\n)]}'\n1", WithPrefixSkip())
+	assert.Error(t, err)
+	p := NewParserFromString(")]}'\n1\n)]}'\n2", WithPrefixSkip())
+	v, err = p.Parse()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), v)
+	_, err = p.Parse()
+	assert.Error(t, err)
+
+	// Every new input may start with a prefix.
+	p = NewParser(strings.NewReader("while(1);[1]"), WithPrefixSkip())
+	v, err = p.UnmarshalFull()
+	require.NoError(t, err)
+	assert.Equal(t, []any{int64(1)}, v)
+	for _, reset := range []func(string){
+		func(s string) { p.Reset(strings.NewReader(s)) },
+		func(s string) { p.ResetSlice([]byte(s)) },
+		func(s string) { p.ResetString(s) },
+	} {
+		reset("for(;;);[2]")
+		v, err = p.UnmarshalFull()
+		require.NoError(t, err)
+		assert.Equal(t, []any{int64(2)}, v)
+	}
+}
+
+func TestWithPrefixSkipBOM(t *testing.T) {
+	bom := "\xef\xbb\xbf"
+	// A byte order mark before the prefix is skipped with it.
+	v, err := parseAllWays(t, bom+")]}'\n[1]", WithPrefixSkip())
+	require.NoError(t, err)
+	assert.Equal(t, []any{int64(1)}, v)
+	// But not one after it, nor one without a prefix, unless detecting the
+	// encoding.
+	_, err = parseAllWays(t, ")]}'\n"+bom+"[1]", WithPrefixSkip())
+	assert.Error(t, err)
+	_, err = parseAllWays(t, bom+"[1]", WithPrefixSkip())
+	assert.Error(t, err)
+	v, err = parseAllWays(t, bom+"[1]", WithPrefixSkip(), WithEncodingDetection())
+	require.NoError(t, err)
+	assert.Equal(t, []any{int64(1)}, v)
+
+	// With encoding detection, prefixes are matched after transcoding.
+	utf16 := encodeText("\ufeff)]}'\n{\"k\": \"v\"}", encodingUTF16LE)
+	v, err = parseAllWays(t, string(utf16), WithPrefixSkip(), WithEncodingDetection())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"k": "v"}, v)
+}
+
+func TestWithPrefixSkipOffsets(t *testing.T) {
+	// Offsets in errors count the prefix, however the input is read.
+	doc := ")]}'\n[1, 2,]" + strings.Repeat(" ", 2*readBufferSize)
+	for _, p := range []Parser{
+		NewParserFromString(doc, WithPrefixSkip()),
+		NewParser(strings.NewReader(doc), WithPrefixSkip()),
+		NewParser(iotest.OneByteReader(strings.NewReader(doc)), WithPrefixSkip()),
+	} {
+		_, err := p.Parse()
+		assert.Error(t, err)
+		assert.Equal(t, int64(11), p.InputOffset())
+	}
+
+	lim := SafeLimits{MaxInputBytes: 8}
+	_, err := parseAllWays(t, ")]}'\n[1]", WithPrefixSkip(), WithLimits(lim))
+	require.NoError(t, err)
+	_, err = parseAllWays(t, ")]}'\n[12]", WithPrefixSkip(), WithLimits(lim))
+	var limitErr *LimitError
+	assert.ErrorAs(t, err, &limitErr)
+}
@@ -0,0 +1,148 @@
+package simplejsonext
+
+import (
+	"fmt"
+	"sort"
+	"unicode/utf8"
+)
+
+// PreviewEllipsis is appended to strings truncated by Preview.
+const PreviewEllipsis = "…"
+
+// PreviewOptions bounds the size of the value produced by Preview. Zero values
+// mean no limit.
+type PreviewOptions struct {
+	// MaxStringBytes is the longest string kept whole. Longer strings are cut
+	// to at most this many bytes, without splitting a UTF-8 sequence, and
+	// PreviewEllipsis is appended.
+	MaxStringBytes int
+	// MaxArrayElements is the most array elements kept. Any further elements
+	// are replaced by a single "(+K more)" string element.
+	MaxArrayElements int
+	// MaxTotalBytes approximately bounds the marshaled size of the whole
+	// preview. Once it is used up, strings are cut short and the remaining
+	// array elements and object members are replaced by "(+K more)" markers;
+	// the markers themselves may exceed the bound slightly.
+	MaxTotalBytes int
+}
+
+// Preview returns a bounded copy of v, suitable for logging, that keeps the
+// structure of v so that it still marshals to valid extended JSON. Object keys
+// are visited in sorted order so the preview is stable, and objects cut short
+// by MaxTotalBytes gain a "…" key holding their "(+K more)" marker. Types
+// other than the simple JSON types are left as they are.
+//
+// The input is not modified.
+func Preview(v any, opts PreviewOptions) any {
+	p := previewer{opts: &opts, budget: opts.MaxTotalBytes}
+	return p.preview(v)
+}
+
+type previewer struct {
+	opts *PreviewOptions
+	// Remaining bytes of the total budget, if there is one
+	budget int
+}
+
+func (p *previewer) limited() bool {
+	return p.opts.MaxTotalBytes > 0
+}
+
+// Charges n bytes against the total budget, returning false if they don't fit.
+func (p *previewer) spend(n int) bool {
+	if !p.limited() {
+		return true
+	}
+	if n > p.budget {
+		return false
+	}
+	p.budget -= n
+	return true
+}
+
+// Bytes charged for each array element before looking at its value: a comma
+// and enough room for a tiny value. This keeps the preview from filling up
+// with elements that had to be cut down to nothing.
+const previewElementBytes = 4
+
+func moreMarker(n int) string {
+	return fmt.Sprintf("(+%d more)", n)
+}
+
+func (p *previewer) preview(v any) any {
+	switch tv := v.(type) {
+	case string:
+		limit := len(tv)
+		if p.opts.MaxStringBytes > 0 && limit > p.opts.MaxStringBytes {
+			limit = p.opts.MaxStringBytes
+		}
+		if p.limited() && limit+2 > p.budget {
+			limit = max(p.budget-2, 0)
+		}
+		p.spend(limit + 2)
+		if limit < len(tv) {
+			return truncateUTF8(tv, limit) + PreviewEllipsis
+		}
+		return tv
+	case []any:
+		res := make([]any, 0, min(len(tv), max(p.opts.MaxArrayElements, 0)+1))
+		p.spend(2)
+		for i, child := range tv {
+			if (p.opts.MaxArrayElements > 0 && i >= p.opts.MaxArrayElements) ||
+				(p.limited() && !p.spend(previewElementBytes)) {
+				return append(res, moreMarker(len(tv)-i))
+			}
+			res = append(res, p.preview(child))
+		}
+		return res
+	case map[string]any:
+		keys := make([]string, 0, len(tv))
+		for key := range tv {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		res := make(map[string]any, len(tv))
+		p.spend(2)
+		for i, key := range keys {
+			if p.limited() && !p.spend(len(key)+4) {
+				res[PreviewEllipsis] = moreMarker(len(keys) - i)
+				break
+			}
+			res[key] = p.preview(tv[key])
+		}
+		return res
+	case *OrderedObject:
+		if tv == nil {
+			break
+		}
+		res := &OrderedObject{Members: make([]Member, 0, len(tv.Members))}
+		p.spend(2)
+		for i, member := range tv.Members {
+			if p.limited() && !p.spend(len(member.Key)+4) {
+				res.Members = append(res.Members,
+					Member{Key: PreviewEllipsis, Value: moreMarker(len(tv.Members) - i)})
+				break
+			}
+			res.Members = append(res.Members, Member{Key: member.Key, Value: p.preview(member.Value)})
+		}
+		return res
+	default:
+		if p.limited() {
+			if b, err := Marshal(v); err == nil && !p.spend(len(b)) {
+				p.budget = 0
+			}
+		}
+	}
+	return v
+}
+
+// Cuts s to at most n bytes without splitting a UTF-8 sequence.
+func truncateUTF8(s string, n int) string {
+	if n >= len(s) {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
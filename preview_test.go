@@ -0,0 +1,82 @@
+package simplejsonext
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreviewStrings(t *testing.T) {
+	res := Preview([]any{"short", "a much longer string"}, PreviewOptions{MaxStringBytes: 6})
+	assert.Equal(t, []any{"short", "a much…"}, res)
+
+	// "ab" followed by a three-byte rune; cutting at 3 or 4 bytes must back
+	// off to the rune boundary
+	for limit, expected := range map[int]string{2: "ab…", 3: "ab…", 4: "ab…", 5: "ab€…"} {
+		res = Preview("ab€cd", PreviewOptions{MaxStringBytes: limit})
+		assert.Equal(t, expected, res, "limit %d", limit)
+		assert.True(t, utf8.ValidString(res.(string)))
+	}
+}
+
+func TestPreviewArrays(t *testing.T) {
+	tree, err := UnmarshalString(`{"xs": [1, 2, 3, 4, 5], "ys": [1, 2], "nested": [[1, 2, 3]]}`)
+	require.NoError(t, err)
+	res := Preview(tree, PreviewOptions{MaxArrayElements: 2})
+	assert.Equal(t, map[string]any{
+		"xs":     []any{int64(1), int64(2), "(+3 more)"},
+		"ys":     []any{int64(1), int64(2)},
+		"nested": []any{[]any{int64(1), int64(2), "(+1 more)"}},
+	}, res)
+}
+
+func TestPreviewTotalBytes(t *testing.T) {
+	tree, err := UnmarshalString(`{
+		"a": "` + strings.Repeat("x", 100) + `",
+		"b": [` + strings.Repeat(`1, `, 100) + `1],
+		"c": {"d": true}
+	}`)
+	require.NoError(t, err)
+
+	for _, limit := range []int{10, 50, 100, 200, 400} {
+		res := Preview(tree, PreviewOptions{MaxTotalBytes: limit})
+		out, err := MarshalToString(res)
+		require.NoError(t, err)
+		// The output is still valid, and within the limit give or take a
+		// marker or two.
+		_, err = UnmarshalString(out)
+		require.NoError(t, err)
+		assert.LessOrEqual(t, len(out), limit+40, out)
+	}
+
+	res := Preview(tree, PreviewOptions{MaxTotalBytes: 30})
+	assert.Equal(t, map[string]any{
+		"a": strings.Repeat("x", 21) + "…",
+		"…": "(+2 more)",
+	}, res)
+
+	// Everything fits
+	res = Preview(tree, PreviewOptions{MaxTotalBytes: 10000})
+	assert.Equal(t, tree, res)
+}
+
+func TestPreviewCombined(t *testing.T) {
+	tree := []any{strings.Repeat("y", 50), strings.Repeat("z", 50), "a", "b"}
+	res := Preview(tree, PreviewOptions{MaxStringBytes: 10, MaxArrayElements: 3, MaxTotalBytes: 30})
+	assert.Equal(t, []any{
+		strings.Repeat("y", 10) + "…",
+		strings.Repeat("z", 6) + "…",
+		"(+2 more)",
+	}, res)
+}
+
+func TestPreviewOrderedObject(t *testing.T) {
+	tree, err := NewParserFromString(`{"z": "long value", "a": [1, 2, 3]}`, WithOrderedObjects()).Parse()
+	require.NoError(t, err)
+	out, err := MarshalToString(Preview(tree, PreviewOptions{MaxStringBytes: 4, MaxArrayElements: 1}))
+	require.NoError(t, err)
+	assert.Equal(t, `{"z":"long…","a":[1,"(+2 more)"]}`, out)
+}
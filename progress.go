@@ -0,0 +1,26 @@
+package simplejsonext
+
+import (
+	"fmt"
+)
+
+// Calls the WithProgress callback if enough input has been consumed since it
+// was last called, or if the input has ended.
+func (p *parser) reportProgress(offset int64, done bool) (err error) {
+	if done {
+		if p.progressDone && offset == p.progressLast {
+			return nil // already reported
+		}
+	} else if offset-p.progressLast < p.cfg.progressEvery {
+		return nil
+	}
+	p.progressLast = offset
+	p.progressDone = done
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("simple json: progress callback panicked: %v", r)
+		}
+	}()
+	p.cfg.progress(offset)
+	return nil
+}
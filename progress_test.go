@@ -0,0 +1,87 @@
+package simplejsonext
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Returns at most n bytes at a time from r, pausing before each read.
+type slowReader struct {
+	r     io.Reader
+	n     int
+	delay time.Duration
+}
+
+func (s *slowReader) Read(b []byte) (int, error) {
+	time.Sleep(s.delay)
+	if len(b) > s.n {
+		b = b[:s.n]
+	}
+	return s.r.Read(b)
+}
+
+func TestWithProgress(t *testing.T) {
+	doc := "[" + strings.Repeat(`{"k": "some value", "n": 12345},`, 3000) + "null]\n"
+	const every = 10_000
+	var calls []int64
+	p := NewParser(&slowReader{r: strings.NewReader(doc), n: 333, delay: time.Microsecond},
+		WithProgress(func(n int64) { calls = append(calls, n) }, every))
+	v, err := p.UnmarshalFull()
+	require.NoError(t, err)
+	assert.Len(t, v, 3001)
+
+	require.NotEmpty(t, calls)
+	assert.Greater(t, len(calls), len(doc)/every-2)
+	assert.LessOrEqual(t, len(calls), len(doc)/every+1)
+	for i := 1; i < len(calls); i++ {
+		assert.Greater(t, calls[i], calls[i-1])
+		if i < len(calls)-1 {
+			assert.GreaterOrEqual(t, calls[i]-calls[i-1], int64(every))
+		}
+	}
+	assert.Equal(t, int64(len(doc)), calls[len(calls)-1])
+	assert.Equal(t, p.InputOffset(), calls[len(calls)-1])
+
+	// A reset parser reports from the start again, and slices only report
+	// at the end.
+	calls = nil
+	p.ResetString(doc)
+	_, err = p.UnmarshalFull()
+	require.NoError(t, err)
+	assert.Equal(t, []int64{int64(len(doc))}, calls)
+
+	// Concatenated values report the end once.
+	calls = nil
+	p.Reset(strings.NewReader("1 2 3"))
+	for range 3 {
+		_, err = p.Parse()
+		require.NoError(t, err)
+	}
+	_, err = p.Parse()
+	assert.Equal(t, io.EOF, err)
+	_, err = p.Parse()
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, []int64{5}, calls)
+}
+
+func TestWithProgressPanic(t *testing.T) {
+	doc := "[" + strings.Repeat("1,", 5000) + "1]"
+	p := NewParser(strings.NewReader(doc), WithProgress(func(n int64) {
+		if n > 2000 {
+			panic("stop")
+		}
+	}, 1))
+	_, err := p.Parse()
+	assert.EqualError(t, err, "simple json: progress callback panicked: stop")
+	var panicErr *PanicError
+	assert.False(t, errors.As(err, &panicErr))
+
+	_, err = UnmarshalWithOptions([]byte(doc), WithProgress(func(int64) { panic("end") }, 1))
+	assert.EqualError(t, err, "simple json: progress callback panicked: end")
+}
@@ -0,0 +1,80 @@
+package simplejsonext
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+var (
+	_ json.Marshaler   = RawMessage{}
+	_ json.Unmarshaler = &RawMessage{}
+)
+
+// RawMessage is the raw text of a JSON value, like json.RawMessage. When
+// decoded into by UnmarshalInto or Parser.ParseInto, it receives a copy of the
+// exact bytes of the value, which is checked but not decoded, so that it can
+// be decoded later, perhaps as something else. The Emitter writes it
+// verbatim after checking that it holds exactly one value, or null if it is
+// empty.
+//
+// Its MarshalJSON and UnmarshalJSON methods behave like those of
+// json.RawMessage, for use inside data handled by encoding/json.
+type RawMessage []byte
+
+// MarshalJSON returns m, or null if m is empty.
+func (m RawMessage) MarshalJSON() ([]byte, error) {
+	if len(m) == 0 {
+		return nullBytes[:], nil
+	}
+	return m, nil
+}
+
+// UnmarshalJSON sets *m to a copy of b.
+func (m *RawMessage) UnmarshalJSON(b []byte) error {
+	*m = append((*m)[:0], b...)
+	return nil
+}
+
+var rawMessageType = reflect.TypeOf(RawMessage(nil))
+
+// Checks that m holds exactly one value, with nothing but whitespace around
+// it.
+func checkRawMessage(m RawMessage) error {
+	p := &parser{readBuf: m, size: len(m)}
+	if err := p.Skip(); err != nil {
+		return fmt.Errorf("simple json: invalid RawMessage: %w", err)
+	}
+	if err := p.CheckEmpty(); err != nil {
+		return fmt.Errorf("simple json: invalid RawMessage: %w", err)
+	}
+	return nil
+}
+
+// Consumes the next value and returns its bytes, without the whitespace
+// around it. For parsers reading a slice or string the bytes alias it;
+// otherwise they are a new copy.
+func (p *parser) readRaw() (raw []byte, err error) {
+	if err = p.skipSpaces(); err != nil {
+		return
+	}
+	start := p.begin
+	if p.reader == nil {
+		if err = p.Skip(); err != nil {
+			return nil, err
+		}
+		return p.readBuf[start:p.begin], nil
+	}
+	// The read buffer is refilled as the value is consumed, so keep what it
+	// held each time.
+	p.capturing = true
+	p.captureStart = start
+	defer func() {
+		p.capturing = false
+		p.captured = nil
+	}()
+	if err = p.Skip(); err != nil {
+		return nil, err
+	}
+	return append(p.captured, p.readBuf[p.captureStart:p.begin]...), nil
+}
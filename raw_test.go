@@ -0,0 +1,181 @@
+package simplejsonext
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const rawPayload = `{"loss": NaN, "range": [-Infinity, Infinity], "label": "a]b,c}\"d", "n": 1}`
+
+func TestRawMessageEnvelope(t *testing.T) {
+	envelope := `{"type": "metrics", "payload": ` + rawPayload + ` , "seq": 7}`
+	var m map[string]RawMessage
+	require.NoError(t, UnmarshalInto([]byte(envelope), &m))
+	assert.Equal(t, map[string]RawMessage{
+		"type":    RawMessage(`"metrics"`),
+		"payload": RawMessage(rawPayload),
+		"seq":     RawMessage(`7`),
+	}, m)
+
+	// The payload is written back untouched.
+	out, err := Marshal(map[string]any{"payload": m["payload"]})
+	require.NoError(t, err)
+	assert.Equal(t, `{"payload":`+rawPayload+`}`, string(out))
+
+	// It can be decoded later.
+	payload, err := Unmarshal(m["payload"])
+	require.NoError(t, err)
+	assert.Equal(t, "a]b,c}\"d", payload.(map[string]any)["label"])
+}
+
+func TestRawMessageFromReader(t *testing.T) {
+	// Long enough that the value spans several refills of the read buffer.
+	long := `"` + strings.Repeat("x", 3*readBufferSize) + `"`
+	doc := `  [` + rawPayload + `, ` + long + `, [[], {}], null]  `
+	var arr []RawMessage
+	p := NewParser(iotest.OneByteReader(strings.NewReader(doc)))
+	require.NoError(t, p.ParseInto(&arr))
+	require.NoError(t, p.CheckEmpty())
+	assert.Equal(t, []RawMessage{RawMessage(rawPayload), RawMessage(long), RawMessage(`[[], {}]`), RawMessage(`null`)}, arr)
+
+	// A top-level RawMessage takes the whole value without the whitespace
+	// around it.
+	var raw RawMessage
+	p.Reset(strings.NewReader(doc))
+	require.NoError(t, p.ParseInto(&raw))
+	assert.Equal(t, strings.TrimSpace(doc), string(raw))
+
+	// The bytes are copied out of the input.
+	input := []byte(`[1, 2]`)
+	require.NoError(t, UnmarshalInto(input, &raw))
+	input[1] = '9'
+	assert.Equal(t, `[1, 2]`, string(raw))
+}
+
+func TestReadRaw(t *testing.T) {
+	long := `"` + strings.Repeat("[,", 2*readBufferSize) + `"`
+	values := []string{
+		rawPayload,
+		`[ 1 , [2, [3, {"a": [ ]}]] ]`,
+		`"{[,]}"`,
+		long,
+		`NaN`,
+		`-Infinity`,
+		`Infinity`,
+		`-1.5e3`,
+		`{}`,
+		`null`,
+	}
+	doc := " \n" + strings.Join(values, " \t\n") + "\r\n"
+	parsers := map[string]func() Parser{
+		"slice":  func() Parser { return NewParserFromSlice([]byte(doc)) },
+		"string": func() Parser { return NewParserFromString(doc) },
+		"one byte": func() Parser {
+			return NewParser(iotest.OneByteReader(strings.NewReader(doc)))
+		},
+	}
+	for name, mkParser := range parsers {
+		p := mkParser()
+		for _, value := range values {
+			raw, err := p.ReadRaw()
+			require.NoError(t, err, name)
+			assert.Equal(t, value, string(raw), name)
+
+			// The raw bytes decode just like the value. NaN is unequal to
+			// itself, so compare with it replaced.
+			expected, err := UnmarshalString(value)
+			require.NoError(t, err)
+			actual, err := Unmarshal(raw)
+			require.NoError(t, err, name)
+			assert.Equal(t, WalkDeNaN(expected), WalkDeNaN(actual), name)
+		}
+		_, err := p.ReadRaw()
+		assert.Equal(t, io.EOF, err, name)
+	}
+
+	// From a slice the bytes alias the input, otherwise they are copied.
+	input := []byte(`[1, 2]`)
+	raw, err := NewParserFromSlice(input).ReadRaw()
+	require.NoError(t, err)
+	input[1] = '9'
+	assert.Equal(t, `[9, 2]`, string(raw))
+	input = []byte(`[1, 2]`)
+	raw, err = NewParser(bytes.NewReader(input)).ReadRaw()
+	require.NoError(t, err)
+	input[1] = '9'
+	assert.Equal(t, `[1, 2]`, string(raw))
+
+	// Malformed values are errors.
+	for _, bad := range []string{`[1, 2`, `{"a" 1}`, `"abc`, `[1,]`} {
+		_, err := NewParserFromString(bad).ReadRaw()
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestUnmarshalInto(t *testing.T) {
+	var ptr *map[string]any
+	require.NoError(t, UnmarshalInto([]byte(`{"a": [1, 2.5]}`), &ptr))
+	require.NotNil(t, ptr)
+	assert.Equal(t, map[string]any{"a": []any{int64(1), 2.5}}, *ptr)
+	require.NoError(t, UnmarshalInto([]byte(`null`), &ptr))
+	assert.Nil(t, ptr)
+
+	var nested map[string][]*RawMessage
+	require.NoError(t, UnmarshalInto([]byte(`{"x": [true, null], "y": []}`), &nested))
+	assert.Equal(t, map[string][]*RawMessage{"x": {(*RawMessage)(&[]byte{'t', 'r', 'u', 'e'}), nil}, "y": {}}, nested)
+
+	var v any
+	require.NoError(t, UnmarshalInto([]byte(`"s"`), &v))
+	assert.Equal(t, "s", v)
+
+	var m map[string]RawMessage
+	for input, expected := range map[string]string{
+		`[1]`:            "simple json: cannot decode array into map[string]simplejsonext.RawMessage",
+		`{"a": 1,}`:      "simple json: expected '\"' but found '}'",
+		`{"a": [1 2]}`:   "simple json: expected ',' but found '2'",
+		`{"a": 1} 2`:     "simple json: remainder of buffer not empty",
+		`{"a": NaN, "b"`: "EOF",
+	} {
+		assert.EqualError(t, UnmarshalInto([]byte(input), &m), expected, input)
+	}
+	assert.Equal(t, errNotPointer, UnmarshalInto([]byte(`1`), m))
+	var ch chan int
+	assert.EqualError(t, UnmarshalInto([]byte(`1`), &ch), "simple json: cannot decode into unsupported type chan int")
+
+	dupes := NewParserFromString(`{"a": 1, "a": 2}`, WithDuplicateKeys(DuplicateKeysFirstWins))
+	m = nil
+	require.NoError(t, dupes.ParseInto(&m))
+	assert.Equal(t, map[string]RawMessage{"a": RawMessage(`1`)}, m)
+}
+
+func TestEmitRawMessage(t *testing.T) {
+	out, err := Marshal([]any{RawMessage(nil), RawMessage(" [1,\n2] ")})
+	require.NoError(t, err)
+	assert.Equal(t, `[null, [1,`+"\n"+`2] ]`, string(out))
+	assert.Equal(t, len(out), EstimateMarshalSize([]any{RawMessage(nil), RawMessage(" [1,\n2] ")}))
+
+	for _, bad := range []string{`[1,`, `1 2`, `{"a"}`} {
+		_, err = Marshal(RawMessage(bad))
+		assert.ErrorContains(t, err, "simple json: invalid RawMessage", bad)
+		assert.Equal(t, -1, EstimateMarshalSize(RawMessage(bad)))
+	}
+
+	// Inside encoding/json, it behaves like json.RawMessage.
+	type envelope struct {
+		Payload RawMessage `json:"payload"`
+		Empty   RawMessage `json:"empty"`
+	}
+	b, err := json.Marshal(envelope{Payload: RawMessage(`{"a":1}`)})
+	require.NoError(t, err)
+	assert.Equal(t, `{"payload":{"a":1},"empty":null}`, string(b))
+	var env envelope
+	require.NoError(t, json.Unmarshal([]byte(`{"payload": [1, 2]}`), &env))
+	assert.Equal(t, RawMessage(`[1, 2]`), env.Payload)
+}
@@ -0,0 +1,139 @@
+package simplejsonext
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+var (
+	errUnescapedQuote   = errors.New("simple json: escaped string has an unescaped quote")
+	errUnfinishedEscape = errors.New("simple json: escaped string ends inside an escape")
+)
+
+// Checks the text of a string a piece at a time, as parseString would decode
+// it, without decoding it.
+type escapeChecker struct {
+	escaped bool
+	hex     int // number of hex digits of a \u escape read so far, or -1
+	digits  [4]byte
+}
+
+func newEscapeChecker() escapeChecker {
+	return escapeChecker{hex: -1}
+}
+
+// Checks b up to the quote that ends the string, returning its index, or -1
+// if b ends first. On error, the index is that of the offending byte.
+func (c *escapeChecker) scan(b []byte) (int, error) {
+	for pos := 0; pos < len(b); pos++ {
+		ch := b[pos]
+		switch {
+		case c.hex >= 0:
+			c.digits[c.hex] = ch
+			if c.hex++; c.hex == len(c.digits) {
+				if _, err := parseHexToRune(c.digits); err != nil {
+					return pos, err
+				}
+				c.hex = -1
+			}
+		case ch < ' ':
+			return pos, errControlChar
+		case c.escaped:
+			c.escaped = false
+			if ch == 'u' {
+				c.hex = 0
+			} else if escapeTable[ch] == 0 {
+				return pos, fmt.Errorf("simple json: invalid escape %c", ch)
+			}
+		case ch == '\\':
+			c.escaped = true
+		case ch == '"':
+			return pos, nil
+		default:
+			pos += ordinaryPrefixLen(b[pos+1:])
+		}
+	}
+	return -1, nil
+}
+
+// Reads a string for WithRawStrings, checking it as parseString does, and
+// returns its text between the quotes with the escapes left in.
+func (p *parser) parseRawString() (v []byte, err error) {
+	start := p.InputOffset()
+	if err = p.readByte('"'); err != nil {
+		return nil, err
+	}
+	c := newEscapeChecker()
+	buffered := false // whether the text is being gathered in strBuf
+	for {
+		var chunk []byte
+		chunk, err = p.take()
+		if err == io.EOF && c.hex >= 0 {
+			return nil, errTruncatedHex
+		} else if err != nil {
+			return nil, err
+		}
+		end, err := c.scan(chunk)
+		if err != nil {
+			p.rewind(len(chunk) - end)
+			return nil, err
+		}
+		if end >= 0 {
+			p.rewind(len(chunk) - end - 1)
+			v = chunk[:end]
+			if buffered {
+				p.strBuf.Write(v)
+				v = p.strBuf.Bytes()
+			}
+			if err = p.checkStringLen(len(v), start); err != nil {
+				return nil, err
+			}
+			if p.cfg.replaceInvalidUTF8 {
+				v = p.replaceInvalidUTF8(v)
+			}
+			return v, nil
+		}
+		if !buffered {
+			p.strBuf.Reset()
+			buffered = true
+		}
+		p.strBuf.Write(chunk)
+		if err = p.checkStringLen(p.strBuf.Len(), start); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Checks that s can be written between quotes as it is, for
+// WithEscapedStrings.
+func checkEscaped(s string) error {
+	c := newEscapeChecker()
+	end, err := c.scan(bytesNoCopy(s))
+	if err != nil {
+		return err
+	} else if end >= 0 {
+		return errUnescapedQuote
+	} else if c.escaped || c.hex >= 0 {
+		return errUnfinishedEscape
+	}
+	return nil
+}
+
+// Writes a string value, which for WithEscapedStrings is already escaped.
+func (e *emitter) emitStringValue(v string) (err error) {
+	if !e.cfg.escapedStrings {
+		return e.emitString(v)
+	}
+	if v, err = e.validUTF8(v); err != nil {
+		return err
+	}
+	if err = checkEscaped(v); err != nil {
+		return err
+	}
+	s := append(e.s[:0], '"')
+	s = append(s, v...)
+	s = append(s, '"')
+	e.s = s[:0]
+	return e.write(s)
+}
@@ -0,0 +1,99 @@
+package simplejsonext
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithRawStrings(t *testing.T) {
+	const doc = `{"a\u0062": "x\ty\"z\\ \u00e9\ud83d\udca5 \/", "list": ["", "plain", "\n"], "n": 1}`
+	for _, p := range []Parser{
+		NewParserFromString(doc, WithRawStrings()),
+		NewParserFromSlice([]byte(doc), WithRawStrings(), WithZeroCopyStrings()),
+		NewParser(iotest.OneByteReader(strings.NewReader(doc)), WithRawStrings()),
+	} {
+		v, err := p.Parse()
+		require.NoError(t, err)
+		// Keys are decoded; values are not.
+		assert.Equal(t, map[string]any{
+			"ab":   `x\ty\"z\\ \u00e9\ud83d\udca5 \/`,
+			"list": []any{"", "plain", `\n`},
+			"n":    int64(1),
+		}, v)
+	}
+
+	// Ordered objects and byte strings too.
+	v, err := UnmarshalWithOptions([]byte(`[{"k": "\"", "j": "\\"}]`), WithRawStrings(), WithOrderedObjects(), WithByteStrings())
+	require.NoError(t, err)
+	assert.Equal(t, []any{&OrderedObject{Members: []Member{{Key: "k", Value: []byte(`\"`)}, {Key: "j", Value: []byte(`\\`)}}}}, v)
+
+	// Strings longer than the read buffer.
+	long := strings.Repeat(`ab\"c`, readBufferSize)
+	v, err = NewParser(strings.NewReader(`"`+long+`"`), WithRawStrings()).Parse()
+	require.NoError(t, err)
+	assert.Equal(t, long, v)
+
+	// Strings are checked just the same.
+	for _, in := range []string{"\"a\nb\"", `"\x"`, `"\u12"`, `"\u12`, `"abc`, `"ab\"`} {
+		_, err = UnmarshalWithOptions([]byte(in), WithRawStrings())
+		_, expected := Unmarshal([]byte(in))
+		if assert.Error(t, err, in) {
+			assert.Equal(t, expected.Error(), err.Error(), in)
+		}
+	}
+	_, err = UnmarshalWithOptions([]byte(`"`+long+`"`), WithRawStrings(), WithLimits(SafeLimits{MaxStringBytes: 100}))
+	var limitErr *LimitError
+	assert.ErrorAs(t, err, &limitErr)
+}
+
+func TestRawStringsRoundTrip(t *testing.T) {
+	// A proxy reading and writing extended JSON leaves every string as it
+	// was, whatever its escapes.
+	const doc = `{"id":"\u0041\u00e9\ud83d\udca5","path":"a\/b","text":"line\none\ttab \"quoted\" \\","tags":["\u2028","\b\f\r"],"nested":{"k":"v"}}`
+	v, err := UnmarshalWithOptions([]byte(doc), WithRawStrings(), WithOrderedObjects())
+	require.NoError(t, err)
+	out, err := MarshalWithOptions(v, WithEscapedStrings())
+	require.NoError(t, err)
+	assert.Equal(t, doc, string(out))
+
+	// Without WithEscapedStrings, the escapes would be escaped again.
+	out, err = Marshal(v)
+	require.NoError(t, err)
+	assert.Contains(t, string(out), `"a\\/b"`)
+
+	// Keys are escaped as usual, and so are strings written for other types.
+	out, err = MarshalWithOptions(map[string]any{"a\"": []byte(`\"`)}, WithEscapedStrings(), WithBytesAsStrings())
+	require.NoError(t, err)
+	assert.Equal(t, `{"a\"":"\\\""}`, string(out))
+}
+
+func TestWithEscapedStringsErrors(t *testing.T) {
+	for _, test := range []struct {
+		s   string
+		err string
+	}{
+		{`a"b`, errUnescapedQuote.Error()},
+		{`ab\`, errUnfinishedEscape.Error()},
+		{`\u12`, errUnfinishedEscape.Error()},
+		{`\x`, "simple json: invalid escape x"},
+		{`\u12zz`, `simple json: expected a hexadecimal unicode code point but found "12zz"`},
+		{"a\nb", errControlChar.Error()},
+	} {
+		var buf bytes.Buffer
+		err := NewEmitter(&buf, WithEscapedStrings()).Emit([]any{test.s})
+		assert.EqualError(t, err, test.err, test.s)
+	}
+
+	// Invalid UTF-8 is handled as for other strings.
+	out, err := MarshalWithOptions("\xff\\n", WithEscapedStrings(), WithInvalidUTF8(InvalidUTF8Replace))
+	require.NoError(t, err)
+	assert.Equal(t, "\"\ufffd\\n\"", string(out))
+	_, err = MarshalWithOptions("\xff", WithEscapedStrings(), WithInvalidUTF8(InvalidUTF8Fail))
+	var utf8Err *InvalidUTF8Error
+	assert.ErrorAs(t, err, &utf8Err)
+}
@@ -0,0 +1,180 @@
+package simplejsonext
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"maps"
+	"regexp"
+	"strings"
+)
+
+// RedactAction determines what Redact does with the value of a matched key.
+type RedactAction int
+
+const (
+	// RedactPlaceholder replaces the value with RedactOptions.Placeholder.
+	// This is the default.
+	RedactPlaceholder RedactAction = iota
+	// RedactDrop removes the member from its object.
+	RedactDrop
+	// RedactHash replaces the value with "sha256:" followed by the hex SHA-256
+	// of its marshaled form, so that equal secrets can still be correlated.
+	// Objects are marshaled in map iteration order, so only scalars hash
+	// stably.
+	RedactHash
+)
+
+// DefaultRedactPlaceholder is the placeholder used when
+// RedactOptions.Placeholder is empty.
+const DefaultRedactPlaceholder = "[REDACTED]"
+
+// RedactOptions determines which keys Redact matches and what it does with
+// their values. A key matches if it satisfies any of Keys, Prefixes or
+// Pattern.
+type RedactOptions struct {
+	// Keys are matched exactly.
+	Keys []string
+	// Prefixes match any key that starts with one of them.
+	Prefixes []string
+	// Pattern, if not nil, matches any key it matches.
+	Pattern *regexp.Regexp
+	// Action is what happens to the value of a matched key.
+	Action RedactAction
+	// Placeholder replaces values under RedactPlaceholder. If empty,
+	// DefaultRedactPlaceholder is used.
+	Placeholder string
+}
+
+func (o *RedactOptions) matches(key string) bool {
+	for _, k := range o.Keys {
+		if key == k {
+			return true
+		}
+	}
+	for _, prefix := range o.Prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return o.Pattern != nil && o.Pattern.MatchString(key)
+}
+
+// Redact returns a copy of v in which the values of all object members with
+// matching keys, at any depth, are replaced or removed according to opts. It
+// also returns the number of members redacted. Values under a matched key are
+// redacted as a whole and not searched any further.
+//
+// The input is not modified. Objects and arrays containing nothing to redact
+// are shared with the result rather than copied.
+func Redact(v any, opts RedactOptions) (any, int) {
+	r := redactor{opts: &opts}
+	return r.redact(v), r.count
+}
+
+type redactor struct {
+	opts  *RedactOptions
+	count int
+}
+
+func (r *redactor) replacement(v any) any {
+	switch r.opts.Action {
+	case RedactHash:
+		b, err := Marshal(v)
+		if err != nil {
+			// Unmarshalable values get hashed as their type name alone.
+			b = []byte(typeName(v))
+		}
+		sum := sha256.Sum256(b)
+		return "sha256:" + hex.EncodeToString(sum[:])
+	default:
+		if r.opts.Placeholder == "" {
+			return DefaultRedactPlaceholder
+		}
+		return r.opts.Placeholder
+	}
+}
+
+func (r *redactor) redact(v any) any {
+	switch tv := v.(type) {
+	case map[string]any:
+		var res map[string]any // copy made upon the first change
+		for key, child := range tv {
+			var newChild any
+			drop := false
+			if r.opts.matches(key) {
+				r.count++
+				if r.opts.Action == RedactDrop {
+					drop = true
+				} else {
+					newChild = r.replacement(child)
+				}
+			} else {
+				newChild = r.redact(child)
+				if identical(newChild, child) {
+					continue
+				}
+			}
+			if res == nil {
+				res = maps.Clone(tv)
+			}
+			if drop {
+				delete(res, key)
+			} else {
+				res[key] = newChild
+			}
+		}
+		if res != nil {
+			return res
+		}
+	case *OrderedObject:
+		if tv == nil {
+			break
+		}
+		var res *OrderedObject
+		for i, member := range tv.Members {
+			var newChild any
+			drop := false
+			if r.opts.matches(member.Key) {
+				r.count++
+				if r.opts.Action == RedactDrop {
+					drop = true
+				} else {
+					newChild = r.replacement(member.Value)
+				}
+			} else {
+				newChild = r.redact(member.Value)
+				if identical(newChild, member.Value) {
+					if res != nil {
+						res.Members = append(res.Members, member)
+					}
+					continue
+				}
+			}
+			if res == nil {
+				res = &OrderedObject{Members: append(make([]Member, 0, len(tv.Members)), tv.Members[:i]...)}
+			}
+			if !drop {
+				res.Members = append(res.Members, Member{Key: member.Key, Value: newChild})
+			}
+		}
+		if res != nil {
+			return res
+		}
+	case []any:
+		var res []any
+		for i, child := range tv {
+			newChild := r.redact(child)
+			if identical(newChild, child) {
+				continue
+			}
+			if res == nil {
+				res = append([]any(nil), tv...)
+			}
+			res[i] = newChild
+		}
+		if res != nil {
+			return res
+		}
+	}
+	return v
+}
@@ -0,0 +1,126 @@
+package simplejsonext
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const redactDoc = `{
+	"user": "alice",
+	"api_key": "abc123",
+	"auth": {"token": "t0k3n", "scopes": ["read"]},
+	"runs": [
+		{"name": "a", "secret_env": {"AWS_SECRET": "x"}},
+		{"name": "b", "password": "hunter2"}
+	],
+	"public": {"list": [1, 2, 3]}
+}`
+
+func TestRedact(t *testing.T) {
+	tree, err := UnmarshalString(redactDoc)
+	require.NoError(t, err)
+	original, err := UnmarshalString(redactDoc)
+	require.NoError(t, err)
+
+	opts := RedactOptions{
+		Keys:     []string{"api_key", "password"},
+		Prefixes: []string{"secret_"},
+		Pattern:  regexp.MustCompile(`(?i)^token$`),
+	}
+	res, count := Redact(tree, opts)
+	assert.Equal(t, 4, count)
+	assert.Equal(t, map[string]any{
+		"user":    "alice",
+		"api_key": "[REDACTED]",
+		"auth":    map[string]any{"token": "[REDACTED]", "scopes": []any{"read"}},
+		"runs": []any{
+			map[string]any{"name": "a", "secret_env": "[REDACTED]"},
+			map[string]any{"name": "b", "password": "[REDACTED]"},
+		},
+		"public": map[string]any{"list": []any{int64(1), int64(2), int64(3)}},
+	}, res)
+	assert.Equal(t, original, tree, "input must not be modified")
+	assert.Equal(t,
+		reflect.ValueOf(tree.(map[string]any)["public"]).UnsafePointer(),
+		reflect.ValueOf(res.(map[string]any)["public"]).UnsafePointer())
+
+	opts.Action = RedactDrop
+	res, count = Redact(tree, opts)
+	assert.Equal(t, 4, count)
+	assert.Equal(t, map[string]any{
+		"user": "alice",
+		"auth": map[string]any{"scopes": []any{"read"}},
+		"runs": []any{
+			map[string]any{"name": "a"},
+			map[string]any{"name": "b"},
+		},
+		"public": map[string]any{"list": []any{int64(1), int64(2), int64(3)}},
+	}, res)
+	assert.Equal(t, original, tree, "input must not be modified")
+
+	opts.Action = RedactPlaceholder
+	opts.Placeholder = "***"
+	res, _ = Redact(tree, opts)
+	s, err := GetString(res, "runs", 1, "password")
+	require.NoError(t, err)
+	assert.Equal(t, "***", s)
+}
+
+func TestRedactHash(t *testing.T) {
+	tree, err := UnmarshalString(`[{"password": "hunter2"}, {"password": "hunter2"}, {"password": "other"}]`)
+	require.NoError(t, err)
+	res, count := Redact(tree, RedactOptions{Keys: []string{"password"}, Action: RedactHash})
+	assert.Equal(t, 3, count)
+
+	first, err := GetString(res, 0, "password")
+	require.NoError(t, err)
+	second, err := GetString(res, 1, "password")
+	require.NoError(t, err)
+	third, err := GetString(res, 2, "password")
+	require.NoError(t, err)
+	// The hash is of the marshaled value, including the quotes
+	sum := sha256.Sum256([]byte(`"hunter2"`))
+	assert.Equal(t, "sha256:"+hex.EncodeToString(sum[:]), first)
+	assert.Equal(t, first, second)
+	assert.NotEqual(t, first, third)
+}
+
+func TestRedactNoMatchSharesTree(t *testing.T) {
+	tree, err := UnmarshalString(redactDoc)
+	require.NoError(t, err)
+	res, count := Redact(tree, RedactOptions{Keys: []string{"nope"}})
+	assert.Equal(t, 0, count)
+	assert.Equal(t, reflect.ValueOf(tree).UnsafePointer(), reflect.ValueOf(res).UnsafePointer())
+
+	res, count = Redact("scalar", RedactOptions{Keys: []string{"nope"}})
+	assert.Equal(t, 0, count)
+	assert.Equal(t, "scalar", res)
+}
+
+func TestRedactOrderedObject(t *testing.T) {
+	tree, err := NewParserFromString(`{"a": 1, "token": 2, "b": [{"token": 3, "c": 4}], "d": 5}`,
+		WithOrderedObjects()).Parse()
+	require.NoError(t, err)
+
+	res, count := Redact(tree, RedactOptions{Keys: []string{"token"}, Action: RedactDrop})
+	assert.Equal(t, 2, count)
+	out, err := MarshalToString(res)
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":1,"b":[{"c":4}],"d":5}`, out)
+
+	res, count = Redact(tree, RedactOptions{Keys: []string{"token"}})
+	assert.Equal(t, 2, count)
+	out, err = MarshalToString(res)
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":1,"token":"[REDACTED]","b":[{"token":"[REDACTED]","c":4}],"d":5}`, out)
+
+	out, err = MarshalToString(tree)
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":1,"token":2,"b":[{"token":3,"c":4}],"d":5}`, out)
+}
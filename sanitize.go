@@ -0,0 +1,141 @@
+package simplejsonext
+
+import (
+	"fmt"
+	"sort"
+)
+
+// KeyCollisionPolicy determines what SanitizeKeysWithOptions does when two keys
+// of the same object are renamed to the same key.
+type KeyCollisionPolicy int
+
+const (
+	// KeyCollisionSuffix renames colliding keys again by appending "_1", "_2",
+	// and so on until they are unique. This is the default.
+	KeyCollisionSuffix KeyCollisionPolicy = iota
+	// KeyCollisionLastWins keeps only the value of the last of the colliding
+	// keys.
+	KeyCollisionLastWins
+	// KeyCollisionError fails with an error naming the colliding keys.
+	KeyCollisionError
+)
+
+// SanitizeOptions configures SanitizeKeysWithOptions.
+type SanitizeOptions struct {
+	// Rename returns the sanitized form of a key.
+	Rename func(key string) string
+	// Collisions is the policy for keys of the same object that are renamed to
+	// the same key.
+	Collisions KeyCollisionPolicy
+}
+
+// SanitizeKeys applies fn to every object key in v, at every depth, and
+// returns the resulting tree. Keys that collide after renaming are given
+// numbered suffixes, as with KeyCollisionSuffix.
+//
+// Values are never changed, and the input is not modified: objects whose keys
+// all stay the same, and that contain no renamed descendants, are shared with
+// the result rather than copied.
+func SanitizeKeys(v any, fn func(key string) string) any {
+	// The suffix policy cannot fail.
+	res, _ := SanitizeKeysWithOptions(v, SanitizeOptions{Rename: fn})
+	return res
+}
+
+// SanitizeKeysWithOptions is like SanitizeKeys, configured by opts.
+//
+// Within a map, keys that are not changed by renaming are placed first and
+// keep their names; the renamed keys are then placed in sorted order, so the
+// outcome of a collision never depends on map iteration order. Within an
+// ordered object, keys are placed in their existing order.
+func SanitizeKeysWithOptions(v any, opts SanitizeOptions) (any, error) {
+	return Walk(v, func(_ []string, v any) (any, error) {
+		switch tv := v.(type) {
+		case map[string]any:
+			return sanitizeMap(tv, &opts)
+		case *OrderedObject:
+			if tv != nil {
+				return sanitizeOrderedObject(tv, &opts)
+			}
+		}
+		return v, nil
+	})
+}
+
+func sanitizeMap(obj map[string]any, opts *SanitizeOptions) (any, error) {
+	var renamed []string
+	for key := range obj {
+		if opts.Rename(key) != key {
+			renamed = append(renamed, key)
+		}
+	}
+	if renamed == nil {
+		return obj, nil
+	}
+	sort.Strings(renamed)
+
+	res := make(map[string]any, len(obj))
+	for key, val := range obj {
+		res[key] = val
+	}
+	for _, key := range renamed {
+		delete(res, key)
+	}
+	for _, key := range renamed {
+		newKey, err := resolveCollision(opts, key, opts.Rename(key), func(k string) bool {
+			_, taken := res[k]
+			return taken
+		})
+		if err != nil {
+			return nil, err
+		}
+		res[newKey] = obj[key]
+	}
+	return res, nil
+}
+
+func sanitizeOrderedObject(obj *OrderedObject, opts *SanitizeOptions) (any, error) {
+	changed := false
+	for _, member := range obj.Members {
+		if opts.Rename(member.Key) != member.Key {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return obj, nil
+	}
+
+	res := &OrderedObject{Members: make([]Member, 0, len(obj.Members))}
+	for _, member := range obj.Members {
+		newKey, err := resolveCollision(opts, member.Key, opts.Rename(member.Key), func(k string) bool {
+			return res.index(k) >= 0
+		})
+		if err != nil {
+			return nil, err
+		}
+		res.Set(newKey, member.Value)
+	}
+	return res, nil
+}
+
+// Decides the final name of a key that was renamed to newKey, given a function
+// reporting which names are already taken.
+func resolveCollision(opts *SanitizeOptions, key string, newKey string, taken func(string) bool) (string, error) {
+	if !taken(newKey) {
+		return newKey, nil
+	}
+	switch opts.Collisions {
+	case KeyCollisionError:
+		return "", fmt.Errorf("simple json: key %q collides with another key when renamed to %q", key, newKey)
+	case KeyCollisionLastWins:
+		return newKey, nil
+	default:
+		for i := 1; ; i++ {
+			suffixed := fmt.Sprintf("%s_%d", newKey, i)
+			if !taken(suffixed) {
+				return suffixed, nil
+			}
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package simplejsonext
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var unsafeKeyChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+func underscoreKeys(key string) string {
+	return unsafeKeyChars.ReplaceAllString(key, "_")
+}
+
+func TestSanitizeKeys(t *testing.T) {
+	tree, err := UnmarshalString(`{
+		"metric/loss": 0.5,
+		"runs": [{"acc@top-1": 0.9, "ok": true}, {"plain": "acc@top-1"}],
+		"clean": {"nested": [1, 2]}
+	}`)
+	require.NoError(t, err)
+
+	res := SanitizeKeys(tree, underscoreKeys)
+	assert.Equal(t, map[string]any{
+		"metric_loss": 0.5,
+		"runs": []any{
+			map[string]any{"acc_top_1": 0.9, "ok": true},
+			map[string]any{"plain": "acc@top-1"},
+		},
+		"clean": map[string]any{"nested": []any{int64(1), int64(2)}},
+	}, res)
+
+	// Unchanged subtrees are shared, and the input is untouched
+	treeMap, resMap := tree.(map[string]any), res.(map[string]any)
+	assert.Equal(t,
+		reflect.ValueOf(treeMap["clean"]).UnsafePointer(),
+		reflect.ValueOf(resMap["clean"]).UnsafePointer())
+	assert.Equal(t,
+		reflect.ValueOf(treeMap["runs"].([]any)[1]).UnsafePointer(),
+		reflect.ValueOf(resMap["runs"].([]any)[1]).UnsafePointer())
+	assert.Contains(t, treeMap, "metric/loss")
+
+	// Nothing to rename returns the very same tree
+	res = SanitizeKeys(resMap, underscoreKeys)
+	assert.Equal(t, reflect.ValueOf(resMap).UnsafePointer(), reflect.ValueOf(res).UnsafePointer())
+}
+
+func TestSanitizeKeysCollisions(t *testing.T) {
+	tree := map[string]any{"a/b": 1, "a-b": 2, "a_b": 3, "x": map[string]any{"a.b": 4, "a:b": 5}}
+
+	res, err := SanitizeKeysWithOptions(tree, SanitizeOptions{Rename: underscoreKeys})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"a_b": 3, "a_b_1": 2, "a_b_2": 1,
+		"x": map[string]any{"a_b": 4, "a_b_1": 5},
+	}, res)
+
+	res, err = SanitizeKeysWithOptions(tree, SanitizeOptions{
+		Rename:     underscoreKeys,
+		Collisions: KeyCollisionLastWins,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"a_b": 1, "x": map[string]any{"a_b": 5}}, res)
+
+	_, err = SanitizeKeysWithOptions(tree, SanitizeOptions{
+		Rename:     underscoreKeys,
+		Collisions: KeyCollisionError,
+	})
+	var walkErr *WalkError
+	require.ErrorAs(t, err, &walkErr)
+	assert.ErrorContains(t, err, "collides with another key when renamed to \"a_b\"")
+}
+
+func TestSanitizeOrderedObject(t *testing.T) {
+	tree, err := NewParserFromString(`{"b/1": 1, "a": [{"b-1": 2, "b/1": 3}], "b_1": 4}`,
+		WithOrderedObjects()).Parse()
+	require.NoError(t, err)
+
+	out, err := MarshalToString(SanitizeKeys(tree, underscoreKeys))
+	require.NoError(t, err)
+	assert.Equal(t, `{"b_1":1,"a":[{"b_1":2,"b_1_1":3}],"b_1_1":4}`, out)
+
+	res, err := SanitizeKeysWithOptions(tree, SanitizeOptions{
+		Rename:     underscoreKeys,
+		Collisions: KeyCollisionLastWins,
+	})
+	require.NoError(t, err)
+	out, err = MarshalToString(res)
+	require.NoError(t, err)
+	assert.Equal(t, `{"b_1":4,"a":[{"b_1":3}]}`, out)
+
+	_, err = SanitizeKeysWithOptions(tree, SanitizeOptions{
+		Rename:     underscoreKeys,
+		Collisions: KeyCollisionError,
+	})
+	assert.EqualError(t, err, `simple json: walk failed at ["a" "0"]: `+
+		`simple json: key "b/1" collides with another key when renamed to "b_1"`)
+}
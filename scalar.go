@@ -0,0 +1,123 @@
+package simplejsonext
+
+import (
+	"math"
+	"unsafe"
+)
+
+// ScalarKindError is returned by the UnmarshalXxxString functions for scalars
+// when the input holds a valid value, but not of the kind asked for.
+type ScalarKindError struct {
+	// Expected describes what was asked for: "an integer", "a number",
+	// "a string" or "a boolean".
+	Expected string
+	// Found is the kind of value found instead. A number that is not an
+	// integer, when one was asked for, is KindNumber, or KindNonFinite if it
+	// is NaN or an infinity.
+	Found Kind
+}
+
+func (e *ScalarKindError) Error() string {
+	found := e.Found.String()
+	if e.Found == KindNumber && e.Expected == "an integer" {
+		found = "number with a fraction or exponent"
+	}
+	return "simple json: expected " + e.Expected + " but found " + found
+}
+
+// UnmarshalInt64String decodes s, which must hold a single integer that fits
+// in an int64, surrounded by nothing but whitespace. A number with a fraction
+// or exponent, even 1.0 or 1e3, is a *ScalarKindError rather than truncated,
+// as is any other kind of value.
+func UnmarshalInt64String(s string) (i int64, err error) {
+	var p parser
+	defer recoverPanic(&err)
+	if err = p.beginScalar(s, numberTy, "an integer"); err != nil {
+		return 0, err
+	}
+	i, f, isFloat, err := p.scanNumber()
+	if err != nil {
+		return 0, err
+	}
+	if isFloat {
+		found := KindNumber
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			found = KindNonFinite
+		}
+		return 0, &ScalarKindError{Expected: "an integer", Found: found}
+	}
+	return i, p.CheckEmpty()
+}
+
+// UnmarshalFloat64String decodes s, which must hold a single number,
+// including an integer, NaN or an infinity, surrounded by nothing but
+// whitespace. Any other kind of value is a *ScalarKindError.
+func UnmarshalFloat64String(s string) (f float64, err error) {
+	var p parser
+	defer recoverPanic(&err)
+	if err = p.beginScalar(s, numberTy, "a number"); err != nil {
+		return 0, err
+	}
+	i, f, isFloat, err := p.scanNumber()
+	if err != nil {
+		return 0, err
+	}
+	if !isFloat {
+		f = float64(i)
+	}
+	return f, p.CheckEmpty()
+}
+
+// UnmarshalStringString decodes s, which must hold a single JSON string
+// surrounded by nothing but whitespace, returning the string it holds. Any
+// other kind of value is a *ScalarKindError. It is like UnquoteString, but
+// takes a string.
+func UnmarshalStringString(s string) (str string, err error) {
+	var p parser
+	defer recoverPanic(&err)
+	if err = p.beginScalar(s, stringTy, "a string"); err != nil {
+		return "", err
+	}
+	v, err := p.parseString()
+	if err != nil {
+		return "", err
+	}
+	return string(v), p.CheckEmpty()
+}
+
+// UnmarshalBoolString decodes s, which must hold true or false surrounded by
+// nothing but whitespace. Any other kind of value is a *ScalarKindError.
+func UnmarshalBoolString(s string) (b bool, err error) {
+	var p parser
+	defer recoverPanic(&err)
+	if err = p.beginScalar(s, boolTy, "a boolean"); err != nil {
+		return false, err
+	}
+	if b, err = p.parseBool(); err != nil {
+		return false, err
+	}
+	return b, p.CheckEmpty()
+}
+
+// Sets up p to parse s, which must start with a value of type ty. If it is
+// some other valid value, it is consumed and a *ScalarKindError returned.
+func (p *parser) beginScalar(s string, ty valType, expected string) error {
+	p.readBuf = unsafe.Slice(unsafe.StringData(s), len(s))
+	p.size = len(s)
+	p.detectEncoding()
+	p.limitInput()
+	found, err := p.parseType()
+	if err != nil || found == ty {
+		return err
+	}
+	if kind := kindOf(found); kind != KindInvalid {
+		if err = p.Skip(); err != nil {
+			return err
+		}
+		return &ScalarKindError{Expected: expected, Found: kind}
+	}
+	if found == commaSym {
+		return errUnexpectedComma
+	}
+	return errUnexpectedEnd
+}
@@ -0,0 +1,145 @@
+package simplejsonext
+
+import (
+	"errors"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnmarshalScalars(t *testing.T) {
+	for _, test := range []struct {
+		doc string
+		i   int64
+		f   float64
+	}{
+		{"42", 42, 42},
+		{" -7 \n", -7, -7},
+		{"9223372036854775807", math.MaxInt64, math.MaxInt64},
+		{"-9223372036854775808", math.MinInt64, math.MinInt64},
+		{"9007199254740993", 1<<53 + 1, 1<<53 + 1},
+	} {
+		i, err := UnmarshalInt64String(test.doc)
+		require.NoError(t, err, test.doc)
+		assert.Equal(t, test.i, i, test.doc)
+		f, err := UnmarshalFloat64String(test.doc)
+		require.NoError(t, err, test.doc)
+		assert.Equal(t, test.f, f, test.doc)
+	}
+
+	for doc, expected := range map[string]float64{
+		"3.14":      3.14,
+		"1e3":       1000,
+		"-0.0":      math.Copysign(0, -1),
+		"Infinity":  math.Inf(1),
+		"-Infinity": math.Inf(-1),
+		"1e400":     math.Inf(1),
+	} {
+		f, err := UnmarshalFloat64String(doc)
+		require.NoError(t, err, doc)
+		assert.Equal(t, expected, f, doc)
+		assert.Equal(t, math.Signbit(expected), math.Signbit(f), doc)
+	}
+	f, err := UnmarshalFloat64String(" NaN ")
+	require.NoError(t, err)
+	assert.True(t, math.IsNaN(f))
+
+	s, err := UnmarshalStringString(` "nameé\n" `)
+	require.NoError(t, err)
+	assert.Equal(t, "nameé\n", s)
+	b, err := UnmarshalBoolString("true")
+	require.NoError(t, err)
+	assert.True(t, b)
+	b, err = UnmarshalBoolString("\tfalse\n")
+	require.NoError(t, err)
+	assert.False(t, b)
+}
+
+func TestUnmarshalScalarErrors(t *testing.T) {
+	for _, test := range []struct {
+		doc string
+		fn  func(string) error
+		err string
+	}{
+		{"3.14", int64Err, "simple json: expected an integer but found number with a fraction or exponent"},
+		{"1.0", int64Err, "simple json: expected an integer but found number with a fraction or exponent"},
+		{"1e3", int64Err, "simple json: expected an integer but found number with a fraction or exponent"},
+		{"NaN", int64Err, "simple json: expected an integer but found non-finite number"},
+		{`"42"`, int64Err, "simple json: expected an integer but found string"},
+		{`"42"`, float64Err, "simple json: expected a number but found string"},
+		{`null`, float64Err, "simple json: expected a number but found null"},
+		{`[1]`, float64Err, "simple json: expected a number but found array"},
+		{`42`, stringErr, "simple json: expected a string but found number"},
+		{`{"a": "b"}`, stringErr, "simple json: expected a string but found object"},
+		{`"true"`, boolErr, "simple json: expected a boolean but found string"},
+		{`0`, boolErr, "simple json: expected a boolean but found number"},
+	} {
+		err := test.fn(test.doc)
+		var kindErr *ScalarKindError
+		if assert.ErrorAs(t, err, &kindErr, test.doc) {
+			assert.EqualError(t, err, test.err, test.doc)
+		}
+	}
+
+	// Errors other than the kind of value are those of the parser.
+	for _, fn := range []func(string) error{int64Err, float64Err, stringErr, boolErr} {
+		assert.ErrorIs(t, fn(""), io.EOF)
+		assert.ErrorIs(t, fn(","), errUnexpectedComma)
+		assert.ErrorIs(t, fn("]"), errUnexpectedEnd)
+		// The mismatched value must itself be valid.
+		err := fn(`[1, }`)
+		assert.Error(t, err)
+		var kindErr *ScalarKindError
+		assert.False(t, errors.As(err, &kindErr), err)
+	}
+	for _, doc := range []string{"1 2", "1,", "1x"} {
+		_, err := UnmarshalInt64String(doc)
+		assert.Error(t, err, doc)
+		_, err = UnmarshalFloat64String(doc)
+		assert.Error(t, err, doc)
+	}
+	_, err := UnmarshalInt64String("9223372036854775808")
+	assert.Error(t, err)
+	_, err = UnmarshalStringString(`"a" "b"`)
+	assert.ErrorIs(t, err, ErrTrailingData)
+	_, err = UnmarshalBoolString(`truer`)
+	assert.Error(t, err)
+	_, err = UnmarshalStringString(`"unterminated`)
+	assert.Error(t, err)
+}
+
+func TestUnmarshalScalarAllocs(t *testing.T) {
+	allocs := testing.AllocsPerRun(100, func() {
+		_, _ = UnmarshalInt64String("12345")
+		_, _ = UnmarshalFloat64String("-1.5e10")
+		_, _ = UnmarshalBoolString("true")
+	})
+	assert.Zero(t, allocs)
+	allocs = testing.AllocsPerRun(100, func() {
+		_, _ = UnmarshalStringString(`"name"`)
+	})
+	assert.Equal(t, 1.0, allocs)
+}
+
+func int64Err(s string) error {
+	_, err := UnmarshalInt64String(s)
+	return err
+}
+
+func float64Err(s string) error {
+	_, err := UnmarshalFloat64String(s)
+	return err
+}
+
+func stringErr(s string) error {
+	_, err := UnmarshalStringString(s)
+	return err
+}
+
+func boolErr(s string) error {
+	_, err := UnmarshalBoolString(s)
+	return err
+}
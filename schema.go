@@ -0,0 +1,196 @@
+package simplejsonext
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Schema summarizes the shape of a set of values: which types were seen at
+// each position and how often, the members of the objects, and the elements
+// of the arrays. A Schema is built with InferSchema or Add, and combined with
+// Merge, so it can be accumulated over a stream of documents.
+//
+// Types are named as in error messages: "null", "bool", "int64", "float64",
+// "string", "array" and "object", or the Go type name of any other value.
+// Seeing several types at one position is not an error; they are all
+// recorded.
+type Schema struct {
+	// Count is the number of values seen at this position.
+	Count int
+	// Types counts the values seen by type name.
+	Types map[string]int
+	// Fields holds the schema of each key seen in objects at this position.
+	// A key whose Count is less than Types["object"] was optional.
+	Fields map[string]*Schema
+	// Items is the schema of all the elements of arrays at this position, or
+	// nil if no array elements were seen.
+	Items *Schema
+}
+
+// InferSchema returns the Schema describing all of the given values.
+func InferSchema(values ...any) *Schema {
+	s := &Schema{}
+	for _, v := range values {
+		s.Add(v)
+	}
+	return s
+}
+
+// Add records one more value in the schema.
+func (s *Schema) Add(v any) {
+	s.Count++
+	if s.Types == nil {
+		s.Types = make(map[string]int)
+	}
+	s.Types[typeName(v)]++
+	switch tv := v.(type) {
+	case []any:
+		for _, child := range tv {
+			if s.Items == nil {
+				s.Items = &Schema{}
+			}
+			s.Items.Add(child)
+		}
+	case map[string]any:
+		for key, child := range tv {
+			s.field(key).Add(child)
+		}
+	case *OrderedObject:
+		if tv != nil {
+			for _, member := range tv.Members {
+				s.field(member.Key).Add(member.Value)
+			}
+		}
+	}
+}
+
+func (s *Schema) field(key string) *Schema {
+	if s.Fields == nil {
+		s.Fields = make(map[string]*Schema)
+	}
+	f := s.Fields[key]
+	if f == nil {
+		f = &Schema{}
+		s.Fields[key] = f
+	}
+	return f
+}
+
+// Merge adds everything recorded in other to s, as if all of the values
+// seen by other had been added to s as well.
+func (s *Schema) Merge(other *Schema) {
+	if other == nil {
+		return
+	}
+	s.Count += other.Count
+	for name, n := range other.Types {
+		if s.Types == nil {
+			s.Types = make(map[string]int)
+		}
+		s.Types[name] += n
+	}
+	for key, f := range other.Fields {
+		s.field(key).Merge(f)
+	}
+	if other.Items != nil {
+		if s.Items == nil {
+			s.Items = &Schema{}
+		}
+		s.Items.Merge(other.Items)
+	}
+}
+
+// Nullable reports whether null was seen at this position.
+func (s *Schema) Nullable() bool {
+	return s.Types["null"] > 0
+}
+
+// Optional reports whether key was missing from some of the objects seen at
+// this position.
+func (s *Schema) Optional(key string) bool {
+	f := s.Fields[key]
+	return f == nil || f.Count < s.Types["object"]
+}
+
+// Order in which type names are listed; others come after, sorted.
+var schemaTypeOrder = map[string]int{
+	"null": 1, "bool": 2, "int64": 3, "float64": 4, "string": 5, "array": 6, "object": 7,
+}
+
+// TypeNames returns the names of the types seen at this position in a fixed
+// order.
+func (s *Schema) TypeNames() []string {
+	names := make([]string, 0, len(s.Types))
+	for name := range s.Types {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		oi, oj := schemaTypeOrder[names[i]], schemaTypeOrder[names[j]]
+		if oi == 0 || oj == 0 {
+			if oi != oj {
+				return oj == 0
+			}
+			return names[i] < names[j]
+		}
+		return oi < oj
+	})
+	return names
+}
+
+// ToValue returns the schema as a simple JSON value, suitable for Marshal:
+// an object with "count" and "types", and "fields" and "items" when present.
+func (s *Schema) ToValue() map[string]any {
+	types := make(map[string]any, len(s.Types))
+	for name, n := range s.Types {
+		types[name] = int64(n)
+	}
+	res := map[string]any{"count": int64(s.Count), "types": types}
+	if s.Fields != nil {
+		fields := make(map[string]any, len(s.Fields))
+		for key, f := range s.Fields {
+			fields[key] = f.ToValue()
+		}
+		res["fields"] = fields
+	}
+	if s.Items != nil {
+		res["items"] = s.Items.ToValue()
+	}
+	return res
+}
+
+// String renders the schema as an indented outline, one position per line,
+// with object keys in sorted order. Each line lists the types seen with their
+// counts, and marks optional keys.
+func (s *Schema) String() string {
+	var sb strings.Builder
+	s.format(&sb, "", "")
+	return sb.String()
+}
+
+func (s *Schema) format(sb *strings.Builder, indent string, label string) {
+	sb.WriteString(indent)
+	sb.WriteString(label)
+	for i, name := range s.TypeNames() {
+		if i > 0 {
+			sb.WriteString(" | ")
+		}
+		fmt.Fprintf(sb, "%s(%d)", name, s.Types[name])
+	}
+	sb.WriteByte('\n')
+	keys := make([]string, 0, len(s.Fields))
+	for key := range s.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		label := fmt.Sprintf("%q: ", key)
+		if s.Optional(key) {
+			label = fmt.Sprintf("%q?: ", key)
+		}
+		s.Fields[key].format(sb, indent+"  ", label)
+	}
+	if s.Items != nil {
+		s.Items.format(sb, indent+"  ", "[]: ")
+	}
+}
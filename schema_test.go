@@ -0,0 +1,95 @@
+package simplejsonext
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func parseAll(t *testing.T, docs ...string) []any {
+	t.Helper()
+	values := make([]any, len(docs))
+	for i, doc := range docs {
+		v, err := UnmarshalString(doc)
+		require.NoError(t, err)
+		values[i] = v
+	}
+	return values
+}
+
+var schemaDocs = []string{
+	`{"id": 1, "loss": 0.5, "tags": ["a", "b"], "meta": {"user": "x"}}`,
+	`{"id": 2, "loss": 1, "tags": [], "meta": null}`,
+	`{"id": 3, "loss": NaN, "tags": ["c", 4], "extra": true}`,
+}
+
+func TestInferSchema(t *testing.T) {
+	s := InferSchema(parseAll(t, schemaDocs...)...)
+
+	assert.Equal(t, 3, s.Count)
+	assert.Equal(t, map[string]int{"object": 3}, s.Types)
+	assert.Equal(t, map[string]int{"int64": 3}, s.Fields["id"].Types)
+	assert.Equal(t, map[string]int{"int64": 1, "float64": 2}, s.Fields["loss"].Types)
+	assert.Equal(t, map[string]int{"string": 3, "int64": 1}, s.Fields["tags"].Items.Types)
+	assert.True(t, s.Fields["meta"].Nullable())
+	assert.False(t, s.Fields["id"].Nullable())
+	assert.False(t, s.Optional("id"))
+	assert.True(t, s.Optional("meta"))
+	assert.True(t, s.Optional("extra"))
+	assert.True(t, s.Optional("never seen"))
+	assert.Equal(t, 1, s.Fields["meta"].Fields["user"].Count)
+	assert.False(t, s.Fields["meta"].Optional("user"))
+
+	assert.Equal(t, `object(3)
+  "extra"?: bool(1)
+  "id": int64(3)
+  "loss": int64(1) | float64(2)
+  "meta"?: null(1) | object(1)
+    "user": string(1)
+  "tags": array(3)
+    []: int64(1) | string(3)
+`, s.String())
+}
+
+func TestSchemaMerge(t *testing.T) {
+	values := parseAll(t, schemaDocs...)
+	whole := InferSchema(values...)
+
+	merged := InferSchema(values[0])
+	merged.Merge(InferSchema(values[1:]...))
+	merged.Merge(nil)
+	merged.Merge(&Schema{})
+	assert.Equal(t, whole, merged)
+
+	incremental := &Schema{}
+	for _, v := range values {
+		incremental.Add(v)
+	}
+	assert.Equal(t, whole, incremental)
+}
+
+func TestSchemaScalarsAndOthers(t *testing.T) {
+	s := InferSchema(nil, "x", int64(1), []any{}, struct{}{})
+	assert.Equal(t, "null(1) | int64(1) | string(1) | array(1) | struct {}(1)\n", s.String())
+	assert.Nil(t, s.Items)
+	assert.Nil(t, s.Fields)
+
+	assert.Equal(t, "\n", InferSchema().String())
+}
+
+func TestSchemaToValue(t *testing.T) {
+	s := InferSchema(parseAll(t, `{"a": [1, 2.5]}`, `{"a": []}`)...)
+	out, err := MarshalToString(s.ToValue()["fields"].(map[string]any)["a"])
+	require.NoError(t, err)
+	v, err := UnmarshalString(out)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"count": int64(2),
+		"types": map[string]any{"array": int64(2)},
+		"items": map[string]any{
+			"count": int64(2),
+			"types": map[string]any{"int64": int64(1), "float64": int64(1)},
+		},
+	}, v)
+}
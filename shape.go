@@ -0,0 +1,428 @@
+package simplejsonext
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// NumberRule says which numbers a Shape of KindNumber accepts.
+type NumberRule int
+
+const (
+	// NumberAny accepts int64 and float64 values alike. This is the default.
+	NumberAny NumberRule = iota
+	// NumberIntegral accepts int64 values, and float64 values with no
+	// fraction, such as 2.0.
+	NumberIntegral
+	// NumberInt64 accepts only int64 values.
+	NumberInt64
+	// NumberFloat64 accepts only float64 values.
+	NumberFloat64
+)
+
+var numberRuleNames = [...]string{
+	NumberAny:      "number",
+	NumberIntegral: "integer",
+	NumberInt64:    "int64",
+	NumberFloat64:  "float64",
+}
+
+// Shape declares what a value must look like for Validate: its kind and,
+// for arrays and objects, the shapes of what they hold. It is not JSON
+// Schema, just enough to check the structure of a document before using it.
+// A Shape is built in Go or read with ParseShape.
+type Shape struct {
+	// Kind is the kind of value required. KindInvalid accepts any value,
+	// and KindNonFinite is not a kind a Shape may require.
+	Kind Kind
+	// Nullable accepts null as well as values of Kind.
+	Nullable bool
+	// Optional allows an object member with this shape to be missing.
+	Optional bool
+
+	// Numbers says which numbers are accepted, for KindNumber.
+	Numbers NumberRule
+	// Finite rejects NaN and infinities, for KindNumber.
+	Finite bool
+
+	// Items is the shape of every element, for KindArray. If nil, elements
+	// are not checked.
+	Items *Shape
+	// Fields are the shapes of the members with these keys, for KindObject.
+	Fields map[string]*Shape
+	// Values is the shape of every member whose key is not in Fields, for
+	// KindObject. If nil, such members are not checked, unless Closed.
+	Values *Shape
+	// Closed rejects members whose key is not in Fields, for KindObject.
+	Closed bool
+}
+
+// Describes the values the shape accepts, like "integer or null".
+func (s *Shape) expected() string {
+	name := s.Kind.String()
+	if s.Kind == KindNumber {
+		name = numberRuleNames[s.Numbers]
+		if s.Finite {
+			name = "finite " + name
+		}
+	}
+	if s.Nullable {
+		name += " or null"
+	}
+	return name
+}
+
+// ValidationError describes one place where a value does not match a Shape.
+type ValidationError struct {
+	// Path is the keys and indices leading to the value, as for Get.
+	Path []any
+	// Expected describes what the shape accepts there, such as "string",
+	// "finite number", "integer or null" or, for members of a closed object
+	// that are not in its fields, "no value".
+	Expected string
+	// Missing is true if there is no value at Path: a required object member
+	// is missing.
+	Missing bool
+	// Found is the kind of the value found. It is KindNonFinite for NaN and
+	// infinities, and KindInvalid if the value is missing or of a type that
+	// is not a simple JSON type.
+	Found Kind
+	// Value is a bounded Preview of the value found.
+	Value any
+}
+
+// The limits of the previews in a ValidationError.
+var validationPreview = PreviewOptions{MaxStringBytes: 32, MaxArrayElements: 4, MaxTotalBytes: 64}
+
+// Error formats the error as, for example:
+//
+//	simple json: at $["metrics"]["loss"]: expected finite number but found non-finite number NaN
+func (e *ValidationError) Error() string {
+	return "simple json: at " + formatPath(e.Path) + ": expected " + e.Expected + " but found " + e.found()
+}
+
+// Describes the value found, with its preview. Numbers are described by
+// their type, since that is what NumberRule decides on.
+func (e *ValidationError) found() string {
+	var name string
+	switch {
+	case e.Missing:
+		return "no value"
+	case e.Found == KindNull:
+		return "null"
+	case e.Found == KindNumber:
+		name = typeName(e.Value)
+	case e.Found == KindInvalid:
+		return fmt.Sprintf("%T", e.Value)
+	default:
+		name = e.Found.String()
+	}
+	text, err := Marshal(e.Value)
+	if err != nil {
+		return name
+	}
+	return name + " " + string(text)
+}
+
+// Validate checks v against s, and returns every place where it does not
+// match, in order of their paths with object members in sorted key order, or
+// nil if it matches. v is a value as Unmarshal produces, with objects that
+// are either a map[string]any or an *OrderedObject.
+func Validate(v any, s *Shape) []ValidationError {
+	var val validator
+	val.validate(v, s)
+	return val.errs
+}
+
+type validator struct {
+	path []any
+	errs []ValidationError
+}
+
+func (val *validator) fail(expected string, v any, found Kind) {
+	val.errs = append(val.errs, ValidationError{
+		Path:     append([]any(nil), val.path...),
+		Expected: expected,
+		Found:    found,
+		Value:    Preview(v, validationPreview),
+	})
+}
+
+func (val *validator) validate(v any, s *Shape) {
+	found := valueKind(v)
+	switch {
+	case s.Kind == KindInvalid:
+		return
+	case found == KindNull && s.Nullable:
+		return
+	case s.Kind == KindNumber && (found == KindNumber || found == KindNonFinite):
+		if !s.acceptsNumber(v, found) {
+			val.fail(s.expected(), v, found)
+		}
+		return
+	case found != s.Kind:
+		val.fail(s.expected(), v, found)
+		return
+	}
+	switch tv := v.(type) {
+	case []any:
+		if s.Items == nil {
+			return
+		}
+		for i, elem := range tv {
+			val.path = append(val.path, i)
+			val.validate(elem, s.Items)
+			val.path = val.path[:len(val.path)-1]
+		}
+	case map[string]any:
+		val.validateObject(s, len(tv), func(key string) (any, bool) {
+			elem, ok := tv[key]
+			return elem, ok
+		}, func(yield func(string, any)) {
+			for key, elem := range tv {
+				yield(key, elem)
+			}
+		})
+	case *OrderedObject:
+		val.validateObject(s, len(tv.Members), tv.Get, func(yield func(string, any)) {
+			for _, m := range tv.Members {
+				yield(m.Key, m.Value)
+			}
+		})
+	}
+}
+
+func (s *Shape) acceptsNumber(v any, found Kind) bool {
+	if num, ok := v.(Number); ok {
+		var err error
+		if v, err = num.Value(); err != nil {
+			return false
+		}
+	}
+	if found == KindNonFinite && s.Finite {
+		return false
+	}
+	switch s.Numbers {
+	case NumberIntegral:
+		f, isFloat := v.(float64)
+		return !isFloat || f == math.Trunc(f) && !math.IsInf(f, 0)
+	case NumberInt64:
+		_, ok := v.(int64)
+		return ok
+	case NumberFloat64:
+		_, ok := v.(float64)
+		return ok
+	}
+	return true
+}
+
+// Checks the members of an object, given how to look them up and list them,
+// reporting on them in sorted key order.
+func (val *validator) validateObject(s *Shape, n int, get func(string) (any, bool), members func(yield func(string, any))) {
+	type member struct {
+		key  string
+		elem any
+	}
+	checked := make([]member, 0, n)
+	for key, field := range s.Fields {
+		if elem, ok := get(key); ok {
+			checked = append(checked, member{key, elem})
+		} else if !field.Optional {
+			checked = append(checked, member{key: key})
+		}
+	}
+	if s.Values != nil || s.Closed {
+		members(func(key string, elem any) {
+			if _, ok := s.Fields[key]; !ok {
+				checked = append(checked, member{key, elem})
+			}
+		})
+	}
+	sort.Slice(checked, func(i, j int) bool { return checked[i].key < checked[j].key })
+	for _, m := range checked {
+		val.path = append(val.path, m.key)
+		field, known := s.Fields[m.key]
+		_, present := get(m.key)
+		switch {
+		case !present:
+			val.errs = append(val.errs, ValidationError{
+				Path:     append([]any(nil), val.path...),
+				Expected: field.expected(),
+				Missing:  true,
+			})
+		case known:
+			val.validate(m.elem, field)
+		case s.Closed:
+			val.fail("no value", m.elem, valueKind(m.elem))
+		default:
+			val.validate(m.elem, s.Values)
+		}
+		val.path = val.path[:len(val.path)-1]
+	}
+}
+
+// Returns the kind of a simple JSON value, or KindInvalid if it is of some
+// other type.
+func valueKind(v any) Kind {
+	switch tv := v.(type) {
+	case nil:
+		return KindNull
+	case bool:
+		return KindBool
+	case int64:
+		return KindNumber
+	case float64:
+		if math.IsNaN(tv) || math.IsInf(tv, 0) {
+			return KindNonFinite
+		}
+		return KindNumber
+	case Number:
+		if f, err := tv.Float64(); err == nil && (math.IsNaN(f) || math.IsInf(f, 0)) {
+			return KindNonFinite
+		}
+		return KindNumber
+	case string:
+		return KindString
+	case []any:
+		return KindArray
+	case map[string]any:
+		return KindObject
+	case *OrderedObject:
+		if tv == nil {
+			return KindNull
+		}
+		return KindObject
+	}
+	return KindInvalid
+}
+
+// ParseShape reads a Shape from its description in extended JSON. A shape is
+// either the name of a kind, or an object with a "kind" and any of the other
+// settings of a Shape:
+//
+//	{
+//	  "kind": "object",
+//	  "fields": {
+//	    "name": "string",
+//	    "metrics": {"kind": "object", "values": {"kind": "number", "finite": true}},
+//	    "tags": {"kind": "array", "items": "string", "optional": true}
+//	  },
+//	  "closed": true
+//	}
+//
+// Kinds are named "any", "null", "boolean", "number", "string", "array" and
+// "object". Number rules are named "number", "integer", "int64" and
+// "float64", as in error messages.
+func ParseShape(b []byte) (*Shape, error) {
+	v, err := Unmarshal(b)
+	if err != nil {
+		return nil, err
+	}
+	return shapeFromValue(v, nil)
+}
+
+func shapeFromValue(v any, path []any) (*Shape, error) {
+	if name, ok := v.(string); ok {
+		kind, err := parseShapeKind(name)
+		if err != nil {
+			return nil, pathError(path, err)
+		}
+		return &Shape{Kind: kind}, nil
+	}
+	obj, ok := v.(map[string]any)
+	if !ok {
+		return nil, pathError(path, &WrongTypeError{Expected: "string or object", Found: v})
+	}
+	s := &Shape{}
+	// Settings are read in sorted order so that errors are the same each time.
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		setting := obj[key]
+		settingPath := append(path[:len(path):len(path)], key)
+		var err error
+		switch key {
+		case "kind":
+			name, ok := setting.(string)
+			if !ok {
+				return nil, pathError(settingPath, &WrongTypeError{Expected: "string", Found: setting})
+			}
+			s.Kind, err = parseShapeKind(name)
+		case "numbers":
+			name, ok := setting.(string)
+			if !ok {
+				return nil, pathError(settingPath, &WrongTypeError{Expected: "string", Found: setting})
+			}
+			s.Numbers, err = parseNumberRule(name)
+		case "nullable":
+			err = setShapeFlag(&s.Nullable, setting)
+		case "optional":
+			err = setShapeFlag(&s.Optional, setting)
+		case "finite":
+			err = setShapeFlag(&s.Finite, setting)
+		case "closed":
+			err = setShapeFlag(&s.Closed, setting)
+		case "items":
+			s.Items, err = shapeFromValue(setting, settingPath)
+		case "values":
+			s.Values, err = shapeFromValue(setting, settingPath)
+		case "fields":
+			fields, ok := setting.(map[string]any)
+			if !ok {
+				return nil, pathError(settingPath, &WrongTypeError{Expected: "object", Found: setting})
+			}
+			s.Fields = make(map[string]*Shape, len(fields))
+			for name, field := range fields {
+				if s.Fields[name], err = shapeFromValue(field, append(settingPath[:len(settingPath):len(settingPath)], name)); err != nil {
+					return nil, err
+				}
+			}
+		default:
+			err = fmt.Errorf("simple json: unknown shape setting %q", key)
+		}
+		if _, isPath := err.(*PathError); isPath {
+			return nil, err
+		} else if err != nil {
+			return nil, pathError(settingPath, err)
+		}
+	}
+	if _, ok := obj["kind"]; !ok {
+		return nil, pathError(path, fmt.Errorf("simple json: shape has no kind"))
+	}
+	return s, nil
+}
+
+func setShapeFlag(flag *bool, v any) error {
+	b, ok := v.(bool)
+	if !ok {
+		return &WrongTypeError{Expected: "bool", Found: v}
+	}
+	*flag = b
+	return nil
+}
+
+func parseShapeKind(name string) (Kind, error) {
+	if name == "any" {
+		return KindInvalid, nil
+	}
+	for kind := KindNull; kind < KindNonFinite; kind++ {
+		if kind.String() == name {
+			return kind, nil
+		}
+	}
+	return 0, fmt.Errorf("simple json: unknown shape kind %q", name)
+}
+
+func parseNumberRule(name string) (NumberRule, error) {
+	for rule, ruleName := range numberRuleNames {
+		if ruleName == name {
+			return NumberRule(rule), nil
+		}
+	}
+	return 0, fmt.Errorf("simple json: unknown number rule %q, expected one of %s", name, strings.Join(numberRuleNames[:], ", "))
+}
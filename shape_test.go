@@ -0,0 +1,140 @@
+package simplejsonext
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func validationStrings(errs []ValidationError) []string {
+	res := make([]string, len(errs))
+	for i := range errs {
+		res[i] = errs[i].Error()
+	}
+	return res
+}
+
+var runShape = &Shape{
+	Kind: KindObject,
+	Fields: map[string]*Shape{
+		"name":    {Kind: KindString},
+		"metrics": {Kind: KindObject, Values: &Shape{Kind: KindNumber, Finite: true}},
+		"tags":    {Kind: KindArray, Items: &Shape{Kind: KindString}, Optional: true},
+		"step":    {Kind: KindNumber, Numbers: NumberIntegral, Nullable: true, Optional: true},
+	},
+}
+
+func TestValidate(t *testing.T) {
+	v, err := Unmarshal([]byte(`{"name": "run-1", "metrics": {"loss": 0.5, "epoch": 3}, "tags": ["a", "b"], "extra": [1]}`))
+	require.NoError(t, err)
+	assert.Nil(t, Validate(v, runShape))
+
+	v, err = Unmarshal([]byte(`{"name": 5, "metrics": {"loss": NaN, "acc": "high", "ok": 1}, "tags": ["a", null, {"b": 1}], "step": 1.5}`))
+	require.NoError(t, err)
+	errs := Validate(v, runShape)
+	assert.Equal(t, []string{
+		`simple json: at $["metrics"]["acc"]: expected finite number but found string "high"`,
+		`simple json: at $["metrics"]["loss"]: expected finite number but found non-finite number NaN`,
+		`simple json: at $["name"]: expected string but found int64 5`,
+		`simple json: at $["step"]: expected integer or null but found float64 1.5`,
+		`simple json: at $["tags"][1]: expected string but found null`,
+		`simple json: at $["tags"][2]: expected string but found object {"b":1}`,
+	}, validationStrings(errs))
+	assert.Equal(t, []any{"metrics", "loss"}, errs[1].Path)
+	assert.Equal(t, "finite number", errs[1].Expected)
+	assert.Equal(t, KindNonFinite, errs[1].Found)
+	assert.True(t, math.IsNaN(errs[1].Value.(float64)))
+	assert.Equal(t, []any{"tags", 2}, errs[5].Path)
+
+	// Missing members, and values that are not objects at all.
+	errs = Validate(map[string]any{"step": nil}, runShape)
+	assert.Equal(t, []string{
+		`simple json: at $["metrics"]: expected object but found no value`,
+		`simple json: at $["name"]: expected string but found no value`,
+	}, validationStrings(errs))
+	assert.True(t, errs[0].Missing)
+	assert.Equal(t, KindInvalid, errs[0].Found)
+	assert.Equal(t, []string{`simple json: at $: expected object but found array [1,2]`},
+		validationStrings(Validate([]any{int64(1), int64(2)}, runShape)))
+	assert.Equal(t, []string{`simple json: at $: expected object but found null`},
+		validationStrings(Validate(nil, runShape)))
+	assert.Equal(t, []string{`simple json: at $: expected object but found struct {}`},
+		validationStrings(Validate(struct{}{}, runShape)))
+
+	// Ordered objects are checked the same way.
+	v, err = UnmarshalWithOptions([]byte(`{"tags": [1], "name": "x", "metrics": {}}`), WithOrderedObjects())
+	require.NoError(t, err)
+	assert.Equal(t, []string{`simple json: at $["tags"][0]: expected string but found int64 1`},
+		validationStrings(Validate(v, runShape)))
+
+	// Anything goes for KindInvalid.
+	assert.Nil(t, Validate([]any{nil, "x"}, &Shape{Kind: KindArray, Items: &Shape{}}))
+}
+
+func TestValidateNumbers(t *testing.T) {
+	values := []any{int64(2), 2.0, 2.5, math.Inf(1), Number("7"), Number("7.5")}
+	for _, test := range []struct {
+		shape    Shape
+		rejected []string
+	}{
+		{Shape{Kind: KindNumber}, nil},
+		{Shape{Kind: KindNumber, Finite: true}, []string{"non-finite number Infinity"}},
+		{Shape{Kind: KindNumber, Numbers: NumberIntegral}, []string{"float64 2.5", "non-finite number Infinity", `simplejsonext.Number 7.5`}},
+		{Shape{Kind: KindNumber, Numbers: NumberInt64}, []string{"float64 2", "float64 2.5", "non-finite number Infinity", `simplejsonext.Number 7.5`}},
+		{Shape{Kind: KindNumber, Numbers: NumberFloat64, Finite: true}, []string{"int64 2", "non-finite number Infinity", `simplejsonext.Number 7`}},
+	} {
+		expected := test.shape.expected()
+		var rejected []string
+		for _, err := range Validate(values, &Shape{Kind: KindArray, Items: &test.shape}) {
+			assert.Equal(t, expected, err.Expected)
+			rejected = append(rejected, strings.SplitN(err.Error(), " but found ", 2)[1])
+		}
+		assert.Equal(t, test.rejected, rejected, expected)
+	}
+}
+
+func TestValidateClosed(t *testing.T) {
+	s := &Shape{Kind: KindObject, Closed: true, Fields: map[string]*Shape{"a": {Kind: KindBool}}}
+	errs := Validate(map[string]any{"a": true, "b": strings.Repeat("x", 100)}, s)
+	assert.Equal(t, []string{
+		`simple json: at $["b"]: expected no value but found string "xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx…"`,
+	}, validationStrings(errs))
+}
+
+func TestParseShape(t *testing.T) {
+	s, err := ParseShape([]byte(`{
+		"kind": "object",
+		"fields": {
+			"name": "string",
+			"metrics": {"kind": "object", "values": {"kind": "number", "finite": true}},
+			"tags": {"kind": "array", "items": "string", "optional": true},
+			"step": {"kind": "number", "numbers": "integer", "nullable": true, "optional": true}
+		}
+	}`))
+	require.NoError(t, err)
+	assert.Equal(t, runShape, s)
+
+	s, err = ParseShape([]byte(`{"kind": "any"}`))
+	require.NoError(t, err)
+	assert.Equal(t, &Shape{}, s)
+
+	for _, test := range []struct {
+		in, err string
+	}{
+		{`5`, `simple json: at $: expected string or object but found int64`},
+		{`"thing"`, `simple json: at $: unknown shape kind "thing"`},
+		{`{"kind": "array", "items": {"kind": "nope"}}`, `simple json: at $["items"]["kind"]: unknown shape kind "nope"`},
+		{`{"kind": "object", "fields": {"a": {"optional": true}}}`, `simple json: at $["fields"]["a"]: shape has no kind`},
+		{`{"kind": "number", "numbers": "big"}`, `simple json: at $["numbers"]: unknown number rule "big", expected one of number, integer, int64, float64`},
+		{`{"kind": "number", "finite": 1}`, `simple json: at $["finite"]: expected bool but found int64`},
+		{`{"kind": "object", "fields": []}`, `simple json: at $["fields"]: expected object but found array`},
+		{`{"kind": "string", "max": 3}`, `simple json: at $["max"]: unknown shape setting "max"`},
+		{`{`, `EOF`},
+	} {
+		_, err := ParseShape([]byte(test.in))
+		assert.EqualError(t, err, test.err, test.in)
+	}
+}
@@ -0,0 +1,241 @@
+package simplejsonext
+
+import (
+	"encoding/base64"
+	"math/big"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// EstimateMarshalSize returns the exact number of bytes Marshal would produce
+// for v, without producing them. It accepts all the same types as the
+// Emitter, and returns -1 if v holds a value that Marshal cannot emit.
+func EstimateMarshalSize(v any) int {
+	n, ok := marshalSize(v)
+	if !ok {
+		return -1
+	}
+	return n
+}
+
+// Follows the same cases as emitter.Emit.
+func marshalSize(v any) (n int, ok bool) {
+	switch vt := v.(type) {
+	case nil:
+		return len(nullBytes), true
+	case bool:
+		if vt {
+			return len(trueBytes), true
+		}
+		return len(falseBytes), true
+	case int64:
+		return intLen(vt), true
+	case int32:
+		return intLen(int64(vt)), true
+	case int:
+		return intLen(int64(vt)), true
+	case uint64:
+		return uintLen(vt), true
+	case uint32:
+		return uintLen(uint64(vt)), true
+	case uint:
+		return uintLen(uint64(vt)), true
+	case float64:
+		return floatLen(vt, 64), true
+	case float32:
+		return floatLen(float64(vt), 32), true
+	case string:
+		return quotedLen(vt), true
+	case Number:
+		_, _, _, err := vt.parse()
+		return len(vt), err == nil
+	case *big.Int:
+		if vt == nil {
+			return len(nullBytes), true
+		}
+		var buf [64]byte
+		return len(vt.Append(buf[:0], 10)), true
+	case *big.Float:
+		if vt == nil {
+			return len(nullBytes), true
+		}
+		var buf [64]byte
+		s, err := appendBigFloat(buf[:0], vt, false)
+		return len(s), err == nil
+	case []any:
+		n = 2 + max(len(vt)-1, 0) // brackets and commas
+		for _, av := range vt {
+			size, ok := marshalSize(av)
+			if !ok {
+				return 0, false
+			}
+			n += size
+		}
+		return n, true
+	case map[string]any:
+		n = 2 + max(len(vt)-1, 0) // braces and commas
+		for key, value := range vt {
+			size, ok := marshalSize(value)
+			if !ok {
+				return 0, false
+			}
+			n += quotedLen(key) + 1 + size // key, colon, and value
+		}
+		return n, true
+	case *OrderedObject:
+		if vt == nil {
+			return len(nullBytes), true
+		}
+		return orderedObjectSize(vt)
+	case OrderedObject:
+		return orderedObjectSize(&vt)
+	case Commented:
+		return marshalSize(vt.Value) // Marshal leaves comments out
+	case RawMessage:
+		if len(vt) == 0 {
+			return len(nullBytes), true
+		}
+		return len(vt), checkRawMessage(vt) == nil
+	case []byte:
+		return base64.StdEncoding.EncodedLen(len(vt)) + 2, true
+	case time.Time:
+		var buf [64]byte
+		return len(vt.AppendFormat(buf[:0], time.RFC3339Nano)) + 2, true
+	case JSONExtAppender:
+		if rv := reflect.ValueOf(vt); rv.Kind() == reflect.Pointer && rv.IsNil() {
+			return len(nullBytes), true
+		}
+		s, err := vt.AppendJSONExt(nil)
+		if err == nil && emitDebug {
+			err = checkAppended(s)
+		}
+		return len(s), err == nil
+	case error:
+		return quotedLen(vt.Error()), true
+	default:
+		rv := reflect.ValueOf(vt)
+		switch rv.Kind() {
+		case reflect.Pointer:
+			if rv.IsNil() {
+				return len(nullBytes), true
+			}
+			return marshalSize(rv.Elem().Interface())
+		case reflect.Slice:
+			n = 2 + max(rv.Len()-1, 0)
+			for i := 0; i < rv.Len(); i++ {
+				size, ok := marshalSize(rv.Index(i).Interface())
+				if !ok {
+					return 0, false
+				}
+				n += size
+			}
+			return n, true
+		case reflect.Map:
+			if rv.Type().Key() != reflect.TypeOf("") {
+				return 0, false
+			}
+			n = 2 + max(rv.Len()-1, 0)
+			iter := rv.MapRange()
+			for iter.Next() {
+				size, ok := marshalSize(iter.Value().Interface())
+				if !ok {
+					return 0, false
+				}
+				n += quotedLen(iter.Key().String()) + 1 + size
+			}
+			return n, true
+		case reflect.Struct:
+			members, err := structMembers(rv)
+			if err != nil {
+				return 0, false
+			}
+			return orderedObjectSize(&OrderedObject{Members: members})
+		}
+		if bv, ok := baseValue(rv); ok {
+			return marshalSize(bv)
+		}
+	}
+	return 0, false
+}
+
+func orderedObjectSize(obj *OrderedObject) (n int, ok bool) {
+	n = 2 + max(len(obj.Members)-1, 0)
+	for _, member := range obj.Members {
+		size, ok := marshalSize(member.Value)
+		if !ok {
+			return 0, false
+		}
+		n += quotedLen(member.Key) + 1 + size
+	}
+	return n, true
+}
+
+// Returns the length of the decimal representation of u.
+func uintLen(u uint64) int {
+	var buf [20]byte
+	return len(strconv.AppendUint(buf[:0], u, 10))
+}
+
+// Rough sizes of values whose exact size would be costly to work out
+const (
+	roughNumberSize = 12
+	roughOtherSize  = 32
+)
+
+// Returns a cheap guess of the length of the JSON for v, for sizing output
+// buffers. Strings and the structure of the common container types are
+// counted exactly, numbers and other values are given fixed sizes, and the
+// total is padded a little so that most guesses err on the large side.
+func roughMarshalSize(v any) int {
+	n := roughSize(v, maxEmitRecursion)
+	return n + n/8
+}
+
+// Values nested more than remainingDepth levels below v are given a fixed
+// size, rather than recursing without limit.
+func roughSize(v any, remainingDepth int) int {
+	if remainingDepth < 0 {
+		return roughOtherSize
+	}
+	switch vt := v.(type) {
+	case nil:
+		return len(nullBytes)
+	case bool:
+		return len(falseBytes)
+	case int64, int32, int, uint64, uint32, uint, float64, float32:
+		return roughNumberSize
+	case string:
+		return len(vt) + 2
+	case Number:
+		return len(vt)
+	case []any:
+		n := 1 + len(vt) // brackets and commas
+		for _, av := range vt {
+			n += roughSize(av, remainingDepth-1)
+		}
+		return n
+	case map[string]any:
+		n := 1 + len(vt)
+		for key, value := range vt {
+			n += len(key) + 3 + roughSize(value, remainingDepth-1) // quotes, colon, and value
+		}
+		return n
+	case *OrderedObject:
+		if vt == nil {
+			return len(nullBytes)
+		}
+		n := 1 + len(vt.Members)
+		for _, member := range vt.Members {
+			n += len(member.Key) + 3 + roughSize(member.Value, remainingDepth-1)
+		}
+		return n
+	case Commented:
+		return len(vt.Comment) + 3 + roughSize(vt.Value, remainingDepth) // slashes and newline
+	case RawMessage:
+		return max(len(vt), len(nullBytes))
+	case []byte:
+		return base64.StdEncoding.EncodedLen(len(vt)) + 2
+	}
+	return roughOtherSize
+}
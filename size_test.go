@@ -0,0 +1,44 @@
+package simplejsonext
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoughMarshalSize(t *testing.T) {
+	rows := make([]any, 1000)
+	for i := range rows {
+		rows[i] = map[string]any{
+			"id":    int64(i),
+			"score": float64(i) / 7,
+			"label": fmt.Sprintf("row %d", i),
+			"flags": []any{true, nil, "x"},
+		}
+	}
+	for _, v := range []any{
+		rows,
+		map[string]any{"text": strings.Repeat("abc", 1000)},
+		&OrderedObject{Members: []Member{{Key: "a", Value: []byte("bytes")}, {Key: "b", Value: int64(-1)}}},
+		[]any{1e300, -1e-300, int64(-9223372036854775808)},
+	} {
+		// The guess should never be so small that the buffer grows more
+		// than once.
+		exact := EstimateMarshalSize(v)
+		rough := roughMarshalSize(v)
+		assert.GreaterOrEqual(t, rough, exact/2, "%v", v)
+		assert.LessOrEqual(t, rough, exact*2, "%v", v)
+
+		b, err := Marshal(v)
+		require.NoError(t, err)
+		assert.Len(t, b, exact)
+
+		// With an exact hint, the buffer is exactly the right size.
+		b2, err := MarshalWithCapacity(v, exact)
+		require.NoError(t, err)
+		assert.Equal(t, len(b2), cap(b2))
+	}
+}
@@ -0,0 +1,78 @@
+package simplejsonext
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+)
+
+var (
+	_ driver.Valuer = Value{}
+	_ sql.Scanner   = &Value{}
+)
+
+// Value makes v a database column value: SQL NULL if v.V is nil, and
+// otherwise the text that Marshal writes for it, which may hold NaN and
+// Infinity. Columns that only accept standard JSON, such as jsonb in
+// PostgreSQL, reject those; use NonFinite to write them another way.
+func (v Value) Value() (driver.Value, error) {
+	return nonFiniteValue{v: v.V, policy: DeNaNKeep}.Value()
+}
+
+// Scan sets v.V to the value decoded by Unmarshal from a []byte or string
+// column, or to nil for NULL.
+func (v *Value) Scan(src any) error {
+	var val any
+	var err error
+	switch src := src.(type) {
+	case nil:
+	case []byte:
+		val, err = Unmarshal(src)
+	case string:
+		val, err = UnmarshalString(src)
+	default:
+		return fmt.Errorf("simple json: cannot scan %T into a Value", src)
+	}
+	if err != nil {
+		return err
+	}
+	v.V = val
+	return nil
+}
+
+// NonFinite returns a column value like v.Value, but with NaN and infinite
+// numbers written as policy says: as strings with DeNaNToString, as null with
+// DeNaNToNull, or not at all with DeNaNError, which fails instead. With
+// DeNaNKeep they are written as they are.
+func (v Value) NonFinite(policy DeNaNPolicy) driver.Valuer {
+	return nonFiniteValue{v: v.V, policy: policy}
+}
+
+type nonFiniteValue struct {
+	v      any
+	policy DeNaNPolicy
+}
+
+func (n nonFiniteValue) Value() (driver.Value, error) {
+	if n.v == nil {
+		return nil, nil
+	}
+	var b []byte
+	var err error
+	switch n.policy {
+	case DeNaNToString:
+		b, err = Marshal(WalkDeNaN(n.v))
+	case DeNaNToNull:
+		b, err = Marshal(WalkDeNaNToNull(n.v))
+	case DeNaNError:
+		b, err = MarshalWithOptions(n.v, WithStrictFloats())
+	default:
+		b, err = Marshal(n.v)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Drivers pass strings to text and JSON columns as they are, where some
+	// would pass []byte as binary data.
+	return string(b), nil
+}
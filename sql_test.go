@@ -0,0 +1,126 @@
+package simplejsonext
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// A database with a single column, which statements set with their only
+// argument or read back.
+type fakeDriver struct {
+	column driver.Value
+}
+
+func (d *fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{d}, nil }
+
+type fakeConn struct{ d *fakeDriver }
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt(c), nil }
+func (fakeConn) Close() error                                { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                   { return nil, errors.New("no transactions") }
+
+type fakeStmt struct{ d *fakeDriver }
+
+func (fakeStmt) Close() error  { return nil }
+func (fakeStmt) NumInput() int { return -1 }
+
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.column = args[0]
+	return driver.RowsAffected(1), nil
+}
+
+func (s fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	return &fakeRows{value: s.d.column}, nil
+}
+
+type fakeRows struct {
+	value driver.Value
+	done  bool
+}
+
+func (*fakeRows) Columns() []string { return []string{"doc"} }
+func (*fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = r.value
+	return nil
+}
+
+var fakeDB = &fakeDriver{}
+
+func init() {
+	sql.Register("simplejsonext-fake", fakeDB)
+}
+
+func TestValueSQL(t *testing.T) {
+	db, err := sql.Open("simplejsonext-fake", "")
+	require.NoError(t, err)
+	defer db.Close()
+
+	doc := []any{math.NaN(), map[string]any{"acc": math.Inf(-1)}, int64(3)}
+	for _, test := range []struct {
+		arg    any
+		stored string
+	}{
+		{Value{doc}, `[NaN,{"acc":-Infinity},3]`},
+		{Value{doc}.NonFinite(DeNaNKeep), `[NaN,{"acc":-Infinity},3]`},
+		{Value{doc}.NonFinite(DeNaNToString), `["NaN",{"acc":"-Infinity"},3]`},
+		{Value{doc}.NonFinite(DeNaNToNull), `[null,{"acc":null},3]`},
+	} {
+		_, err = db.Exec("set", test.arg)
+		require.NoError(t, err)
+		assert.Equal(t, test.stored, fakeDB.column)
+		var out Value
+		require.NoError(t, db.QueryRow("get").Scan(&out))
+		expected, err := Unmarshal([]byte(test.stored))
+		require.NoError(t, err)
+		assert.Equal(t, WalkDeNaN(expected), WalkDeNaN(out.V))
+	}
+	// The document itself is left as it was.
+	assert.True(t, math.IsNaN(doc[0].(float64)))
+	assert.Equal(t, map[string]any{"acc": math.Inf(-1)}, doc[1])
+
+	_, err = db.Exec("set", Value{doc}.NonFinite(DeNaNError))
+	assert.ErrorContains(t, err, "simple json: cannot emit non-finite float")
+	_, err = db.Exec("set", Value{func() {}})
+	assert.ErrorContains(t, err, "simple json: cannot emit unsupported type")
+
+	// NULL goes both ways.
+	_, err = db.Exec("set", Value{})
+	require.NoError(t, err)
+	assert.Nil(t, fakeDB.column)
+	out := Value{V: "stale"}
+	require.NoError(t, db.QueryRow("get").Scan(&out))
+	assert.Nil(t, out.V)
+}
+
+func TestValueScan(t *testing.T) {
+	var v Value
+	require.NoError(t, v.Scan([]byte(`[1, NaN]`)))
+	require.Len(t, v.V, 2)
+	assert.True(t, math.IsNaN(v.V.([]any)[1].(float64)))
+	require.NoError(t, v.Scan(`{"a": "b"}`))
+	assert.Equal(t, map[string]any{"a": "b"}, v.V)
+
+	// The value does not share memory with the column, which database/sql
+	// may reuse.
+	src := []byte(`["abc"]`)
+	require.NoError(t, v.Scan(src))
+	copy(src, `["xyz"]`)
+	assert.Equal(t, []any{"abc"}, v.V)
+
+	assert.Error(t, v.Scan(`{"a": `))
+	assert.EqualError(t, v.Scan(int64(1)), "simple json: cannot scan int64 into a Value")
+	assert.Equal(t, []any{"abc"}, v.V)
+}
@@ -0,0 +1,176 @@
+package simplejsonext
+
+import (
+	"reflect"
+	"sync"
+)
+
+// TreeStats describes the size and shape of a simple JSON value.
+type TreeStats struct {
+	Objects int // number of objects, including ordered objects
+	Arrays  int // number of arrays
+	Strings int // number of string values, not counting object keys
+	Numbers int // number of int64, float64, and Number values
+	Bools   int // number of bool values
+	Nulls   int // number of nil values
+	Others  int // number of values of any other type
+
+	// MaxDepth is the deepest nesting of objects and arrays: 0 for a scalar,
+	// 1 for a flat object or array, and so on.
+	MaxDepth int
+	// StringBytes is the total length of all string values.
+	StringBytes int
+	// KeyBytes is the total length of all object keys.
+	KeyBytes int
+	// MarshaledSize is the length of the compact JSON that Marshal would
+	// produce for the value, not counting any values of other types.
+	MarshaledSize int
+}
+
+// An object or array being counted by Stats.
+type statsFrame struct {
+	v    any             // []any, map[string]any or *OrderedObject
+	i    int             // index of the next element or member
+	iter reflect.MapIter // over the members of a map
+}
+
+// Where the members of maps are read into, since reflect.MapIter.Key and
+// Value allocate for each one.
+type mapMemberReader struct {
+	key                string
+	val                any
+	keyValue, valValue reflect.Value // of key and val
+}
+
+var mapMemberReaders = sync.Pool{New: func() any {
+	r := &mapMemberReader{}
+	r.keyValue, r.valValue = reflect.ValueOf(&r.key).Elem(), reflect.ValueOf(&r.val).Elem()
+	return r
+}}
+
+// Stats counts the values in v by type and measures its depth and size. It
+// does not recurse, so it is safe for trees of any depth, and it allocates
+// only for trees nested more than 32 levels deep.
+func Stats(v any) (s TreeStats) {
+	// The objects and arrays v is in, with where each is up to.
+	var stackArray [32]statsFrame
+	stack := stackArray[:0]
+	var members *mapMemberReader // taken from the pool at the first map
+	for {
+		if s.count(v, len(stack)) {
+			stack = append(stack, statsFrame{v: v})
+			if m, ok := v.(map[string]any); ok {
+				stack[len(stack)-1].iter.Reset(reflect.ValueOf(m))
+				if members == nil {
+					members = mapMemberReaders.Get().(*mapMemberReader)
+				}
+			}
+		}
+		// Find the next value to count, leaving objects and arrays that have
+		// no more.
+		for {
+			if len(stack) == 0 {
+				if members != nil {
+					members.key, members.val = "", nil
+					mapMemberReaders.Put(members)
+				}
+				return
+			}
+			top := &stack[len(stack)-1]
+			found := false
+			switch tv := top.v.(type) {
+			case []any:
+				if found = top.i < len(tv); found {
+					v = tv[top.i]
+				}
+			case *OrderedObject:
+				if found = top.i < len(tv.Members); found {
+					s.countKey(tv.Members[top.i].Key)
+					v = tv.Members[top.i].Value
+				}
+			default:
+				if found = top.iter.Next(); found {
+					members.keyValue.SetIterKey(&top.iter)
+					members.valValue.SetIterValue(&top.iter)
+					s.countKey(members.key)
+					v = members.val
+				}
+			}
+			if found {
+				top.i++
+				break
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+}
+
+// Counts v, found in depth objects and arrays, and reports whether it is an
+// object or array whose members and elements are to be counted next.
+func (s *TreeStats) count(v any, depth int) bool {
+	switch tv := v.(type) {
+	case nil:
+		s.Nulls++
+		s.MarshaledSize += len(nullBytes)
+	case bool:
+		s.Bools++
+		if tv {
+			s.MarshaledSize += len(trueBytes)
+		} else {
+			s.MarshaledSize += len(falseBytes)
+		}
+	case int64:
+		s.Numbers++
+		s.MarshaledSize += intLen(tv)
+	case float64:
+		s.Numbers++
+		s.MarshaledSize += floatLen(tv, 64)
+	case Number:
+		s.Numbers++
+		s.MarshaledSize += len(tv)
+	case string:
+		s.Strings++
+		s.StringBytes += len(tv)
+		s.MarshaledSize += quotedLen(tv)
+	case []any:
+		s.Arrays++
+		s.MaxDepth = max(s.MaxDepth, depth+1)
+		s.MarshaledSize += 2 + max(len(tv)-1, 0) // brackets and commas
+		return true
+	case map[string]any:
+		s.Objects++
+		s.MaxDepth = max(s.MaxDepth, depth+1)
+		s.MarshaledSize += 2 + max(len(tv)-1, 0) // braces and commas
+		return true
+	case *OrderedObject:
+		if tv == nil {
+			s.Nulls++
+			s.MarshaledSize += len(nullBytes)
+			return false
+		}
+		s.Objects++
+		s.MaxDepth = max(s.MaxDepth, depth+1)
+		s.MarshaledSize += 2 + max(len(tv.Members)-1, 0)
+		return true
+	default:
+		s.Others++
+	}
+	return false
+}
+
+func (s *TreeStats) countKey(key string) {
+	s.KeyBytes += len(key)
+	s.MarshaledSize += quotedLen(key) + 1 // key and colon
+}
+
+// Returns the length of the decimal representation of i.
+func intLen(i int64) int {
+	var buf [20]byte
+	return len(AppendInt(buf[:0], i))
+}
+
+// Returns the length of f as written by the Emitter with the given bit size.
+func floatLen(f float64, bitSize int) int {
+	var buf [32]byte
+	return len(AppendFloat(buf[:0], f, FloatFormat{BitSize: bitSize}))
+}
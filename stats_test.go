@@ -0,0 +1,96 @@
+package simplejsonext
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStats(t *testing.T) {
+	tree, err := UnmarshalString(`{
+		"name": "run\n1",
+		"values": [1, -2.5, NaN, -Infinity, 1e300, null, true, false],
+		"nested": {"deep": [[{}]], "": ""}
+	}`)
+	require.NoError(t, err)
+	s := Stats(tree)
+	assert.Equal(t, TreeStats{
+		Objects:       3,
+		Arrays:        3,
+		Strings:       2,
+		Numbers:       5,
+		Bools:         2,
+		Nulls:         1,
+		MaxDepth:      5,
+		StringBytes:   5,
+		KeyBytes:      len("name") + len("values") + len("nested") + len("deep"),
+		MarshaledSize: s.MarshaledSize,
+	}, s)
+
+	out, err := Marshal(tree)
+	require.NoError(t, err)
+	assert.Equal(t, len(out), s.MarshaledSize)
+}
+
+func TestStatsMarshaledSize(t *testing.T) {
+	for _, v := range []any{
+		nil,
+		int64(math.MinInt64),
+		math.Inf(1),
+		math.Copysign(0, -1),
+		5e-324,
+		"\x00\x1f\"\\/  \U0001f4a5",
+		[]any{},
+		map[string]any{},
+		map[string]any{"\t": []any{map[string]any{"a": nil}}},
+		&OrderedObject{Members: []Member{{"b", int64(1)}, {"a", "x"}}},
+	} {
+		out, err := Marshal(v)
+		require.NoError(t, err)
+		assert.Equal(t, len(out), Stats(v).MarshaledSize, "%s", out)
+	}
+}
+
+func TestStatsDeep(t *testing.T) {
+	const depth = 1_000_000
+	var tree any = "bottom"
+	for i := 0; i < depth; i++ {
+		if i%2 == 0 {
+			tree = []any{tree}
+		} else {
+			tree = map[string]any{"k": tree}
+		}
+	}
+	s := Stats(tree)
+	assert.Equal(t, depth, s.MaxDepth)
+	assert.Equal(t, depth/2, s.Arrays)
+	assert.Equal(t, depth/2, s.Objects)
+	assert.Equal(t, 1, s.Strings)
+}
+
+func TestStatsOthers(t *testing.T) {
+	s := Stats([]any{struct{}{}, 5})
+	assert.Equal(t, 2, s.Others)
+	assert.Equal(t, 1, s.Arrays)
+}
+
+func TestStatsAllocations(t *testing.T) {
+	tree, err := UnmarshalString(`{"a": [1, 2.5, "three", {"b": [true, null]}], "c": "d"}`)
+	require.NoError(t, err)
+	allocs := testing.AllocsPerRun(100, func() {
+		Stats(tree)
+	})
+	assert.Zero(t, allocs)
+
+	// However wide the objects and arrays are.
+	wide := make([]any, 1000)
+	for i := range wide {
+		wide[i] = map[string]any{"i": int64(i), "list": []any{"x"}}
+	}
+	var v any = []any{wide, map[string]any{"wide": wide}}
+	assert.Zero(t, testing.AllocsPerRun(100, func() {
+		Stats(v)
+	}))
+}
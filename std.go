@@ -0,0 +1,162 @@
+package simplejsonext
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// NonFinitePolicy determines what happens to NaN and infinite numbers when
+// producing values for consumers that only understand standard JSON.
+type NonFinitePolicy int
+
+const (
+	// NonFiniteToString replaces NaN and infinities with the strings "NaN",
+	// "Infinity" and "-Infinity", like WalkDeNaN.
+	NonFiniteToString NonFinitePolicy = iota
+	// NonFiniteToNull replaces NaN and infinities with nil.
+	NonFiniteToNull
+	// NonFiniteError fails on NaN and infinities.
+	NonFiniteError
+)
+
+// LargeIntPolicy determines what happens to integers that a float64 cannot
+// represent exactly, those beyond ±2^53.
+type LargeIntPolicy int
+
+const (
+	// LargeIntError fails on integers that would lose precision.
+	LargeIntError LargeIntPolicy = iota
+	// LargeIntToString replaces such integers with their decimal string.
+	LargeIntToString
+	// LargeIntRound converts such integers to the nearest float64 anyway.
+	LargeIntRound
+)
+
+// Largest magnitude below which every integer is exactly representable as a
+// float64
+const maxExactFloatInt = 1 << 53
+
+// StdJSONOptions configures ToStdJSONWithOptions.
+type StdJSONOptions struct {
+	NonFinite NonFinitePolicy
+	LargeInts LargeIntPolicy
+}
+
+// ToStdJSON converts a simple JSON value to the conventions of encoding/json:
+// every number becomes a float64, NaN and infinities become strings as with
+// NonFiniteToString, and ordered objects become maps. Integers that a float64
+// cannot hold exactly are an error. The result is a deep copy that shares no
+// containers with v, and can always be marshaled by encoding/json if v holds
+// only simple JSON types.
+func ToStdJSON(v any) (any, error) {
+	return ToStdJSONWithOptions(v, StdJSONOptions{})
+}
+
+// ToStdJSONWithOptions is like ToStdJSON, with the given policies for
+// non-finite numbers and large integers.
+//
+// Errors are returned as a *PathError naming where in v the problem was.
+func ToStdJSONWithOptions(v any, opts StdJSONOptions) (any, error) {
+	c := stdConverter{opts: &opts}
+	return c.convert(v)
+}
+
+type stdConverter struct {
+	opts *StdJSONOptions
+	path []any
+}
+
+func (c *stdConverter) convert(v any) (any, error) {
+	switch tv := v.(type) {
+	case int64:
+		if tv > maxExactFloatInt || tv < -maxExactFloatInt {
+			switch c.opts.LargeInts {
+			case LargeIntToString:
+				return strconv.FormatInt(tv, 10), nil
+			case LargeIntRound:
+				// convert below
+			default:
+				return nil, pathError(c.path,
+					fmt.Errorf("integer %d cannot be represented exactly as a float64", tv))
+			}
+		}
+		return float64(tv), nil
+	case float64:
+		if math.IsNaN(tv) || math.IsInf(tv, 0) {
+			switch c.opts.NonFinite {
+			case NonFiniteToNull:
+				return nil, nil
+			case NonFiniteError:
+				return nil, pathError(c.path, fmt.Errorf("non-finite number %v is not valid JSON", tv))
+			default:
+				return deNaN(tv), nil
+			}
+		}
+		return tv, nil
+	case []any:
+		res := make([]any, len(tv))
+		for i, child := range tv {
+			c.path = append(c.path, i)
+			converted, err := c.convert(child)
+			c.path = c.path[:len(c.path)-1]
+			if err != nil {
+				return nil, err
+			}
+			res[i] = converted
+		}
+		return res, nil
+	case map[string]any:
+		res := make(map[string]any, len(tv))
+		for key, child := range tv {
+			if err := c.convertMember(res, key, child); err != nil {
+				return nil, err
+			}
+		}
+		return res, nil
+	case *OrderedObject:
+		if tv == nil {
+			return nil, nil
+		}
+		res := make(map[string]any, len(tv.Members))
+		for _, member := range tv.Members {
+			if err := c.convertMember(res, member.Key, member.Value); err != nil {
+				return nil, err
+			}
+		}
+		return res, nil
+	default:
+		return v, nil
+	}
+}
+
+func (c *stdConverter) convertMember(res map[string]any, key string, v any) error {
+	c.path = append(c.path, key)
+	converted, err := c.convert(v)
+	c.path = c.path[:len(c.path)-1]
+	if err != nil {
+		return err
+	}
+	res[key] = converted
+	return nil
+}
+
+// FromStdJSON converts a value decoded by encoding/json, in which every number
+// is a float64, to the representation the parser produces: float64 values
+// that are integral and within the range of int64 become int64. Negative zero
+// stays a float64 so its sign is not lost, and all other values are left as
+// they are.
+//
+// The input is not modified; containers holding converted numbers are copied,
+// and the rest are shared with the result.
+func FromStdJSON(v any) any {
+	res, _ := Walk(v, func(_ []string, v any) (any, error) {
+		if f, ok := v.(float64); ok && !(f == 0 && math.Signbit(f)) {
+			if i, ok := AsInt64(f); ok {
+				return i, nil
+			}
+		}
+		return v, nil
+	})
+	return res
+}
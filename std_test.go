@@ -0,0 +1,137 @@
+package simplejsonext
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const stdDoc = `{
+	"int": 42,
+	"float": 1.5,
+	"nan": NaN,
+	"infs": [Infinity, -Infinity],
+	"exact": [9007199254740992, -9007199254740992],
+	"nested": {"list": [{"a": null, "b": true, "c": "str"}]}
+}`
+
+// Checks that v survives a round trip through encoding/json unchanged.
+func assertStdRoundTrip(t *testing.T, v any) {
+	t.Helper()
+	b, err := json.Marshal(v)
+	require.NoError(t, err)
+	var back any
+	require.NoError(t, json.Unmarshal(b, &back))
+	assert.Equal(t, v, back)
+}
+
+func TestToStdJSON(t *testing.T) {
+	tree, err := UnmarshalString(stdDoc)
+	require.NoError(t, err)
+
+	res, err := ToStdJSON(tree)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"int":    42.0,
+		"float":  1.5,
+		"nan":    "NaN",
+		"infs":   []any{"Infinity", "-Infinity"},
+		"exact":  []any{9007199254740992.0, -9007199254740992.0},
+		"nested": map[string]any{"list": []any{map[string]any{"a": nil, "b": true, "c": "str"}}},
+	}, res)
+	assertStdRoundTrip(t, res)
+
+	// The result shares nothing with the input
+	res.(map[string]any)["nested"].(map[string]any)["list"].([]any)[0].(map[string]any)["a"] = 1
+	a, err := Get(tree, "nested", "list", 0, "a")
+	require.NoError(t, err)
+	assert.Nil(t, a)
+
+	res, err = ToStdJSONWithOptions(tree, StdJSONOptions{NonFinite: NonFiniteToNull})
+	require.NoError(t, err)
+	assert.Nil(t, res.(map[string]any)["nan"])
+	assert.Equal(t, []any{nil, nil}, res.(map[string]any)["infs"])
+	assertStdRoundTrip(t, res)
+
+	_, err = ToStdJSONWithOptions(tree, StdJSONOptions{NonFinite: NonFiniteError})
+	var pathErr *PathError
+	require.ErrorAs(t, err, &pathErr)
+	assert.Contains(t, [][]any{{"nan"}, {"infs", 0}, {"infs", 1}}, pathErr.Path)
+}
+
+func TestToStdJSONLargeInts(t *testing.T) {
+	tree := []any{int64(1<<53 + 1), int64(-(1<<53 + 1)), int64(math.MaxInt64)}
+
+	_, err := ToStdJSON(tree)
+	assert.EqualError(t, err,
+		"simple json: at $[0]: integer 9007199254740993 cannot be represented exactly as a float64")
+
+	res, err := ToStdJSONWithOptions(tree, StdJSONOptions{LargeInts: LargeIntToString})
+	require.NoError(t, err)
+	assert.Equal(t, []any{"9007199254740993", "-9007199254740993", "9223372036854775807"}, res)
+	assertStdRoundTrip(t, res)
+
+	res, err = ToStdJSONWithOptions(tree, StdJSONOptions{LargeInts: LargeIntRound})
+	require.NoError(t, err)
+	assert.Equal(t, []any{9007199254740992.0, -9007199254740992.0, 9223372036854775807.0}, res)
+	assertStdRoundTrip(t, res)
+}
+
+func TestToStdJSONOrderedObject(t *testing.T) {
+	tree, err := NewParserFromString(`{"b": 1, "a": {"c": NaN}}`, WithOrderedObjects()).Parse()
+	require.NoError(t, err)
+	res, err := ToStdJSON(tree)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"b": 1.0, "a": map[string]any{"c": "NaN"}}, res)
+	assertStdRoundTrip(t, res)
+}
+
+func TestFromStdJSON(t *testing.T) {
+	var tree any
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"int": 42,
+		"float": 1.5,
+		"zero": 0,
+		"neg_zero": -0.0,
+		"exp": 1e3,
+		"bounds": [9007199254740991, 9007199254740992, 9007199254740993, -9007199254740993],
+		"huge": [9223372036854775808, -9223372036854775808, 1e300],
+		"other": {"s": "1", "b": true, "n": null}
+	}`), &tree))
+
+	res := FromStdJSON(tree).(map[string]any)
+	assert.Equal(t, int64(42), res["int"])
+	assert.Equal(t, 1.5, res["float"])
+	assert.Equal(t, int64(0), res["zero"])
+	assert.Equal(t, int64(1000), res["exp"])
+	// Above 2^53 encoding/json has already rounded the values to the nearest
+	// float64, which are still integral
+	assert.Equal(t, []any{
+		int64(9007199254740991),
+		int64(9007199254740992),
+		int64(9007199254740992),
+		int64(-9007199254740992),
+	}, res["bounds"])
+	// 2^63 does not fit in int64, but -2^63 does
+	assert.Equal(t, []any{9223372036854775808.0, int64(math.MinInt64), 1e300}, res["huge"])
+	assert.Equal(t, map[string]any{"s": "1", "b": true, "n": nil}, res["other"])
+
+	negZero, ok := res["neg_zero"].(float64)
+	require.True(t, ok, "negative zero must stay a float64")
+	assert.True(t, math.Signbit(negZero))
+
+	// The input still holds floats
+	assert.Equal(t, 42.0, tree.(map[string]any)["int"])
+}
+
+func TestStdJSONRoundTrip(t *testing.T) {
+	tree, err := UnmarshalString(`{"a": [1, -2, 3.25, -0.0, 9007199254740992], "b": {"c": "d"}}`)
+	require.NoError(t, err)
+	std, err := ToStdJSON(tree)
+	require.NoError(t, err)
+	back := FromStdJSON(std)
+	assert.Equal(t, tree, back)
+}
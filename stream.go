@@ -0,0 +1,118 @@
+package simplejsonext
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+)
+
+// Decoder reads and decodes JSON values from a stream, with the same methods
+// as json.Decoder, so that code using one can switch to the other by changing
+// its imports. Values decode as they would with Parser.ParseInto.
+type Decoder struct {
+	p *parser
+}
+
+// NewDecoder returns a new Decoder that reads from r. Like json.Decoder, it
+// may read data from r beyond the values requested.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{p: NewParser(r).(*parser)}
+}
+
+// Decode reads the next JSON value from its input and stores it in the value
+// pointed to by v, which may be any destination that UnmarshalInto accepts.
+// At the end of the input, it returns the exact error io.EOF.
+func (d *Decoder) Decode(v any) error {
+	if err := d.p.beginTokenValue(); err != nil {
+		return err
+	}
+	err := d.p.ParseInto(v)
+	if len(d.p.tokens) > 0 {
+		d.p.endTokenValue()
+	}
+	return err
+}
+
+// UseNumber makes the Decoder produce numbers as Number values, which keep
+// their exact text, rather than as int64 and float64.
+func (d *Decoder) UseNumber() {
+	d.p.cfg.exactNumbers = true
+}
+
+// DisallowUnknownFields makes the Decoder fail when an object decoded into a
+// struct has a member that matches none of its fields, rather than skipping
+// the member.
+func (d *Decoder) DisallowUnknownFields() {
+	d.p.cfg.disallowUnknownFields = true
+}
+
+// More reports whether there is another element in the current array or
+// object being read with Token, or another value in the input.
+func (d *Decoder) More() bool {
+	return d.p.More()
+}
+
+// Token returns the next token in the input, as Parser.StdToken does.
+func (d *Decoder) Token() (json.Token, error) {
+	return d.p.StdToken()
+}
+
+// InputOffset returns the offset in the input of the end of the most recent
+// token or value read.
+func (d *Decoder) InputOffset() int64 {
+	return d.p.InputOffset()
+}
+
+// Buffered returns a reader of the data remaining in the Decoder's buffer,
+// which it has read from its input but not yet decoded.
+func (d *Decoder) Buffered() io.Reader {
+	return bytes.NewReader(d.p.readBuf[d.p.begin:d.p.size])
+}
+
+// Encoder writes JSON values to a stream, with the same methods as
+// json.Encoder, so that code using one can switch to the other by changing
+// its imports. Values are written as by the Emitter, with the keys of maps
+// sorted as json.Encoder sorts them.
+type Encoder struct {
+	w    io.Writer
+	opts emitConfig
+	buf  bytes.Buffer
+}
+
+// NewEncoder returns a new Encoder that writes to w. Like json.Encoder, it
+// escapes HTML characters in strings until told otherwise.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w, opts: emitConfig{escapeHTML: true, escapeSeparators: true, sortKeys: true}}
+}
+
+// Encode writes the JSON encoding of v to the stream, followed by a newline.
+// Nothing is written if v cannot be encoded.
+func (enc *Encoder) Encode(v any) error {
+	enc.buf.Reset()
+	e := GetEmitter(&enc.buf)
+	defer PutEmitter(e)
+	e.(*emitter).cfg = enc.opts
+	if err := e.Emit(v); err != nil {
+		return err
+	}
+	enc.buf.WriteByte('\n')
+	_, err := enc.w.Write(enc.buf.Bytes())
+	if enc.buf.Cap() > oversizedBuffer {
+		enc.buf = bytes.Buffer{}
+	}
+	return err
+}
+
+// SetIndent makes the Encoder indent each value as WithIndent does.
+// Calling it with two empty strings turns indentation off.
+func (enc *Encoder) SetIndent(prefix, indent string) {
+	enc.opts.indented = prefix != "" || indent != ""
+	enc.opts.prefix = prefix
+	enc.opts.indent = indent
+}
+
+// SetEscapeHTML sets whether <, > and & are escaped in strings. As with
+// json.Encoder, U+2028 and U+2029 are escaped either way.
+func (enc *Encoder) SetEscapeHTML(on bool) {
+	enc.opts.escapeHTML = on
+}
@@ -0,0 +1,158 @@
+package simplejsonext
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The methods that Decoder and json.Decoder share.
+type stdDecoder interface {
+	Decode(v any) error
+	More() bool
+	Token() (json.Token, error)
+	Buffered() io.Reader
+	InputOffset() int64
+	UseNumber()
+}
+
+// The methods that Encoder and json.Encoder share.
+type stdEncoder interface {
+	Encode(v any) error
+	SetIndent(prefix, indent string)
+	SetEscapeHTML(on bool)
+}
+
+var (
+	_ stdDecoder = &json.Decoder{}
+	_ stdDecoder = &Decoder{}
+	_ stdEncoder = &json.Encoder{}
+	_ stdEncoder = &Encoder{}
+)
+
+// Normalizes a decoded value for comparison: the packages differ in their
+// number types, but not in how encoding/json writes them.
+func normalized(t *testing.T, v any) string {
+	if n, ok := v.(Number); ok {
+		v = json.Number(n)
+	}
+	b, err := json.Marshal(v)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestDecoderCompatibility(t *testing.T) {
+	const input = `{"a": [1, 2.5, "x"]} [true, null] "tail" {"b": {"c": [0]}}` + "\n" + `[{"d": 1}, {"e": 2}] 7 `
+	run := func(d stdDecoder) (log []string) {
+		// Decode a few whole values.
+		for i := 0; i < 3; i++ {
+			var v any
+			if err := d.Decode(&v); err != nil {
+				return append(log, "error")
+			}
+			log = append(log, normalized(t, v), normalizedBool(d.More()), fmt.Sprint(d.InputOffset()))
+		}
+		// Then walk into an object with tokens, decoding a member's value.
+		for i := 0; i < 2; i++ {
+			tok, err := d.Token()
+			if err != nil {
+				return append(log, "error")
+			}
+			log = append(log, normalized(t, tok))
+		}
+		var v any
+		if err := d.Decode(&v); err != nil {
+			return append(log, "error")
+		}
+		log = append(log, normalized(t, v))
+		tok, _ := d.Token()
+		log = append(log, normalized(t, tok))
+		// Then stream an array's elements.
+		tok, _ = d.Token()
+		log = append(log, normalized(t, tok))
+		for d.More() {
+			if err := d.Decode(&v); err != nil {
+				return append(log, "error")
+			}
+			log = append(log, normalized(t, v))
+		}
+		tok, _ = d.Token()
+		log = append(log, normalized(t, tok))
+		d.UseNumber()
+		if err := d.Decode(&v); err != nil {
+			return append(log, "error")
+		}
+		log = append(log, normalized(t, v), normalizedBool(d.More()))
+		err := d.Decode(&v)
+		log = append(log, normalizedBool(err == io.EOF))
+		return log
+	}
+
+	expected := run(json.NewDecoder(strings.NewReader(input)))
+	assert.NotContains(t, expected, "error")
+	assert.Equal(t, expected, run(NewDecoder(strings.NewReader(input))))
+	assert.Equal(t, expected, run(NewDecoder(iotest.OneByteReader(strings.NewReader(input)))))
+}
+
+func normalizedBool(b bool) string {
+	if b {
+		return "true"
+	}
+	return "false"
+}
+
+func TestDecoderBuffered(t *testing.T) {
+	d := NewDecoder(strings.NewReader(`{"a": 1} trailing`))
+	var v map[string]any
+	require.NoError(t, d.Decode(&v))
+	rest, err := io.ReadAll(d.Buffered())
+	require.NoError(t, err)
+	assert.Equal(t, " trailing", string(rest))
+
+	d = NewDecoder(strings.NewReader(`1.50`))
+	d.UseNumber()
+	var n any
+	require.NoError(t, d.Decode(&n))
+	assert.Equal(t, Number("1.50"), n)
+}
+
+func TestEncoderCompatibility(t *testing.T) {
+	values := []any{
+		map[string]any{"html": "<a href=\"x\">&amp;</a>", "b": int64(2), "a": int64(1), "B": nil},
+		[]any{map[string]any{"sep": "line para "}},
+		[]any{int64(1), 2.5, "x", nil, true, []any{}, map[string]any{}},
+		"plain",
+		map[string]any{"nested": []any{map[string]any{"k": []any{int64(1), int64(2)}, "j": "x"}}, "m": map[string]int{"z": 1, "y": 2}},
+	}
+	run := func(enc stdEncoder, out *bytes.Buffer) string {
+		for _, v := range values {
+			require.NoError(t, enc.Encode(v))
+		}
+		enc.SetEscapeHTML(false)
+		enc.SetIndent(">", "\t")
+		for _, v := range values {
+			require.NoError(t, enc.Encode(v))
+		}
+		enc.SetIndent("", "")
+		for _, v := range values {
+			require.NoError(t, enc.Encode(v))
+		}
+		return out.String()
+	}
+	var stdOut, ourOut bytes.Buffer
+	expected := run(json.NewEncoder(&stdOut), &stdOut)
+	assert.Equal(t, expected, run(NewEncoder(&ourOut), &ourOut))
+
+	// Nothing is written for values that cannot be encoded.
+	ourOut.Reset()
+	enc := NewEncoder(&ourOut)
+	assert.Error(t, enc.Encode([]any{int64(1), complex(1, 2)}))
+	assert.Zero(t, ourOut.Len())
+}
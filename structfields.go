@@ -0,0 +1,252 @@
+package simplejsonext
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// A field of a struct as it appears in JSON.
+type structField struct {
+	name  string // the key of the member
+	index []int  // for reflect.Value.FieldByIndex, through embedded structs
+	typ   reflect.Type
+	// The name of the Go field, through the embedded structs it is promoted
+	// from, for error messages
+	goName    string
+	tagged    bool // whether the name came from a json tag
+	omitEmpty bool // whether the field is left out when empty
+}
+
+type structInfo struct {
+	fields []structField // in the order of their declaration
+	byName map[string]*structField
+}
+
+var structInfoCache sync.Map // reflect.Type -> *structInfo
+
+// Returns the fields of the struct type t that take part in JSON, following
+// the rules of encoding/json: exported fields, and unexported embedded
+// structs, are included unless tagged `json:"-"`; a tag may rename a field;
+// and the fields of embedded structs without a name in their tag are
+// promoted, unless a field with the same name is less deeply embedded or,
+// at the same depth, tagged.
+func cachedStructInfo(t reflect.Type) *structInfo {
+	if info, ok := structInfoCache.Load(t); ok {
+		return info.(*structInfo)
+	}
+	info, _ := structInfoCache.LoadOrStore(t, newStructInfo(t))
+	return info.(*structInfo)
+}
+
+func newStructInfo(t reflect.Type) *structInfo {
+	type candidate struct {
+		structField
+		depth int
+	}
+	var candidates []candidate
+	type embedded struct {
+		typ    reflect.Type
+		index  []int
+		goName string
+	}
+	current := []embedded{{typ: t}}
+	visited := map[reflect.Type]bool{}
+	for depth := 0; len(current) > 0; depth++ {
+		var next []embedded
+		for _, outer := range current {
+			if visited[outer.typ] {
+				continue
+			}
+			visited[outer.typ] = true
+			for i := range outer.typ.NumField() {
+				sf := outer.typ.Field(i)
+				ft := sf.Type
+				if sf.Anonymous && ft.Kind() == reflect.Pointer {
+					ft = ft.Elem()
+				}
+				if !sf.IsExported() && !(sf.Anonymous && ft.Kind() == reflect.Struct) {
+					continue
+				}
+				tag := sf.Tag.Get("json")
+				if tag == "-" {
+					continue
+				}
+				name, opts, _ := strings.Cut(tag, ",")
+				index := append(outer.index[:len(outer.index):len(outer.index)], i)
+				goName := sf.Name
+				if outer.goName != "" {
+					goName = outer.goName + "." + sf.Name
+				}
+				if sf.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+					next = append(next, embedded{typ: ft, index: index, goName: goName})
+					continue
+				}
+				if !sf.IsExported() {
+					continue
+				}
+				f := candidate{structField{
+					name:      name,
+					index:     index,
+					typ:       sf.Type,
+					goName:    goName,
+					tagged:    name != "",
+					omitEmpty: hasTagOption(opts, "omitempty"),
+				}, depth}
+				if name == "" {
+					f.name = sf.Name
+				}
+				candidates = append(candidates, f)
+			}
+		}
+		current = next
+	}
+
+	// Of the fields with each name, keep the one that dominates the others,
+	// if any does.
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := &candidates[i], &candidates[j]
+		if a.name != b.name {
+			return a.name < b.name
+		} else if a.depth != b.depth {
+			return a.depth < b.depth
+		}
+		return a.tagged && !b.tagged
+	})
+	var fields []structField
+	for i := 0; i < len(candidates); {
+		j := i + 1
+		for j < len(candidates) && candidates[j].name == candidates[i].name {
+			j++
+		}
+		first := candidates[i]
+		if j == i+1 || candidates[i+1].depth > first.depth || first.tagged && !candidates[i+1].tagged {
+			fields = append(fields, first.structField)
+		}
+		i = j
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		a, b := fields[i].index, fields[j].index
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+
+	info := &structInfo{fields: fields, byName: make(map[string]*structField, len(fields))}
+	for i := range info.fields {
+		info.byName[info.fields[i].name] = &info.fields[i]
+	}
+	return info
+}
+
+// Returns the field for key, matching names exactly or, failing that,
+// ignoring case as encoding/json does.
+func (info *structInfo) field(key []byte) *structField {
+	if f, ok := info.byName[string(key)]; ok {
+		return f
+	}
+	for i := range info.fields {
+		if strings.EqualFold(info.fields[i].name, string(key)) {
+			return &info.fields[i]
+		}
+	}
+	return nil
+}
+
+func hasTagOption(opts, option string) bool {
+	for opts != "" {
+		var opt string
+		opt, opts, _ = strings.Cut(opts, ",")
+		if opt == option {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the members that the struct rv is written as, in the order its
+// fields are declared, leaving out fields tagged omitempty that are empty
+// and fields of nil embedded pointers. Fields of types that can never be
+// written are an error naming them.
+func structMembers(rv reflect.Value) ([]Member, error) {
+	info := cachedStructInfo(rv.Type())
+	members := make([]Member, 0, len(info.fields))
+Fields:
+	for i := range info.fields {
+		f := &info.fields[i]
+		if !emittableType(f.typ) {
+			return nil, fmt.Errorf("simple json: cannot emit field %s.%s of unsupported type %s", rv.Type(), f.goName, f.typ)
+		}
+		fv := rv
+		for j, x := range f.index {
+			if j > 0 && fv.Kind() == reflect.Pointer {
+				if fv.IsNil() {
+					continue Fields
+				}
+				fv = fv.Elem()
+			}
+			fv = fv.Field(x)
+		}
+		if f.omitEmpty && isEmptyValue(fv) {
+			continue
+		}
+		members = append(members, Member{Key: f.name, Value: fv.Interface()})
+	}
+	return members, nil
+}
+
+// Reports whether values of type t might be written, which values of
+// interface types always might.
+func emittableType(t reflect.Type) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Complex64, reflect.Complex128, reflect.UnsafePointer:
+		return false
+	case reflect.Map:
+		return t.Key().Kind() == reflect.String
+	}
+	return true
+}
+
+// Reports whether a field tagged omitempty is left out, by the rules of
+// encoding/json.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Interface, reflect.Pointer:
+		return v.IsZero()
+	}
+	return false
+}
+
+// Returns the value of rv, of a named or sized bool, integer, float or string
+// type that the Emitter has no case for, as the type it does have one for.
+func baseValue(rv reflect.Value) (any, bool) {
+	switch rv.Kind() {
+	case reflect.Bool:
+		return rv.Bool(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return rv.Uint(), true
+	case reflect.Float32:
+		return float32(rv.Float()), true
+	case reflect.Float64:
+		return rv.Float(), true
+	case reflect.String:
+		return rv.String(), true
+	}
+	return nil, false
+}
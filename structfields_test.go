@@ -0,0 +1,131 @@
+package simplejsonext
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type emitLabel string
+
+type emitMeta struct {
+	Created int64  `json:"created"`
+	Owner   string `json:"owner,omitempty"`
+}
+
+type EmitAudit struct {
+	By   string `json:"by"`
+	Note string
+}
+
+type emitRecord struct {
+	emitMeta
+	*EmitAudit
+	Name     string              `json:"name"`
+	Owner    string              `json:"owner"` // shadows emitMeta.Owner
+	Label    emitLabel           `json:"label,omitempty"`
+	Score    float64             `json:"score,omitempty"`
+	Small    int8                `json:"small"`
+	Flag     bool                `json:"flag,omitempty"`
+	Tags     []string            `json:"tags,omitempty"`
+	Attrs    map[string]int      `json:"attrs,omitempty"`
+	Parent   *emitRecord         `json:"parent,omitempty"`
+	Children []emitRecord        `json:"children,omitempty"`
+	Extra    any                 `json:"extra,omitempty"`
+	Nested   map[string]emitMeta `json:"nested,omitempty"`
+	Skipped  string              `json:"-"`
+	Zero     float32             `json:",omitempty"`
+	private  int
+}
+
+func TestMarshalStruct(t *testing.T) {
+	v := emitRecord{
+		emitMeta:  emitMeta{Created: 5, Owner: "hidden"},
+		EmitAudit: &EmitAudit{By: "me"},
+		Name:      "root",
+		Owner:     "team",
+		Label:     "x",
+		Small:     -2,
+		Tags:      []string{"a"},
+		Attrs:     map[string]int{"n": 1},
+		Parent:    &emitRecord{Name: "up"},
+		Children:  []emitRecord{{Name: "kid", Score: 0.5, Flag: true}},
+		Extra:     []any{int64(1), "two"},
+		Nested:    map[string]emitMeta{"m": {Created: 1}},
+		Skipped:   "no",
+	}
+	const expected = `{"created":5,"by":"me","Note":"","name":"root","owner":"team","label":"x","small":-2,` +
+		`"tags":["a"],"attrs":{"n":1},"parent":{"created":0,"name":"up","owner":"","small":0},` +
+		`"children":[{"created":0,"name":"kid","owner":"","score":0.5,"small":0,"flag":true}],` +
+		`"extra":[1,"two"],"nested":{"m":{"created":1}}}`
+	std, err := json.Marshal(v)
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(std))
+
+	out, err := Marshal(v)
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(out))
+	s, err := MarshalToString(&v)
+	require.NoError(t, err)
+	assert.Equal(t, expected, s)
+	assert.Equal(t, len(expected), EstimateMarshalSize(v))
+	assert.NoError(t, CheckMarshalable(v))
+
+	// Read back, it is the same but for the fields that were left out.
+	var back emitRecord
+	require.NoError(t, UnmarshalInto(out, &back))
+	v.emitMeta.Owner, v.Skipped = "", ""
+	assert.Equal(t, v, back)
+
+	// Nil embedded pointers and empty values leave their fields out.
+	out, err = Marshal(emitRecord{Zero: 1})
+	require.NoError(t, err)
+	assert.Equal(t, `{"created":0,"name":"","owner":"","small":0,"Zero":1}`, string(out))
+}
+
+func TestMarshalStructOptions(t *testing.T) {
+	type point struct {
+		X, Y float64
+	}
+	v := []point{{1, math.NaN()}, {math.Inf(-1), 0.25}}
+	out, err := MarshalWithOptions(v, WithIndent("", " "))
+	require.NoError(t, err)
+	assert.Equal(t, "[\n {\n  \"X\": 1,\n  \"Y\": NaN\n },\n {\n  \"X\": -Infinity,\n  \"Y\": 0.25\n }\n]", string(out))
+	_, err = MarshalWithOptions(v, WithStrictFloats())
+	assert.EqualError(t, err, "simple json: cannot emit non-finite float NaN")
+
+	// Deep values are written the same way.
+	deep := nestValue(point{X: 1}, 3*maxEmitRecursion, wrapSlice)
+	out, err = Marshal(deep)
+	require.NoError(t, err)
+	assert.Equal(t, strings.Repeat("[", 3*maxEmitRecursion)+`{"X":1,"Y":0}`+strings.Repeat("]", 3*maxEmitRecursion), string(out))
+}
+
+func TestMarshalStructErrors(t *testing.T) {
+	type callbacks struct {
+		Name   string
+		OnDone func() `json:"on_done,omitempty"`
+	}
+	for _, v := range []any{callbacks{}, []any{&callbacks{Name: "x"}}, map[string]any{"deep": nestValue(callbacks{}, 3*maxEmitRecursion, wrapSlice)}} {
+		_, err := Marshal(v)
+		assert.ErrorContains(t, err, "simple json: cannot emit field simplejsonext.callbacks.OnDone of unsupported type func()")
+		assert.Equal(t, -1, EstimateMarshalSize(v))
+	}
+
+	type keyed struct {
+		Inner struct {
+			ByID map[int]string
+		}
+		Extra any
+	}
+	_, err := Marshal(keyed{})
+	assert.EqualError(t, err, `simple json: cannot emit field struct { ByID map[int]string }.ByID of unsupported type map[int]string`)
+	_, err = Marshal(keyed{Extra: make(chan int)})
+	assert.ErrorContains(t, err, "cannot emit field")
+	_, err = Marshal(struct{ Extra any }{make(chan int)})
+	assert.EqualError(t, err, "simple json: cannot emit unsupported type chan int")
+}
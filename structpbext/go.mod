@@ -0,0 +1,17 @@
+module github.com/wandb/simplejsonext/structpbext
+
+go 1.22.1
+
+require (
+	github.com/stretchr/testify v1.9.0
+	github.com/wandb/simplejsonext v0.0.0
+	google.golang.org/protobuf v1.34.2
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/wandb/simplejsonext => ../
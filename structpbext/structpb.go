@@ -0,0 +1,82 @@
+// Package structpbext converts between the values of package simplejsonext
+// and the google.protobuf.Value messages of package structpb. It is a module
+// of its own so that simplejsonext itself depends on nothing but the standard
+// library.
+package structpbext
+
+import (
+	"math"
+
+	"github.com/wandb/simplejsonext"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// ToStructpb converts a simple JSON value to a *structpb.Value, with the
+// conventions of simplejsonext.ToStdJSON: every number becomes a number
+// value, NaN and infinities become the strings "NaN", "Infinity" and
+// "-Infinity", and ordered objects become struct values, which have no order.
+// Integers that a number value cannot hold exactly, those beyond ±2^53, are an
+// error.
+//
+// A number value can hold NaN and infinities, but protojson and most other
+// encoders of JSON cannot write them, so they are not kept by default.
+func ToStructpb(v any) (*structpb.Value, error) {
+	return ToStructpbWithOptions(v, simplejsonext.StdJSONOptions{})
+}
+
+// ToStructpbWithOptions is like ToStructpb, with the given policies for
+// non-finite numbers and large integers.
+//
+// Errors are returned as a *simplejsonext.PathError naming where in v the
+// problem was, except for values of types that are not simple JSON.
+func ToStructpbWithOptions(v any, opts simplejsonext.StdJSONOptions) (*structpb.Value, error) {
+	std, err := simplejsonext.ToStdJSONWithOptions(v, opts)
+	if err != nil {
+		return nil, err
+	}
+	return structpb.NewValue(std)
+}
+
+// FromStructpb converts a *structpb.Value to a simple JSON value, in the form
+// the parser would produce from the protojson encoding of v: numbers that are
+// integral and within the range of int64 become int64, as with
+// simplejsonext.FromStdJSON, and struct values become maps. Numbers that
+// protojson cannot write, NaN and infinities, are kept as float64 values.
+// Strings are left as they are, even those that ToStructpb made of NaN,
+// infinities or large integers. A nil v, or one with no kind set, converts to
+// nil.
+func FromStructpb(v *structpb.Value) any {
+	switch kind := v.GetKind().(type) {
+	case *structpb.Value_NumberValue:
+		f := kind.NumberValue
+		if f == 0 && math.Signbit(f) {
+			return f
+		} else if i, ok := simplejsonext.AsInt64(f); ok {
+			return i
+		}
+		return f
+	case *structpb.Value_StringValue:
+		return kind.StringValue
+	case *structpb.Value_BoolValue:
+		return kind.BoolValue
+	case *structpb.Value_StructValue:
+		fields := kind.StructValue.GetFields()
+		m := make(map[string]any, len(fields))
+		for key, field := range fields {
+			m[key] = FromStructpb(field)
+		}
+		return m
+	case *structpb.Value_ListValue:
+		values := kind.ListValue.GetValues()
+		var s []any // nil if empty, as the parser makes it
+		if len(values) > 0 {
+			s = make([]any, len(values))
+		}
+		for i, value := range values {
+			s[i] = FromStructpb(value)
+		}
+		return s
+	default: // null, or no kind
+		return nil
+	}
+}
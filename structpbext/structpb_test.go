@@ -0,0 +1,101 @@
+package structpbext
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wandb/simplejsonext"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestRoundTrip(t *testing.T) {
+	v := map[string]any{
+		"int":     int64(-42),
+		"max":     int64(1 << 53),
+		"float":   1.5,
+		"integer": 2.0,
+		"str":     "é",
+		"null":    nil,
+		"bool":    true,
+		"list":    []any{int64(1), []any{}, map[string]any{}},
+		"ordered": &simplejsonext.OrderedObject{Members: []simplejsonext.Member{{Key: "z", Value: int64(1)}}},
+	}
+	// What the parser would produce from the same value written as JSON.
+	expected := map[string]any{
+		"int":     int64(-42),
+		"max":     int64(1 << 53),
+		"float":   1.5,
+		"integer": int64(2),
+		"str":     "é",
+		"null":    nil,
+		"bool":    true,
+		"list":    []any{int64(1), []any(nil), map[string]any{}},
+		"ordered": map[string]any{"z": int64(1)},
+	}
+	pb, err := ToStructpb(v)
+	require.NoError(t, err)
+	assert.Equal(t, expected, FromStructpb(pb))
+	assert.Equal(t, -42.0, pb.GetStructValue().GetFields()["int"].GetNumberValue())
+
+	// Through protojson, as another service would see it.
+	text, err := protojson.Marshal(pb)
+	require.NoError(t, err)
+	parsed, err := simplejsonext.Unmarshal(text)
+	require.NoError(t, err)
+	assert.Equal(t, expected, parsed)
+	var decoded structpb.Value
+	require.NoError(t, protojson.Unmarshal(text, &decoded))
+	assert.Equal(t, expected, FromStructpb(&decoded))
+
+	assert.Nil(t, FromStructpb(nil))
+	assert.Nil(t, FromStructpb(&structpb.Value{}))
+}
+
+func TestNonFinite(t *testing.T) {
+	v := []any{math.NaN(), math.Inf(1), math.Inf(-1), 0.5}
+	for _, test := range []struct {
+		policy   simplejsonext.NonFinitePolicy
+		expected any
+	}{
+		{simplejsonext.NonFiniteToString, []any{"NaN", "Infinity", "-Infinity", 0.5}},
+		{simplejsonext.NonFiniteToNull, []any{nil, nil, nil, 0.5}},
+	} {
+		pb, err := ToStructpbWithOptions(v, simplejsonext.StdJSONOptions{NonFinite: test.policy})
+		require.NoError(t, err)
+		assert.Equal(t, test.expected, FromStructpb(pb))
+		text, err := protojson.Marshal(pb)
+		require.NoError(t, err)
+		parsed, err := simplejsonext.Unmarshal(text)
+		require.NoError(t, err)
+		assert.Equal(t, test.expected, parsed)
+	}
+	_, err := ToStructpbWithOptions(v, simplejsonext.StdJSONOptions{NonFinite: simplejsonext.NonFiniteError})
+	assert.EqualError(t, err, "simple json: at $[0]: non-finite number NaN is not valid JSON")
+
+	// A number value holding NaN, from elsewhere, converts to NaN, though
+	// protojson cannot write it.
+	res := FromStructpb(structpb.NewNumberValue(math.NaN()))
+	assert.True(t, math.IsNaN(res.(float64)))
+	_, err = protojson.Marshal(structpb.NewNumberValue(math.NaN()))
+	assert.Error(t, err)
+}
+
+func TestLargeInts(t *testing.T) {
+	v := map[string]any{"id": int64(math.MaxInt64)}
+	_, err := ToStructpb(v)
+	assert.EqualError(t, err, `simple json: at $["id"]: integer 9223372036854775807 cannot be represented exactly as a float64`)
+
+	pb, err := ToStructpbWithOptions(v, simplejsonext.StdJSONOptions{LargeInts: simplejsonext.LargeIntToString})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"id": "9223372036854775807"}, FromStructpb(pb))
+
+	pb, err = ToStructpbWithOptions(v, simplejsonext.StdJSONOptions{LargeInts: simplejsonext.LargeIntRound})
+	require.NoError(t, err)
+	assert.Equal(t, float64(math.MaxInt64), pb.GetStructValue().GetFields()["id"].GetNumberValue())
+
+	_, err = ToStructpb(func() {})
+	assert.Error(t, err)
+}
@@ -0,0 +1,34 @@
+package simplejsonext
+
+import "io"
+
+// LastCompleteValueOffset finds where the last complete value in b ends, for
+// recovering an append-only log of concatenated or newline-delimited values
+// whose writer may have stopped partway through one. It returns the offset
+// just past that value, or 0 if there is none, and whether anything other
+// than whitespace follows it. Whatever follows is taken to be a value cut
+// short; a number at the very end of b counts as one too, since it may have
+// lost digits. If what follows is invalid in a way that more data could not
+// fix, err says why, and end is still the offset of the last good value.
+func LastCompleteValueOffset(b []byte) (end int64, partial bool, err error) {
+	p := parser{readBuf: b, size: len(b)}
+	for {
+		var ty valType
+		ty, err = p.parseType()
+		if err == io.EOF {
+			return end, false, nil
+		} else if err == nil {
+			err = p.Skip()
+		}
+		atEnd := p.begin >= p.size
+		if err == nil && !(ty == numberTy && atEnd) {
+			end = int64(p.begin)
+			continue
+		}
+		if err == nil || err == io.EOF || atEnd {
+			// The data ran out before the value did.
+			return end, true, nil
+		}
+		return end, true, err
+	}
+}
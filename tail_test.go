@@ -0,0 +1,73 @@
+package simplejsonext
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLastCompleteValueOffset(t *testing.T) {
+	records := []string{
+		`{"step": 1, "loss": 0.25, "name": "run \"one\"\\"}`,
+		`{"s": "é😀 é\t", "nested": [[], {}, [1, [2, {"x": null}]]]}`,
+		`-12.5e-3`,
+		`[true, false, null, NaN, -Infinity, 1e999, -0, 123456789012345678901234]`,
+		`"top level string with \/ escapes A"`,
+		`{"last": [Infinity]}`,
+	}
+	// Records are variously separated, including not at all.
+	separators := []string{"\n", " ", "\r\n", "", "\n\n"}
+	var sb strings.Builder
+	var ends []int
+	for i, record := range records {
+		sb.WriteString(record)
+		ends = append(ends, sb.Len())
+		sb.WriteString(separators[i%len(separators)])
+	}
+	log := sb.String()
+
+	for cut := 0; cut <= len(log); cut++ {
+		b := []byte(log[:cut])
+		end, partial, err := LastCompleteValueOffset(b)
+		require.NoError(t, err, "cut at %d", cut)
+
+		var expected int
+		for i, recordEnd := range ends {
+			// A number at the very end might be cut short.
+			if recordEnd < cut || recordEnd == cut && !strings.HasPrefix(records[i], "-") {
+				expected = recordEnd
+			}
+		}
+		require.Equal(t, int64(expected), end, "cut at %d: %q", cut, b)
+		require.Equal(t, strings.TrimSpace(log[expected:cut]) != "", partial, "cut at %d: %q", cut, b)
+	}
+}
+
+func TestLastCompleteValueOffsetInvalid(t *testing.T) {
+	for _, test := range []struct {
+		in      string
+		end     int64
+		partial bool
+		err     string
+	}{
+		{``, 0, false, ""},
+		{" \n\t", 0, false, ""},
+		{"{}\n", 2, false, ""},
+		{"7", 0, true, ""},
+		{"7 ", 1, false, ""},
+		{"[1, 2.5e", 0, true, ""},
+		{`{"a": 1}` + "\n" + `{"a": x}` + "\n" + `{"a": 2}`, 8, true, "simple json: expected token but found 'x'"},
+		{`{"a": 1} ]`, 8, true, errUnexpectedEnd.Error()},
+	} {
+		end, partial, err := LastCompleteValueOffset([]byte(test.in))
+		assert.Equal(t, test.end, end, test.in)
+		assert.Equal(t, test.partial, partial, test.in)
+		if test.err == "" {
+			assert.NoError(t, err, test.in)
+		} else {
+			assert.EqualError(t, err, test.err, test.in)
+		}
+	}
+}
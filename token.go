@@ -0,0 +1,195 @@
+package simplejsonext
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// What StdToken expects to find next.
+type tokenState byte
+
+const (
+	tokenValue tokenState = iota // a value: at the top level, or after ',' in an array or ':'
+	tokenFirst                   // the first element of a container, or its end
+	tokenNext                    // ',' or the end of the container after an element
+	tokenKey                     // an object key, after ','
+)
+
+var (
+	errExpectedKey = errors.New("simple json: expected object key")
+	errNotAtValue  = errors.New("simple json: not at beginning of value")
+)
+
+func (p *parser) StdToken() (json.Token, error) {
+	for {
+		ty, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		switch p.tokenState {
+		case tokenNext:
+			if ty == endGroupSym {
+				return p.closeToken()
+			}
+			if err = p.consumeComma(ty); err != nil {
+				return nil, err
+			}
+			if p.tokens[len(p.tokens)-1] == '{' {
+				p.tokenState = tokenKey
+			} else {
+				p.tokenState = tokenValue
+			}
+			continue
+		case tokenFirst:
+			if ty == endGroupSym {
+				return p.closeToken()
+			}
+			if p.tokens[len(p.tokens)-1] == '{' {
+				p.tokenState = tokenKey
+			} else {
+				p.tokenState = tokenValue
+			}
+		}
+
+		if p.tokenState == tokenKey {
+			if ty == endGroupSym {
+				return nil, errUnexpectedEnd
+			} else if ty == commaSym {
+				return nil, errUnexpectedComma
+			} else if ty != stringTy {
+				return nil, errExpectedKey
+			}
+			key, err := p.parseString()
+			if err != nil {
+				return nil, err
+			}
+			k := p.makeKey(key)
+			if err = p.skipSpaces(); err != nil {
+				return nil, err
+			}
+			if err = p.readByte(':'); err != nil {
+				return nil, err
+			}
+			p.tokenState = tokenValue
+			return k, nil
+		}
+		return p.valueToken(ty)
+	}
+}
+
+// Returns the value token starting with a byte of type ty.
+func (p *parser) valueToken(ty valType) (tok json.Token, err error) {
+	if len(p.tokens) > p.maxDepth() && ty != commaSym && ty != endGroupSym {
+		return nil, p.depthError(p.maxDepth() - len(p.tokens))
+	}
+	switch ty {
+	case nilTy:
+		err = p.consumeNull()
+	case boolTy:
+		tok, err = p.parseBool()
+	case numberTy:
+		i, f, isFloat, err := p.scanNumber()
+		if err != nil {
+			return nil, err
+		}
+		if !isFloat {
+			f = float64(i)
+		} else if p.replacesNaN(f) {
+			tok = p.deNaNValue(f)
+			break
+		}
+		tok = f
+	case stringTy:
+		var str []byte
+		if str, err = p.parseString(); err == nil {
+			tok = p.makeString(str)
+		}
+	case arrayTy, objectTy:
+		open := p.readBuf[p.begin]
+		p.begin++
+		p.tokens = append(p.tokens, open)
+		p.tokenState = tokenFirst
+		return json.Delim(open), nil
+	case commaSym:
+		return nil, errUnexpectedComma
+	case endGroupSym:
+		return nil, errUnexpectedEnd
+	default:
+		panic("unreachable")
+	}
+	if err != nil {
+		return nil, err
+	}
+	p.endTokenValue()
+	return tok, nil
+}
+
+// Consumes the end of the innermost open container.
+func (p *parser) closeToken() (json.Token, error) {
+	open := p.tokens[len(p.tokens)-1]
+	close := byte(']')
+	if open == '{' {
+		close = '}'
+	}
+	if err := p.readByte(close); err != nil {
+		return nil, err
+	}
+	p.tokens = p.tokens[:len(p.tokens)-1]
+	p.endTokenValue()
+	return json.Delim(close), nil
+}
+
+// Sets the state after a complete value.
+func (p *parser) endTokenValue() {
+	if len(p.tokens) > 0 {
+		p.tokenState = tokenNext
+	} else {
+		p.tokenState = tokenValue
+	}
+}
+
+func (p *parser) More() bool {
+	if err := p.skipSpaces(); err != nil || p.begin >= p.size {
+		return false
+	}
+	b := p.readBuf[p.begin]
+	return b != ']' && b != '}'
+}
+
+// Prepares to read a whole value in the middle of a StdToken stream,
+// consuming the ',' before it if need be. The caller must call endTokenValue
+// once the value is read.
+func (p *parser) beginTokenValue() error {
+	if len(p.tokens) == 0 {
+		return nil
+	}
+	inObject := p.tokens[len(p.tokens)-1] == '{'
+	switch p.tokenState {
+	case tokenNext:
+		ty, err := p.parseType()
+		if err != nil {
+			return err
+		}
+		if err = p.consumeComma(ty); err != nil {
+			return err
+		}
+		if inObject {
+			p.tokenState = tokenKey
+			return errNotAtValue
+		}
+		p.tokenState = tokenValue
+	case tokenFirst:
+		if inObject {
+			return errNotAtValue
+		}
+	case tokenKey:
+		return errNotAtValue
+	}
+	return nil
+}
+
+// Forgets any containers StdToken was in.
+func (p *parser) resetTokens() {
+	p.tokens = p.tokens[:0]
+	p.tokenState = tokenValue
+}
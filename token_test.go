@@ -0,0 +1,109 @@
+package simplejsonext
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type tokenStep struct {
+	More  bool
+	Token json.Token
+}
+
+// Reads all tokens with next, calling more before each like a typical loop.
+func collectTokens(next func() (json.Token, error), more func() bool) (steps []tokenStep, err error) {
+	for {
+		m := more()
+		var tok json.Token
+		tok, err = next()
+		if err != nil {
+			return
+		}
+		steps = append(steps, tokenStep{m, tok})
+	}
+}
+
+func TestStdTokenConformance(t *testing.T) {
+	for _, doc := range []string{
+		`null`,
+		`true false`,
+		`"stré\n"`,
+		`-1.5e3 0 42 -7`,
+		`[]`,
+		`{}`,
+		`[1, [2, [3, []]], {}]`,
+		`{"a": 1, "b": [true, null, "x"], "c": {"d": {}}, "e": ""}`,
+		" \n[ { \"k\" : [ ] } , 3.25 ]\t{\"z\":null} [] ",
+		`[{"id": 1, "tags": ["a", "b"]}, {"id": 2, "tags": []}]`,
+	} {
+		dec := json.NewDecoder(strings.NewReader(doc))
+		expected, expectedErr := collectTokens(dec.Token, dec.More)
+		require.Equal(t, io.EOF, expectedErr, doc)
+
+		p := NewParserFromString(doc)
+		actual, err := collectTokens(p.StdToken, p.More)
+		assert.Equal(t, io.EOF, err, doc)
+		assert.Equal(t, expected, actual, doc)
+
+		// Reading a byte at a time makes no difference.
+		p = NewParser(iotest.OneByteReader(strings.NewReader(doc)))
+		actual, err = collectTokens(p.StdToken, p.More)
+		assert.Equal(t, io.EOF, err, doc)
+		assert.Equal(t, expected, actual, doc)
+	}
+}
+
+func TestStdTokenExtended(t *testing.T) {
+	p := NewParserFromString(`[NaN, Infinity, -Infinity, 9007199254740993]`)
+	var toks []json.Token
+	for {
+		tok, err := p.StdToken()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		toks = append(toks, tok)
+	}
+	require.Len(t, toks, 6)
+	assert.True(t, math.IsNaN(toks[1].(float64)))
+	toks = append(toks[:1], toks[2:]...)
+	assert.Equal(t, []json.Token{json.Delim('['), math.Inf(1), math.Inf(-1), float64(9007199254740992), json.Delim(']')}, toks)
+}
+
+func TestStdTokenErrors(t *testing.T) {
+	for _, doc := range []string{`[1,]`, `[1 2]`, `{"a" 1}`, `{1: 2}`, `{"a": 1,}`, `[}`, `]`, `,`, `{,"a":1}`, `[,1]`} {
+		p := NewParserFromString(doc)
+		var err error
+		for err == nil {
+			_, err = p.StdToken()
+		}
+		assert.NotEqual(t, io.EOF, err, doc)
+	}
+
+	// The limit is the same as for Parse.
+	p := NewParserFromString(nestedJSON("[", maxDepth+1))
+	var err error
+	for err == nil {
+		_, err = p.StdToken()
+	}
+	assert.Equal(t, &DepthError{Depth: maxDepth + 1, Offset: maxDepth + 1}, err)
+	p = NewParserFromString(nestedJSON("[", maxDepth))
+	for err = nil; err == nil; {
+		_, err = p.StdToken()
+	}
+	assert.Equal(t, io.EOF, err)
+
+	// Resetting forgets the open containers.
+	p.ResetString(`1`)
+	tok, err := p.StdToken()
+	require.NoError(t, err)
+	assert.Equal(t, float64(1), tok)
+	assert.False(t, p.More())
+}
@@ -0,0 +1,422 @@
+package simplejsonext
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"strconv"
+)
+
+// TranscodeOptions configures Transcode.
+type TranscodeOptions struct {
+	// NonFinite is what NaN and infinite numbers become. DeNaNKeep, the zero
+	// value, cannot be written as standard JSON and means DeNaNToNull.
+	NonFinite DeNaNPolicy
+	// ParseOptions configure the parser reading the input.
+	ParseOptions []ParseOption
+}
+
+// Transcode copies the JSON values read from src to dst as standard JSON,
+// without building them in memory, so that only the nesting depth of the
+// input bounds the memory used. The values may be concatenated or
+// newline-delimited, and each is written on a line of its own.
+//
+// NaN and infinite numbers are replaced as opts.NonFinite says. Numbers that
+// only this package accepts are respelled in standard form, as in 1. to 1,
+// -.5 to -0.5 and 007 to 7; this is exact, but a number that was only a float
+// because of its spelling reads back as an integer. Everything else is copied
+// exactly, including the text of every standard number, and the order of
+// object keys. The output is compact.
+func Transcode(dst io.Writer, src io.Reader, opts TranscodeOptions) error {
+	w := bufio.NewWriterSize(dst, readBufferSize)
+	p := NewParser(src, opts.ParseOptions...).(*parser)
+	p.cfg.deNaN = opts.NonFinite
+	if p.cfg.deNaN == DeNaNKeep {
+		p.cfg.deNaN = DeNaNToNull
+	}
+	t := transcoder{p: p, e: NewEmitter(w).(*emitter)}
+	for {
+		if _, err := p.parseType(); err == io.EOF {
+			return w.Flush()
+		} else if err != nil {
+			return err
+		}
+		if err := t.value(p.maxDepth()); err == io.EOF {
+			return io.ErrUnexpectedEOF
+		} else if err != nil {
+			return err
+		}
+		if err := w.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+}
+
+// Reformat copies the JSON values read from src to dst, changing nothing but
+// the whitespace between their tokens. Like Transcode, it does not build the
+// values in memory, so that only the nesting depth of the input bounds the
+// memory used, and writes each value on a line of its own.
+//
+// With an empty indent the output is compact. Otherwise each element of an
+// array or object starts a new line, indented by one copy of indent for each
+// level of nesting, as with WithIndent. Unlike Transcode, every token is
+// copied byte for byte, including numbers such as 1. and -.1, NaN and
+// Infinity, and the escapes in strings. The input is checked as it is copied,
+// and a syntax error is returned as a *SyntaxError with its offset in src, by
+// which point the output up to it may have been written.
+func Reformat(dst io.Writer, src io.Reader, indent string) error {
+	var opts []EmitOption
+	if indent != "" {
+		opts = append(opts, WithIndent("", indent))
+	}
+	w := bufio.NewWriterSize(dst, readBufferSize)
+	t := transcoder{p: NewParser(src).(*parser), e: NewEmitter(w, opts...).(*emitter), raw: true}
+	return t.copyValues(w)
+}
+
+// FilterCopy copies the JSON values read from src to dst as Reformat does with
+// an empty indent, but leaves out every object member for which drop returns
+// true. drop is passed the path of keys and array indices to the member, as
+// WalkDeNaNFunc passes it, and the path slice is reused between calls. The
+// values of dropped members are checked but not kept, so that, as with
+// Reformat, only the nesting depth of the input bounds the memory used.
+//
+// Everything else is copied byte for byte, except that keys are written with
+// the escapes of Marshal, which only differs if they had unnecessary ones.
+func FilterCopy(dst io.Writer, src io.Reader, drop func(path []string) bool) error {
+	w := bufio.NewWriterSize(dst, readBufferSize)
+	t := transcoder{p: NewParser(src).(*parser), e: NewEmitter(w).(*emitter), raw: true, drop: drop}
+	return t.copyValues(w)
+}
+
+// Copies values for Reformat and FilterCopy, each on a line of its own.
+func (t *transcoder) copyValues(w *bufio.Writer) error {
+	p := t.p
+	for {
+		if _, err := p.parseType(); err == io.EOF {
+			return w.Flush()
+		} else if err != nil {
+			return t.syntaxError(err)
+		}
+		if err := t.value(p.maxDepth()); err != nil {
+			if w.Flush() == err {
+				return err // writing failed, not reading
+			}
+			return t.syntaxError(err)
+		}
+		if err := w.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+}
+
+type transcoder struct {
+	p   *parser
+	e   *emitter
+	num []byte
+	raw bool // copy strings and numbers exactly, for Reformat
+	// For FilterCopy, which members to leave out, and the path to the value
+	// being copied
+	drop func(path []string) bool
+	path []string
+}
+
+// Wraps an error from the parser with where it happened.
+func (t *transcoder) syntaxError(err error) error {
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return &SyntaxError{Offset: t.p.InputOffset(), Err: err}
+}
+
+// Like doVisit, but writes the value instead.
+func (t *transcoder) value(remainingDepth int) (err error) {
+	p, e := t.p, t.e
+	if remainingDepth < 0 {
+		return p.depthError(remainingDepth)
+	}
+	var ty valType
+	ty, err = p.parseType()
+	if err != nil {
+		return
+	}
+	switch ty {
+	case nilTy:
+		if err = p.consumeNull(); err != nil {
+			return
+		}
+		return e.emitNil()
+	case boolTy:
+		var b bool
+		if b, err = p.parseBool(); err != nil {
+			return
+		}
+		return e.emitBool(b)
+	case numberTy:
+		return t.number()
+	case stringTy:
+		if t.raw {
+			return t.rawString()
+		}
+		var str []byte
+		if str, err = p.parseString(); err != nil {
+			return
+		}
+		return e.emitString(stringNoCopy(str))
+	case arrayTy:
+		return t.group('[', ']', remainingDepth)
+	case objectTy:
+		return t.group('{', '}', remainingDepth)
+	case commaSym:
+		return errUnexpectedComma
+	case endGroupSym:
+		return errUnexpectedEnd
+	default:
+		panic("unreachable")
+	}
+}
+
+// Writes an array or object, following the same rules as visitGroup.
+func (t *transcoder) group(open, close byte, remainingDepth int) (err error) {
+	p, e := t.p, t.e
+	if err = p.readByte(open); err != nil {
+		return
+	}
+	// Look ahead for the end, so that an empty array or object is not
+	// indented.
+	size := 1
+	if ty, err := p.parseType(); err == nil && ty == endGroupSym {
+		size = 0
+	}
+	if open == '{' {
+		err = e.emitMapBegin(size)
+	} else {
+		err = e.emitArrayBegin(size)
+	}
+	if err != nil {
+		return
+	}
+	first := true
+	written := 0
+	for n := 0; ; n++ {
+		var ty valType
+		ty, err = p.parseType()
+		if err != nil {
+			return
+		}
+		if ty == endGroupSym {
+			if err = p.readByte(close); err != nil {
+				return
+			}
+			if open == '{' {
+				return e.emitMapEnd(size)
+			}
+			return e.emitArrayEnd(size)
+		} else if first {
+			if ty == commaSym {
+				return errUnexpectedComma
+			}
+			first = false
+		} else if err = p.consumeComma(ty); err != nil {
+			return
+		}
+		if err = p.checkCount(n, open == '{'); err != nil {
+			return
+		}
+		if open == '{' && t.drop != nil {
+			var dropped bool
+			if dropped, err = t.filterKey(remainingDepth, written); err != nil {
+				return
+			} else if dropped {
+				continue
+			}
+		} else if open == '{' {
+			if written > 0 {
+				if err = e.emitNext(); err != nil {
+					return
+				}
+			}
+			if err = p.skipSpaces(); err != nil {
+				return
+			}
+			if t.raw {
+				if err = t.rawString(); err != nil {
+					return
+				}
+			} else {
+				var key []byte
+				if key, err = p.parseString(); err != nil {
+					return
+				}
+				if err = e.emitString(stringNoCopy(key)); err != nil {
+					return
+				}
+			}
+			if err = p.skipSpaces(); err != nil {
+				return
+			}
+			if err = p.readByte(':'); err != nil {
+				return
+			}
+			if err = e.emitMapValue(); err != nil {
+				return
+			}
+		} else if written > 0 {
+			if err = e.emitNext(); err != nil {
+				return
+			}
+		}
+		if t.drop != nil && open == '[' {
+			t.path = append(t.path, strconv.Itoa(n))
+		}
+		if err = t.value(remainingDepth - 1); err != nil {
+			return
+		}
+		if t.drop != nil {
+			t.path = t.path[:len(t.path)-1]
+		}
+		written++
+	}
+}
+
+// For FilterCopy, reads the key of an object member, and either skips its
+// value, if the member is to be dropped, or writes the key and adds it to the
+// path.
+func (t *transcoder) filterKey(remainingDepth, written int) (dropped bool, err error) {
+	p, e := t.p, t.e
+	if err = p.skipSpaces(); err != nil {
+		return
+	}
+	var key []byte
+	if key, err = p.parseString(); err != nil {
+		return
+	}
+	// Copied before reading on, which may overwrite it.
+	t.path = append(t.path, string(key))
+	if err = p.skipSpaces(); err != nil {
+		return
+	}
+	if err = p.readByte(':'); err != nil {
+		return
+	}
+	if t.drop(t.path) {
+		t.path = t.path[:len(t.path)-1]
+		return true, p.doVisit(remainingDepth-1, NopVisitor{})
+	}
+	if written > 0 {
+		if err = e.emitNext(); err != nil {
+			return
+		}
+	}
+	if err = e.emitString(t.path[len(t.path)-1]); err != nil {
+		return
+	}
+	return false, e.emitMapValue()
+}
+
+// Writes a number with its text unchanged if it is standard, and respelled if
+// not.
+func (t *transcoder) number() error {
+	p, e := t.p, t.e
+	view, isFloat, err := p.scanNumberText()
+	if err != nil {
+		return err
+	}
+	_, f, isFloat, err := p.convertNumber(view, isFloat)
+	if err != nil {
+		return err
+	}
+	if t.raw {
+		return e.write(view)
+	}
+	if isFloat && (math.IsNaN(f) || math.IsInf(f, 0)) {
+		if s, ok := p.deNaNValue(f).(string); ok {
+			return e.emitString(s)
+		}
+		return e.emitNil()
+	}
+	if !isStandardNumber(view) {
+		t.num = appendStandardNumber(t.num[:0], view)
+		view = t.num
+	}
+	err = e.write(view)
+	return err
+}
+
+// Appends the standard spelling of the finite number b, which the parser has
+// accepted: without leading zeros, and with digits on both sides of any
+// decimal point.
+func appendStandardNumber(dst, b []byte) []byte {
+	i := 0
+	if b[0] == '-' {
+		dst = append(dst, '-')
+		i++
+	}
+	start := i
+	for i < len(b) && b[i] >= '0' && b[i] <= '9' {
+		i++
+	}
+	integral := b[start:i]
+	for len(integral) > 1 && integral[0] == '0' {
+		integral = integral[1:]
+	}
+	if len(integral) == 0 {
+		dst = append(dst, '0')
+	}
+	dst = append(dst, integral...)
+	if i < len(b) && b[i] == '.' {
+		i++
+		start = i
+		for i < len(b) && b[i] >= '0' && b[i] <= '9' {
+			i++
+		}
+		if i > start {
+			dst = append(dst, '.')
+			dst = append(dst, b[start:i]...)
+		}
+	}
+	// The exponent, if any, is already standard.
+	return append(dst, b[i:]...)
+}
+
+// Writes a string with its text unchanged, a chunk at a time, checking it as
+// parseString does.
+func (t *transcoder) rawString() error {
+	p, e := t.p, t.e
+	start := p.InputOffset()
+	if err := p.readByte('"'); err != nil {
+		return err
+	}
+	if err := e.write(quote[:]); err != nil {
+		return err
+	}
+	c := newEscapeChecker()
+	n := 0 // length of the string so far
+	for {
+		chunk, err := p.take()
+		if err == io.EOF && c.hex >= 0 {
+			return errTruncatedHex
+		} else if err != nil {
+			return err
+		}
+		end, err := c.scan(chunk)
+		if err != nil {
+			p.rewind(len(chunk) - end)
+			return err
+		}
+		if end >= 0 {
+			p.rewind(len(chunk) - end - 1)
+			if err = p.checkStringLen(n+end, start); err != nil {
+				return err
+			}
+			return e.write(chunk[:end+1])
+		}
+		n += len(chunk)
+		if err = p.checkStringLen(n, start); err != nil {
+			return err
+		}
+		if err = e.write(chunk); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,350 @@
+package simplejsonext
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"runtime"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func transcodeString(t *testing.T, s string, opts TranscodeOptions) string {
+	var out bytes.Buffer
+	require.NoError(t, Transcode(&out, strings.NewReader(s), opts))
+	return out.String()
+}
+
+func TestTranscode(t *testing.T) {
+	for _, test := range []struct {
+		in, out string
+	}{
+		{``, ``},
+		{` 1 `, "1\n"},
+		{`[1., -.5, 007, -00.50, 1.e5, -0, 0.0e-0, 1E+2]`, "[1,-0.5,7,-0.50,1e5,-0,0.0e-0,1E+2]\n"},
+		{`123456789012345678901234567890 1.00000000000000000001`, "123456789012345678901234567890\n1.00000000000000000001\n"},
+		{`[NaN, Infinity, -Infinity, 1e999, -1e999]`, "[null,null,null,null,null]\n"},
+		{`{"a" : "\u00e9\n", "b":[ true,false ,null ],"":{}}`, `{"a":"é\n","b":[true,false,null],"":{}}` + "\n"},
+		{"{\"z\":1,\"a\":2}{}[]\n\n\"x\"\n", "{\"z\":1,\"a\":2}\n{}\n[]\n\"x\"\n"},
+	} {
+		assert.Equal(t, test.out, transcodeString(t, test.in, TranscodeOptions{}), test.in)
+	}
+
+	assert.Equal(t, "[\"NaN\",\"-Infinity\",\"Infinity\",1.5]\n",
+		transcodeString(t, `[NaN, -Infinity, 1e400, 1.5]`, TranscodeOptions{NonFinite: DeNaNToString}))
+	assert.Equal(t, "[1,[[1]]]\n",
+		transcodeString(t, `[1,[[1]]]`, TranscodeOptions{ParseOptions: []ParseOption{WithMaxDepth(3)}}))
+}
+
+func TestTranscodeErrors(t *testing.T) {
+	for _, test := range []struct {
+		in   string
+		opts TranscodeOptions
+		err  string
+	}{
+		{`[1, 2`, TranscodeOptions{}, io.ErrUnexpectedEOF.Error()},
+		{`{"a": [1, NaN]}`, TranscodeOptions{NonFinite: DeNaNError}, `simple json: non-finite number "NaN"`},
+		{`[1, 2] 3 ]`, TranscodeOptions{}, errUnexpectedEnd.Error()},
+		{`[[1]]`, TranscodeOptions{ParseOptions: []ParseOption{WithMaxDepth(1)}}, "simple json: maximum nesting depth exceeded: depth 2 at offset 2"},
+	} {
+		err := Transcode(io.Discard, strings.NewReader(test.in), test.opts)
+		assert.EqualError(t, err, test.err, test.in)
+	}
+}
+
+// Writes a random value of up to depth levels, spelling numbers in the
+// extended ways that keep them floats or integers.
+func writeRandomValue(sb *strings.Builder, r *rand.Rand, depth int) {
+	kind := r.Intn(10)
+	if depth == 0 {
+		kind %= 7
+	}
+	switch kind {
+	case 0:
+		sb.WriteString([]string{"null", "true", "false"}[r.Intn(3)])
+	case 1:
+		fmt.Fprintf(sb, "%d", r.Int63()-r.Int63())
+	case 2:
+		sb.WriteString([]string{"NaN", "Infinity", "-Infinity", "1e999", "-.25", "007", "-00.5e3", "1.e-2", "-0"}[r.Intn(9)])
+	case 3:
+		fmt.Fprintf(sb, "%g", r.NormFloat64()*1e10)
+	case 4:
+		sb.WriteString("123456789012345678901234567890")
+	case 5, 6:
+		sb.Write(AppendQuote(nil, string([]rune{rune(r.Intn(0x3000)), '"', '\\', '\n', rune(r.Intn(0x80))})))
+	case 7, 8:
+		sb.WriteByte('[')
+		for i := r.Intn(5); i > 0; i-- {
+			writeRandomValue(sb, r, depth-1)
+			if i > 1 {
+				sb.WriteString(" , ")
+			}
+		}
+		sb.WriteByte(']')
+	case 9:
+		sb.WriteByte('{')
+		for i := r.Intn(5); i > 0; i-- {
+			fmt.Fprintf(sb, "%q:\t", fmt.Sprint("k", i))
+			writeRandomValue(sb, r, depth-1)
+			if i > 1 {
+				sb.WriteByte(',')
+			}
+		}
+		sb.WriteByte('}')
+	}
+}
+
+func TestTranscodeLarge(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	var sb strings.Builder
+	for sb.Len() < 1<<20 {
+		writeRandomValue(&sb, r, 8)
+		// Values are variously concatenated and newline-delimited.
+		sb.WriteString([]string{" ", "\t", "\n", "\r\n\n"}[r.Intn(4)])
+	}
+	in := sb.String()
+
+	for _, policy := range []DeNaNPolicy{DeNaNToString, DeNaNToNull} {
+		var out bytes.Buffer
+		opts := TranscodeOptions{NonFinite: policy}
+		require.NoError(t, Transcode(&out, iotest.HalfReader(strings.NewReader(in)), opts))
+
+		// Each line is one standard JSON value, equal to the input value as
+		// parsed with the same policy.
+		p := NewParserFromString(in, WithDeNaN(policy))
+		scanner := bufio.NewScanner(&out)
+		scanner.Buffer(nil, len(in))
+		lines := 0
+		for scanner.Scan() {
+			line := scanner.Bytes()
+			require.True(t, json.Valid(line), string(line))
+			expected, err := p.Parse()
+			require.NoError(t, err)
+			actual, err := Unmarshal(line)
+			require.NoError(t, err)
+			require.Equal(t, expected, actual)
+			lines++
+		}
+		require.NoError(t, scanner.Err())
+		_, err := p.Parse()
+		assert.Equal(t, io.EOF, err)
+		assert.Greater(t, lines, 1000)
+	}
+}
+
+type failingWriter struct{ err error }
+
+func (w failingWriter) Write([]byte) (int, error) {
+	return 0, w.err
+}
+
+type countingWriter struct{ n int64 }
+
+func (w *countingWriter) Write(b []byte) (int, error) {
+	w.n += int64(len(b))
+	return len(b), nil
+}
+
+func reformatString(t *testing.T, s string, indent string) string {
+	var out bytes.Buffer
+	require.NoError(t, Reformat(&out, iotest.OneByteReader(strings.NewReader(s)), indent))
+	return out.String()
+}
+
+func TestReformat(t *testing.T) {
+	const in = ` [1., -.1, 007 ,NaN,-Infinity, 1e999, "é\n\/ []{},:" ,true, null, {}, [ ], {"a" :[ ], "b" : {"c":1 }}] `
+	assert.Equal(t,
+		`[1.,-.1,007,NaN,-Infinity,1e999,"é\n\/ []{},:",true,null,{},[],{"a":[],"b":{"c":1}}]`+"\n",
+		reformatString(t, in, ""))
+	assert.Equal(t, `[
+  1.,
+  -.1,
+  007,
+  NaN,
+  -Infinity,
+  1e999,
+  "é\n\/ []{},:",
+  true,
+  null,
+  {},
+  [],
+  {
+    "a": [],
+    "b": {
+      "c": 1
+    }
+  }
+]
+`, reformatString(t, in, "  "))
+
+	assert.Equal(t, "", reformatString(t, " \n", ""))
+	assert.Equal(t, "1\n{}\n\"x\"\n", reformatString(t, "1{}\n\n\"x\"", "\t"))
+	assert.Equal(t, "[\n\t[\n\t\t1\n\t]\n]\n", reformatString(t, "[[1]]", "\t"))
+}
+
+func TestReformatErrors(t *testing.T) {
+	for _, test := range []struct {
+		in  string
+		err string
+	}{
+		{`[1, 2`, "simple json: offset 5: " + io.ErrUnexpectedEOF.Error()},
+		{`{"a" 1}`, "simple json: offset 5: simple json: expected ':' but found '1'"},
+		{`["ok", "bad \x"]`, `simple json: offset 13: simple json: invalid escape x`},
+		{`["\u12"]`, `simple json: offset 7: simple json: expected a hexadecimal unicode code point but found "12\"]"`},
+		{`"\u12`, "simple json: offset 5: " + errTruncatedHex.Error()},
+		{"\"a\nb\"", "simple json: offset 2: " + errControlChar.Error()},
+		{`"abc`, "simple json: offset 4: " + io.ErrUnexpectedEOF.Error()},
+		{`[1 2]`, "simple json: offset 3: simple json: expected ',' but found '2'"},
+		{`1 ]`, "simple json: offset 2: " + errUnexpectedEnd.Error()},
+		{`[1x]`, "simple json: offset 2: simple json: expected token but found 'x'"},
+	} {
+		err := Reformat(io.Discard, strings.NewReader(test.in), "")
+		var syntaxErr *SyntaxError
+		if assert.ErrorAs(t, err, &syntaxErr, test.in) {
+			assert.EqualError(t, err, test.err, test.in)
+		}
+	}
+
+	// Errors writing are returned as they are.
+	errWrite := errors.New("write failed")
+	err := Reformat(failingWriter{errWrite}, strings.NewReader(strings.Repeat("[1, 2] ", 10000)), "")
+	assert.Equal(t, errWrite, err)
+}
+
+// Removes the whitespace between the tokens of JSON text.
+func stripSpaces(s string) string {
+	var sb strings.Builder
+	inString, escaped := false, false
+	for i := 0; i < len(s); i++ {
+		b := s[i]
+		switch {
+		case inString:
+			inString = escaped || b != '"'
+			escaped = !escaped && b == '\\'
+		case b == '"':
+			inString = true
+		case b == ' ' || b == '\t' || b == '\n' || b == '\r':
+			continue
+		}
+		sb.WriteByte(b)
+	}
+	return sb.String()
+}
+
+func TestReformatLarge(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	var sb strings.Builder
+	for sb.Len() < 1<<20 {
+		writeRandomValue(&sb, r, 8)
+		sb.WriteString([]string{" ", "\t", "\n", "\r\n\n"}[r.Intn(4)])
+	}
+	in := sb.String()
+
+	// Every token is kept byte for byte, whatever the indentation.
+	var compact, indented, again bytes.Buffer
+	require.NoError(t, Reformat(&compact, iotest.HalfReader(strings.NewReader(in)), ""))
+	require.NoError(t, Reformat(&indented, iotest.HalfReader(strings.NewReader(in)), " \t"))
+	assert.Equal(t, stripSpaces(in), stripSpaces(compact.String()))
+	assert.Equal(t, stripSpaces(in), stripSpaces(indented.String()))
+	require.NoError(t, Reformat(&again, bytes.NewReader(indented.Bytes()), ""))
+	assert.Equal(t, compact.String(), again.String())
+	assert.Greater(t, strings.Count(compact.String(), "\n"), 1000)
+}
+
+func TestReformatMemory(t *testing.T) {
+	const unit = `{"a": [1., -.1, NaN, "x\"y"], "b": {}, "c": "` + "é" + `"}` + "\n"
+	long := `["` + strings.Repeat("long string ", 1<<20) + `"]`
+	mkInput := func() io.Reader {
+		return io.MultiReader(
+			io.LimitReader(&endlessReader{repeat: unit}, int64(len(unit)*100000)),
+			strings.NewReader(long),
+		)
+	}
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	var out countingWriter
+	err := Reformat(&out, mkInput(), "  ")
+	runtime.ReadMemStats(&after)
+	require.NoError(t, err)
+	assert.Greater(t, out.n, int64(len(unit)*100000+len(long)))
+	// What is allocated does not grow with the input, some 17MB of it.
+	assert.Less(t, after.TotalAlloc-before.TotalAlloc, uint64(256<<10))
+}
+
+func filterString(t *testing.T, in string, drop ...string) string {
+	t.Helper()
+	var out bytes.Buffer
+	require.NoError(t, FilterCopy(&out, strings.NewReader(in), func(path []string) bool {
+		for _, key := range drop {
+			if path[len(path)-1] == key {
+				return true
+			}
+		}
+		return false
+	}))
+	return out.String()
+}
+
+func TestFilterCopy(t *testing.T) {
+	for _, test := range []struct {
+		drop []string
+		out  string
+	}{
+		{nil, `{"a":1,"b":{"a":[1.,{"a":NaN,"x":2}],"c":"é"},"x":{"x":{}}}`},
+		{[]string{"a"}, `{"b":{"c":"é"},"x":{"x":{}}}`},
+		{[]string{"b"}, `{"a":1,"x":{"x":{}}}`},
+		{[]string{"x"}, `{"a":1,"b":{"a":[1.,{"a":NaN}],"c":"é"}}`},
+		{[]string{"c"}, `{"a":1,"b":{"a":[1.,{"a":NaN,"x":2}]},"x":{"x":{}}}`},
+		{[]string{"a", "x"}, `{"b":{"c":"é"}}`},
+		{[]string{"a", "b", "x"}, `{}`},
+	} {
+		in := ` {"a": 1, "b" : {"a": [1., {"a": NaN, "x": 2}], "c": "é"}, "x": {"x": {}}} `
+		assert.Equal(t, test.out+"\n", filterString(t, in, test.drop...), test.drop)
+	}
+	// The only member, and the middle one, at several depths.
+	assert.Equal(t, "[{},{\"b\":{}},[{}]]\n", filterString(t, `[{"a": 1}, {"b": {"a": [2]}}, [{"a": {"b": 3}}]]`, "a"))
+	assert.Equal(t, "{\"a\":1,\"c\":{\"a\":1,\"c\":3}}\n", filterString(t, `{"a":1,"b":2,"c":{"a":1,"b":{"b":2},"c":3}}`, "b"))
+
+	// Paths name array indices, and values are copied one after another.
+	var paths [][]string
+	var out bytes.Buffer
+	require.NoError(t, FilterCopy(&out, strings.NewReader(`{"runs": [{"id": 1, "api_key": "k"}, {"id": 2}]} {"id": 3}`), func(path []string) bool {
+		paths = append(paths, append([]string(nil), path...))
+		return len(path) == 3 && path[1] == "0"
+	}))
+	assert.Equal(t, "{\"runs\":[{},{\"id\":2}]}\n{\"id\":3}\n", out.String())
+	assert.Equal(t, [][]string{{"runs"}, {"runs", "0", "id"}, {"runs", "0", "api_key"}, {"runs", "1", "id"}, {"id"}}, paths)
+
+	// Dropped values are still checked.
+	err := FilterCopy(io.Discard, strings.NewReader(`{"a": [1, }, "b": 2}`), func([]string) bool { return true })
+	var syntaxErr *SyntaxError
+	require.ErrorAs(t, err, &syntaxErr)
+	assert.Equal(t, int64(10), syntaxErr.Offset)
+}
+
+func TestFilterCopyMemory(t *testing.T) {
+	const unit = `{"id": 1, "history": [` + `{"loss": 0.5, "step": 100}, {"loss": 0.25, "step": 200}` + `], "api_key": "secret"}` + "\n"
+	const n = 100000
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	var out bytes.Buffer
+	err := FilterCopy(&out, io.LimitReader(&endlessReader{repeat: unit}, int64(len(unit)*n)), func(path []string) bool {
+		return len(path) == 1 && (path[0] == "history" || path[0] == "api_key")
+	})
+	runtime.ReadMemStats(&after)
+	require.NoError(t, err)
+	assert.Equal(t, strings.Repeat(`{"id":1}`+"\n", n), out.String())
+	// Beyond the output, what is allocated is for the keys, which do not
+	// outlive their members.
+	assert.Less(t, after.TotalAlloc-before.TotalAlloc, uint64(out.Cap()+8*3*n+256<<10))
+}
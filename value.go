@@ -0,0 +1,37 @@
+package simplejsonext
+
+import "encoding/json"
+
+var (
+	_ json.Marshaler   = Value{}
+	_ json.Unmarshaler = &Value{}
+)
+
+// Value holds a simple JSON value inside data that is encoded and decoded
+// with encoding/json. It is marshaled with Marshal and unmarshaled with
+// Unmarshal, so V keeps int64 and float64 numbers distinct and ordered
+// objects keep their order.
+//
+// encoding/json checks that the output of MarshalJSON is valid standard JSON
+// and only passes valid standard JSON to UnmarshalJSON, so within data that
+// encoding/json handles, a Value holding NaN or an infinity cannot be
+// marshaled and such numbers cannot be unmarshaled. Calling MarshalJSON
+// directly produces the extended JSON, which is then not strict JSON.
+type Value struct {
+	V any
+}
+
+// MarshalJSON returns Marshal(v.V).
+func (v Value) MarshalJSON() ([]byte, error) {
+	return Marshal(v.V)
+}
+
+// UnmarshalJSON sets v.V to the value decoded from b by Unmarshal.
+func (v *Value) UnmarshalJSON(b []byte) error {
+	val, err := Unmarshal(b)
+	if err != nil {
+		return err
+	}
+	v.V = val
+	return nil
+}
@@ -0,0 +1,64 @@
+package simplejsonext
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type valueHolder struct {
+	Name   string `json:"name"`
+	Config Value  `json:"config"`
+	Extra  *Value `json:"extra,omitempty"`
+}
+
+func TestValueInStdStruct(t *testing.T) {
+	ordered := &OrderedObject{Members: []Member{{Key: "z", Value: int64(1)}, {Key: "a", Value: 2.0}}}
+	in := valueHolder{
+		Name:   "run",
+		Config: Value{map[string]any{"lr": 0.5, "epochs": int64(10), "big": int64(math.MaxInt64), "nested": ordered}},
+	}
+	b, err := json.Marshal(in)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"name": "run", "config": {"lr": 0.5, "epochs": 10, "big": 9223372036854775807, "nested": {"z": 1, "a": 2}}}`, string(b))
+
+	var out valueHolder
+	require.NoError(t, json.Unmarshal(b, &out))
+	assert.Equal(t, "run", out.Name)
+	assert.Nil(t, out.Extra)
+	// Numbers keep their types, and integers keep their precision.
+	assert.Equal(t, map[string]any{
+		"lr":     0.5,
+		"epochs": int64(10),
+		"big":    int64(math.MaxInt64),
+		"nested": map[string]any{"z": int64(1), "a": int64(2)},
+	}, out.Config.V)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"config": null, "extra": [1, 1.5]}`), &out))
+	assert.Nil(t, out.Config.V)
+	require.NotNil(t, out.Extra)
+	assert.Equal(t, []any{int64(1), 1.5}, out.Extra.V)
+}
+
+func TestValueExtended(t *testing.T) {
+	// Called directly, the methods handle extended JSON.
+	v := Value{[]any{math.Inf(1), "x"}}
+	b, err := v.MarshalJSON()
+	require.NoError(t, err)
+	assert.Equal(t, `[Infinity,"x"]`, string(b))
+	var out Value
+	require.NoError(t, out.UnmarshalJSON([]byte(`{"a": -Infinity}`)))
+	assert.Equal(t, map[string]any{"a": math.Inf(-1)}, out.V)
+
+	// encoding/json rejects it.
+	_, err = json.Marshal(valueHolder{Config: v})
+	assert.Error(t, err)
+	assert.Error(t, json.Unmarshal([]byte(`{"config": NaN}`), &out))
+
+	_, err = json.Marshal(valueHolder{Config: Value{make(chan int)}})
+	assert.ErrorContains(t, err, "simple json: cannot emit unsupported type")
+	assert.Error(t, out.UnmarshalJSON([]byte(`[1] 2`)))
+}
@@ -0,0 +1,163 @@
+package simplejsonext
+
+// Visitor receives the parts of a value from Parser.Visit as they are parsed,
+// without any of them being boxed into an any or copied into a new string, so
+// that whole documents can be processed without allocating for each value.
+//
+// The bytes passed to OnString and OnKey are only valid until the method
+// returns.
+type Visitor interface {
+	OnNull() error
+	OnBool(v bool) error
+	OnInt(v int64) error
+	OnFloat(v float64) error
+	OnString(v []byte) error
+	OnArrayBegin() error
+	OnArrayEnd() error
+	OnObjectBegin() error
+	// OnKey is called with each key of an object, before its value.
+	OnKey(k []byte) error
+	OnObjectEnd() error
+}
+
+// NopVisitor is a Visitor that does nothing. Embed it in a type to implement
+// only the methods that matter.
+type NopVisitor struct{}
+
+func (NopVisitor) OnNull() error         { return nil }
+func (NopVisitor) OnBool(bool) error     { return nil }
+func (NopVisitor) OnInt(int64) error     { return nil }
+func (NopVisitor) OnFloat(float64) error { return nil }
+func (NopVisitor) OnString([]byte) error { return nil }
+func (NopVisitor) OnArrayBegin() error   { return nil }
+func (NopVisitor) OnArrayEnd() error     { return nil }
+func (NopVisitor) OnObjectBegin() error  { return nil }
+func (NopVisitor) OnKey([]byte) error    { return nil }
+func (NopVisitor) OnObjectEnd() error    { return nil }
+
+// Like doParse, but passes the value to v instead of building it.
+func (p *parser) doVisit(remainingDepth int, v Visitor) (err error) {
+	if remainingDepth < 0 {
+		return p.depthError(remainingDepth)
+	}
+	var ty valType
+	ty, err = p.parseType()
+	if err != nil {
+		return
+	}
+	switch ty {
+	case nilTy:
+		if err = p.consumeNull(); err != nil {
+			return
+		}
+		return v.OnNull()
+	case boolTy:
+		var b bool
+		if b, err = p.parseBool(); err != nil {
+			return
+		}
+		return v.OnBool(b)
+	case numberTy:
+		i, f, isFloat, err := p.scanNumber()
+		if err != nil {
+			return err
+		} else if !isFloat {
+			return v.OnInt(i)
+		} else if p.replacesNaN(f) {
+			if s, ok := p.deNaNValue(f).(string); ok {
+				return v.OnString([]byte(s))
+			}
+			return v.OnNull()
+		}
+		return v.OnFloat(f)
+	case stringTy:
+		var str []byte
+		if str, err = p.parseString(); err != nil {
+			return
+		}
+		return v.OnString(str)
+	case arrayTy:
+		return p.visitGroup('[', ']', remainingDepth, v)
+	case objectTy:
+		return p.visitGroup('{', '}', remainingDepth, v)
+	case commaSym:
+		return errUnexpectedComma
+	case endGroupSym:
+		return errUnexpectedEnd
+	default:
+		panic("unreachable")
+	}
+}
+
+// Visits an array or object, following the same rules as doParseArray and
+// parseMembers.
+func (p *parser) visitGroup(open, close byte, remainingDepth int, v Visitor) (err error) {
+	err = p.readByte(open)
+	if err != nil {
+		return
+	}
+	if open == '{' {
+		err = v.OnObjectBegin()
+	} else {
+		err = v.OnArrayBegin()
+	}
+	if err != nil {
+		return
+	}
+	first := true
+	for n := 0; ; n++ {
+		var ty valType
+		ty, err = p.parseType()
+		if err != nil {
+			return
+		}
+		if ty == endGroupSym {
+			if err = p.readByte(close); err != nil {
+				return
+			}
+			if open == '{' {
+				return v.OnObjectEnd()
+			}
+			return v.OnArrayEnd()
+		} else if first {
+			if ty == commaSym {
+				return errUnexpectedComma
+			}
+			first = false
+		} else {
+			err = p.consumeComma(ty)
+			if err != nil {
+				return
+			}
+		}
+		if err = p.checkCount(n, open == '{'); err != nil {
+			return
+		}
+		if open == '{' {
+			err = p.skipSpaces()
+			if err != nil {
+				return
+			}
+			var key []byte
+			key, err = p.parseString()
+			if err != nil {
+				return
+			}
+			if err = v.OnKey(key); err != nil {
+				return
+			}
+			err = p.skipSpaces()
+			if err != nil {
+				return
+			}
+			err = p.readByte(':')
+			if err != nil {
+				return
+			}
+		}
+		err = p.doVisit(remainingDepth-1, v)
+		if err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package simplejsonext
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Records every call as a line of text.
+type recordingVisitor struct {
+	calls []string
+}
+
+func (r *recordingVisitor) add(format string, args ...any) error {
+	r.calls = append(r.calls, fmt.Sprintf(format, args...))
+	return nil
+}
+
+func (r *recordingVisitor) OnNull() error           { return r.add("null") }
+func (r *recordingVisitor) OnBool(v bool) error     { return r.add("bool %v", v) }
+func (r *recordingVisitor) OnInt(v int64) error     { return r.add("int %d", v) }
+func (r *recordingVisitor) OnFloat(v float64) error { return r.add("float %v", v) }
+func (r *recordingVisitor) OnString(v []byte) error { return r.add("string %q", v) }
+func (r *recordingVisitor) OnArrayBegin() error     { return r.add("[") }
+func (r *recordingVisitor) OnArrayEnd() error       { return r.add("]") }
+func (r *recordingVisitor) OnObjectBegin() error    { return r.add("{") }
+func (r *recordingVisitor) OnKey(k []byte) error    { return r.add("key %q", k) }
+func (r *recordingVisitor) OnObjectEnd() error      { return r.add("}") }
+
+func TestVisit(t *testing.T) {
+	p := NewParserFromString(`{"a": [1, 2.5, "x\ny", true, null, {}], "b": -Infinity} 7`)
+	r := &recordingVisitor{}
+	require.NoError(t, p.Visit(r))
+	assert.Equal(t, []string{
+		"{",
+		`key "a"`, "[", "int 1", "float 2.5", `string "x\ny"`, "bool true", "null", "{", "}", "]",
+		`key "b"`, "float -Inf",
+		"}",
+	}, r.calls)
+
+	// The parser continues with the next value.
+	r.calls = nil
+	require.NoError(t, p.Visit(r))
+	assert.Equal(t, []string{"int 7"}, r.calls)
+	assert.NoError(t, p.CheckEmpty())
+	assert.Equal(t, io.EOF, p.Visit(r))
+}
+
+type stopAtKey struct {
+	NopVisitor
+	stop string
+}
+
+var errStopVisit = errors.New("stop")
+
+func (s stopAtKey) OnKey(k []byte) error {
+	if string(k) == s.stop {
+		return errStopVisit
+	}
+	return nil
+}
+
+func TestVisitStops(t *testing.T) {
+	p := NewParserFromString(`{"a": 1, "b": 2}`)
+	assert.ErrorIs(t, p.Visit(stopAtKey{stop: "b"}), errStopVisit)
+
+	for _, input := range []string{`[1,]`, `{"a" 1}`, `[1 2]`, `{,}`, `]`, strings.Repeat("[", maxDepth+2)} {
+		p.ResetString(input)
+		assert.Error(t, p.Visit(NopVisitor{}), input)
+	}
+}
+
+func TestVisitNoAllocs(t *testing.T) {
+	doc := []byte(`[1, 2.5, "three", {"four": [true, false, null]}, "esc\taped"]`)
+	p := NewParserFromSlice(doc)
+	allocs := testing.AllocsPerRun(100, func() {
+		p.ResetSlice(doc)
+		if err := p.Visit(NopVisitor{}); err != nil {
+			t.Fatal(err)
+		}
+	})
+	assert.Zero(t, allocs)
+}
@@ -0,0 +1,369 @@
+package simplejsonext
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"reflect"
+	"strconv"
+	"unsafe"
+)
+
+// WalkFunc is called by Walk for each node of a tree. It receives the path of
+// keys and array indices leading to the node, and returns the value that should
+// replace it (or the same value, to leave it as it is).
+//
+// The path slice is reused between calls; copy it if it needs to be retained.
+type WalkFunc func(path []string, v any) (any, error)
+
+// WalkError is returned by Walk when the callback fails, recording where in the
+// tree it happened.
+type WalkError struct {
+	Path []string
+	Err  error
+}
+
+func (e *WalkError) Error() string {
+	return fmt.Sprintf("simple json: walk failed at %q: %s", e.Path, e.Err)
+}
+
+func (e *WalkError) Unwrap() error {
+	return e.Err
+}
+
+// Walk traverses a simple JSON value depth-first, calling fn for every node
+// and building a new tree from the values it returns. Containers (maps, []any
+// slices, and ordered objects) are visited after their children, and fn
+// receives them already rebuilt with their children's replacements. Array
+// indices appear in the path in decimal.
+//
+// The input is never modified. Containers with no replaced descendants are
+// shared between the input and the result rather than copied, so a walk that
+// replaces nothing allocates next to nothing and returns v itself. Any other
+// type is treated as a leaf. Trees of any depth are walked without exhausting
+// the goroutine stack.
+//
+// If fn returns an error, the walk stops and the error is returned wrapped in a
+// *WalkError.
+func Walk(v any, fn WalkFunc) (any, error) {
+	w := walker{visitor: walkFuncVisitor(fn)}
+	return w.walk(v)
+}
+
+// What the walker calls for every node. Visitors that only sometimes need
+// the path get it from pathStrings, so that it is not built for every node.
+type walkVisitor interface {
+	visit(w *walker, v any) (any, error)
+}
+
+type walkFuncVisitor WalkFunc
+
+func (fn walkFuncVisitor) visit(w *walker, v any) (any, error) {
+	return fn(w.pathStrings(), v)
+}
+
+// Walk recurses no deeper than this, and continues with an explicit stack
+// past it.
+const maxWalkRecursion = 1000
+
+// A key, or an array index if the index is not negative.
+type pathStep struct {
+	key   string
+	index int
+}
+
+type walker struct {
+	visitor walkVisitor
+	path    []pathStep
+	strs    []string // the first steps of the path as strings
+	depth   int
+	// For shallow paths, to save allocating them
+	pathBuf [16]pathStep
+	strsBuf [16]string
+}
+
+func (w *walker) push(step pathStep) {
+	if w.path == nil {
+		w.path = w.pathBuf[:0]
+	}
+	w.path = append(w.path, step)
+}
+
+func (w *walker) pop() {
+	w.path = w.path[:len(w.path)-1]
+	if len(w.strs) > len(w.path) {
+		w.strs = w.strs[:len(w.path)]
+	}
+}
+
+// Returns the path to the current node. Each step is converted to a string
+// only once while it is on the path.
+func (w *walker) pathStrings() []string {
+	if w.strs == nil {
+		w.strs = w.strsBuf[:0]
+	}
+	for _, step := range w.path[len(w.strs):] {
+		if step.index >= 0 {
+			w.strs = append(w.strs, strconv.Itoa(step.index))
+		} else {
+			w.strs = append(w.strs, step.key)
+		}
+	}
+	return w.strs
+}
+
+func (w *walker) walk(v any) (res any, err error) {
+	if w.depth >= maxWalkRecursion {
+		return w.walkDeep(v)
+	}
+	w.depth++
+	res, err = w.walkNode(v)
+	w.depth--
+	return
+}
+
+// Walks the children of v, if it has any, and then v.
+func (w *walker) walkNode(v any) (res any, err error) {
+	switch tv := v.(type) {
+	case map[string]any:
+		var rebuilt map[string]any // copy made upon the first change
+		for key, child := range tv {
+			w.push(pathStep{key: key, index: -1})
+			res, err = w.walk(child)
+			w.pop()
+			if err != nil {
+				return nil, err
+			}
+			if !identical(res, child) {
+				if rebuilt == nil {
+					rebuilt = maps.Clone(tv)
+				}
+				rebuilt[key] = res
+			}
+		}
+		if rebuilt != nil {
+			v = rebuilt
+		}
+	case []any:
+		var rebuilt []any
+		for i, child := range tv {
+			w.push(pathStep{index: i})
+			res, err = w.walk(child)
+			w.pop()
+			if err != nil {
+				return nil, err
+			}
+			if !identical(res, child) {
+				if rebuilt == nil {
+					rebuilt = append([]any(nil), tv...)
+				}
+				rebuilt[i] = res
+			}
+		}
+		if rebuilt != nil {
+			v = rebuilt
+		}
+	case *OrderedObject:
+		if tv == nil {
+			break
+		}
+		var rebuilt *OrderedObject
+		for i, member := range tv.Members {
+			w.push(pathStep{key: member.Key, index: -1})
+			res, err = w.walk(member.Value)
+			w.pop()
+			if err != nil {
+				return nil, err
+			}
+			if !identical(res, member.Value) {
+				if rebuilt == nil {
+					rebuilt = &OrderedObject{Members: append([]Member(nil), tv.Members...)}
+				}
+				rebuilt.Members[i].Value = res
+			}
+		}
+		if rebuilt != nil {
+			v = rebuilt
+		}
+	}
+	return w.visit(v)
+}
+
+func (w *walker) visit(v any) (any, error) {
+	res, err := w.visitor.visit(w, v)
+	if err != nil {
+		return nil, &WalkError{Path: append([]string(nil), w.pathStrings()...), Err: err}
+	}
+	return res, nil
+}
+
+// A container being walked by walkDeep.
+type walkFrame struct {
+	v       any      // the container
+	keys    []string // of a map, in the order visited
+	n       int      // number of children
+	i       int      // index of the child being walked
+	rebuilt any      // copy made upon the first change
+}
+
+func newWalkFrame(v any) (f walkFrame, ok bool) {
+	f.v = v
+	switch tv := v.(type) {
+	case map[string]any:
+		f.keys = make([]string, 0, len(tv))
+		for key := range tv {
+			f.keys = append(f.keys, key)
+		}
+		f.n = len(tv)
+	case []any:
+		f.n = len(tv)
+	case *OrderedObject:
+		if tv == nil {
+			return f, false
+		}
+		f.n = len(tv.Members)
+	default:
+		return f, false
+	}
+	return f, true
+}
+
+// Returns the path step to the current child, and the child.
+func (f *walkFrame) child() (pathStep, any) {
+	switch tv := f.v.(type) {
+	case map[string]any:
+		return pathStep{key: f.keys[f.i], index: -1}, tv[f.keys[f.i]]
+	case []any:
+		return pathStep{index: f.i}, tv[f.i]
+	case *OrderedObject:
+		return pathStep{key: tv.Members[f.i].Key, index: -1}, tv.Members[f.i].Value
+	}
+	panic("unreachable")
+}
+
+// Records what the current child was replaced with, and moves to the next.
+func (f *walkFrame) finishChild(res any) {
+	if _, child := f.child(); !identical(res, child) {
+		switch tv := f.v.(type) {
+		case map[string]any:
+			if f.rebuilt == nil {
+				f.rebuilt = maps.Clone(tv)
+			}
+			f.rebuilt.(map[string]any)[f.keys[f.i]] = res
+		case []any:
+			if f.rebuilt == nil {
+				f.rebuilt = append([]any(nil), tv...)
+			}
+			f.rebuilt.([]any)[f.i] = res
+		case *OrderedObject:
+			if f.rebuilt == nil {
+				f.rebuilt = &OrderedObject{Members: append([]Member(nil), tv.Members...)}
+			}
+			f.rebuilt.(*OrderedObject).Members[f.i].Value = res
+		}
+	}
+	f.i++
+}
+
+// Walks v just as walk does, but keeps the containers it is in on the heap
+// instead of the goroutine stack.
+func (w *walker) walkDeep(v any) (res any, err error) {
+	var stack []walkFrame
+	for {
+		if f, ok := newWalkFrame(v); ok {
+			stack = append(stack, f)
+		} else {
+			if res, err = w.visit(v); err != nil {
+				return nil, err
+			}
+			if len(stack) == 0 {
+				return res, nil
+			}
+			w.pop()
+			stack[len(stack)-1].finishChild(res)
+		}
+		// Find the next child to walk, finishing any containers that have
+		// no more.
+		for {
+			top := &stack[len(stack)-1]
+			if top.i < top.n {
+				var step pathStep
+				step, v = top.child()
+				w.push(step)
+				break
+			}
+			container := top.v
+			if top.rebuilt != nil {
+				container = top.rebuilt
+			}
+			if res, err = w.visit(container); err != nil {
+				return nil, err
+			}
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				return res, nil
+			}
+			w.pop()
+			stack[len(stack)-1].finishChild(res)
+		}
+	}
+}
+
+// Reports whether a and b are the very same value: the same scalar, or the same
+// container, of any slice or map type, rather than merely an equal one.
+func identical(a, b any) bool {
+	// The very same interface value, as when a value is returned unchanged,
+	// is identical whatever its type. This much is cheap enough to inline.
+	return *(*[2]unsafe.Pointer)(unsafe.Pointer(&a)) == *(*[2]unsafe.Pointer)(unsafe.Pointer(&b)) ||
+		identicalValues(a, b)
+}
+
+func identicalValues(a, b any) bool {
+	switch av := a.(type) {
+	case map[string]any:
+		bv, ok := b.(map[string]any)
+		return ok && reflect.ValueOf(av).UnsafePointer() == reflect.ValueOf(bv).UnsafePointer()
+	case []any:
+		bv, ok := b.([]any)
+		return ok && len(av) == len(bv) && unsafe.SliceData(av) == unsafe.SliceData(bv)
+	case float64:
+		// Compare bits so that NaN is identical to itself
+		bv, ok := b.(float64)
+		return ok && math.Float64bits(av) == math.Float64bits(bv)
+	case float32:
+		bv, ok := b.(float32)
+		return ok && math.Float32bits(av) == math.Float32bits(bv)
+	case string:
+		bv, ok := b.(string)
+		return ok && av == bv
+	case int64:
+		bv, ok := b.(int64)
+		return ok && av == bv
+	case bool:
+		bv, ok := b.(bool)
+		return ok && av == bv
+	case nil:
+		return b == nil
+	}
+	ta := reflect.TypeOf(a)
+	if ta != reflect.TypeOf(b) {
+		return false
+	}
+	if ta == nil {
+		return true
+	}
+	switch ta.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.String, reflect.Pointer:
+		return a == b
+	case reflect.Map:
+		return reflect.ValueOf(a).UnsafePointer() == reflect.ValueOf(b).UnsafePointer()
+	case reflect.Slice:
+		ra, rb := reflect.ValueOf(a), reflect.ValueOf(b)
+		return ra.Len() == rb.Len() && ra.UnsafePointer() == rb.UnsafePointer()
+	}
+	// Anything else is not something we can cheaply compare for identity, so
+	// it is assumed to have changed.
+	return false
+}
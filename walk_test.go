@@ -0,0 +1,191 @@
+package simplejsonext
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWalkVisitsChildrenFirst(t *testing.T) {
+	tree, err := UnmarshalString(`{"a": [1, {"b": true}], "c": null}`)
+	require.NoError(t, err)
+
+	var visited []string
+	res, err := Walk(tree, func(path []string, v any) (any, error) {
+		visited = append(visited, strings.Join(path, "."))
+		return v, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, tree, res)
+
+	position := make(map[string]int)
+	for i, p := range visited {
+		position[p] = i
+	}
+	assert.Len(t, visited, 6)
+	assert.Less(t, position["a.0"], position["a"])
+	assert.Less(t, position["a.1.b"], position["a.1"])
+	assert.Less(t, position["a.1"], position["a"])
+	assert.Equal(t, len(visited)-1, position[""], "root is visited last")
+}
+
+func TestWalkReplacesAndShares(t *testing.T) {
+	tree, err := UnmarshalString(`{"convert": {"ms": 1500}, "keep": {"x": [1, 2]}, "list": [[3], [4000]]}`)
+	require.NoError(t, err)
+	original, err := UnmarshalString(`{"convert": {"ms": 1500}, "keep": {"x": [1, 2]}, "list": [[3], [4000]]}`)
+	require.NoError(t, err)
+
+	res, err := Walk(tree, func(_ []string, v any) (any, error) {
+		if i, ok := v.(int64); ok && i >= 1000 {
+			return float64(i) / 1000, nil
+		}
+		return v, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"convert": map[string]any{"ms": 1.5},
+		"keep":    map[string]any{"x": []any{int64(1), int64(2)}},
+		"list":    []any{[]any{int64(3)}, []any{4.0}},
+	}, res)
+	// The input is untouched
+	assert.Equal(t, original, tree)
+
+	treeMap, resMap := tree.(map[string]any), res.(map[string]any)
+	assert.Equal(t,
+		reflect.ValueOf(treeMap["keep"]).UnsafePointer(),
+		reflect.ValueOf(resMap["keep"]).UnsafePointer(),
+		"unmodified subtrees are shared")
+	assert.Same(t, &treeMap["list"].([]any)[0].([]any)[0], &resMap["list"].([]any)[0].([]any)[0])
+	assert.NotSame(t, &treeMap["list"].([]any)[1].([]any)[0], &resMap["list"].([]any)[1].([]any)[0])
+
+	// A walk that changes nothing returns the very same tree
+	res, err = Walk(tree, func(_ []string, v any) (any, error) { return v, nil })
+	require.NoError(t, err)
+	assert.Equal(t, reflect.ValueOf(tree).UnsafePointer(), reflect.ValueOf(res).UnsafePointer())
+}
+
+func TestWalkNaNIsUnchanged(t *testing.T) {
+	tree := []any{math.NaN()}
+	res, err := Walk(tree, func(_ []string, v any) (any, error) { return v, nil })
+	require.NoError(t, err)
+	assert.Same(t, &tree[0], &res.([]any)[0])
+}
+
+func TestWalkSharesTypedLeaves(t *testing.T) {
+	tree := map[string]any{
+		"floats": []float64{1, 2},
+		"counts": map[string]int{"a": 1},
+		"inner":  []any{[]string{"x"}},
+	}
+	res, err := Walk(tree, func(_ []string, v any) (any, error) { return v, nil })
+	require.NoError(t, err)
+	assert.Equal(t, reflect.ValueOf(tree).UnsafePointer(), reflect.ValueOf(res).UnsafePointer(),
+		"unchanged typed slices and maps are not copied")
+
+	// A slice of the same memory but another length is a change.
+	res, err = Walk(tree, func(_ []string, v any) (any, error) {
+		if f, ok := v.([]float64); ok {
+			return f[:1], nil
+		}
+		return v, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []float64{1}, res.(map[string]any)["floats"])
+	assert.Equal(t, []float64{1, 2}, tree["floats"])
+}
+
+func TestWalkAllocations(t *testing.T) {
+	wide := make(map[string]any, 1000)
+	for i := range 1000 {
+		wide[fmt.Sprint("k", i)] = []any{float64(i), "s", nil, map[string]any{"deep": []any{int64(i)}}}
+	}
+	var tree any = []any{wide, make([]any, 5000)}
+	identity := func(_ []string, v any) (any, error) { return v, nil }
+
+	// Walking an unchanged tree copies nothing, and the path costs nothing
+	// for keys and small indices.
+	assert.LessOrEqual(t, testing.AllocsPerRun(10, func() {
+		res, err := Walk(wide, identity)
+		require.NoError(t, err)
+		assert.True(t, identical(wide, res))
+	}), 1.0)
+	// Nor when the path is never needed, whatever the indices.
+	assert.LessOrEqual(t, testing.AllocsPerRun(10, func() {
+		_, changed := WalkDeNaNChanged(tree)
+		assert.False(t, changed)
+	}), 1.0)
+}
+
+func TestWalkVeryDeep(t *testing.T) {
+	const depth = 3 * maxWalkRecursion
+	tree := nestValue(map[string]any{"x": int64(1), "y": "z"}, depth, wrapSlice, wrapMap, wrapOrdered)
+	var visited, pathLen int
+	res, err := Walk(tree, func(path []string, v any) (any, error) {
+		visited++
+		if v == int64(1) {
+			pathLen = len(path)
+			assert.Equal(t, []string{"0", "x"}, path[len(path)-2:])
+			return int64(2), nil
+		}
+		return v, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, depth+3, visited)
+	assert.Equal(t, depth+1, pathLen)
+	assert.Equal(t, nestValue(map[string]any{"x": int64(2), "y": "z"}, depth, wrapSlice, wrapMap, wrapOrdered), res)
+
+	_, err = Walk(tree, func(path []string, v any) (any, error) {
+		if v == "z" {
+			return nil, errors.New("stop")
+		}
+		return v, nil
+	})
+	var walkErr *WalkError
+	require.ErrorAs(t, err, &walkErr)
+	assert.Len(t, walkErr.Path, depth+1)
+}
+
+func TestWalkError(t *testing.T) {
+	tree, err := UnmarshalString(`{"a": {"b": [0, "bad"]}}`)
+	require.NoError(t, err)
+
+	errBad := errors.New("bad value")
+	_, err = Walk(tree, func(_ []string, v any) (any, error) {
+		if v == "bad" {
+			return nil, errBad
+		}
+		return v, nil
+	})
+	var walkErr *WalkError
+	require.ErrorAs(t, err, &walkErr)
+	assert.Equal(t, []string{"a", "b", "1"}, walkErr.Path)
+	assert.ErrorIs(t, err, errBad)
+	assert.EqualError(t, err, `simple json: walk failed at ["a" "b" "1"]: bad value`)
+}
+
+func TestWalkOrderedObject(t *testing.T) {
+	p := NewParserFromString(`{"b": 1, "a": {"c": 2}}`, WithOrderedObjects())
+	tree, err := p.Parse()
+	require.NoError(t, err)
+
+	res, err := Walk(tree, func(path []string, v any) (any, error) {
+		if i, ok := v.(int64); ok {
+			return strings.Join(path, "/") + "=" + string(rune('0'+i)), nil
+		}
+		return v, nil
+	})
+	require.NoError(t, err)
+	out, err := MarshalToString(res)
+	require.NoError(t, err)
+	assert.Equal(t, `{"b":"b=1","a":{"c":"a/c=2"}}`, out)
+
+	out, err = MarshalToString(tree)
+	require.NoError(t, err)
+	assert.Equal(t, `{"b":1,"a":{"c":2}}`, out)
+}